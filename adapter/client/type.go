@@ -9,12 +9,30 @@ const (
 	Timeout   = "Timeout"
 	Keepalive = "keepalive"
 	MsgLen    = "MsgLen"
+	Overflow  = "Overflow"
 
 	SendMsg     = "SendMsg"
 	IsReconnect = "IsReconnect"
 )
 
 type SubscribeData struct {
+	Key    string //频道及交易对, 取消订阅时按 Key 从重连列表删除
 	Action *func(ctx context.Context) error
 	Param  context.Context
 }
+
+// SubscribeKey 频道及交易对组成的订阅 Key
+func SubscribeKey(channel, symbol string) string {
+	return channel + "." + symbol
+}
+
+// RemoveSubscribe 删除 Key 相同的订阅, 返回新的列表
+func RemoveSubscribe(list []SubscribeData, key string) []SubscribeData {
+	res := make([]SubscribeData, 0, len(list))
+	for _, m := range list {
+		if m.Key != key {
+			res = append(res, m)
+		}
+	}
+	return res
+}
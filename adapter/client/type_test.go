@@ -0,0 +1,24 @@
+package client
+
+import "testing"
+
+func TestRemoveSubscribe(t *testing.T) {
+	list := []SubscribeData{
+		{Key: SubscribeKey("futures.tickers", "BTC_USDT")},
+		{Key: SubscribeKey("futures.tickers", "ETH_USDT")},
+		{Key: SubscribeKey("futures.order_book", "BTC_USDT")},
+	}
+	if list[0].Key != "futures.tickers.BTC_USDT" {
+		t.Fatalf("key got %s", list[0].Key)
+	}
+	res := RemoveSubscribe(list, SubscribeKey("futures.tickers", "BTC_USDT"))
+	if len(res) != 2 || res[0].Key != list[1].Key || res[1].Key != list[2].Key {
+		t.Fatalf("remove got %+v", res)
+	}
+	if len(list) != 3 || list[0].Key != "futures.tickers.BTC_USDT" {
+		t.Fatal("source list modified")
+	}
+	if res = RemoveSubscribe(res, "none"); len(res) != 2 {
+		t.Fatalf("remove missing key got %d", len(res))
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"crypto/tls"
 	"net/http"
 	"net/url"
+	"sync/atomic"
 	"time"
 
+	"high-freq-quant-go/adapter/queue"
 	"high-freq-quant-go/adapter/text"
 
 	"high-freq-quant-go/core/log"
@@ -24,10 +26,13 @@ type WssSocket struct {
 	Timeout      time.Duration
 	MsgLen       int64
 	MsgQueue     *chan *[]byte
-	Status       int //0:init 1:opened 2closed 3:reconnecting
-	ReConnectMsg []context.Context
-	ErrorHandle  func(err error) error
+	Overflow     queue.Policy          //MsgQueue 已满时的策略, 原始消息没有 key, Conflate 按 DropOldest 处理
+	Status       int                   //0:init 1:opened 2closed 3:reconnecting
+	ReConnectMsg []context.Context     //ErrorHandle 为空时记录的订阅, 重连后重新发送
+	ErrorHandle  func(err error) error //断线处理, 不为空时由调用方重连并维护订阅列表, 连接不再记录订阅
 	lastResponse int64
+
+	pushed, dropped int64
 }
 
 func NewWssSocket(ctx context.Context, errorHandle func(error) error) (*WssSocket, error) {
@@ -61,6 +66,9 @@ func (sc *WssSocket) Init() {
 	sc.Keepalive = text.GetBool(sc.Ctx, Keepalive)
 	sc.Timeout = time.Duration(text.GetInt64(sc.Ctx, Timeout))
 	sc.MsgLen = text.GetInt64(sc.Ctx, MsgLen)
+	if p, ok := sc.Ctx.Value(Overflow).(queue.Policy); ok {
+		sc.Overflow = p
+	}
 	sc.ReConnectMsg = []context.Context{}
 }
 
@@ -151,9 +159,9 @@ func (sc *WssSocket) ReadMsg() {
 			}
 			switch msgType {
 			case websocket.TextMessage: //文本数据
-				*sc.MsgQueue <- &message
+				sc.push(&message)
 			case websocket.BinaryMessage: //二进制数据
-				*sc.MsgQueue <- &message
+				sc.push(&message)
 			case websocket.CloseMessage: //关闭
 				log.Warnln(log.Wss, sc.Id, "received close")
 			case websocket.PingMessage: //Ping
@@ -167,6 +175,41 @@ func (sc *WssSocket) ReadMsg() {
 	}
 }
 
+func (sc *WssSocket) push(msg *[]byte) {
+	atomic.AddInt64(&sc.pushed, 1)
+	q := *sc.MsgQueue
+	if sc.Overflow == queue.Block {
+		q <- msg
+		return
+	}
+	for {
+		select {
+		case q <- msg:
+			return
+		default:
+		}
+		select {
+		case <-q:
+			atomic.AddInt64(&sc.dropped, 1)
+		default:
+		}
+	}
+}
+
+// Stat 原始消息队列统计
+func (sc *WssSocket) Stat() queue.Stat {
+	st := queue.Stat{
+		Name:    sc.Id,
+		Policy:  sc.Overflow,
+		Pushed:  atomic.LoadInt64(&sc.pushed),
+		Dropped: atomic.LoadInt64(&sc.dropped),
+	}
+	if q := sc.MsgQueue; q != nil {
+		st.Len, st.Cap = len(*q), cap(*q)
+	}
+	return st
+}
+
 func (sc *WssSocket) ErrorReConnect(err error) error {
 	// error handle, no return
 	var reErr error
@@ -201,8 +244,9 @@ func (sc *WssSocket) PingPong() {
 
 }
 
+// RegisterMsg 设置 ErrorHandle 时由调用方在重连后重新订阅, 不再记录
 func (sc *WssSocket) RegisterMsg(param context.Context) {
-	if text.GetBool(param, IsReconnect) {
+	if sc.ErrorHandle != nil || text.GetBool(param, IsReconnect) {
 		return
 	}
 	param = context.WithValue(param, IsReconnect, true)
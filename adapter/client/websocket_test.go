@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// testServer 记录收到的消息, 第一个连接读到第一条消息后断开, 模拟断线
+func testServer(t *testing.T) (*httptest.Server, chan string) {
+	msgs := make(chan string, 10)
+	up := websocket.Upgrader{}
+	var conns int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := up.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		first := atomic.AddInt32(&conns, 1) == 1
+		for {
+			_, msg, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			msgs <- string(msg)
+			if first {
+				return
+			}
+		}
+	}))
+	return srv, msgs
+}
+
+func testCtx(ctx context.Context, srv *httptest.Server) context.Context {
+	ctx = context.WithValue(ctx, WssUrl, "ws"+strings.TrimPrefix(srv.URL, "http"))
+	ctx = context.WithValue(ctx, Timeout, int64(1))
+	return context.WithValue(ctx, MsgLen, int64(10))
+}
+
+func waitMsg(t *testing.T, msgs chan string) string {
+	select {
+	case msg := <-msgs:
+		return msg
+	case <-time.After(3 * time.Second):
+		t.Fatal("message not received")
+		return ""
+	}
+}
+
+// 没有 ErrorHandle 时连接记录订阅, 重连后重新发送
+func TestWssSocketReConnect(t *testing.T) {
+	srv, msgs := testServer(t)
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sc, err := NewWssSocket(testCtx(ctx, srv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sc.SendMsg(context.WithValue(ctx, SendMsg, []byte("sub"))); err != nil {
+		t.Fatal(err)
+	}
+	if msg := waitMsg(t, msgs); msg != "sub" {
+		t.Fatalf("send got %s", msg)
+	}
+	// 服务端断开后重连并重新订阅, 重新发送的订阅不重复记录
+	if msg := waitMsg(t, msgs); msg != "sub" {
+		t.Fatalf("resend got %s", msg)
+	}
+	if len(sc.ReConnectMsg) != 1 {
+		t.Fatalf("reconnect list got %d", len(sc.ReConnectMsg))
+	}
+}
+
+// 设置 ErrorHandle 时重连及重新订阅由调用方处理, 连接不记录订阅
+func TestWssSocketErrorHandle(t *testing.T) {
+	srv, msgs := testServer(t)
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handled := make(chan error, 10)
+	sc, err := NewWssSocket(testCtx(ctx, srv), func(err error) error {
+		handled <- err
+		cancel()
+		return errors.New("closed")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sc.SendMsg(context.WithValue(ctx, SendMsg, []byte("sub"))); err != nil {
+		t.Fatal(err)
+	}
+	waitMsg(t, msgs)
+	select {
+	case <-handled:
+	case <-time.After(3 * time.Second):
+		t.Fatal("error handle not called")
+	}
+	if len(sc.ReConnectMsg) != 0 {
+		t.Fatalf("reconnect list got %d", len(sc.ReConnectMsg))
+	}
+}
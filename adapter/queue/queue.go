@@ -0,0 +1,170 @@
+// Package queue 有界消息队列, 队列已满时按策略阻塞, 丢弃最旧消息或按 key 合并
+package queue
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Policy 队列已满时的处理策略
+type Policy int
+
+const (
+	Block      Policy = iota //阻塞直到消费端读取
+	DropOldest               //丢弃队列中最旧的一条
+	Conflate                 //同一 key 只保留最新一条, 适用于订单薄等全量状态
+)
+
+func (p Policy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case DropOldest:
+		return "drop-oldest"
+	case Conflate:
+		return "conflate"
+	}
+	return "unknown"
+}
+
+// Stat 队列统计
+type Stat struct {
+	Name      string
+	Policy    Policy
+	Len, Cap  int
+	Pushed    int64 //写入次数
+	Dropped   int64 //丢弃的消息数
+	Conflated int64 //被同 key 新消息覆盖的消息数
+}
+
+type Queue struct {
+	Name string
+	C    chan interface{} //消费端读取
+
+	ctx    context.Context
+	policy Policy
+
+	lk     sync.Mutex
+	latest map[string]interface{}
+	keys   []string
+	notify chan struct{}
+
+	pushed, dropped, conflated int64
+}
+
+func New(ctx context.Context, name string, size int64, policy Policy) *Queue {
+	return NewOut(ctx, name, make(chan interface{}, size), policy)
+}
+
+// NewOut 写入已有通道, 如调用方通过 context 传入的 BookDataChannel
+func NewOut(ctx context.Context, name string, out chan interface{}, policy Policy) *Queue {
+	q := &Queue{
+		Name:   name,
+		C:      out,
+		ctx:    ctx,
+		policy: policy,
+	}
+	if policy == Conflate {
+		q.latest = map[string]interface{}{}
+		q.notify = make(chan struct{}, 1)
+		go q.run()
+	}
+	return q
+}
+
+func (q *Queue) Policy() Policy {
+	return q.policy
+}
+
+// Push 按策略写入, key 仅 Conflate 使用, 一般为交易对
+func (q *Queue) Push(key string, v interface{}) {
+	atomic.AddInt64(&q.pushed, 1)
+	switch q.policy {
+	case DropOldest:
+		q.dropOldest(v)
+	case Conflate:
+		q.conflate(key, v)
+	default:
+		select {
+		case q.C <- v:
+		case <-q.ctx.Done():
+		}
+	}
+}
+
+func (q *Queue) dropOldest(v interface{}) {
+	for {
+		select {
+		case q.C <- v:
+			return
+		default:
+		}
+		select {
+		case <-q.C:
+			atomic.AddInt64(&q.dropped, 1)
+		default:
+		}
+	}
+}
+
+func (q *Queue) conflate(key string, v interface{}) {
+	q.lk.Lock()
+	if _, ok := q.latest[key]; ok {
+		atomic.AddInt64(&q.conflated, 1)
+	} else {
+		q.keys = append(q.keys, key)
+	}
+	q.latest[key] = v
+	q.lk.Unlock()
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run 按 key 首次写入的顺序把最新消息转入 C, 转入时阻塞
+func (q *Queue) run() {
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-q.notify:
+		}
+		for {
+			q.lk.Lock()
+			if len(q.keys) == 0 {
+				q.lk.Unlock()
+				break
+			}
+			key := q.keys[0]
+			q.keys = q.keys[1:]
+			v := q.latest[key]
+			delete(q.latest, key)
+			q.lk.Unlock()
+			select {
+			case q.C <- v:
+			case <-q.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (q *Queue) Stat() Stat {
+	n := len(q.C)
+	if q.policy == Conflate {
+		q.lk.Lock()
+		n += len(q.keys)
+		q.lk.Unlock()
+	}
+	return Stat{
+		Name:      q.Name,
+		Policy:    q.policy,
+		Len:       n,
+		Cap:       cap(q.C),
+		Pushed:    atomic.LoadInt64(&q.pushed),
+		Dropped:   atomic.LoadInt64(&q.dropped),
+		Conflated: atomic.LoadInt64(&q.conflated),
+	}
+}
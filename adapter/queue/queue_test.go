@@ -0,0 +1,67 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDropOldest(t *testing.T) {
+	q := New(context.Background(), "depth", 3, DropOldest)
+	for i := 0; i < 10; i++ {
+		q.Push("", i)
+	}
+	for _, want := range []int{7, 8, 9} {
+		if got := (<-q.C).(int); got != want {
+			t.Fatalf("got %d want %d", got, want)
+		}
+	}
+	if st := q.Stat(); st.Dropped != 7 || st.Pushed != 10 || st.Len != 0 {
+		t.Fatalf("stat %+v", st)
+	}
+}
+
+func TestConflate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan interface{})
+	q := NewOut(ctx, "book", out, Conflate)
+	q.Push("BTC", 1)
+	// 等待转发协程取走第一条并阻塞在 out 上
+	deadline := time.Now().Add(time.Second)
+	for q.Stat().Len != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i := 2; i <= 5; i++ {
+		q.Push("BTC", i)
+	}
+	q.Push("ETH", 10)
+	for _, want := range []int{1, 5, 10} {
+		if got := (<-out).(int); got != want {
+			t.Fatalf("got %d want %d", got, want)
+		}
+	}
+	if st := q.Stat(); st.Conflated != 3 || st.Pushed != 6 {
+		t.Fatalf("stat %+v", st)
+	}
+}
+
+func TestBlockDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := New(ctx, "user", 1, Block)
+	q.Push("", 1)
+	done := make(chan struct{})
+	go func() {
+		q.Push("", 2)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Push still blocked after cancel")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"high-freq-quant-go/adapter/sort"
 
 	"high-freq-quant-go/adapter/convert"
+	"high-freq-quant-go/core/exch"
 )
 
 type OrderBook struct {
@@ -26,6 +27,7 @@ type OrderBook struct {
 	ResetTime int64
 	MaxGear   int
 	End       *chan int
+	Scale     exch.Scale //价格刻度, 为空时按原始浮点解析
 }
 
 func NewOrderBook(path string, gear int, ms int64) *OrderBook {
@@ -110,9 +112,22 @@ func (ob *OrderBook) setNextData() {
 	}
 }
 
+// SetScale 设置价格刻度, 一般取 BaseInfo.PriceScale()
+func (ob *OrderBook) SetScale(sc exch.Scale) {
+	ob.Scale = sc
+}
+
+// ParsePrice 按刻度解析价格, 同一档位得到相同的 key
+func (ob *OrderBook) ParsePrice(s string) float64 {
+	if ob.Scale.IsZero() {
+		return convert.GetFloat64(s)
+	}
+	return ob.Scale.Float64(ob.Scale.Parse(s))
+}
+
 func (ob *OrderBook) UpdateOrderBook() {
 	for _, line := range ob.Data {
-		price := convert.GetFloat64(line[2])
+		price := ob.ParsePrice(line[2])
 		size := convert.GetFloat64(line[3])
 		if line[5] == "1" {
 			ob.Create(line[4], price, size)
@@ -0,0 +1,77 @@
+package exch
+
+import (
+	"context"
+	"math"
+
+	"high-freq-quant-go/core/log"
+)
+
+// 改单方式
+const (
+	AmendNative   = "native"         //交易所改单接口, 订单Id不变
+	AmendEmulated = "cancel_replace" //撤单后按剩余数量重新下单, 订单Id改变
+)
+
+// AmendResult 改单结果
+type AmendResult struct {
+	Order     *Order //改单后的订单, 模拟改单且已全部成交时为 nil
+	Cancelled *Order //模拟改单时被撤销的原订单
+	Path      string //AmendNative 或 AmendEmulated
+}
+
+// EmulateAmend 不支持改单的交易所先撤单, 再按新总数量减去已成交数量下单,
+// 新订单沿用原订单的 Tif 及只挂单, 新数量不大于已成交数量时只撤单. 撤单返回的价格或 Tif
+// 未知时不重新下单, 避免按市价成交. 撤单成功而下单失败时返回的结果中 Cancelled 不为空
+func EmulateAmend(ctx context.Context, tr Trader, req *AmendRequest) (*AmendResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	old, err := tr.CancelOrder(ctx, &CancelRequest{Symbol: req.Symbol, Id: req.Id})
+	if err != nil {
+		return nil, err
+	}
+	res := &AmendResult{Cancelled: old, Path: AmendEmulated}
+	size, price := req.Size, req.Price
+	if size == 0 {
+		size = old.Size
+	}
+	if price == 0 {
+		price = old.Price
+	}
+	// 各交易所返回的未成交数量符号不一致, 按绝对值计算
+	filled := math.Abs(old.Size) - math.Abs(old.Left)
+	left := math.Abs(size) - filled
+	if left <= 1e-12 {
+		log.Infoln(log.Conn, req.Symbol, req.Id, "amend cancelled only, filled", filled)
+		return res, nil
+	}
+	if price == 0 || old.Tif == "" {
+		log.Errorln(log.Conn, req.Symbol, req.Id, "amend cancelled but not replaced, price", price, "tif", old.Tif)
+		return res, ErrAmendReplace
+	}
+	if size < 0 {
+		left = -left
+	}
+	uuid := req.UUID
+	if uuid == "" {
+		uuid = NewClientId()
+	}
+	o, err := tr.PlaceOrder(ctx, &OrderRequest{
+		Symbol: req.Symbol,
+		Size:   left,
+		Price:  price,
+		UUID:   uuid,
+		Tif:    old.Tif,
+
+		PostOnly:     old.PostOnly,
+		ReduceOnly:   old.ReduceOnly,
+		PositionSide: old.PositionSide,
+	})
+	if err != nil {
+		log.Errorln(log.Conn, req.Symbol, req.Id, "amend cancelled but replace error", err)
+		return res, err
+	}
+	res.Order = o
+	return res, nil
+}
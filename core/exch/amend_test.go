@@ -0,0 +1,69 @@
+package exch
+
+import (
+	"context"
+	"testing"
+)
+
+type amendMock struct {
+	Exchange
+	order  *Order
+	placed *OrderRequest
+}
+
+func (m *amendMock) CancelOrder(ctx context.Context, req *CancelRequest) (*Order, error) {
+	o := *m.order
+	o.Status = OrderFinished
+	return &o, nil
+}
+
+func (m *amendMock) PlaceOrder(ctx context.Context, req *OrderRequest) (*Order, error) {
+	m.placed = req
+	return req.Order(), nil
+}
+
+func TestEmulateAmend(t *testing.T) {
+	// 空单 -5 已成交 2, 未成交数量按无符号返回
+	m := &amendMock{order: &Order{Id: "1", Symbol: "BTC_USDT", Size: -5, Left: 3, Price: 100, Tif: OrderPoc}}
+	res, err := EmulateAmend(context.Background(), m, &AmendRequest{Symbol: "BTC_USDT", Id: "1", Size: -6})
+	if err != nil || res.Path != AmendEmulated || res.Cancelled == nil {
+		t.Fatalf("amend %+v %v", res, err)
+	}
+	p := m.placed
+	if p.Size != -4 || p.Price != 100 || p.Tif != OrderPoc || p.UUID == "" {
+		t.Fatalf("replace %+v", p)
+	}
+
+	m.placed = nil
+	res, err = EmulateAmend(context.Background(), m, &AmendRequest{Symbol: "BTC_USDT", Id: "1", Size: -2, Price: 101})
+	if err != nil || res.Order != nil || m.placed != nil {
+		t.Fatalf("new size not above filled should only cancel %+v %v", res, err)
+	}
+
+	if _, err = EmulateAmend(context.Background(), m, &AmendRequest{Symbol: "BTC_USDT", Id: "1"}); err != ErrInvalidOrder {
+		t.Fatalf("empty amend got %v", err)
+	}
+}
+
+func TestEmulateAmendSizeOnly(t *testing.T) {
+	// 只改数量, 撤单返回的价格及 poc 沿用到新订单
+	m := &amendMock{order: &Order{Id: "1", Symbol: "BTC_USDT", Size: 5, Left: 5, Price: 100, Tif: OrderPoc, PostOnly: true}}
+	res, err := EmulateAmend(context.Background(), m, &AmendRequest{Symbol: "BTC_USDT", Id: "1", Size: 3})
+	if err != nil || res.Order == nil {
+		t.Fatalf("amend %+v %v", res, err)
+	}
+	if p := m.placed; p.Price != 100 || p.Tif != OrderPoc || !p.PostOnly || p.Size != 3 {
+		t.Fatalf("replace %+v", p)
+	}
+
+	// 撤单返回没有价格及 Tif 时不能按市价重新下单
+	m = &amendMock{order: &Order{Id: "1", Symbol: "BTC_USDT", Size: 5, Left: 5}}
+	res, err = EmulateAmend(context.Background(), m, &AmendRequest{Symbol: "BTC_USDT", Id: "1", Size: 3})
+	if err != ErrAmendReplace || m.placed != nil || res.Cancelled == nil {
+		t.Fatalf("unknown price replaced %+v %v", m.placed, err)
+	}
+	m.order.Price = 100
+	if _, err = EmulateAmend(context.Background(), m, &AmendRequest{Symbol: "BTC_USDT", Id: "1", Size: 3}); err != ErrAmendReplace || m.placed != nil {
+		t.Fatalf("unknown tif replaced %+v %v", m.placed, err)
+	}
+}
@@ -0,0 +1,196 @@
+package exch
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"high-freq-quant-go/adapter/text"
+	"high-freq-quant-go/core/log"
+)
+
+const MapBookerKey = "map"
+
+// Booker 订单薄统一接口
+//
+// 连接器持有 Meta().Rw 写锁批量调用 UpdateAsk/UpdateBid 并更新 UpdateID UpdateTime,
+// 实现可以依赖该锁而不自行加锁; 其余方法自行加锁, 调用时不能持有 Meta().Rw.
+// 调用 UpdateAsk/UpdateBid 前须先 SetBook, GetBook 返回的 asks 升序 bids 降序.
+type Booker interface {
+	Meta() *BookMeta
+
+	SetScale(sc Scale)
+	GetScale() Scale
+	SetBook(askMap, bidMap map[string]float64)
+	UpdateAsk(price string, size float64)
+	UpdateBid(price string, size float64)
+
+	GetAskLen() int
+	GetBidLen() int
+	GetAskMap() map[string]float64
+	GetBidMap() map[string]float64
+	GetBook() ([]float64, []float64, map[float64]float64, map[float64]float64) //asks, bids, askBook, bidBook
+
+	// View 持有读锁调用 fn, fn 内不能调用订单薄的其他方法
+	View(fn func(asks, bids Levels))
+}
+
+// Levels 从最优价开始按价格顺序遍历一边档位, fn 返回 false 停止
+type Levels func(fn func(price, size float64) bool)
+
+// BookLevel 一档价格及数量
+type BookLevel struct {
+	Price float64
+	Size  float64
+}
+
+// TopBooker 支持最优价查询及按调用方缓冲区读取前 N 档的订单薄
+type TopBooker interface {
+	Booker
+	BestAsk() (BookLevel, bool)
+	BestBid() (BookLevel, bool)
+	TopN(asks, bids []BookLevel) (int, int)
+}
+
+// BookMeta 订单薄公共信息, 各实现内嵌
+type BookMeta struct {
+	Name, Symbol   string
+	Exname, Extype string
+
+	IsReady    bool
+	UpdateID   int64
+	ResponTime int64
+	UpdateTime int64
+	Rw         sync.RWMutex //连接器批量更新锁
+
+	hooks []BookHook
+	nhook int32
+	hk    sync.Mutex
+}
+
+// BookUpdate 一批增量, 数量已换算, 数量为 0 时删除该档
+type BookUpdate struct {
+	FirstID, LastID int64 //FirstID 不大于上一批 LastID+1 时视为连续
+	Asks, Bids      map[string]float64
+}
+
+// BookHook 连接器应用一批增量并释放 Meta().Rw 后在订单薄协程调用, 调用期间订单薄不会被修改,
+// 返回 true 时移除
+type BookHook func(bk Booker, u *BookUpdate) bool
+
+// AddHook 如 BookVerifier 记录增量
+func (bm *BookMeta) AddHook(h BookHook) {
+	bm.hk.Lock()
+	defer bm.hk.Unlock()
+	bm.hooks = append(bm.hooks, h)
+	atomic.StoreInt32(&bm.nhook, int32(len(bm.hooks)))
+}
+
+// Hooked 有 BookHook 时连接器才构造 BookUpdate 并调用 RunHooks
+func (bm *BookMeta) Hooked() bool {
+	return atomic.LoadInt32(&bm.nhook) > 0
+}
+
+// RunHooks bk 为 bm 所属的订单薄
+func (bm *BookMeta) RunHooks(bk Booker, u *BookUpdate) {
+	bm.hk.Lock()
+	hooks := bm.hooks
+	bm.hooks = nil
+	bm.hk.Unlock()
+	keep := hooks[:0]
+	for _, h := range hooks {
+		if !h(bk, u) {
+			keep = append(keep, h)
+		}
+	}
+	bm.hk.Lock()
+	bm.hooks = append(keep, bm.hooks...)
+	atomic.StoreInt32(&bm.nhook, int32(len(bm.hooks)))
+	bm.hk.Unlock()
+}
+
+func (bm *BookMeta) InitMeta(ctx context.Context) {
+	bm.Symbol = strings.ToUpper(text.GetString(ctx, CtxSymbol))
+	bm.Exname = strings.ToLower(text.GetString(ctx, CtxExname))
+	bm.Extype = strings.ToLower(text.GetString(ctx, CtxExtype))
+	bm.IsReady = false
+	bm.Name = bm.Exname + "_" + bm.Extype + "_" + bm.Symbol
+}
+
+func (bm *BookMeta) Meta() *BookMeta {
+	return bm
+}
+
+type BookerInstance func(ctx context.Context) Booker
+
+var (
+	AllBooker = map[string]BookerInstance{
+		MapBookerKey: func(ctx context.Context) Booker { return NewMapBooker(ctx) },
+	}
+	symbolBooker = map[string]string{}
+	bookerLock   sync.RWMutex
+)
+
+func RegisterBooker(name string, booker BookerInstance) {
+	if booker == nil {
+		panic("exch.Booker: Register booker is nil")
+	}
+	bookerLock.Lock()
+	defer bookerLock.Unlock()
+	if _, ok := AllBooker[name]; ok {
+		panic("exch.Booker: Register called twice for booker " + name)
+	}
+	AllBooker[name] = booker
+}
+
+// BookerNames 已注册的订单薄实现
+func BookerNames() []string {
+	bookerLock.RLock()
+	defer bookerLock.RUnlock()
+	names := make([]string, 0, len(AllBooker))
+	for n := range AllBooker {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetSymbolBooker 指定交易对使用的订单薄实现, name 为空恢复默认
+func SetSymbolBooker(symbol, name string) {
+	bookerLock.Lock()
+	defer bookerLock.Unlock()
+	symbol = strings.ToUpper(symbol)
+	if name == "" {
+		delete(symbolBooker, symbol)
+		return
+	}
+	symbolBooker[symbol] = name
+}
+
+// BookerKind 订单薄实现, 优先级 CtxBooker > SetSymbolBooker > map
+func BookerKind(ctx context.Context) string {
+	if name := text.GetString(ctx, CtxBooker); name != "" {
+		return name
+	}
+	bookerLock.RLock()
+	defer bookerLock.RUnlock()
+	if name, ok := symbolBooker[strings.ToUpper(text.GetString(ctx, CtxSymbol))]; ok {
+		return name
+	}
+	return MapBookerKey
+}
+
+// NewBooker 按 BookerKind 创建订单薄, 未注册的实现回退到 map
+func NewBooker(ctx context.Context) Booker {
+	name := BookerKind(ctx)
+	bookerLock.RLock()
+	instanceFunc, ok := AllBooker[name]
+	bookerLock.RUnlock()
+	if !ok {
+		log.Errorf(log.Conn, "booker is not register %s (forgot to import?)", name)
+		return NewMapBooker(ctx)
+	}
+	return instanceFunc(ctx)
+}
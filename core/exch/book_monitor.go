@@ -0,0 +1,189 @@
+package exch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cmap "github.com/orcaman/concurrent-map"
+
+	"high-freq-quant-go/adapter/timer"
+	"high-freq-quant-go/core/log"
+)
+
+// 订单薄异常类型
+const (
+	BookStale    = "stale"    //超过 MaxAge 未更新
+	BookCrossed  = "crossed"  //买一价 >= 卖一价
+	BookEmpty    = "empty"    //买卖某一边没有档位
+	BookShallow  = "shallow"  //档位数少于 MinLevels
+	BookDiverged = "diverged" //与 REST 快照不一致, 见 BookVerifier
+)
+
+// BookMonitorConfig 订单薄监控参数, 通过 WithBookMonitor 设置
+type BookMonitorConfig struct {
+	Interval  time.Duration //检查间隔
+	MaxAge    time.Duration //最长未更新时间, <= 0 不检查
+	MinLevels int           //每边最少档位数, <= 0 不检查
+	Cooldown  time.Duration //同一交易对两次重新拉取快照的最短间隔
+}
+
+var DefaultBookMonitor = BookMonitorConfig{
+	Interval: time.Second,
+	MaxAge:   30 * time.Second,
+	Cooldown: 5 * time.Second,
+}
+
+func WithBookMonitor(ctx context.Context, cfg BookMonitorConfig) context.Context {
+	return context.WithValue(ctx, CtxBookMonitor, cfg)
+}
+
+func GetBookMonitor(ctx context.Context) BookMonitorConfig {
+	if ret, ok := ctx.Value(CtxBookMonitor).(BookMonitorConfig); ok {
+		return ret
+	}
+	return DefaultBookMonitor
+}
+
+// BookIssue 订单薄异常事件
+type BookIssue struct {
+	Symbol, Kind   string
+	Age            int64 //距上次更新的毫秒数
+	Ask, Bid       float64
+	AskLen, BidLen int
+	Time           int64
+	Diff           *BookDiff //BookDiverged 时的逐档差异
+}
+
+// CheckBook 检查订单薄, 未初始化或正在初始化的订单薄只检查更新时间
+func CheckBook(bk Booker, cfg BookMonitorConfig, now int64) *BookIssue {
+	bm := bk.Meta()
+	bm.Rw.RLock()
+	ready, updateTime := bm.IsReady, bm.UpdateTime
+	bm.Rw.RUnlock()
+	if updateTime == 0 {
+		return nil
+	}
+	issue := &BookIssue{Symbol: bm.Symbol, Age: now - updateTime, Time: now}
+	if cfg.MaxAge > 0 && issue.Age > cfg.MaxAge.Milliseconds() {
+		issue.Kind = BookStale
+		return issue
+	}
+	if !ready {
+		return nil
+	}
+	issue.AskLen, issue.BidLen = bk.GetAskLen(), bk.GetBidLen()
+	issue.Ask, issue.Bid, _ = BestPrice(bk)
+	switch {
+	case issue.AskLen == 0 || issue.BidLen == 0:
+		issue.Kind = BookEmpty
+	case issue.Bid >= issue.Ask:
+		issue.Kind = BookCrossed
+	case cfg.MinLevels > 0 && (issue.AskLen < cfg.MinLevels || issue.BidLen < cfg.MinLevels):
+		issue.Kind = BookShallow
+	default:
+		return nil
+	}
+	return issue
+}
+
+// BookMonitor 定时检查连接的所有订单薄, 发现异常时标记未就绪并重新拉取快照
+type BookMonitor struct {
+	Sign   string
+	ctx    context.Context
+	cfg    BookMonitorConfig
+	books  cmap.ConcurrentMap
+	resync func(symbol string)
+	feed   *Feed
+
+	lk     sync.Mutex
+	last   map[string]int64
+	counts map[string]int64
+}
+
+// NewBookMonitor books 为连接的交易对订单薄, resync 须在订单薄处理协程中执行 InitOrderbook
+func NewBookMonitor(ctx context.Context, sign string, books cmap.ConcurrentMap, resync func(symbol string)) *BookMonitor {
+	return &BookMonitor{
+		Sign:   sign,
+		ctx:    ctx,
+		cfg:    GetBookMonitor(ctx),
+		books:  books,
+		resync: resync,
+		feed:   GetFeed(ctx),
+		last:   map[string]int64{},
+		counts: map[string]int64{},
+	}
+}
+
+func (m *BookMonitor) Run() {
+	if m.cfg.Interval <= 0 {
+		return
+	}
+	tr := time.NewTicker(m.cfg.Interval)
+	defer tr.Stop()
+	for {
+		select {
+		case <-m.ctx.Done():
+			log.Warnln(log.Wss, m.Sign, " BookMonitor return by done")
+			return
+		case <-tr.C:
+			m.Check(timer.MicNow())
+		}
+	}
+}
+
+// Check 检查一轮, 返回发现的异常
+func (m *BookMonitor) Check(now int64) []*BookIssue {
+	var issues []*BookIssue
+	for item := range m.books.IterBuffered() {
+		bk, ok := item.Val.(Booker)
+		if !ok {
+			continue
+		}
+		issue := CheckBook(bk, m.cfg, now)
+		if issue == nil {
+			continue
+		}
+		issue.Symbol = item.Key
+		issues = append(issues, issue)
+		m.handle(bk, issue)
+	}
+	return issues
+}
+
+// handle 同一交易对在 Cooldown 内只处理一次, 避免快照拉取后仍未恢复时反复告警
+func (m *BookMonitor) handle(bk Booker, issue *BookIssue) {
+	m.lk.Lock()
+	if issue.Time-m.last[issue.Symbol] < m.cfg.Cooldown.Milliseconds() {
+		m.lk.Unlock()
+		return
+	}
+	m.last[issue.Symbol] = issue.Time
+	m.counts[issue.Kind]++
+	m.lk.Unlock()
+
+	log.Warnf(log.Wss, "%s %s book monitor %s age=%dms ask=%v bid=%v levels=%d/%d \r\n",
+		m.Sign, issue.Symbol, issue.Kind, issue.Age, issue.Ask, issue.Bid, issue.AskLen, issue.BidLen)
+	// UpdateID 置 0, 有新消息时连接器也会重新拉取快照
+	bm := bk.Meta()
+	bm.Rw.Lock()
+	bm.IsReady = false
+	bm.UpdateID = 0
+	bm.Rw.Unlock()
+
+	m.feed.PubBookIssue(issue)
+	if m.resync != nil {
+		m.resync(issue.Symbol)
+	}
+}
+
+// Stats 各类异常的累计次数
+func (m *BookMonitor) Stats() map[string]int64 {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	res := make(map[string]int64, len(m.counts))
+	for k, v := range m.counts {
+		res[k] = v
+	}
+	return res
+}
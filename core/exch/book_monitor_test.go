@@ -0,0 +1,85 @@
+package exch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cmap "github.com/orcaman/concurrent-map"
+)
+
+func TestCheckBook(t *testing.T) {
+	cfg := BookMonitorConfig{MaxAge: time.Second, MinLevels: 3}
+	bk := statBook()
+	bm := bk.Meta()
+	if issue := CheckBook(bk, cfg, 1000); issue != nil {
+		t.Fatalf("not initialized got %+v", issue)
+	}
+	bm.UpdateTime = 1000
+	if issue := CheckBook(bk, cfg, 2500); issue == nil || issue.Kind != BookStale || issue.Age != 1500 {
+		t.Fatalf("stale got %+v", issue)
+	}
+	if issue := CheckBook(bk, cfg, 1500); issue != nil {
+		t.Fatalf("not ready got %+v", issue)
+	}
+	bm.IsReady = true
+	if issue := CheckBook(bk, cfg, 1500); issue != nil {
+		t.Fatalf("healthy got %+v", issue)
+	}
+
+	bm.Rw.Lock()
+	bk.UpdateBid("101", 1)
+	bm.Rw.Unlock()
+	if issue := CheckBook(bk, cfg, 1500); issue == nil || issue.Kind != BookCrossed || issue.Bid != 101 {
+		t.Fatalf("crossed got %+v", issue)
+	}
+	bm.Rw.Lock()
+	bk.UpdateBid("101", 0)
+	bk.UpdateAsk("103", 0)
+	bm.Rw.Unlock()
+	if issue := CheckBook(bk, cfg, 1500); issue == nil || issue.Kind != BookShallow || issue.AskLen != 2 {
+		t.Fatalf("shallow got %+v", issue)
+	}
+	bk.SetBook(map[string]float64{}, map[string]float64{"100": 1})
+	if issue := CheckBook(bk, cfg, 1500); issue == nil || issue.Kind != BookEmpty {
+		t.Fatalf("empty got %+v", issue)
+	}
+}
+
+func TestBookMonitor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fd := NewFeed(ctx)
+	ctx = context.WithValue(ctx, CtxFeed, fd)
+	ctx = WithBookMonitor(ctx, BookMonitorConfig{MaxAge: time.Second, Cooldown: time.Second})
+	issues := make(chan *BookIssue, 10)
+	fd.OnBookIssue("BTC_USDT", func(issue *BookIssue) { issues <- issue })
+
+	bk := statBook()
+	bm := bk.Meta()
+	bm.IsReady, bm.UpdateID, bm.UpdateTime = true, 10, 1000
+	books := cmap.New()
+	books.Set("BTC_USDT", bk)
+	var resynced []string
+	m := NewBookMonitor(ctx, "test", books, func(symbol string) { resynced = append(resynced, symbol) })
+
+	if got := m.Check(3000); len(got) != 1 || got[0].Kind != BookStale {
+		t.Fatalf("check got %v", got)
+	}
+	if bm.IsReady || bm.UpdateID != 0 || len(resynced) != 1 {
+		t.Fatalf("after stale ready=%v id=%d resync=%v", bm.IsReady, bm.UpdateID, resynced)
+	}
+	// Cooldown 内不重复处理
+	m.Check(3500)
+	if len(resynced) != 1 || m.Stats()[BookStale] != 1 {
+		t.Fatalf("cooldown resync=%v stats=%v", resynced, m.Stats())
+	}
+	select {
+	case issue := <-issues:
+		if issue.Symbol != "BTC_USDT" || issue.Kind != BookStale {
+			t.Fatalf("event got %+v", issue)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no issue event")
+	}
+}
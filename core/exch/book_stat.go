@@ -0,0 +1,138 @@
+package exch
+
+import "math"
+
+// 订单薄分析, 每个函数只持有一次读锁, 按价格顺序遍历档位不复制整个订单薄
+
+func best(levels Levels) (price, size float64, ok bool) {
+	levels(func(p, s float64) bool {
+		price, size, ok = p, s, true
+		return false
+	})
+	return
+}
+
+// BestPrice 最优卖价及买价
+func BestPrice(bk Booker) (ask, bid float64, ok bool) {
+	bk.View(func(asks, bids Levels) {
+		var aok, bok bool
+		ask, _, aok = best(asks)
+		bid, _, bok = best(bids)
+		ok = aok && bok
+	})
+	return
+}
+
+// SpreadBps 买卖价差, 以中间价的万分之一计
+func SpreadBps(bk Booker) float64 {
+	ask, bid, ok := BestPrice(bk)
+	if !ok || ask+bid == 0 {
+		return 0
+	}
+	return (ask - bid) / ((ask + bid) / 2) * 1e4
+}
+
+// MicroPrice 按最优档数量加权的中间价, 买盘量大时偏向卖价
+func MicroPrice(bk Booker) (micro float64) {
+	bk.View(func(asks, bids Levels) {
+		ask, askSize, aok := best(asks)
+		bid, bidSize, bok := best(bids)
+		if !aok || !bok || askSize+bidSize == 0 {
+			return
+		}
+		micro = (ask*bidSize + bid*askSize) / (askSize + bidSize)
+	})
+	return
+}
+
+// Imbalance 前 n 档买卖量失衡 (bid-ask)/(bid+ask), 取值 [-1, 1], n <= 0 计算全部档位
+func Imbalance(bk Booker, n int) (imb float64) {
+	bk.View(func(asks, bids Levels) {
+		askSize, bidSize := sumTop(asks, n), sumTop(bids, n)
+		if askSize+bidSize == 0 {
+			return
+		}
+		imb = (bidSize - askSize) / (bidSize + askSize)
+	})
+	return
+}
+
+func sumTop(levels Levels, n int) (total float64) {
+	i := 0
+	levels(func(p, s float64) bool {
+		total += s
+		i++
+		return n <= 0 || i < n
+	})
+	return
+}
+
+// DepthWithin 中间价上下 bps 范围内的累计挂单数量
+func DepthWithin(bk Booker, bps float64) (askSize, bidSize float64) {
+	bk.View(func(asks, bids Levels) {
+		ask, _, aok := best(asks)
+		bid, _, bok := best(bids)
+		if !aok || !bok {
+			return
+		}
+		mid := (ask + bid) / 2
+		hi, lo := mid*(1+bps/1e4), mid*(1-bps/1e4)
+		asks(func(p, s float64) bool {
+			if p > hi {
+				return false
+			}
+			askSize += s
+			return true
+		})
+		bids(func(p, s float64) bool {
+			if p < lo {
+				return false
+			}
+			bidSize += s
+			return true
+		})
+	})
+	return
+}
+
+// SweepPrice 市价成交 size 数量的平均价, size > 0 买入吃卖盘, size < 0 卖出吃买盘,
+// filled 为按当前深度可成交的数量
+func SweepPrice(bk Booker, size float64) (avg, filled float64) {
+	return sweep(bk, size, false)
+}
+
+// SweepQuotePrice 市价成交 value 计价金额的平均价, 符号含义同 SweepPrice, filled 为成交数量
+func SweepQuotePrice(bk Booker, value float64) (avg, filled float64) {
+	return sweep(bk, value, true)
+}
+
+func sweep(bk Booker, amount float64, quote bool) (avg, filled float64) {
+	if amount == 0 {
+		return 0, 0
+	}
+	left := math.Abs(amount)
+	var cost float64
+	bk.View(func(asks, bids Levels) {
+		levels := asks
+		if amount < 0 {
+			levels = bids
+		}
+		levels(func(p, s float64) bool {
+			take := s
+			if quote {
+				take = math.Min(s, left/p)
+				left -= take * p
+			} else {
+				take = math.Min(s, left)
+				left -= take
+			}
+			filled += take
+			cost += take * p
+			return left > 0
+		})
+	})
+	if filled == 0 {
+		return 0, 0
+	}
+	return cost / filled, filled
+}
@@ -0,0 +1,58 @@
+package exch
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func statBook() *MapBooker {
+	bk := NewMapBooker(context.Background())
+	bk.SetBook(
+		map[string]float64{"101": 2, "100.5": 1, "103": 5},
+		map[string]float64{"100": 3, "99": 4, "97": 10},
+	)
+	return bk
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestBookStat(t *testing.T) {
+	bk := statBook()
+	if ask, bid, ok := BestPrice(bk); !ok || ask != 100.5 || bid != 100 {
+		t.Fatalf("best got %v %v %v", ask, bid, ok)
+	}
+	if got := SpreadBps(bk); !near(got, 0.5/100.25*1e4) {
+		t.Fatalf("spread got %v", got)
+	}
+	if got := MicroPrice(bk); !near(got, (100.5*3+100*1)/4) {
+		t.Fatalf("micro got %v", got)
+	}
+	if got := Imbalance(bk, 2); !near(got, (7.0-3)/10) {
+		t.Fatalf("imbalance got %v", got)
+	}
+	if a, b := DepthWithin(bk, 150); a != 3 || b != 7 {
+		t.Fatalf("depth got %v %v", a, b)
+	}
+}
+
+func TestSweepPrice(t *testing.T) {
+	bk := statBook()
+	if avg, filled := SweepPrice(bk, 2); filled != 2 || !near(avg, (100.5+101)/2) {
+		t.Fatalf("buy got %v %v", avg, filled)
+	}
+	if avg, filled := SweepPrice(bk, -5); filled != 5 || !near(avg, (100*3+99*2)/5.0) {
+		t.Fatalf("sell got %v %v", avg, filled)
+	}
+	if _, filled := SweepPrice(bk, 100); filled != 8 {
+		t.Fatalf("insufficient depth got %v", filled)
+	}
+	if avg, filled := SweepQuotePrice(bk, 100.5+101); !near(filled, 2) || !near(avg, 100.75) {
+		t.Fatalf("quote got %v %v", avg, filled)
+	}
+	if avg, filled := SweepPrice(NewMapBooker(context.Background()), 1); avg != 0 || filled != 0 {
+		t.Fatalf("empty got %v %v", avg, filled)
+	}
+}
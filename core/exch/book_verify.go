@@ -0,0 +1,422 @@
+package exch
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	cmap "github.com/orcaman/concurrent-map"
+
+	"high-freq-quant-go/core/log"
+)
+
+// BookSnapshot REST 深度快照, 数量已按订单薄的单位换算
+type BookSnapshot struct {
+	Symbol     string
+	UpdateID   int64
+	Time       int64 //交易所返回时间, 毫秒
+	Asks, Bids map[string]float64
+}
+
+// LevelDiff 一档不一致, Remote 或 Local 为 0 表示该档只在另一边存在
+type LevelDiff struct {
+	Ask           bool
+	Price         float64
+	Remote, Local float64
+}
+
+// BookDiff 快照与本地订单薄在快照价格范围内的逐档比较
+type BookDiff struct {
+	Symbol   string
+	UpdateID int64 //快照 id
+	LocalID  int64 //比较时本地订单薄 id, 与 UpdateID 相等时完全对齐
+	Levels   int   //比较的档位数
+	Missing  int   //快照有本地无
+	Extra    int   //本地有快照无
+	Mismatch int   //数量不同
+	Diffs    []LevelDiff
+}
+
+// MaxLevelDiffs BookDiff.Diffs 最多记录的档数
+var MaxLevelDiffs = 20
+
+// Ratio 不一致档位占比
+func (d *BookDiff) Ratio() float64 {
+	if d.Levels == 0 {
+		return 0
+	}
+	return float64(d.Missing+d.Extra+d.Mismatch) / float64(d.Levels)
+}
+
+func (d *BookDiff) add(ask bool, price, remote, local float64) {
+	switch {
+	case local == 0:
+		d.Missing++
+	case remote == 0:
+		d.Extra++
+	default:
+		d.Mismatch++
+	}
+	if len(d.Diffs) < MaxLevelDiffs {
+		d.Diffs = append(d.Diffs, LevelDiff{Ask: ask, Price: price, Remote: remote, Local: local})
+	}
+}
+
+func sizeEqual(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// CompareBook 按订单薄价格刻度逐档比较, 快照通常只有前若干档, 只比较快照最差价以内的档位
+func CompareBook(bk Booker, snap *BookSnapshot) *BookDiff {
+	sc := bk.GetScale()
+	diff := &BookDiff{Symbol: snap.Symbol, UpdateID: snap.UpdateID}
+	ra, rb := fixedLevels(sc, snap.Asks), fixedLevels(sc, snap.Bids)
+	bk.View(func(asks, bids Levels) {
+		diff.LocalID = bk.Meta().UpdateID
+		compareSide(diff, sc, true, ra, asks)
+		compareSide(diff, sc, false, rb, bids)
+	})
+	return diff
+}
+
+func fixedLevels(sc Scale, m map[string]float64) map[Fixed]float64 {
+	levels := make(map[Fixed]float64, len(m))
+	for p, s := range m {
+		if s != 0 {
+			levels[sc.Parse(p)] = s
+		}
+	}
+	return levels
+}
+
+// applyLevels 增量按价格刻度应用到快照档位
+func applyLevels(sc Scale, levels map[Fixed]float64, m map[string]float64) {
+	for p, s := range m {
+		if s == 0 {
+			delete(levels, sc.Parse(p))
+		} else {
+			levels[sc.Parse(p)] = s
+		}
+	}
+}
+
+// compareSide levels 比较时会被修改
+func compareSide(diff *BookDiff, sc Scale, ask bool, levels map[Fixed]float64, local Levels) {
+	if len(levels) == 0 {
+		return
+	}
+	var worst Fixed
+	first := true
+	for t := range levels {
+		if first || (ask && t > worst) || (!ask && t < worst) {
+			worst, first = t, false
+		}
+	}
+	local(func(p, s float64) bool {
+		t := sc.FromFloat(p)
+		if (ask && t > worst) || (!ask && t < worst) {
+			return false
+		}
+		diff.Levels++
+		rs, ok := levels[t]
+		if !ok {
+			diff.add(ask, p, 0, s)
+			return true
+		}
+		delete(levels, t)
+		if !sizeEqual(rs, s) {
+			diff.add(ask, p, rs, s)
+		}
+		return true
+	})
+	for t, rs := range levels {
+		diff.Levels++
+		diff.add(ask, sc.Float64(t), rs, 0)
+	}
+}
+
+// BookVerifyConfig REST 校验参数, 通过 WithBookVerify 设置
+type BookVerifyConfig struct {
+	Interval  time.Duration //每轮校验间隔, <= 0 不校验
+	Tolerance float64       //不一致档位占比超过该值时重建订单薄
+	Wait      time.Duration //等待本地订单薄追上快照 id 的最长时间
+	Buffer    int           //等待快照期间最多记录的增量批数, 超过时跳过本轮
+}
+
+var DefaultBookVerify = BookVerifyConfig{
+	Interval:  time.Minute,
+	Tolerance: 0.2,
+	Wait:      5 * time.Second,
+	Buffer:    1000,
+}
+
+func WithBookVerify(ctx context.Context, cfg BookVerifyConfig) context.Context {
+	return context.WithValue(ctx, CtxBookVerify, cfg)
+}
+
+func GetBookVerify(ctx context.Context) BookVerifyConfig {
+	if ret, ok := ctx.Value(CtxBookVerify).(BookVerifyConfig); ok {
+		return ret
+	}
+	return DefaultBookVerify
+}
+
+// 校验统计
+const (
+	VerifyChecked  = "checked"
+	VerifyDiverged = "diverged"
+	VerifyFailed   = "failed"
+	VerifySkipped  = "skipped"
+)
+
+// BookVerifier 定时拉取 REST 快照, 拉取前开始记录本地增量, 快照返回后按记录的增量重放到本地订单薄的 id,
+// 在同一 id 上逐档比较, 不一致超过容忍度时重建. 增量不连续或记录不全时跳过本轮, 不视为不一致
+type BookVerifier struct {
+	Sign   string
+	ctx    context.Context
+	cfg    BookVerifyConfig
+	books  cmap.ConcurrentMap
+	fetch  func(symbol string) (*BookSnapshot, error)
+	resync func(symbol string)
+	feed   *Feed
+
+	lk     sync.Mutex
+	counts map[string]int64
+}
+
+func NewBookVerifier(ctx context.Context, sign string, books cmap.ConcurrentMap,
+	fetch func(symbol string) (*BookSnapshot, error), resync func(symbol string)) *BookVerifier {
+	return &BookVerifier{
+		Sign:   sign,
+		ctx:    ctx,
+		cfg:    GetBookVerify(ctx),
+		books:  books,
+		fetch:  fetch,
+		resync: resync,
+		feed:   GetFeed(ctx),
+		counts: map[string]int64{},
+	}
+}
+
+func (v *BookVerifier) Run() {
+	if v.cfg.Interval <= 0 {
+		return
+	}
+	tr := time.NewTicker(v.cfg.Interval)
+	defer tr.Stop()
+	for {
+		select {
+		case <-v.ctx.Done():
+			log.Warnln(log.Wss, v.Sign, " BookVerifier return by done")
+			return
+		case <-tr.C:
+			for _, symbol := range v.books.Keys() {
+				v.Verify(symbol)
+			}
+		}
+	}
+}
+
+// Verify 校验一个交易对, 本地订单薄未就绪或在 Wait 内未对齐时返回 ErrBookBehind,
+// 无法对齐到同一 id 时返回 ErrBookUnaligned
+func (v *BookVerifier) Verify(symbol string) (*BookDiff, error) {
+	booki, ok := v.books.Get(symbol)
+	if !ok {
+		v.count(VerifyFailed)
+		return nil, ErrBookBehind
+	}
+	bk := booki.(Booker)
+	bm := bk.Meta()
+	max := v.cfg.Buffer
+	if max <= 0 {
+		max = DefaultBookVerify.Buffer
+	}
+	rp := &bookReplay{sc: bk.GetScale(), max: max, res: make(chan *BookDiff, 1)}
+	// 先挂 hook 再读 id, 之后的增量都会被记录
+	bm.AddHook(rp.hook)
+	bm.Rw.RLock()
+	ready := bm.IsReady
+	rp.base = bm.UpdateID
+	bm.Rw.RUnlock()
+	if !ready {
+		rp.stop()
+		v.count(VerifyFailed)
+		return nil, ErrBookBehind
+	}
+	snap, err := v.fetch(symbol)
+	if err != nil {
+		rp.stop()
+		v.count(VerifyFailed)
+		log.Warnln(log.Wss, v.Sign, symbol, "BookVerifier snapshot error", err)
+		return nil, err
+	}
+	snap.Symbol = symbol
+	rp.start(bk, snap)
+	var diff *BookDiff
+	select {
+	case diff = <-rp.res:
+	case <-time.After(v.cfg.Wait):
+		rp.stop()
+		v.count(VerifyFailed)
+		log.Warnln(log.Wss, v.Sign, symbol, "BookVerifier", ErrBookBehind, snap.UpdateID)
+		return nil, ErrBookBehind
+	case <-v.ctx.Done():
+		rp.stop()
+		return nil, v.ctx.Err()
+	}
+	if diff == nil {
+		v.count(VerifySkipped)
+		log.Debugln(log.Wss, v.Sign, symbol, "BookVerifier skipped, not aligned with snapshot", snap.UpdateID)
+		return nil, ErrBookUnaligned
+	}
+	v.count(VerifyChecked)
+	if diff.Ratio() <= v.cfg.Tolerance {
+		log.Debugf(log.Wss, "%s %s BookVerifier ok id=%d local=%d levels=%d diff=%d \r\n",
+			v.Sign, symbol, diff.UpdateID, diff.LocalID, diff.Levels, len(diff.Diffs))
+		return diff, nil
+	}
+	v.count(VerifyDiverged)
+	log.Warnf(log.Wss, "%s %s BookVerifier diverged id=%d local=%d levels=%d missing=%d extra=%d mismatch=%d %+v \r\n",
+		v.Sign, symbol, diff.UpdateID, diff.LocalID, diff.Levels, diff.Missing, diff.Extra, diff.Mismatch, diff.Diffs)
+	v.feed.PubBookIssue(&BookIssue{Symbol: symbol, Kind: BookDiverged, Time: snap.Time, Diff: diff})
+	// 重建由连接器的订单薄协程完成, 校验不修改订单薄状态
+	if v.resync != nil {
+		v.resync(symbol)
+	}
+	return diff, nil
+}
+
+// bookReplay 快照返回前记录增量, 返回后把快照重放到本地订单薄的 id 再比较,
+// 比较在订单薄读锁内进行, 结果为 nil 表示无法对齐
+type bookReplay struct {
+	lk   sync.Mutex
+	sc   Scale
+	max  int
+	base int64 //开始记录时本地订单薄的 id
+	ups  []*BookUpdate
+	snap *BookSnapshot
+	done bool
+	res  chan *BookDiff
+}
+
+func (r *bookReplay) hook(bk Booker, u *BookUpdate) bool {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	if r.done {
+		return true
+	}
+	if len(r.ups) >= r.max {
+		r.finish(nil)
+		return true
+	}
+	r.ups = append(r.ups, u)
+	if r.snap == nil {
+		return false
+	}
+	return r.try(bk)
+}
+
+// start 快照返回后立即尝试一次, 订单薄没有新增量时也能比较
+func (r *bookReplay) start(bk Booker, snap *BookSnapshot) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	if r.done {
+		return
+	}
+	r.snap = snap
+	r.try(bk)
+}
+
+func (r *bookReplay) stop() {
+	r.lk.Lock()
+	r.done = true
+	r.lk.Unlock()
+}
+
+func (r *bookReplay) finish(diff *BookDiff) {
+	r.done = true
+	r.res <- diff
+}
+
+// try 持有 r.lk 调用, 返回是否结束
+func (r *bookReplay) try(bk Booker) bool {
+	snap := r.snap
+	state := replayWait
+	var diff *BookDiff
+	bk.View(func(asks, bids Levels) {
+		local := bk.Meta().UpdateID
+		if local < snap.UpdateID {
+			return
+		}
+		// 开始记录时本地已越过快照, 无法回到快照的 id
+		if r.base > snap.UpdateID {
+			state = replaySkip
+			return
+		}
+		ra, rb := fixedLevels(r.sc, snap.Asks), fixedLevels(r.sc, snap.Bids)
+		state = r.replay(ra, rb, snap.UpdateID, local)
+		if state != replayAligned {
+			return
+		}
+		diff = &BookDiff{Symbol: snap.Symbol, UpdateID: snap.UpdateID, LocalID: local}
+		compareSide(diff, r.sc, true, ra, asks)
+		compareSide(diff, r.sc, false, rb, bids)
+	})
+	switch state {
+	case replayAligned:
+		r.finish(diff)
+	case replaySkip:
+		r.finish(nil)
+	default:
+		return false
+	}
+	return true
+}
+
+const (
+	replayWait = iota
+	replayAligned
+	replaySkip
+)
+
+// replay 把 id 之后的增量应用到快照档位, 增量须从 id+1 起连续.
+// 本地已更新而 hook 尚未调用时等待下一次 hook
+func (r *bookReplay) replay(asks, bids map[Fixed]float64, id, local int64) int {
+	next := id
+	for _, u := range r.ups {
+		if u.LastID <= next {
+			continue
+		}
+		if u.FirstID > next+1 {
+			return replaySkip
+		}
+		applyLevels(r.sc, asks, u.Asks)
+		applyLevels(r.sc, bids, u.Bids)
+		next = u.LastID
+	}
+	switch {
+	case next == local:
+		return replayAligned
+	case next < local:
+		return replayWait
+	}
+	return replaySkip
+}
+
+func (v *BookVerifier) count(kind string) {
+	v.lk.Lock()
+	v.counts[kind]++
+	v.lk.Unlock()
+}
+
+// Stats 校验次数, 不一致, 跳过及失败次数
+func (v *BookVerifier) Stats() map[string]int64 {
+	v.lk.Lock()
+	defer v.lk.Unlock()
+	res := make(map[string]int64, len(v.counts))
+	for k, n := range v.counts {
+		res[k] = n
+	}
+	return res
+}
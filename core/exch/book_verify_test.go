@@ -0,0 +1,132 @@
+package exch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cmap "github.com/orcaman/concurrent-map"
+)
+
+func TestCompareBook(t *testing.T) {
+	bk := statBook()
+	bk.Meta().UpdateID = 7
+	snap := &BookSnapshot{
+		UpdateID: 7,
+		Asks:     map[string]float64{"100.50": 1, "101": 2.5},
+		Bids:     map[string]float64{"100": 3, "99.5": 1, "99": 4},
+	}
+	diff := CompareBook(bk, snap)
+	// 快照只到 101 及 99, 本地 103 及 97 不比较
+	if diff.LocalID != 7 || diff.Levels != 5 {
+		t.Fatalf("got %+v", diff)
+	}
+	if diff.Mismatch != 1 || diff.Missing != 1 || diff.Extra != 0 {
+		t.Fatalf("got %+v", diff)
+	}
+	if !near(diff.Ratio(), 2.0/5) {
+		t.Fatalf("ratio got %v", diff.Ratio())
+	}
+	for _, d := range diff.Diffs {
+		if d.Ask && (d.Price != 101 || d.Remote != 2.5 || d.Local != 2) {
+			t.Fatalf("ask diff got %+v", d)
+		}
+		if !d.Ask && (d.Price != 99.5 || d.Local != 0) {
+			t.Fatalf("bid diff got %+v", d)
+		}
+	}
+}
+
+// commit 模拟连接器的订单薄协程应用一批增量
+func commit(bk Booker, u *BookUpdate) {
+	bm := bk.Meta()
+	bm.Rw.Lock()
+	for p, s := range u.Asks {
+		bk.UpdateAsk(p, s)
+	}
+	for p, s := range u.Bids {
+		bk.UpdateBid(p, s)
+	}
+	bm.UpdateID = u.LastID
+	bm.Rw.Unlock()
+	if bm.Hooked() {
+		bm.RunHooks(bk, u)
+	}
+}
+
+func TestBookVerifier(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = WithBookVerify(ctx, BookVerifyConfig{Tolerance: 0.2, Wait: 100 * time.Millisecond, Buffer: 100})
+	bk := statBook()
+	bm := bk.Meta()
+	bm.IsReady, bm.UpdateID = true, 5
+	books := cmap.New()
+	books.Set("BTC_USDT", bk)
+
+	snap := &BookSnapshot{UpdateID: 6, Asks: bk.GetAskMap(), Bids: bk.GetBidMap()}
+	var fetched func()
+	fetch := func(symbol string) (*BookSnapshot, error) {
+		cp := *snap
+		if fetched != nil {
+			fetched()
+		}
+		return &cp, nil
+	}
+	var resynced []string
+	v := NewBookVerifier(ctx, "test", books, fetch, func(symbol string) { resynced = append(resynced, symbol) })
+
+	// 本地订单薄追上快照 id 后再比较
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		commit(bk, &BookUpdate{FirstID: 6, LastID: 6})
+	}()
+	diff, err := v.Verify("BTC_USDT")
+	if err != nil || diff.LocalID != 6 || diff.Ratio() != 0 || len(resynced) != 0 {
+		t.Fatalf("aligned got %+v %v %v", diff, err, resynced)
+	}
+
+	// 快照返回前本地已更新, 记录的增量重放到快照上再比较
+	fetched = func() {
+		commit(bk, &BookUpdate{FirstID: 7, LastID: 8, Asks: map[string]float64{"101": 0, "102": 1}})
+	}
+	if diff, err = v.Verify("BTC_USDT"); err != nil || diff.UpdateID != 6 || diff.LocalID != 8 || diff.Ratio() != 0 {
+		t.Fatalf("replay got %+v %v", diff, err)
+	}
+	snap.UpdateID, snap.Asks = 8, bk.GetAskMap()
+
+	// 增量不连续时跳过, 不重建
+	fetched = func() {
+		commit(bk, &BookUpdate{FirstID: 10, LastID: 11, Asks: map[string]float64{"102": 2}})
+	}
+	if _, err = v.Verify("BTC_USDT"); err != ErrBookUnaligned || len(resynced) != 0 {
+		t.Fatalf("gap got %v %v", err, resynced)
+	}
+	// 本地已越过快照时跳过
+	fetched = nil
+	if _, err = v.Verify("BTC_USDT"); err != ErrBookUnaligned || len(resynced) != 0 {
+		t.Fatalf("overshoot got %v %v", err, resynced)
+	}
+
+	snap.UpdateID = 11
+	snap.Asks = map[string]float64{"100.5": 9, "102": 9, "103": 9}
+	if diff, err = v.Verify("BTC_USDT"); err != nil || diff.Mismatch != 3 || len(resynced) != 1 {
+		t.Fatalf("diverged got %+v %v %v", diff, err, resynced)
+	}
+	// 校验不修改订单薄状态, 已结束的 hook 在下一批增量时移除
+	if !bm.IsReady || bm.UpdateID != 11 {
+		t.Fatalf("meta changed ready=%v id=%d", bm.IsReady, bm.UpdateID)
+	}
+	commit(bk, &BookUpdate{FirstID: 12, LastID: 12})
+	if bm.Hooked() {
+		t.Fatal("hook not removed")
+	}
+	snap.UpdateID = 13
+	if _, err = v.Verify("BTC_USDT"); err != ErrBookBehind {
+		t.Fatalf("behind got %v", err)
+	}
+	st := v.Stats()
+	if st[VerifyChecked] != 3 || st[VerifyDiverged] != 1 || st[VerifySkipped] != 2 || st[VerifyFailed] != 1 {
+		t.Fatalf("stats got %v", st)
+	}
+}
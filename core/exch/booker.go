@@ -2,48 +2,79 @@ package exch
 
 import (
 	"context"
-	"strings"
-	"sync"
-
-	"high-freq-quant-go/adapter/convert"
-	"high-freq-quant-go/adapter/sort"
-	"high-freq-quant-go/adapter/text"
+	"sort"
 )
 
-type Booker struct {
-	Name, Symbol   string
-	Exname, Extype string
+// MapBooker 定点价格为键的订单薄, 另外维护按价格排序的档位, 增删档位时二分查找插入, 读取时不再排序
+type MapBooker struct {
+	BookMeta
 
-	asks, bids       []float64
-	askBook, bidBook map[float64]float64
-	cacheTime        int64
+	askMap, bidMap map[Fixed]float64
+	asks, bids     []Fixed //asks 升序, bids 降序
+	scale          Scale
+}
 
-	askMap, bidMap map[string]float64
-	IsReady        bool
-	UpdateID       int64
-	ResponTime     int64
-	UpdateTime     int64
-	Rw             sync.RWMutex
+func NewMapBooker(ctx context.Context) *MapBooker {
+	bk := &MapBooker{scale: DecimalScale(BookFloat)}
+	bk.InitMeta(ctx)
+	return bk
 }
 
-func NewBooker(ctx context.Context) *Booker {
-	bk := Booker{
-		Symbol:  strings.ToUpper(text.GetString(ctx, CtxSymbol)),
-		Exname:  strings.ToLower(text.GetString(ctx, CtxExname)),
-		Extype:  strings.ToLower(text.GetString(ctx, CtxExtype)),
-		IsReady: false,
+// SetScale 设置价格刻度, 一般取 BaseInfo.PriceScale(), 已有档位按新刻度重建
+func (bk *MapBooker) SetScale(sc Scale) {
+	bk.Rw.Lock()
+	defer bk.Rw.Unlock()
+	old := bk.scale
+	bk.scale = sc
+	if bk.askMap == nil || bk.bidMap == nil {
+		return
 	}
-	bk.Name = bk.Exname + "_" + bk.Extype + "_" + bk.Symbol
-	return &bk
+	askMap, bidMap := make(map[Fixed]float64, len(bk.askMap)), make(map[Fixed]float64, len(bk.bidMap))
+	for p, s := range bk.askMap {
+		askMap[sc.FromFloat(old.Float64(p))] = s
+	}
+	for p, s := range bk.bidMap {
+		bidMap[sc.FromFloat(old.Float64(p))] = s
+	}
+	bk.askMap, bk.bidMap = askMap, bidMap
+	bk.asks, bk.bids = sortedPrices(askMap, false), sortedPrices(bidMap, true)
+}
+
+func (bk *MapBooker) GetScale() Scale {
+	bk.Rw.RLock()
+	defer bk.Rw.RUnlock()
+	return bk.scale
 }
 
-func (bk *Booker) SetBook(AskMap, BidMap map[string]float64) {
+func (bk *MapBooker) SetBook(AskMap, BidMap map[string]float64) {
+	askMap, bidMap := make(map[Fixed]float64, len(AskMap)), make(map[Fixed]float64, len(BidMap))
 	bk.Rw.Lock()
 	defer bk.Rw.Unlock()
-	bk.askMap, bk.bidMap = AskMap, BidMap
+	for p, s := range AskMap {
+		if s == 0 {
+			continue
+		}
+		askMap[bk.scale.Parse(p)] = s
+	}
+	for p, s := range BidMap {
+		if s == 0 {
+			continue
+		}
+		bidMap[bk.scale.Parse(p)] = s
+	}
+	bk.askMap, bk.bidMap = askMap, bidMap
+	bk.asks, bk.bids = sortedPrices(askMap, false), sortedPrices(bidMap, true)
+}
+
+func (bk *MapBooker) UpdateAsk(p string, size float64) {
+	bk.UpdateAskFixed(bk.scale.Parse(p), size)
 }
 
-func (bk *Booker) UpdateAsk(price string, size float64) {
+func (bk *MapBooker) UpdateBid(p string, size float64) {
+	bk.UpdateBidFixed(bk.scale.Parse(p), size)
+}
+
+func (bk *MapBooker) UpdateAskFixed(price Fixed, size float64) {
 	isIn := false
 	if _, ok := bk.askMap[price]; ok {
 		isIn = true
@@ -53,12 +84,16 @@ func (bk *Booker) UpdateAsk(price string, size float64) {
 	}
 	if size == 0 && isIn {
 		delete(bk.askMap, price)
+		bk.asks = removePrice(bk.asks, price, false)
 		return
 	}
+	if !isIn {
+		bk.asks = insertPrice(bk.asks, price, false)
+	}
 	bk.askMap[price] = size
 }
 
-func (bk *Booker) UpdateBid(price string, size float64) {
+func (bk *MapBooker) UpdateBidFixed(price Fixed, size float64) {
 	isIn := false
 	if _, ok := bk.bidMap[price]; ok {
 		isIn = true
@@ -68,12 +103,16 @@ func (bk *Booker) UpdateBid(price string, size float64) {
 	}
 	if size == 0 && isIn {
 		delete(bk.bidMap, price)
+		bk.bids = removePrice(bk.bids, price, true)
 		return
 	}
+	if !isIn {
+		bk.bids = insertPrice(bk.bids, price, true)
+	}
 	bk.bidMap[price] = size
 }
 
-func (bk *Booker) GetAskLen() int {
+func (bk *MapBooker) GetAskLen() int {
 	bk.Rw.RLock()
 	defer bk.Rw.RUnlock()
 	if bk.askMap == nil {
@@ -82,7 +121,7 @@ func (bk *Booker) GetAskLen() int {
 	return len(bk.askMap)
 }
 
-func (bk *Booker) GetBidLen() int {
+func (bk *MapBooker) GetBidLen() int {
 	bk.Rw.RLock()
 	defer bk.Rw.RUnlock()
 	if bk.bidMap == nil {
@@ -91,64 +130,108 @@ func (bk *Booker) GetBidLen() int {
 	return len(bk.bidMap)
 }
 
-func (bk *Booker) GetAskMap() map[string]float64 {
+func (bk *MapBooker) GetAskMap() map[string]float64 {
 	bk.Rw.RLock()
 	defer bk.Rw.RUnlock()
 	askMap := map[string]float64{}
 	if bk.askMap != nil {
 		for p, s := range bk.askMap {
-			askMap[p] = s
+			askMap[bk.scale.String(p)] = s
 		}
 	}
 	return askMap
 }
 
-func (bk *Booker) GetBidMap() map[string]float64 {
+func (bk *MapBooker) GetBidMap() map[string]float64 {
 	bk.Rw.RLock()
 	defer bk.Rw.RUnlock()
 	bidMap := map[string]float64{}
 	if bk.bidMap != nil {
 		for p, s := range bk.bidMap {
-			bidMap[p] = s
+			bidMap[bk.scale.String(p)] = s
 		}
 	}
 	return bidMap
 }
 
 //asks, bids, askBook, bidBook
-func (bk *Booker) GetBook() ([]float64, []float64, map[float64]float64, map[float64]float64) {
-	asks, bids := []float64{}, []float64{}
-	askBook, bidBook := map[float64]float64{}, map[float64]float64{}
+func (bk *MapBooker) GetBook() ([]float64, []float64, map[float64]float64, map[float64]float64) {
 	bk.Rw.RLock()
 	defer bk.Rw.RUnlock()
 	if bk.askMap == nil || bk.bidMap == nil {
-		return asks, bids, askBook, bidBook
+		return []float64{}, []float64{}, map[float64]float64{}, map[float64]float64{}
 	}
-	var wg sync.WaitGroup
-	wg.Add(2)
-	go func(asks *[]float64, askBook map[float64]float64, wg *sync.WaitGroup) {
-		defer wg.Done()
-		for p, s := range bk.askMap {
-			fp := convert.GetFloat64(p)
-			*asks = append(*asks, fp)
-			askBook[fp] = s
+	asks, askBook := bk.book(bk.asks, bk.askMap)
+	bids, bidBook := bk.book(bk.bids, bk.bidMap)
+	return asks, bids, askBook, bidBook
+}
+
+func (bk *MapBooker) book(prices []Fixed, levels map[Fixed]float64) ([]float64, map[float64]float64) {
+	list, book := make([]float64, 0, len(prices)), make(map[float64]float64, len(prices))
+	for _, p := range prices {
+		fp := bk.scale.Float64(p)
+		list = append(list, fp)
+		book[fp] = levels[p]
+	}
+	return list, book
+}
+
+func (bk *MapBooker) View(fn func(asks, bids Levels)) {
+	bk.Rw.RLock()
+	defer bk.Rw.RUnlock()
+	fn(bk.levels(bk.asks, bk.askMap), bk.levels(bk.bids, bk.bidMap))
+}
+
+func (bk *MapBooker) levels(prices []Fixed, book map[Fixed]float64) Levels {
+	return func(fn func(price, size float64) bool) {
+		for _, p := range prices {
+			if !fn(bk.scale.Float64(p), book[p]) {
+				return
+			}
 		}
-	}(&asks, askBook, &wg)
-	go func(bids *[]float64, bidBook map[float64]float64, wg *sync.WaitGroup) {
-		defer wg.Done()
-		for p, s := range bk.bidMap {
-			fp := convert.GetFloat64(p)
-			*bids = append(*bids, fp)
-			bidBook[fp] = s
+	}
+}
+
+// sortedPrices 档位价格排序, desc 为 true 时降序
+func sortedPrices(book map[Fixed]float64, desc bool) []Fixed {
+	prices := make([]Fixed, 0, len(book))
+	for p := range book {
+		prices = append(prices, p)
+	}
+	sort.Slice(prices, func(i, j int) bool {
+		if desc {
+			return prices[i] > prices[j]
+		}
+		return prices[i] < prices[j]
+	})
+	return prices
+}
+
+// searchPrice 价格在排序档位中的位置, 不存在时为插入位置
+func searchPrice(prices []Fixed, p Fixed, desc bool) int {
+	return sort.Search(len(prices), func(i int) bool {
+		if desc {
+			return prices[i] <= p
 		}
-	}(&bids, bidBook, &wg)
-	wg.Wait()
-	if bk.cacheTime != bk.UpdateTime {
-		sort.SortSlice(asks, 0, len(asks)-1)
-		sort.RSortSlice(bids, 0, len(bids)-1)
-		bk.asks, bk.bids, bk.cacheTime = asks, bids, bk.UpdateTime
-	} else {
-		asks, bids = bk.asks, bk.bids
+		return prices[i] >= p
+	})
+}
+
+func insertPrice(prices []Fixed, p Fixed, desc bool) []Fixed {
+	i := searchPrice(prices, p, desc)
+	if i < len(prices) && prices[i] == p {
+		return prices
 	}
-	return asks, bids, askBook, bidBook
+	prices = append(prices, 0)
+	copy(prices[i+1:], prices[i:])
+	prices[i] = p
+	return prices
+}
+
+func removePrice(prices []Fixed, p Fixed, desc bool) []Fixed {
+	i := searchPrice(prices, p, desc)
+	if i == len(prices) || prices[i] != p {
+		return prices
+	}
+	return append(prices[:i], prices[i+1:]...)
 }
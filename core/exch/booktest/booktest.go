@@ -0,0 +1,223 @@
+// Package booktest 订单薄实现的一致性测试及回放基准
+package booktest
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"high-freq-quant-go/core/exch"
+)
+
+func newBook(f exch.BookerInstance) exch.Booker {
+	ctx := exch.WithSymbol(context.Background(), "btc_usdt")
+	ctx = context.WithValue(ctx, exch.CtxExname, exch.Gate)
+	ctx = context.WithValue(ctx, exch.CtxExtype, exch.Futures)
+	return f(ctx)
+}
+
+// RunConformance 所有 exch.Booker 实现需通过的行为测试
+func RunConformance(t *testing.T, f exch.BookerInstance) {
+	t.Run("Meta", func(t *testing.T) {
+		bm := newBook(f).Meta()
+		if bm.Symbol != "BTC_USDT" || bm.Name != "gate_futures_BTC_USDT" || bm.IsReady {
+			t.Fatalf("meta got %+v", bm)
+		}
+	})
+	t.Run("Empty", func(t *testing.T) {
+		bk := newBook(f)
+		asks, bids, askBook, bidBook := bk.GetBook()
+		if len(asks)+len(bids)+len(askBook)+len(bidBook) != 0 || bk.GetAskLen() != 0 || bk.GetBidLen() != 0 {
+			t.Fatal("new book should be empty")
+		}
+	})
+	t.Run("SetBook", func(t *testing.T) {
+		bk := newBook(f)
+		bk.SetBook(map[string]float64{"101": 1, "100.5": 2, "102": 0}, map[string]float64{"99": 3, "100": 4})
+		Apply(bk, &DepthUpdate{}, 1)
+		asks, bids, askBook, bidBook := bk.GetBook()
+		if !equal(asks, []float64{100.5, 101}) || !equal(bids, []float64{100, 99}) {
+			t.Fatalf("got asks %v bids %v", asks, bids)
+		}
+		if askBook[100.5] != 2 || bidBook[99] != 3 || bk.GetAskLen() != 2 || bk.GetBidLen() != 2 {
+			t.Fatalf("got askBook %v bidBook %v", askBook, bidBook)
+		}
+	})
+	t.Run("Update", func(t *testing.T) {
+		bk := newBook(f)
+		Apply(bk, &DepthUpdate{Snapshot: true, Asks: []Level{{"101", 1}}, Bids: []Level{{"99", 1}}}, 1)
+		Apply(bk, &DepthUpdate{
+			Asks: []Level{{"100.5", 2}, {"101", 0}, {"103", 0}, {"102", 5}},
+			Bids: []Level{{"99.5", 2}, {"99", 7}},
+		}, 2)
+		asks, bids, askBook, bidBook := bk.GetBook()
+		if !equal(asks, []float64{100.5, 102}) || !equal(bids, []float64{99.5, 99}) {
+			t.Fatalf("got asks %v bids %v", asks, bids)
+		}
+		if _, ok := askBook[101]; ok || askBook[102] != 5 || bidBook[99] != 7 {
+			t.Fatalf("got askBook %v bidBook %v", askBook, bidBook)
+		}
+		if bk.Meta().UpdateID != 1 {
+			t.Fatalf("update id got %d", bk.Meta().UpdateID)
+		}
+	})
+	t.Run("PriceKey", func(t *testing.T) {
+		bk := newBook(f)
+		Apply(bk, &DepthUpdate{Snapshot: true, Asks: []Level{{"0.10", 1}}, Bids: []Level{{"0.090", 1}}}, 1)
+		Apply(bk, &DepthUpdate{Asks: []Level{{"0.1", 3}}, Bids: []Level{{"0.09", 0}}}, 2)
+		if m := bk.GetAskMap(); len(m) != 1 || m["0.1"] != 3 {
+			t.Fatalf("got askMap %v", m)
+		}
+		if m := bk.GetBidMap(); len(m) != 0 {
+			t.Fatalf("got bidMap %v", m)
+		}
+	})
+	t.Run("SetScale", func(t *testing.T) {
+		bk := newBook(f)
+		bk.SetScale(exch.NewScale(0.5))
+		if bk.GetScale() != exch.NewScale(0.5) {
+			t.Fatalf("scale got %+v", bk.GetScale())
+		}
+		Apply(bk, &DepthUpdate{Snapshot: true, Asks: []Level{{"100.5", 1}}, Bids: []Level{{"99", 1}}}, 1)
+		Apply(bk, &DepthUpdate{Asks: []Level{{"100.50", 2}}}, 2)
+		asks, _, askBook, _ := bk.GetBook()
+		if !equal(asks, []float64{100.5}) || askBook[100.5] != 2 {
+			t.Fatalf("got asks %v book %v", asks, askBook)
+		}
+	})
+	t.Run("Replay", func(t *testing.T) {
+		updates := GenDepth(2000, 1)
+		bk, ref := newBook(f), newBook(f)
+		for i, du := range updates {
+			Apply(bk, du, int64(i+1))
+		}
+		want := replayRef(ref, updates)
+		asks, bids, askBook, bidBook := bk.GetBook()
+		if !equal(asks, want.asks) || !equal(bids, want.bids) {
+			t.Fatalf("replay asks %d bids %d, want %d %d", len(asks), len(bids), len(want.asks), len(want.bids))
+		}
+		for _, p := range want.asks {
+			if askBook[p] != want.askBook[p] {
+				t.Fatalf("ask %v got %v want %v", p, askBook[p], want.askBook[p])
+			}
+		}
+		for _, p := range want.bids {
+			if bidBook[p] != want.bidBook[p] {
+				t.Fatalf("bid %v got %v want %v", p, bidBook[p], want.bidBook[p])
+			}
+		}
+	})
+	t.Run("View", func(t *testing.T) {
+		bk := newBook(f)
+		for i, du := range GenDepth(200, 4) {
+			Apply(bk, du, int64(i+1))
+		}
+		asks, bids, askBook, bidBook := bk.GetBook()
+		var vasks, vbids []float64
+		bk.View(func(al, bl exch.Levels) {
+			al(func(p, s float64) bool {
+				if s != askBook[p] {
+					t.Errorf("ask %v size %v want %v", p, s, askBook[p])
+				}
+				vasks = append(vasks, p)
+				return true
+			})
+			bl(func(p, s float64) bool {
+				if s != bidBook[p] {
+					t.Errorf("bid %v size %v want %v", p, s, bidBook[p])
+				}
+				vbids = append(vbids, p)
+				return len(vbids) < 3
+			})
+		})
+		if !equal(vasks, asks) || !equal(vbids, bids[:3]) {
+			t.Fatalf("view asks %d bids %v, want %d %v", len(vasks), vbids, len(asks), bids[:3])
+		}
+	})
+	t.Run("Concurrent", func(t *testing.T) {
+		bk := newBook(f)
+		updates := GenDepth(500, 2)
+		Apply(bk, updates[0], 1)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i, du := range updates[1:] {
+				Apply(bk, du, int64(i+2))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range updates {
+				bk.GetBook()
+				bk.GetAskLen()
+				bk.GetBidMap()
+				exch.SweepPrice(bk, 100)
+			}
+		}()
+		wg.Wait()
+	})
+}
+
+type refBook struct {
+	asks, bids       []float64
+	askBook, bidBook map[float64]float64
+}
+
+// replayRef 用普通 map 重放得到期望结果, 价格按默认刻度对齐
+func replayRef(bk exch.Booker, updates []*DepthUpdate) *refBook {
+	sc := bk.GetScale()
+	ref := &refBook{askBook: map[float64]float64{}, bidBook: map[float64]float64{}}
+	apply := func(book map[float64]float64, levels []Level) {
+		for _, l := range levels {
+			p := sc.Float64(sc.Parse(l.Price))
+			if l.Size == 0 {
+				delete(book, p)
+				continue
+			}
+			book[p] = l.Size
+		}
+	}
+	for _, du := range updates {
+		if du.Snapshot {
+			ref.askBook, ref.bidBook = map[float64]float64{}, map[float64]float64{}
+		}
+		apply(ref.askBook, du.Asks)
+		apply(ref.bidBook, du.Bids)
+	}
+	for p := range ref.askBook {
+		ref.asks = append(ref.asks, p)
+	}
+	for p := range ref.bidBook {
+		ref.bids = append(ref.bids, p)
+	}
+	sort.Float64s(ref.asks)
+	sort.Sort(sort.Reverse(sort.Float64Slice(ref.bids)))
+	return ref
+}
+
+// RunReplay 按刻度 sc 回放深度记录, 每条记录后读取一次订单薄
+func RunReplay(b *testing.B, f exch.BookerInstance, sc exch.Scale, updates []*DepthUpdate) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		bk := newBook(f)
+		bk.SetScale(sc)
+		for j, du := range updates {
+			Apply(bk, du, int64(j+1))
+			bk.GetBook()
+		}
+	}
+}
+
+func equal(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
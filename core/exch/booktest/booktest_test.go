@@ -0,0 +1,55 @@
+package booktest
+
+import (
+	"os"
+	"testing"
+
+	"high-freq-quant-go/adapter/convert"
+	"high-freq-quant-go/core/exch"
+	_ "high-freq-quant-go/core/exch/cbooker"
+	_ "high-freq-quant-go/core/exch/mbooker"
+	_ "high-freq-quant-go/core/exch/tbooker"
+)
+
+func TestConformance(t *testing.T) {
+	for _, name := range exch.BookerNames() {
+		t.Run(name, func(t *testing.T) {
+			RunConformance(t, exch.AllBooker[name])
+		})
+	}
+}
+
+// BenchmarkReplay 设置 BOOK_DEPTH_FILE 及 BOOK_DEPTH_STEP 回放录制的深度, 否则使用生成数据
+func BenchmarkReplay(b *testing.B) {
+	updates, sc := GenDepth(5000, 1), exch.NewScale(0.01)
+	if path := os.Getenv("BOOK_DEPTH_FILE"); path != "" {
+		var err error
+		if updates, err = ReadDepthFile(path); err != nil {
+			b.Fatal(err)
+		}
+		sc = exch.NewScale(convert.GetFloat64(os.Getenv("BOOK_DEPTH_STEP")))
+	}
+	for _, name := range exch.BookerNames() {
+		b.Run(name, func(b *testing.B) {
+			RunReplay(b, exch.AllBooker[name], sc, updates)
+		})
+	}
+}
+
+// BenchmarkReplayView 每条记录后通过 View 读取前几档, 档位增删频繁时不应每次重新排序
+func BenchmarkReplayView(b *testing.B) {
+	updates, sc := GenDepth(5000, 1), exch.NewScale(0.01)
+	for _, name := range exch.BookerNames() {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bk := newBook(exch.AllBooker[name])
+				bk.SetScale(sc)
+				for j, du := range updates {
+					Apply(bk, du, int64(j+1))
+					exch.SweepPrice(bk, 10)
+				}
+			}
+		})
+	}
+}
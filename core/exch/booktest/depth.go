@@ -0,0 +1,124 @@
+package booktest
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+
+	"high-freq-quant-go/core/exch"
+)
+
+// Level 一档深度, Size 为 0 表示删除
+type Level struct {
+	Price string  `json:"p"`
+	Size  float64 `json:"s"`
+}
+
+// DepthUpdate 一条深度记录, Snapshot 为全量快照, 否则为增量
+type DepthUpdate struct {
+	Snapshot bool    `json:"snapshot,omitempty"`
+	Asks     []Level `json:"a"`
+	Bids     []Level `json:"b"`
+}
+
+// ReadDepth 按行读取 json 格式的深度记录
+func ReadDepth(r io.Reader) ([]*DepthUpdate, error) {
+	updates := []*DepthUpdate{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		du := &DepthUpdate{}
+		if err := json.Unmarshal(sc.Bytes(), du); err != nil {
+			return nil, err
+		}
+		updates = append(updates, du)
+	}
+	return updates, sc.Err()
+}
+
+func ReadDepthFile(path string) ([]*DepthUpdate, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadDepth(f)
+}
+
+// WriteDepth 每条记录写一行 json, 用于录制连接器收到的深度
+func WriteDepth(w io.Writer, updates ...*DepthUpdate) error {
+	enc := json.NewEncoder(w)
+	for _, du := range updates {
+		if err := enc.Encode(du); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GenDepth 生成一个快照加 n 条增量的随机游走深度, 没有录制文件时使用
+func GenDepth(n int, seed int64) []*DepthUpdate {
+	rd := rand.New(rand.NewSource(seed))
+	const step, levels = 0.01, 200
+	mid := 30000.0
+	price := func(p float64) string {
+		return strconv.FormatFloat(p, 'f', 2, 64)
+	}
+	snap := &DepthUpdate{Snapshot: true}
+	for i := 1; i <= levels; i++ {
+		snap.Asks = append(snap.Asks, Level{price(mid + float64(i)*step), float64(rd.Intn(1000) + 1)})
+		snap.Bids = append(snap.Bids, Level{price(mid - float64(i)*step), float64(rd.Intn(1000) + 1)})
+	}
+	updates := []*DepthUpdate{snap}
+	for i := 0; i < n; i++ {
+		mid += float64(rd.Intn(3)-1) * step
+		du := &DepthUpdate{}
+		for j := rd.Intn(10) + 1; j > 0; j-- {
+			off := float64(rd.Intn(levels)+1) * step
+			size := float64(rd.Intn(1000))
+			if rd.Intn(4) == 0 {
+				size = 0
+			}
+			du.Asks = append(du.Asks, Level{price(mid + off), size})
+			du.Bids = append(du.Bids, Level{price(mid - off), size})
+		}
+		updates = append(updates, du)
+	}
+	return updates
+}
+
+// Apply 按连接器的方式把一条记录写入订单薄
+func Apply(bk exch.Booker, du *DepthUpdate, updateTime int64) {
+	if du.Snapshot {
+		askMap, bidMap := make(map[string]float64, len(du.Asks)), make(map[string]float64, len(du.Bids))
+		for _, l := range du.Asks {
+			askMap[l.Price] = l.Size
+		}
+		for _, l := range du.Bids {
+			bidMap[l.Price] = l.Size
+		}
+		bk.SetBook(askMap, bidMap)
+		bm := bk.Meta()
+		bm.Rw.Lock()
+		bm.UpdateTime = updateTime
+		bm.Rw.Unlock()
+		return
+	}
+	bm := bk.Meta()
+	bm.Rw.Lock()
+	for _, l := range du.Bids {
+		bk.UpdateBid(l.Price, l.Size)
+	}
+	for _, l := range du.Asks {
+		bk.UpdateAsk(l.Price, l.Size)
+	}
+	bm.UpdateID++
+	bm.UpdateTime = updateTime
+	bm.Rw.Unlock()
+}
@@ -2,43 +2,54 @@ package cbooker
 
 import (
 	"context"
-	"strings"
 	"sync"
 
 	"high-freq-quant-go/core/exch"
 
-	"high-freq-quant-go/adapter/convert"
-
-	"high-freq-quant-go/adapter/text"
-
 	"high-freq-quant-go/adapter/sort"
 	cmap "github.com/orcaman/concurrent-map"
 )
 
+const BookerKey = "cbooker"
+
 type Booker struct {
-	Name, Symbol   string
-	Exname, Extype string
+	exch.BookMeta
 	asks, bids     []float64
 	askMap, bidMap *cmap.ConcurrentMap
-	IsReady        bool
-	UpdateID       int64
-	ResponTime     int64
-	UpdateTime     int64
+	scale          exch.Scale
 	rw             sync.RWMutex
 }
 
 func NewBooker(ctx context.Context) *Booker {
-	bk := Booker{
-		Symbol:  strings.ToUpper(text.GetString(ctx, exch.CtxSymbol)),
-		Exname:  strings.ToLower(text.GetString(ctx, exch.CtxExname)),
-		Extype:  strings.ToLower(text.GetString(ctx, exch.CtxExtype)),
-		IsReady: false,
+	askMap, bidMap := cmap.New(), cmap.New()
+	bk := &Booker{
+		askMap: &askMap,
+		bidMap: &bidMap,
+		scale:  exch.DecimalScale(exch.BookFloat),
 	}
-	bk.Name = bk.Exname + "_" + bk.Extype + "_" + bk.Symbol
-	return &bk
+	bk.InitMeta(ctx)
+	return bk
+}
+
+// SetScale 设置价格刻度, 已有档位按新刻度重建
+func (bk *Booker) SetScale(sc exch.Scale) {
+	askData, bidData := bk.GetAskMap(), bk.GetBidMap()
+	bk.rw.Lock()
+	bk.scale = sc
+	bk.rw.Unlock()
+	bk.SetBook(askData, bidData)
+}
+
+func (bk *Booker) GetScale() exch.Scale {
+	bk.rw.RLock()
+	defer bk.rw.RUnlock()
+	return bk.scale
 }
 
 func (bk *Booker) SetBook(AskData, BidData map[string]float64) {
+	bk.rw.RLock()
+	sc := bk.scale
+	bk.rw.RUnlock()
 	var asks, bids []float64
 	askMap, bidMap := cmap.New(), cmap.New()
 
@@ -47,23 +58,29 @@ func (bk *Booker) SetBook(AskData, BidData map[string]float64) {
 	go func(asks *[]float64, askMap *cmap.ConcurrentMap, AskData map[string]float64, wg *sync.WaitGroup) {
 		defer wg.Done()
 		for p, s := range AskData {
-			fp := convert.GetFloat64(p)
+			fp := sc.Parse(p)
 			if fp == 0 || s == 0 {
 				continue
 			}
-			*asks = append(*asks, fp)
-			askMap.Set(p, s)
+			key := sc.String(fp)
+			if _, ok := askMap.Get(key); !ok {
+				*asks = append(*asks, sc.Float64(fp))
+			}
+			askMap.Set(key, s)
 		}
 	}(&asks, &askMap, AskData, &wg)
 	go func(bids *[]float64, bidMap *cmap.ConcurrentMap, BidData map[string]float64, wg *sync.WaitGroup) {
 		defer wg.Done()
 		for p, s := range BidData {
-			fp := convert.GetFloat64(p)
+			fp := sc.Parse(p)
 			if fp == 0 || s == 0 {
 				continue
 			}
-			*bids = append(*bids, fp)
-			bidMap.Set(p, s)
+			key := sc.String(fp)
+			if _, ok := bidMap.Get(key); !ok {
+				*bids = append(*bids, sc.Float64(fp))
+			}
+			bidMap.Set(key, s)
 		}
 	}(&bids, &bidMap, BidData, &wg)
 	wg.Wait()
@@ -76,9 +93,10 @@ func (bk *Booker) SetBook(AskData, BidData map[string]float64) {
 }
 
 func (bk *Booker) UpdateAsk(price string, size float64) {
-	p := convert.GetFloat64(price)
 	bk.rw.Lock()
 	defer bk.rw.Unlock()
+	fp := bk.scale.Parse(price)
+	p, price := bk.scale.Float64(fp), bk.scale.String(fp)
 	isIn := false
 	if _, ok := bk.askMap.Get(price); ok {
 		isIn = true
@@ -98,9 +116,10 @@ func (bk *Booker) UpdateAsk(price string, size float64) {
 }
 
 func (bk *Booker) UpdateBid(price string, size float64) {
-	p := convert.GetFloat64(price)
 	bk.rw.Lock()
 	defer bk.rw.Unlock()
+	fp := bk.scale.Parse(price)
+	p, price := bk.scale.Float64(fp), bk.scale.String(fp)
 	isIn := false
 	if _, ok := bk.bidMap.Get(price); ok {
 		isIn = true
@@ -140,7 +159,7 @@ func (bk *Booker) GetAskBook() map[float64]float64 {
 	defer bk.rw.RUnlock()
 	askBook := map[float64]float64{}
 	for p, s := range bk.askMap.Items() {
-		sp := convert.GetFloat64(p)
+		sp := bk.scale.Float64(bk.scale.Parse(p))
 		askBook[sp] = s.(float64)
 	}
 	return askBook
@@ -151,19 +170,30 @@ func (bk *Booker) GetBidBook() map[float64]float64 {
 	defer bk.rw.RUnlock()
 	bidBook := map[float64]float64{}
 	for p, s := range bk.bidMap.Items() {
-		sp := convert.GetFloat64(p)
+		sp := bk.scale.Float64(bk.scale.Parse(p))
 		bidBook[sp] = s.(float64)
 	}
 	return bidBook
 }
 
+func (bk *Booker) GetAskLen() int {
+	bk.rw.RLock()
+	defer bk.rw.RUnlock()
+	return bk.askMap.Count()
+}
+
+func (bk *Booker) GetBidLen() int {
+	bk.rw.RLock()
+	defer bk.rw.RUnlock()
+	return bk.bidMap.Count()
+}
+
 func (bk *Booker) GetAskMap() map[string]float64 {
 	bk.rw.RLock()
 	defer bk.rw.RUnlock()
 	askMap := map[string]float64{}
 	for p, s := range bk.askMap.Items() {
-		sp := convert.GetString(p)
-		askMap[sp] = s.(float64)
+		askMap[p] = s.(float64)
 	}
 	return askMap
 }
@@ -173,8 +203,7 @@ func (bk *Booker) GetBidMap() map[string]float64 {
 	defer bk.rw.RUnlock()
 	bidMap := map[string]float64{}
 	for p, s := range bk.bidMap.Items() {
-		sp := convert.GetString(p)
-		bidMap[sp] = s.(float64)
+		bidMap[p] = s.(float64)
 	}
 	return bidMap
 }
@@ -189,14 +218,14 @@ func (bk *Booker) GetBook() ([]float64, []float64, map[float64]float64, map[floa
 	go func(askBook map[float64]float64, askMap *cmap.ConcurrentMap, wg *sync.WaitGroup) {
 		defer wg.Done()
 		for p, s := range askMap.Items() {
-			sp := convert.GetFloat64(p)
+			sp := bk.scale.Float64(bk.scale.Parse(p))
 			askBook[sp] = s.(float64)
 		}
 	}(askBook, bk.askMap, &wg)
 	go func(bidBook map[float64]float64, bidMap *cmap.ConcurrentMap, wg *sync.WaitGroup) {
 		defer wg.Done()
 		for p, s := range bidMap.Items() {
-			sp := convert.GetFloat64(p)
+			sp := bk.scale.Float64(bk.scale.Parse(p))
 			bidBook[sp] = s.(float64)
 		}
 	}(bidBook, bk.bidMap, &wg)
@@ -207,3 +236,25 @@ func (bk *Booker) GetBook() ([]float64, []float64, map[float64]float64, map[floa
 	copy(bids, bk.bids)
 	return asks, bids, askBook, bidBook
 }
+
+func (bk *Booker) View(fn func(asks, bids exch.Levels)) {
+	bk.rw.RLock()
+	defer bk.rw.RUnlock()
+	fn(bk.levels(bk.asks, bk.askMap), bk.levels(bk.bids, bk.bidMap))
+}
+
+func (bk *Booker) levels(prices []float64, book *cmap.ConcurrentMap) exch.Levels {
+	return func(fn func(price, size float64) bool) {
+		for _, p := range prices {
+			s, _ := book.Get(bk.scale.String(bk.scale.FromFloat(p)))
+			size, _ := s.(float64)
+			if !fn(p, size) {
+				return
+			}
+		}
+	}
+}
+
+func init() {
+	exch.RegisterBooker(BookerKey, func(ctx context.Context) exch.Booker { return NewBooker(ctx) })
+}
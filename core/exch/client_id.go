@@ -0,0 +1,128 @@
+package exch
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 自定义订单 Id 格式: <tag>.<instance>.<seq>, tag 为策略标识, instance 为进程实例, seq 为进程内递增序号,
+// 只使用字母数字及 '.', 序号 36^7 以内时满足 gate text(t- 前缀后最多 28 位) 及 binance newClientOrderId(最多 36 位)
+const (
+	clientIdSep    = "."
+	instanceLen    = 7
+	MaxClientTag   = 12 //策略标识最大长度
+	clientIdMaxLen = 28 //所有交易所中最短的限制
+)
+
+// ClientIdMaxLen 交易所自定义订单 Id 的最大长度, 不含交易所要求的前缀
+var ClientIdMaxLen = map[string]int{
+	Gate:    28,
+	Binance: 36,
+}
+
+// Instance 进程实例标识, pid 及启动毫秒时间的 36 进制
+var Instance = newInstance()
+
+func newInstance() string {
+	pid := strconv.FormatInt(int64(os.Getpid())%(36*36), 36)
+	ms := strconv.FormatInt(time.Now().UnixNano()/1e6%(36*36*36*36*36), 36)
+	return padLeft(pid, 2) + padLeft(ms, instanceLen-2)
+}
+
+func padLeft(s string, n int) string {
+	if len(s) >= n {
+		return s
+	}
+	return strings.Repeat("0", n-len(s)) + s
+}
+
+// CleanTag 策略标识只保留字母数字, 超过 MaxClientTag 时截断
+func CleanTag(tag string) string {
+	b := make([]byte, 0, len(tag))
+	for i := 0; i < len(tag) && len(b) < MaxClientTag; i++ {
+		if c := tag[i]; isAlnum(c) {
+			b = append(b, c)
+		}
+	}
+	return string(b)
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// ClientIdGen 自定义订单 Id 生成器, 并发安全, 序号原子递增
+type ClientIdGen struct {
+	prefix string
+	seq    uint64
+}
+
+func NewClientIdGen(tag string) *ClientIdGen {
+	return &ClientIdGen{prefix: CleanTag(tag) + clientIdSep + Instance + clientIdSep}
+}
+
+func (g *ClientIdGen) Next() string {
+	seq := atomic.AddUint64(&g.seq, 1)
+	return g.prefix + strconv.FormatUint(seq, 36)
+}
+
+var defaultIds = NewClientIdGen("")
+
+// NewClientId 使用默认生成器生成不带策略标识的 Id
+func NewClientId() string {
+	return defaultIds.Next()
+}
+
+// ClientId 解析后的自定义订单 Id
+type ClientId struct {
+	Tag      string
+	Instance string
+	Seq      uint64
+}
+
+// ParseClientId 解析成交及订单推送中的自定义订单 Id, 忽略交易所前缀, 如 gate 的 t-
+func ParseClientId(id string) (ClientId, bool) {
+	res := ClientId{}
+	if i := strings.LastIndexByte(id, '-'); i >= 0 {
+		id = id[i+1:]
+	}
+	parts := strings.Split(id, clientIdSep)
+	if len(parts) != 3 || len(parts[1]) != instanceLen || len(parts[0]) > MaxClientTag {
+		return res, false
+	}
+	seq, err := strconv.ParseUint(parts[2], 36, 64)
+	if err != nil || CleanTag(parts[0]) != parts[0] || CleanTag(parts[1]) != parts[1] {
+		return res, false
+	}
+	res.Tag, res.Instance, res.Seq = parts[0], parts[1], seq
+	return res, true
+}
+
+// OrderTag 订单的策略标识, 不是生成器生成的 Id 时返回空
+func OrderTag(o *Order) string {
+	if o == nil {
+		return ""
+	}
+	if cid, ok := ParseClientId(o.UUID); ok {
+		return cid.Tag
+	}
+	return ""
+}
+
+// FitClientId 去掉交易所不支持的字符并按长度截断, 生成器生成的 Id 原样返回
+func FitClientId(exname, id string) string {
+	max, ok := ClientIdMaxLen[exname]
+	if !ok {
+		max = clientIdMaxLen
+	}
+	b := make([]byte, 0, len(id))
+	for i := 0; i < len(id) && len(b) < max; i++ {
+		if c := id[i]; isAlnum(c) || c == '.' || c == '_' || c == '-' {
+			b = append(b, c)
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,47 @@
+package exch
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestClientIdGen(t *testing.T) {
+	g := NewClientIdGen("grid_BTC-usdt.v2long")
+	var lk sync.Mutex
+	seen := map[string]bool{}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				id := g.Next()
+				lk.Lock()
+				seen[id] = true
+				lk.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(seen) != 8000 {
+		t.Fatalf("duplicate ids %d", len(seen))
+	}
+
+	id := g.Next()
+	if len(id) > ClientIdMaxLen[Gate] || FitClientId(Gate, id) != id || FitClientId(Binance, id) != id {
+		t.Fatalf("id %s does not fit", id)
+	}
+	// gate 成交推送带 t- 前缀
+	cid, ok := ParseClientId("t-" + id)
+	if !ok || cid.Tag != "gridBTCusdtv" || cid.Instance != Instance || cid.Seq != 8001 {
+		t.Fatalf("parse %s got %+v %v", id, cid, ok)
+	}
+	if tag := OrderTag(&Order{UUID: GetUUID("ETH_USDT")}); tag != "ETHUSDT" {
+		t.Fatalf("tag got %s", tag)
+	}
+	for _, bad := range []string{"", "abc", "t-123456", "a.b.c", "tag.0000000.zz!"} {
+		if _, ok := ParseClientId(bad); ok {
+			t.Fatalf("parsed %q", bad)
+		}
+	}
+}
@@ -22,12 +22,23 @@ const (
 	CtxExname = "CtxExname"
 	CtxExtype = "CtxExtype"
 
-	CtxOrder  = "CtxOrder"
-	CtxOrders = "CtxOrders"
-	CtxLv     = "CtxLeverage"
-	CtxChange = "CtxChange"
-	Symbols   = "CtxSymbols"
-	CtxChan   = "CtxChan"
+	CtxOrder       = "CtxOrder"
+	CtxOrders      = "CtxOrders"
+	CtxLv          = "CtxLeverage"
+	CtxChange      = "CtxChange"
+	Symbols        = "CtxSymbols"
+	CtxChan        = "CtxChan"
+	CtxBooker      = "CtxBooker"
+	CtxFeed        = "CtxFeed"
+	CtxQueue       = "CtxQueue"
+	CtxBookMonitor = "CtxBookMonitor"
+	CtxBookVerify  = "CtxBookVerify"
+	CtxReconcile   = "CtxReconcile"
+	CtxRateLimit   = "CtxRateLimit"
+	CtxRisk        = "CtxRisk"
+
+	CtxHedgeMode    = "CtxHedgeMode"
+	CtxPositionSide = "CtxPositionSide"
 
 	ApiSign  = "ApiSign"
 	ConnSign = "ConnSign"
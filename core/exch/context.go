@@ -1,6 +1,10 @@
 package exch
 
-import "context"
+import (
+	"context"
+
+	"high-freq-quant-go/adapter/convert"
+)
 
 func GetOrder(ctx context.Context) *Order {
 	if ret, ok := ctx.Value(CtxOrder).(*Order); ok {
@@ -22,3 +26,29 @@ func GetChan(ctx context.Context) *PubChan {
 	}
 	return nil
 }
+
+func GetFeed(ctx context.Context) *Feed {
+	if ret, ok := ctx.Value(CtxFeed).(*Feed); ok {
+		return ret
+	}
+	return nil
+}
+
+// GetLeverage 兼容字符串及数值类型的杠杆参数
+func GetLeverage(ctx context.Context) float64 {
+	switch lv := ctx.Value(CtxLv).(type) {
+	case string:
+		return convert.GetFloat64(lv)
+	case float64:
+		return lv
+	case int:
+		return float64(lv)
+	case int64:
+		return float64(lv)
+	}
+	return 0
+}
+
+func WithSymbol(ctx context.Context, symbol string) context.Context {
+	return context.WithValue(ctx, CtxSymbol, symbol)
+}
@@ -0,0 +1,48 @@
+package exch
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrEmptySymbol        = errors.New("exch: symbol is empty")
+	ErrEmptyOrder         = errors.New("exch: order request is empty")
+	ErrEmptyOrderId       = errors.New("exch: order id is empty")
+	ErrZeroSize           = errors.New("exch: order size is 0")
+	ErrInvalidSize        = errors.New("exch: order size is not a multiple of contract size")
+	ErrInvalidPrice       = errors.New("exch: order price is invalid")
+	ErrInvalidOrder       = errors.New("exch: order params are invalid")
+	ErrInvalidTransition  = errors.New("exch: invalid order state transition")
+	ErrZeroLeverage       = errors.New("exch: leverage is 0")
+	ErrZeroChange         = errors.New("exch: margin change is 0")
+	ErrNotSupported       = errors.New("exch: not supported by exchange")
+	ErrNoBaseInfo         = errors.New("exch: symbol base info not found")
+	ErrWssNotStarted      = errors.New("exch: wss start error")
+	ErrBookBehind         = errors.New("exch: order book is behind snapshot")
+	ErrBookUnaligned      = errors.New("exch: order book can not be aligned with snapshot")
+	ErrNoInstrument       = errors.New("exch: instrument not found")
+	ErrNoInstrumentLoader = errors.New("exch: instrument loader not registered")
+	ErrNoConn             = errors.New("exch: exchange conn is unavailable")
+	ErrRateLimit          = errors.New("exch: request rate limit exceeded")
+	ErrRiskRejected       = errors.New("exch: order rejected by risk check")
+	ErrPostOnlyRejected   = errors.New("exch: post-only order would take liquidity")
+	ErrPositionSide       = errors.New("exch: position side is required in hedge mode")
+	ErrAccountSetup       = errors.New("exch: account setup not applied")
+	ErrAmendReplace       = errors.New("exch: cancelled order has no price or tif, not replaced")
+)
+
+// PostOnlyError 只挂单的订单会立即成交被交易所拒绝, errors.Is(err, ErrPostOnlyRejected) 为 true
+type PostOnlyError struct {
+	Exname string
+	Symbol string
+	Detail string
+}
+
+func (e *PostOnlyError) Error() string {
+	return fmt.Sprintf("%s: %s %s %s", ErrPostOnlyRejected, e.Exname, e.Symbol, e.Detail)
+}
+
+func (e *PostOnlyError) Unwrap() error {
+	return ErrPostOnlyRejected
+}
@@ -6,16 +6,50 @@ import (
 	"high-freq-quant-go/core/log"
 )
 
+// Trader 类型化的交易接口, context 只用于取消和超时控制
+type Trader interface {
+	PlaceOrder(ctx context.Context, req *OrderRequest) (*Order, error)               //创建订单
+	PlaceBatchOrder(ctx context.Context, reqs []*OrderRequest) ([]*Order, error)     //批量创建订单
+	CancelOrder(ctx context.Context, req *CancelRequest) (*Order, error)             //取消订单
+	CancelAllOrder(ctx context.Context, symbol string) ([]*Order, error)             //取消所有订单
+	AmendOrder(ctx context.Context, req *AmendRequest) (*AmendResult, error)         //修改订单数量及价格
+	SetLeverage(ctx context.Context, symbol string, lv float64) (*Position, error)   //更新杠杠(逐仓)
+	SetMargin(ctx context.Context, symbol string, change float64) (*Position, error) //更新保证金
+	SetMarginMode(ctx context.Context, symbol, mode string) error                    //设置保证金模式 isolated,crossed, 已是该模式时不修改
+	SetPositionMode(ctx context.Context, hedge bool) error                           //设置单向或双向持仓, 需与账号 HedgeMode 一致, 已是该模式时不修改
+	SetRiskLimit(ctx context.Context, symbol string, limit float64) error            //设置风险限额, 已是该限额时不修改
+	FetchPosition(ctx context.Context, symbol string) (*Position, error)             //REST 查询仓位及杠杆、保证金模式, 不依赖仓位订阅
+
+	SubscribeTicker(ctx context.Context, symbol string) error    //基础信息
+	SubscribeOrderBook(ctx context.Context, symbol string) error //订阅订单薄
+	SubscribeOrder(ctx context.Context, symbol string) error     //订阅用户委托单
+	SubscribeUserTrade(ctx context.Context, symbol string) error //订阅用户成交单
+	SubscribePosition(ctx context.Context, symbol string) error  //订阅用户仓位
+	SubscribeBalance(ctx context.Context, symbol string) error   //订阅账号资金
+
+	UnsubscribeTicker(ctx context.Context, symbol string) error    //取消基础信息订阅
+	UnsubscribeOrderBook(ctx context.Context, symbol string) error //取消订单薄订阅
+	UnsubscribeOrder(ctx context.Context, symbol string) error     //取消用户委托单订阅
+	UnsubscribeUserTrade(ctx context.Context, symbol string) error //取消用户成交单订阅
+	UnsubscribePosition(ctx context.Context, symbol string) error  //取消用户仓位订阅
+
+	SubscribePublicTrades(ctx context.Context, symbol string) error   //订阅公开逐笔成交, 通过 Feed.OnPublicTrade 接收
+	UnsubscribePublicTrades(ctx context.Context, symbol string) error //取消公开逐笔成交订阅
+}
+
 type Exchange interface {
-	GetStatus() int                                 //获取交易所状态 1可用 其他不可用
-	GetApiSign() string                             //获取Api标识
-	GetExName() string                              //获取交易所名称
-	GetExType() string                              //获取交易所类型
-	GetBaseInfo(ctx context.Context) *BaseInfo      //交易基本信息
-	GetPosition(ctx context.Context) *Position      //获取仓位
-	GetOrder(ctx context.Context) map[string]*Order //获取当前委托单
-	GetOrderBook(ctx context.Context) *Booker       //获取订单薄
-	GetBalance(ctx context.Context) *Balance        //获取当前账号资金
+	Trader
+
+	GetStatus() int                                        //获取交易所状态 1可用 其他不可用
+	GetApiSign() string                                    //获取Api标识
+	GetExName() string                                     //获取交易所名称
+	GetExType() string                                     //获取交易所类型
+	GetBaseInfo(ctx context.Context) *BaseInfo             //交易基本信息
+	GetPosition(ctx context.Context) *Position             //获取仓位
+	GetOrder(ctx context.Context) map[string]*Order        //获取当前委托单
+	GetTriggerOrder(ctx context.Context) map[string]*Order //获取未触发的条件单
+	GetOrderBook(ctx context.Context) Booker               //获取订单薄
+	GetBalance(ctx context.Context) *Balance               //获取当前账号资金
 
 	GetTradeChan(ctx context.Context) *chan *Order //获取成交推送队列
 
@@ -35,6 +69,15 @@ type Exchange interface {
 	SubUserTrade(ctx context.Context) error //订阅用户成交单
 	SubPosition(ctx context.Context) error  //订阅用户仓位
 	SubBalance(ctx context.Context) error   //订阅账号资金
+
+	UnsubTicker(ctx context.Context) error    //取消基础信息订阅
+	UnsubOrderBook(ctx context.Context) error //取消订单薄订阅
+	UnsubOrder(ctx context.Context) error     //取消用户委托单订阅
+	UnsubUserTrade(ctx context.Context) error //取消用户成交单订阅
+	UnsubPosition(ctx context.Context) error  //取消用户仓位订阅
+
+	SubPublicTrades(ctx context.Context) error   //订阅公开逐笔成交
+	UnsubPublicTrades(ctx context.Context) error //取消公开逐笔成交订阅
 }
 
 type ConnInstance func(ctx context.Context) Exchange
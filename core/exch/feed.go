@@ -0,0 +1,321 @@
+package exch
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 推送类型
+const (
+	FeedBook     = "book"
+	FeedTrade    = "trade"
+	FeedPublic   = "public_trade"
+	FeedOrder    = "order"
+	FeedPosition = "position"
+	FeedBalance  = "balance"
+	FeedIssue    = "issue"
+	FeedDrift    = "drift"
+)
+
+// Feed 行情及账户推送, 每个订阅者有独立的有界队列及推送协程, 慢订阅者只丢弃自己的消息
+type Feed struct {
+	ctx  context.Context
+	subs map[string]map[int64]*Subscription
+	seq  int64
+	rw   sync.RWMutex
+}
+
+func NewFeed(ctx context.Context) *Feed {
+	return &Feed{
+		ctx:  ctx,
+		subs: map[string]map[int64]*Subscription{},
+	}
+}
+
+type SubOption func(sub *Subscription)
+
+// WithQueueLen 订阅队列长度, 默认 MsgChannelLen
+func WithQueueLen(n int) SubOption {
+	return func(sub *Subscription) {
+		if n > 0 {
+			sub.qlen = n
+		}
+	}
+}
+
+// WithConflate 每个交易对只保留最新一条未处理的消息, 适用于订单薄
+func WithConflate() SubOption {
+	return func(sub *Subscription) {
+		sub.conflate = true
+	}
+}
+
+// WithBlocking 队列满时推送方等待不丢弃, 处理慢会阻塞连接的推送协程, 只用于必须完整的数据如 OMS
+func WithBlocking() SubOption {
+	return func(sub *Subscription) {
+		sub.blocking = true
+	}
+}
+
+type Subscription struct {
+	Kind   string
+	Symbol string //交易对或资产, 为空订阅全部
+
+	feed     *Feed
+	id       int64
+	fn       func(v interface{})
+	qlen     int
+	queue    chan interface{}
+	conflate bool
+	blocking bool
+	latest   map[string]interface{}
+	lk       sync.Mutex
+	notify   chan struct{}
+	done     chan struct{}
+	once     sync.Once
+
+	dropped, conflated int64
+}
+
+// Dropped 队列已满丢弃的消息数
+func (sub *Subscription) Dropped() int64 {
+	return atomic.LoadInt64(&sub.dropped)
+}
+
+// Conflated 被新消息覆盖的消息数
+func (sub *Subscription) Conflated() int64 {
+	return atomic.LoadInt64(&sub.conflated)
+}
+
+// Unsubscribe 取消订阅, 已在队列中的消息不再推送
+func (sub *Subscription) Unsubscribe() {
+	sub.once.Do(func() {
+		// 先结束等待中的阻塞推送, 推送方持有 fd.rw 读锁
+		close(sub.done)
+		fd := sub.feed
+		fd.rw.Lock()
+		delete(fd.subs[sub.Kind], sub.id)
+		fd.rw.Unlock()
+	})
+}
+
+func (sub *Subscription) push(key string, v interface{}) {
+	if sub.conflate {
+		sub.lk.Lock()
+		if _, ok := sub.latest[key]; ok {
+			atomic.AddInt64(&sub.conflated, 1)
+		}
+		sub.latest[key] = v
+		sub.lk.Unlock()
+		select {
+		case sub.notify <- struct{}{}:
+		default:
+		}
+		return
+	}
+	if sub.blocking {
+		select {
+		case sub.queue <- v:
+		case <-sub.done:
+		case <-sub.feed.ctx.Done():
+		}
+		return
+	}
+	select {
+	case sub.queue <- v:
+	default:
+		atomic.AddInt64(&sub.dropped, 1)
+	}
+}
+
+func (sub *Subscription) run() {
+	for {
+		select {
+		case <-sub.done:
+			return
+		case <-sub.feed.ctx.Done():
+			return
+		case v := <-sub.queue:
+			sub.fn(v)
+		case <-sub.notify:
+			sub.lk.Lock()
+			latest := sub.latest
+			sub.latest = map[string]interface{}{}
+			sub.lk.Unlock()
+			for _, v := range latest {
+				sub.fn(v)
+			}
+		}
+	}
+}
+
+func (fd *Feed) subscribe(kind, symbol string, fn func(v interface{}), opts []SubOption) *Subscription {
+	sub := &Subscription{
+		Kind:   kind,
+		Symbol: strings.ToUpper(symbol),
+		feed:   fd,
+		fn:     fn,
+		qlen:   int(MsgChannelLen),
+		latest: map[string]interface{}{},
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(sub)
+	}
+	sub.queue = make(chan interface{}, sub.qlen)
+	fd.rw.Lock()
+	fd.seq++
+	sub.id = fd.seq
+	if _, ok := fd.subs[kind]; !ok {
+		fd.subs[kind] = map[int64]*Subscription{}
+	}
+	fd.subs[kind][sub.id] = sub
+	fd.rw.Unlock()
+	go sub.run()
+	return sub
+}
+
+func (fd *Feed) publish(kind, symbol string, v interface{}) {
+	if fd == nil {
+		return
+	}
+	symbol = strings.ToUpper(symbol)
+	fd.rw.RLock()
+	defer fd.rw.RUnlock()
+	for _, sub := range fd.subs[kind] {
+		if sub.Symbol != "" && sub.Symbol != symbol {
+			continue
+		}
+		sub.push(symbol, v)
+	}
+}
+
+func (fd *Feed) OnBook(symbol string, fn func(bk Booker), opts ...SubOption) *Subscription {
+	return fd.subscribe(FeedBook, symbol, func(v interface{}) { fn(v.(Booker)) }, opts)
+}
+
+func (fd *Feed) OnTrade(symbol string, fn func(o *Order), opts ...SubOption) *Subscription {
+	return fd.subscribe(FeedTrade, symbol, func(v interface{}) { fn(v.(*Order)) }, opts)
+}
+
+// OnPublicTrade 公开逐笔成交, 需先 SubscribePublicTrades
+func (fd *Feed) OnPublicTrade(symbol string, fn func(t *Trade), opts ...SubOption) *Subscription {
+	return fd.subscribe(FeedPublic, symbol, func(v interface{}) { fn(v.(*Trade)) }, opts)
+}
+
+func (fd *Feed) OnOrder(symbol string, fn func(o *Order), opts ...SubOption) *Subscription {
+	return fd.subscribe(FeedOrder, symbol, func(v interface{}) { fn(v.(*Order)) }, opts)
+}
+
+func (fd *Feed) OnPosition(symbol string, fn func(pos *Position), opts ...SubOption) *Subscription {
+	return fd.subscribe(FeedPosition, symbol, func(v interface{}) { fn(v.(*Position)) }, opts)
+}
+
+// OnBalance asset 为空订阅全部资产
+func (fd *Feed) OnBalance(asset string, fn func(ba *Balance), opts ...SubOption) *Subscription {
+	return fd.subscribe(FeedBalance, asset, func(v interface{}) { fn(v.(*Balance)) }, opts)
+}
+
+// OnBookIssue 订单薄异常, 见 BookMonitor
+func (fd *Feed) OnBookIssue(symbol string, fn func(issue *BookIssue), opts ...SubOption) *Subscription {
+	return fd.subscribe(FeedIssue, symbol, func(v interface{}) { fn(v.(*BookIssue)) }, opts)
+}
+
+// OnDrift 仓位及资金校对偏差, symbol 为交易对或资产, 见 Reconciler
+func (fd *Feed) OnDrift(symbol string, fn func(d *Drift), opts ...SubOption) *Subscription {
+	return fd.subscribe(FeedDrift, symbol, func(v interface{}) { fn(v.(*Drift)) }, opts)
+}
+
+// PubBook 推送订单薄本身, 订阅者读取时需按 Booker 的方法加锁
+func (fd *Feed) PubBook(symbol string, bk Booker) {
+	fd.publish(FeedBook, symbol, bk)
+}
+
+// PubTrade 以下推送均为副本, 订阅者可以持有及修改
+func (fd *Feed) PubTrade(o *Order) {
+	cp := *o
+	fd.publish(FeedTrade, o.Symbol, &cp)
+}
+
+func (fd *Feed) PubPublicTrade(t *Trade) {
+	cp := *t
+	fd.publish(FeedPublic, t.Symbol, &cp)
+}
+
+func (fd *Feed) PubOrder(o *Order) {
+	cp := *o
+	fd.publish(FeedOrder, o.Symbol, &cp)
+}
+
+func (fd *Feed) PubPosition(pos *Position) {
+	cp := *pos
+	fd.publish(FeedPosition, pos.Symbol, &cp)
+}
+
+func (fd *Feed) PubBalance(ba *Balance) {
+	cp := *ba
+	fd.publish(FeedBalance, ba.Asset, &cp)
+}
+
+func (fd *Feed) PubBookIssue(issue *BookIssue) {
+	cp := *issue
+	fd.publish(FeedIssue, issue.Symbol, &cp)
+}
+
+func (fd *Feed) PubDrift(d *Drift) {
+	cp := *d
+	fd.publish(FeedDrift, d.Symbol, &cp)
+}
+
+// Feeds 多个 Exchanger 共用同一连接时的推送集合
+type Feeds struct {
+	list []*Feed
+	rw   sync.RWMutex
+}
+
+func (fs *Feeds) Add(fd *Feed) {
+	if fd == nil {
+		return
+	}
+	fs.rw.Lock()
+	defer fs.rw.Unlock()
+	for _, f := range fs.list {
+		if f == fd {
+			return
+		}
+	}
+	fs.list = append(fs.list, fd)
+}
+
+func (fs *Feeds) each(fn func(fd *Feed)) {
+	fs.rw.RLock()
+	defer fs.rw.RUnlock()
+	for _, fd := range fs.list {
+		if fd.ctx.Err() == nil {
+			fn(fd)
+		}
+	}
+}
+
+func (fs *Feeds) PubTrade(o *Order) {
+	fs.each(func(fd *Feed) { fd.PubTrade(o) })
+}
+
+func (fs *Feeds) PubOrder(o *Order) {
+	fs.each(func(fd *Feed) { fd.PubOrder(o) })
+}
+
+func (fs *Feeds) PubPosition(pos *Position) {
+	fs.each(func(fd *Feed) { fd.PubPosition(pos) })
+}
+
+func (fs *Feeds) PubBalance(ba *Balance) {
+	fs.each(func(fd *Feed) { fd.PubBalance(ba) })
+}
+
+func (fs *Feeds) PubDrift(d *Drift) {
+	fs.each(func(fd *Feed) { fd.PubDrift(d) })
+}
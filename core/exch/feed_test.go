@@ -0,0 +1,122 @@
+package exch
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFeedSymbol(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fd := NewFeed(ctx)
+	var btc, all int64
+	fd.OnTrade("btc_usdt", func(o *Order) { atomic.AddInt64(&btc, 1) })
+	fd.OnTrade("", func(o *Order) { atomic.AddInt64(&all, 1) })
+	fd.PubTrade(&Order{Symbol: "BTC_USDT"})
+	fd.PubTrade(&Order{Symbol: "ETH_USDT"})
+	waitFor(t, func() bool { return atomic.LoadInt64(&all) == 2 })
+	if n := atomic.LoadInt64(&btc); n != 1 {
+		t.Fatalf("btc got %d", n)
+	}
+
+	o := &Order{Symbol: "BTC_USDT", Id: "1"}
+	got := make(chan *Order, 1)
+	fd.OnOrder("", func(or *Order) { got <- or })
+	fd.PubOrder(o)
+	if or := <-got; or == o || or.Id != "1" {
+		t.Fatalf("order got %p %+v", or, or)
+	}
+}
+
+func TestFeedBackpressure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fd := NewFeed(ctx)
+	block := make(chan struct{})
+	var n int64
+	slow := fd.OnPosition("", func(pos *Position) {
+		<-block
+		atomic.AddInt64(&n, 1)
+	}, WithQueueLen(2))
+	var fast int64
+	fd.OnPosition("", func(pos *Position) { atomic.AddInt64(&fast, 1) })
+	for i := 0; i < 10; i++ {
+		fd.PubPosition(&Position{Symbol: "BTC_USDT"})
+	}
+	waitFor(t, func() bool { return atomic.LoadInt64(&fast) == 10 })
+	close(block)
+	waitFor(t, func() bool { return atomic.LoadInt64(&n)+slow.Dropped() == 10 })
+	if slow.Dropped() < 7 {
+		t.Fatalf("dropped got %d", slow.Dropped())
+	}
+
+	slow.Unsubscribe()
+	slow.Unsubscribe()
+	before := atomic.LoadInt64(&n)
+	fd.PubPosition(&Position{Symbol: "BTC_USDT"})
+	waitFor(t, func() bool { return atomic.LoadInt64(&fast) == 11 })
+	if atomic.LoadInt64(&n) != before {
+		t.Fatal("unsubscribed still receives")
+	}
+}
+
+func TestFeedConflate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fd := NewFeed(ctx)
+	block := make(chan struct{})
+	last := make(chan Booker, 10)
+	sub := fd.OnBook("", func(bk Booker) {
+		<-block
+		last <- bk
+	}, WithConflate())
+	a, b := NewMapBooker(ctx), NewMapBooker(ctx)
+	fd.PubBook("BTC_USDT", a)
+	waitFor(t, func() bool {
+		sub.lk.Lock()
+		defer sub.lk.Unlock()
+		return len(sub.latest) == 0
+	})
+	for i := 0; i < 5; i++ {
+		fd.PubBook("BTC_USDT", a)
+	}
+	fd.PubBook("ETH_USDT", b)
+	close(block)
+	got := map[Booker]int{}
+	for i := 0; i < 3; i++ {
+		got[<-last]++
+	}
+	if got[a] != 2 || got[b] != 1 || sub.Conflated() != 4 {
+		t.Fatalf("got %v conflated %d", got, sub.Conflated())
+	}
+}
+
+func TestFeedPublicTrade(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fd := NewFeed(ctx)
+	got := make(chan *Trade, 2)
+	fd.OnPublicTrade("BTC_USDT", func(tr *Trade) { got <- tr })
+	fd.PubPublicTrade(&Trade{Symbol: "ETH_USDT", Id: "1"})
+	tr := &Trade{Symbol: "BTC_USDT", Id: "2", Price: 100, Size: -0.5, Side: TakerSide(-0.5)}
+	fd.PubPublicTrade(tr)
+	if r := <-got; r == tr || r.Id != "2" || r.Side != SideSell {
+		t.Fatalf("public trade got %p %+v", r, r)
+	}
+	if TakerSide(1) != SideBuy {
+		t.Fatal("taker side of positive size")
+	}
+}
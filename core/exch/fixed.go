@@ -0,0 +1,286 @@
+package exch
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// BookFloat 未设置最小变动单位时订单薄价格的默认精度
+const BookFloat = 10
+
+var pow10 = [...]int64{1, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10, 1e11, 1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18}
+
+// Fixed 定点数, 实际数值为 Fixed * Scale 的最小变动单位
+type Fixed int64
+
+// Scale 定点数刻度
+type Scale struct {
+	Float int   //小数位数
+	Step  int64 //最小变动单位, 以 10^-Float 计
+}
+
+// NewScale 由最小变动单位生成刻度, 如 0.5 0.01 0.0025
+func NewScale(step float64) Scale {
+	if step <= 0 {
+		return DecimalScale(BookFloat)
+	}
+	n := 0
+	s := strconv.FormatFloat(step, 'f', -1, 64)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		n = len(s) - i - 1
+	}
+	if n > 18 {
+		n = 18
+	}
+	st := int64(math.Round(step * float64(pow10[n])))
+	if st <= 0 {
+		st = 1
+	}
+	return Scale{Float: n, Step: st}
+}
+
+// DecimalScale 按小数位数生成刻度
+func DecimalScale(n int) Scale {
+	if n < 0 {
+		n = 0
+	}
+	if n > 18 {
+		n = 18
+	}
+	return Scale{Float: n, Step: 1}
+}
+
+func (sc Scale) IsZero() bool {
+	return sc.Step == 0
+}
+
+// Parse 按十进制字符串精确解析, 超出刻度的部分四舍五入
+func (sc Scale) Parse(s string) Fixed {
+	return sc.parse(s, false)
+}
+
+// ParseFloor 按十进制字符串精确解析, 超出刻度的部分向零取整
+func (sc Scale) ParseFloor(s string) Fixed {
+	return sc.parse(s, true)
+}
+
+func (sc Scale) parse(s string, floor bool) Fixed {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	if strings.ContainsAny(s, "eE") {
+		f, _ := strconv.ParseFloat(s, 64)
+		if floor {
+			return sc.FromFloatFloor(f)
+		}
+		return sc.FromFloat(f)
+	}
+	neg := false
+	if s[0] == '-' || s[0] == '+' {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	ip, fp := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		ip, fp = s[:i], s[i+1:]
+	}
+	var units int64
+	for _, c := range ip {
+		if c < '0' || c > '9' {
+			return 0
+		}
+		units = units*10 + int64(c-'0')
+	}
+	for i := 0; i < sc.Float; i++ {
+		d := int64(0)
+		if i < len(fp) {
+			if fp[i] < '0' || fp[i] > '9' {
+				return 0
+			}
+			d = int64(fp[i] - '0')
+		}
+		units = units*10 + d
+	}
+	v := units
+	if floor {
+		if sc.Step > 1 {
+			v = units / sc.Step
+		}
+	} else {
+		if len(fp) > sc.Float && fp[sc.Float] >= '5' {
+			units++
+		}
+		v = sc.divRound(units)
+	}
+	if neg {
+		v = -v
+	}
+	return Fixed(v)
+}
+
+// FromFloat 按最小变动单位四舍五入
+func (sc Scale) FromFloat(f float64) Fixed {
+	if sc.IsZero() {
+		return 0
+	}
+	return Fixed(math.Round(f * float64(pow10[sc.Float]) / float64(sc.Step)))
+}
+
+// FromFloatFloor 按最小变动单位向零取整
+func (sc Scale) FromFloatFloor(f float64) Fixed {
+	if sc.IsZero() {
+		return 0
+	}
+	v := f * float64(pow10[sc.Float]) / float64(sc.Step)
+	//消除 0.3*10=2.9999999 之类的浮点误差
+	if r := math.Round(v); math.Abs(v-r) < 1e-9 {
+		return Fixed(r)
+	}
+	return Fixed(math.Trunc(v))
+}
+
+func (sc Scale) Float64(v Fixed) float64 {
+	return float64(int64(v)*sc.Step) / float64(pow10[sc.Float])
+}
+
+// String 十进制字符串, 去掉末尾的 0
+func (sc Scale) String(v Fixed) string {
+	units := int64(v) * sc.Step
+	neg := units < 0
+	if neg {
+		units = -units
+	}
+	s := strconv.FormatInt(units, 10)
+	if sc.Float > 0 {
+		if len(s) <= sc.Float {
+			s = strings.Repeat("0", sc.Float-len(s)+1) + s
+		}
+		i := len(s) - sc.Float
+		s = strings.TrimRight(s[:i]+"."+s[i:], "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	if neg && s != "0" {
+		s = "-" + s
+	}
+	return s
+}
+
+// Rescale 换算到另一刻度, 按十进制精确换算, 超出刻度的部分四舍五入
+func (sc Scale) Rescale(v Fixed, to Scale) Fixed {
+	if sc == to {
+		return v
+	}
+	return to.Parse(sc.String(v))
+}
+
+// RescaleFloor 换算到另一刻度, 超出刻度的部分向零取整
+func (sc Scale) RescaleFloor(v Fixed, to Scale) Fixed {
+	if sc == to {
+		return v
+	}
+	return to.ParseFloor(sc.String(v))
+}
+
+// Round 按最小变动单位四舍五入后的浮点数
+func (sc Scale) Round(f float64) float64 {
+	return sc.Float64(sc.FromFloat(f))
+}
+
+// Floor 按最小变动单位向零取整后的浮点数
+func (sc Scale) Floor(f float64) float64 {
+	return sc.Float64(sc.FromFloatFloor(f))
+}
+
+func (sc Scale) divRound(units int64) int64 {
+	if sc.Step <= 1 {
+		return units
+	}
+	q, r := units/sc.Step, units%sc.Step
+	if r*2 >= sc.Step {
+		q++
+	}
+	return q
+}
+
+// PriceScale 价格刻度, 未设置最小变动单位时按价格精度
+func (bi *BaseInfo) PriceScale() Scale {
+	if bi.MinPriceStep > 0 {
+		return NewScale(bi.MinPriceStep)
+	}
+	return DecimalScale(bi.PriceFloat)
+}
+
+// SizeScale 数量刻度, 未设置最小变动单位时按数量精度
+func (bi *BaseInfo) SizeScale() Scale {
+	if bi.MinSizeStep > 0 {
+		return NewScale(bi.MinSizeStep)
+	}
+	return DecimalScale(bi.SizeFloat)
+}
+
+// fixedValid 定点值已设置且 Price 或 Size 未被修改
+func fixedValid(sc Scale, v Fixed, f float64) bool {
+	return !sc.IsZero() && sc.Float64(v) == f
+}
+
+// parseFloat 刻度为零值时按浮点解析
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return f
+}
+
+// SetPrice 设置定点价格, 同时更新 Price
+func (o *Order) SetPrice(sc Scale, v Fixed) {
+	o.FixedPrice, o.PriceScale, o.Price = v, sc, sc.Float64(v)
+}
+
+// SetSize 设置定点数量 多正 空负, 同时更新 Size
+func (o *Order) SetSize(sc Scale, v Fixed) {
+	o.FixedSize, o.SizeScale, o.Size = v, sc, sc.Float64(v)
+}
+
+// ParsePrice 按刻度解析交易所返回的价格, 刻度为零值时只设置 Price
+func (o *Order) ParsePrice(sc Scale, s string) {
+	if sc.IsZero() {
+		o.Price = parseFloat(s)
+		return
+	}
+	o.SetPrice(sc, sc.Parse(s))
+}
+
+// ParseSize 按刻度解析交易所返回的数量, 刻度为零值时只设置 Size
+func (o *Order) ParseSize(sc Scale, s string) {
+	if sc.IsZero() {
+		o.Size = parseFloat(s)
+		return
+	}
+	o.SetSize(sc, sc.Parse(s))
+}
+
+// PriceFixed 订单价格按刻度四舍五入, 带定点价格时按十进制换算
+func (o *Order) PriceFixed(sc Scale) Fixed {
+	if fixedValid(o.PriceScale, o.FixedPrice, o.Price) {
+		return o.PriceScale.Rescale(o.FixedPrice, sc)
+	}
+	return sc.FromFloat(o.Price)
+}
+
+// SizeFixed 订单数量按刻度向零取整, 保留方向, 带定点数量时按十进制换算
+func (o *Order) SizeFixed(sc Scale) Fixed {
+	if fixedValid(o.SizeScale, o.FixedSize, o.Size) {
+		return o.SizeScale.RescaleFloor(o.FixedSize, sc)
+	}
+	return sc.FromFloatFloor(o.Size)
+}
+
+// SetPrice 设置定点价格, 同时更新 Price
+func (r *OrderRequest) SetPrice(sc Scale, v Fixed) {
+	r.FixedPrice, r.PriceScale, r.Price = v, sc, sc.Float64(v)
+}
+
+// SetSize 设置定点数量 多正 空负, 同时更新 Size
+func (r *OrderRequest) SetSize(sc Scale, v Fixed) {
+	r.FixedSize, r.SizeScale, r.Size = v, sc, sc.Float64(v)
+}
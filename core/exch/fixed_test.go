@@ -0,0 +1,106 @@
+package exch
+
+import "testing"
+
+func TestScaleParse(t *testing.T) {
+	cases := []struct {
+		step float64
+		in   string
+		want string
+	}{
+		{0.1, "0.10", "0.1"},
+		{0.1, "0.1", "0.1"},
+		{0.01, "123.456", "123.46"},
+		{0.5, "100.3", "100.5"},
+		{0.5, "100.2", "100"},
+		{0.0025, "1.0037", "1.0025"},
+		{1, "-42.6", "-43"},
+		{0.000001, "0.0000101", "0.00001"},
+		{0.01, "1e-2", "0.01"},
+	}
+	for _, c := range cases {
+		sc := NewScale(c.step)
+		if got := sc.String(sc.Parse(c.in)); got != c.want {
+			t.Errorf("step %v parse %s: got %s, want %s", c.step, c.in, got, c.want)
+		}
+	}
+	sc := NewScale(0.1)
+	if sc.Parse("0.10") != sc.Parse("0.1") {
+		t.Fatal("0.10 and 0.1 should map to the same key")
+	}
+}
+
+func TestScaleFloat(t *testing.T) {
+	sc := NewScale(0.01)
+	if got := sc.Round(0.1 + 0.2); got != 0.3 {
+		t.Fatalf("round got %v", got)
+	}
+	if got := sc.Float64(sc.FromFloat(19.999)); got != 20 {
+		t.Fatalf("round got %v", got)
+	}
+	ss := NewScale(0.001)
+	if got := ss.Floor(0.0096); got != 0.009 {
+		t.Fatalf("floor got %v", got)
+	}
+	if got := ss.Floor(0.3); got != 0.3 {
+		t.Fatalf("floor got %v", got)
+	}
+	if got := ss.Floor(-0.0096); got != -0.009 {
+		t.Fatalf("floor got %v", got)
+	}
+}
+
+func TestBaseInfoScale(t *testing.T) {
+	info := &BaseInfo{PriceFloat: 2, MinSizeStep: 0.5}
+	if got := info.PriceScale().String(info.PriceScale().Parse("1.005")); got != "1.01" {
+		t.Fatalf("price scale got %s", got)
+	}
+	o := &Order{Price: 10.26, Size: -1.7}
+	if got := info.SizeScale().String(o.SizeFixed(info.SizeScale())); got != "-1.5" {
+		t.Fatalf("size got %s", got)
+	}
+}
+
+func TestOrderFixed(t *testing.T) {
+	ps, ss := NewScale(0.00000001), NewScale(0.001)
+	o := &Order{}
+	o.ParsePrice(ps, "0.00001234")
+	o.ParseSize(ss, "-1234567.891")
+	if got := ps.String(o.PriceFixed(ps)); got != "0.00001234" {
+		t.Fatalf("price got %s", got)
+	}
+	if got := NewScale(0.01).String(o.SizeFixed(NewScale(0.01))); got != "-1234567.89" {
+		t.Fatalf("size got %s", got)
+	}
+	req := NewOrderRequest(o)
+	if r := req.Order(); r.FixedPrice != o.FixedPrice || r.SizeScale != ss {
+		t.Fatalf("request lost fixed %+v", r)
+	}
+	// 修改 Price 后定点值失效, 按新的 Price 取整
+	req.Price = 0.00002
+	if got := ps.String(req.Order().PriceFixed(ps)); got != "0.00002" {
+		t.Fatalf("stale price got %s", got)
+	}
+	if got := ss.ParseFloor("-0.0019"); got != -1 {
+		t.Fatalf("parse floor got %d", got)
+	}
+	if got := ps.Rescale(ps.Parse("0.000012345"), NewScale(0.000005)); got != 2 {
+		t.Fatalf("rescale got %d", got)
+	}
+}
+
+func TestBookerScale(t *testing.T) {
+	bk := &MapBooker{scale: DecimalScale(BookFloat)}
+	bk.SetBook(map[string]float64{"0.10": 1, "0.2": 2}, map[string]float64{"0.09": 3})
+	bk.UpdateAsk("0.1", 5)
+	bk.UpdateBid("0.090", 0)
+	bk.UpdateTime = 1
+	asks, bids, askBook, _ := bk.GetBook()
+	if len(asks) != 2 || len(bids) != 0 || askBook[0.1] != 5 {
+		t.Fatalf("got asks %v bids %v book %v", asks, bids, askBook)
+	}
+	bk.SetScale(NewScale(0.1))
+	if m := bk.GetAskMap(); m["0.1"] != 5 || m["0.2"] != 2 {
+		t.Fatalf("rescale got %v", m)
+	}
+}
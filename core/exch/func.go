@@ -1,33 +1,31 @@
 package exch
 
 import (
-	"fmt"
+	"strconv"
 	"strings"
-	"time"
-
-	"high-freq-quant-go/core/stty"
+	"sync/atomic"
 )
 
-var LastOrderCode string
-var LastOrderId int64
-
+// GetBaseQuote 从已加载的合约信息中查询, 未加载时只拆分 BASE_QUOTE[_20060102] 格式的统一交易对,
+// 交易所交易对如 BTCUSDT BTCUSDT_230630 返回空
 func GetBaseQuote(symbol string) (string, string) {
-	base, quote := "", ""
-	quotes := []string{"USDT", "BTC", "ETH", "_USD", "BUSD", "BNB", "AUD",
-		"BIDR", "BRL", "EUR", "GBP", "RUB", "TRY", "TUSD", "USDC", "DAI",
-		"IDRT", "UAH", "NGN", "VAI", "USDP", "UST", "XRP", "TRX", "DOGE", "DOT"}
-	ln := len(symbol)
-	for _, q := range quotes {
-		l := ln - len(q)
-		suf := symbol[l:]
-		if suf == q {
-			pre := symbol[:l]
-			base = strings.TrimRight(pre, "_")
-			quote = q
-			break
+	if in := FindInstrument(symbol); in != nil {
+		return in.Base, in.Quote
+	}
+	pq := strings.SplitN(symbol, "_", 3)
+	if len(pq) < 2 || pq[0] == "" || pq[1] == "" || isDigits(pq[1]) || (len(pq) == 3 && (len(pq[2]) != 8 || !isDigits(pq[2]))) {
+		return "", ""
+	}
+	return pq[0], pq[1]
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
 		}
 	}
-	return base, quote
+	return s != ""
 }
 
 // asset 成交保证金变化
@@ -60,13 +58,8 @@ func SumPosAvgPrice(ap, as, bp, bs float64) (float64, float64, float64, float64)
 	return p, s, pnl, asset
 }
 
+// GetUUID 以交易对为标识生成自定义订单 Id, 与 NewClientId 共用序号
 func GetUUID(symbol string) string {
-	code := symbol + time.Now().Format(stty.TimeFormatStr)
-	if LastOrderCode != code {
-		LastOrderCode = code
-		LastOrderId = 0
-	}
-	LastOrderId++
-	code = fmt.Sprintf("%s%04d", code, LastOrderId)
-	return code
+	seq := atomic.AddUint64(&defaultIds.seq, 1)
+	return CleanTag(symbol) + clientIdSep + Instance + clientIdSep + strconv.FormatUint(seq, 36)
 }
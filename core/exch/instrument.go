@@ -0,0 +1,285 @@
+package exch
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Instrument 交易对在某个交易所的合约信息, Symbol 为统一交易对 BASE_QUOTE[_20060102],
+// VenueSymbol 为交易所交易对. 交易所价格及数量与统一单位的换算:
+// 价格 = 交易所价格 / Multiplier, 数量 = 交易所数量 * Multiplier * ContractSize
+type Instrument struct {
+	Symbol         string
+	VenueSymbol    string
+	Exname, Extype string
+	Base           string  //统一交易币, 如 1000SHIB 为 SHIB
+	Quote          string  //计价货币
+	Settle         string  //结算货币
+	Multiplier     float64 //交易所一单位对应的交易币数量, 如 1000SHIB 为 1000
+	ContractSize   float64 //一张合约对应的交易所单位, 如 gate quanto_multiplier, 现货为 1
+	TickSize       float64 //交易所价格最小变动单位
+	StepSize       float64 //交易所数量最小变动单位
+	MinSize        float64 //交易所最小下单数量
+	ExpireTime     int64   //交割时间, 永续为 0
+	LastUpdateTime int64
+}
+
+// shift Multiplier 为 10 的整数次幂时的位数, 否则返回 -1
+func (in *Instrument) shift() int {
+	if in == nil || in.Multiplier <= 1 {
+		return 0
+	}
+	n := int(math.Round(math.Log10(in.Multiplier)))
+	if n < len(pow10) && float64(pow10[n]) == in.Multiplier {
+		return n
+	}
+	return -1
+}
+
+func (in *Instrument) multiplier() float64 {
+	if in == nil || in.Multiplier <= 0 {
+		return 1
+	}
+	return in.Multiplier
+}
+
+func (in *Instrument) unit() float64 {
+	u := in.multiplier()
+	if in != nil && in.ContractSize > 0 {
+		u *= in.ContractSize
+	}
+	return u
+}
+
+// PriceFromVenue 交易所价格换算为统一价格, 未加载合约信息时(nil)原样返回
+func (in *Instrument) PriceFromVenue(price float64) float64 {
+	return price / in.multiplier()
+}
+
+func (in *Instrument) PriceToVenue(price float64) float64 {
+	return price * in.multiplier()
+}
+
+// PriceStr 交易所价格字符串换算为统一价格字符串, 10 的整数次幂按十进制移位, 不经过浮点运算
+func (in *Instrument) PriceStr(price string) string {
+	switch n := in.shift(); {
+	case n == 0:
+		return TrimZero(price)
+	case n > 0 && n <= 9:
+		return DecimalScale(9 + n).String(DecimalScale(9).Parse(price))
+	}
+	p, _ := strconv.ParseFloat(price, 64)
+	return strconv.FormatFloat(in.PriceFromVenue(p), 'f', -1, 64)
+}
+
+// PriceScale 统一价格的刻度, 由交易所价格刻度按 Multiplier 缩小
+func (in *Instrument) PriceScale(sc Scale) Scale {
+	switch n := in.shift(); {
+	case n == 0:
+		return sc
+	case n > 0:
+		sc.Float += n
+		if sc.Float > 18 {
+			sc.Float = 18
+		}
+		return sc
+	}
+	return NewScale(in.PriceFromVenue(sc.Float64(1)))
+}
+
+// SizeFromVenue 交易所数量(张数)换算为统一数量
+func (in *Instrument) SizeFromVenue(size float64) float64 {
+	return trimNoise(size * in.unit())
+}
+
+func (in *Instrument) SizeToVenue(size float64) float64 {
+	return trimNoise(size / in.unit())
+}
+
+// trimNoise 去掉乘除单位产生的浮点误差, 如 0.0003 / 0.0001 = 2.9999999999999996
+func trimNoise(f float64) float64 {
+	r := math.Round(f * 1e9)
+	if math.IsInf(r, 0) || math.Abs(r) > 1<<53 {
+		return f
+	}
+	return r / 1e9
+}
+
+// TrimZero 去掉小数末尾的 0
+func TrimZero(s string) string {
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimRight(s, ".")
+	}
+	return s
+}
+
+// ParseMultiplier 拆分交易所资产名的数量前缀, 如 1000SHIB 1000000MOG 1MBABYDOGE,
+// 只识别 10 及以上的 10 的整数次幂, 1INCH 等名称不拆分
+func ParseMultiplier(asset string) (string, float64) {
+	i := 0
+	for i < len(asset) && asset[i] >= '0' && asset[i] <= '9' {
+		i++
+	}
+	if i == 0 || i == len(asset) {
+		return asset, 1
+	}
+	num, rest := asset[:i], asset[i:]
+	if num == "1" && len(rest) > 1 && rest[0] == 'M' {
+		return rest[1:], 1e6
+	}
+	if len(num) < 2 || len(num) > len(pow10) || num[0] != '1' || strings.Trim(num[1:], "0") != "" {
+		return asset, 1
+	}
+	return rest, float64(pow10[len(num)-1])
+}
+
+// InstrumentReload 未找到交易对时两次加载合约信息的最短间隔
+var InstrumentReload = time.Minute
+
+type instrumentVenue struct {
+	lk       sync.Mutex //加载锁
+	load     func() ([]*Instrument, error)
+	loadTime time.Time
+	loading  int32 //后台加载中
+}
+
+var instruments = struct {
+	rw       sync.RWMutex
+	bySymbol map[string]*Instrument
+	byVenue  map[string]*Instrument
+	byName   map[string]*Instrument //统一交易对及交易所交易对, 不区分交易所
+	venues   map[string]*instrumentVenue
+}{
+	bySymbol: map[string]*Instrument{},
+	byVenue:  map[string]*Instrument{},
+	byName:   map[string]*Instrument{},
+	venues:   map[string]*instrumentVenue{},
+}
+
+func venueKey(exname, extype string) string {
+	return exname + "_" + extype
+}
+
+// RegisterInstrumentLoader 注册交易所合约信息加载函数, 查询不到交易对时按 InstrumentReload 间隔加载,
+// 连接器在包 init 中注册, 加载函数只使用公共接口
+func RegisterInstrumentLoader(exname, extype string, load func() ([]*Instrument, error)) {
+	key := venueKey(exname, extype)
+	instruments.rw.Lock()
+	v, ok := instruments.venues[key]
+	if !ok {
+		instruments.venues[key] = &instrumentVenue{load: load}
+	}
+	instruments.rw.Unlock()
+	if ok {
+		v.lk.Lock()
+		v.load = load
+		v.lk.Unlock()
+	}
+}
+
+// RegisterInstruments 更新合约信息, 同时按统一交易对及交易所交易对索引
+func RegisterInstruments(list ...*Instrument) {
+	instruments.rw.Lock()
+	defer instruments.rw.Unlock()
+	for _, in := range list {
+		key := venueKey(in.Exname, in.Extype)
+		instruments.bySymbol[key+"_"+in.Symbol] = in
+		instruments.byVenue[key+"_"+in.VenueSymbol] = in
+		instruments.byName[in.Symbol] = in
+		instruments.byName[in.VenueSymbol] = in
+	}
+}
+
+// LoadInstruments 调用加载函数并注册, 同一交易所同时只加载一次
+func LoadInstruments(exname, extype string) error {
+	instruments.rw.RLock()
+	v, ok := instruments.venues[venueKey(exname, extype)]
+	instruments.rw.RUnlock()
+	if !ok {
+		return ErrNoInstrumentLoader
+	}
+	v.lk.Lock()
+	defer v.lk.Unlock()
+	return v.reload(time.Time{})
+}
+
+// reload since 之后已加载过时跳过, since 为零值时强制加载
+func (v *instrumentVenue) reload(since time.Time) error {
+	if !since.IsZero() && v.loadTime.After(since) {
+		return nil
+	}
+	v.loadTime = time.Now()
+	list, err := v.load()
+	if err != nil {
+		return err
+	}
+	RegisterInstruments(list...)
+	return nil
+}
+
+// background 后台加载, 已在加载时跳过, 不阻塞查询方
+func (v *instrumentVenue) background(since time.Time) {
+	if !atomic.CompareAndSwapInt32(&v.loading, 0, 1) {
+		return
+	}
+	go func() {
+		defer atomic.StoreInt32(&v.loading, 0)
+		v.lk.Lock()
+		defer v.lk.Unlock()
+		v.reload(since)
+	}()
+}
+
+// lookupInstrument 未找到时按 InstrumentReload 间隔加载, wait 为 false 时后台加载并直接返回 nil
+func lookupInstrument(index map[string]*Instrument, exname, extype, symbol string, wait bool) *Instrument {
+	key := venueKey(exname, extype)
+	instruments.rw.RLock()
+	in, ok := index[key+"_"+symbol]
+	v := instruments.venues[key]
+	instruments.rw.RUnlock()
+	if ok || v == nil {
+		return in
+	}
+	since := time.Now().Add(-InstrumentReload)
+	if !wait {
+		v.background(since)
+		return nil
+	}
+	// 等待其他协程的加载结束, 最近已加载过则不再请求
+	v.lk.Lock()
+	err := v.reload(since)
+	v.lk.Unlock()
+	if err != nil {
+		return nil
+	}
+	instruments.rw.RLock()
+	defer instruments.rw.RUnlock()
+	return index[key+"_"+symbol]
+}
+
+// GetInstrument 按统一交易对查询, 未找到时后台加载并返回 nil, 不阻塞推送协程
+func GetInstrument(exname, extype, symbol string) *Instrument {
+	return lookupInstrument(instruments.bySymbol, exname, extype, symbol, false)
+}
+
+// WaitInstrument 按统一交易对查询, 未找到时等待加载, 用于 REST 下单等调用方
+func WaitInstrument(exname, extype, symbol string) *Instrument {
+	return lookupInstrument(instruments.bySymbol, exname, extype, symbol, true)
+}
+
+// VenueInstrument 按交易所交易对查询, 未找到时后台加载并返回 nil
+func VenueInstrument(exname, extype, venue string) *Instrument {
+	return lookupInstrument(instruments.byVenue, exname, extype, venue, false)
+}
+
+// FindInstrument 在已加载的所有交易所中按统一交易对或交易所交易对查询, 不触发加载
+func FindInstrument(symbol string) *Instrument {
+	instruments.rw.RLock()
+	defer instruments.rw.RUnlock()
+	return instruments.byName[symbol]
+}
@@ -0,0 +1,115 @@
+package exch
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseMultiplier(t *testing.T) {
+	cases := []struct {
+		asset, base string
+		mul         float64
+	}{
+		{"1000SHIB", "SHIB", 1000},
+		{"1000000MOG", "MOG", 1e6},
+		{"1MBABYDOGE", "BABYDOGE", 1e6},
+		{"1INCH", "1INCH", 1},
+		{"BTC", "BTC", 1},
+		{"2000ABC", "2000ABC", 1},
+	}
+	for _, c := range cases {
+		if base, mul := ParseMultiplier(c.asset); base != c.base || mul != c.mul {
+			t.Fatalf("%s got %s %v", c.asset, base, mul)
+		}
+	}
+}
+
+func TestInstrumentConvert(t *testing.T) {
+	in := &Instrument{Multiplier: 1000, ContractSize: 1}
+	if got := in.PriceStr("0.0123400"); got != "0.00001234" {
+		t.Fatalf("price str got %s", got)
+	}
+	if got := in.SizeFromVenue(2.5); got != 2500 || in.SizeToVenue(got) != 2.5 {
+		t.Fatalf("size got %v", got)
+	}
+	if sc := in.PriceScale(NewScale(0.000001)); sc.Float != 9 || sc.Step != 1 {
+		t.Fatalf("scale got %+v", sc)
+	}
+	gate := &Instrument{Multiplier: 1, ContractSize: 0.0001}
+	if got := gate.SizeFromVenue(3); got != 0.0003 || gate.SizeToVenue(got) != 3 {
+		t.Fatalf("contract size got %v", got)
+	}
+	// 未加载合约信息时按 1 换算
+	var none *Instrument
+	if none.PriceStr("1.50") != "1.5" || none.SizeFromVenue(2) != 2 || none.PriceFromVenue(3) != 3 {
+		t.Fatal("nil instrument not identity")
+	}
+}
+
+func TestInstrumentRegistry(t *testing.T) {
+	loads := 0
+	fail := true
+	RegisterInstrumentLoader("test", Futures, func() ([]*Instrument, error) {
+		loads++
+		if fail {
+			return nil, errors.New("down")
+		}
+		return []*Instrument{{
+			Symbol: "SHIB_USDT", VenueSymbol: "1000SHIBUSDT", Exname: "test", Extype: Futures,
+			Base: "SHIB", Quote: "USDT", Multiplier: 1000,
+		}}, nil
+	})
+	if in := WaitInstrument("test", Futures, "SHIB_USDT"); in != nil || loads != 1 {
+		t.Fatalf("failed load got %v loads=%d", in, loads)
+	}
+	// InstrumentReload 内不重复加载
+	if in := VenueInstrument("test", Futures, "1000SHIBUSDT"); in != nil || loads != 1 {
+		t.Fatalf("throttle got %v loads=%d", in, loads)
+	}
+	fail = false
+	if err := LoadInstruments("test", Futures); err != nil || loads != 2 {
+		t.Fatalf("load err=%v loads=%d", err, loads)
+	}
+	in := VenueInstrument("test", Futures, "1000SHIBUSDT")
+	if in == nil || in.Symbol != "SHIB_USDT" || GetInstrument("test", Futures, "SHIB_USDT") != in {
+		t.Fatalf("lookup got %+v", in)
+	}
+	if base, quote := GetBaseQuote("1000SHIBUSDT"); base != "SHIB" || quote != "USDT" {
+		t.Fatalf("base quote got %s %s", base, quote)
+	}
+	if base, quote := GetBaseQuote("ETH_BTC"); base != "ETH" || quote != "BTC" {
+		t.Fatalf("base quote got %s %s", base, quote)
+	}
+	// 未加载的交易所交易对不按 _ 拆分
+	if base, quote := GetBaseQuote("XRPUSDT_230630"); base != "" || quote != "" {
+		t.Fatalf("venue base quote got %s %s", base, quote)
+	}
+	if base, quote := GetBaseQuote("BTC_USDT_20230630"); base != "BTC" || quote != "USDT" {
+		t.Fatalf("delivery base quote got %s %s", base, quote)
+	}
+	if err := LoadInstruments("none", Spot); err != ErrNoInstrumentLoader {
+		t.Fatalf("no loader got %v", err)
+	}
+}
+
+func TestInstrumentBackground(t *testing.T) {
+	var loads int32
+	block := make(chan struct{})
+	RegisterInstrumentLoader("bg", Spot, func() ([]*Instrument, error) {
+		atomic.AddInt32(&loads, 1)
+		<-block
+		return []*Instrument{{Symbol: "BTC_USDT", VenueSymbol: "BTCUSDT", Exname: "bg", Extype: Spot, Base: "BTC", Quote: "USDT"}}, nil
+	})
+	// 推送协程查询不等待加载, 加载中不重复请求
+	for i := 0; i < 3; i++ {
+		if in := VenueInstrument("bg", Spot, "BTCUSDT"); in != nil {
+			t.Fatalf("got %+v", in)
+		}
+	}
+	close(block)
+	waitFor(t, func() bool { return GetInstrument("bg", Spot, "BTC_USDT") != nil })
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Fatalf("loads got %d", n)
+	}
+}
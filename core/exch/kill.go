@@ -0,0 +1,399 @@
+package exch
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"high-freq-quant-go/adapter/text"
+	"high-freq-quant-go/core/log"
+)
+
+// KillConfig 紧急停止参数, 通过 Kill.Configure 设置
+type KillConfig struct {
+	Flatten     bool          //撤单后按仓位反向下 ioc 单平仓
+	Retries     int           //撤单及平仓最多尝试次数
+	Interval    time.Duration //每次尝试后等待推送确认的时间
+	MaxDrawdown float64       //权益从最高点回撤超过该值时触发, <= 0 不检查
+	OnDrift     bool          //仓位校对告警时触发
+	Symbols     []string      //除订单及仓位外额外撤单的交易对
+}
+
+var DefaultKill = KillConfig{
+	Retries:  10,
+	Interval: time.Second,
+}
+
+// KillResult 单个账号及交易对的处理结果
+type KillResult struct {
+	Sign       string
+	Symbol     string
+	Cancelled  bool    //REST 查询及推送确认没有挂单
+	OpenOrders int     //最后一次确认时的挂单数
+	Position   float64 //最后一次确认时的仓位, 双向持仓时为两个方向数量的绝对值之和
+	Errors     []string
+}
+
+// KillReport 紧急停止结果
+type KillReport struct {
+	Reason     string
+	Start, End time.Time
+	Results    []*KillResult
+	Errors     []string //查询账号挂单失败, 可能遗漏交易对
+}
+
+// Done 所有交易对均已撤单, 开启平仓时仓位均为 0
+func (r *KillReport) Done() bool {
+	if len(r.Errors) != 0 {
+		return false
+	}
+	for _, res := range r.Results {
+		if !res.Cancelled || res.Position != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// KillSwitch 紧急停止, 触发后所有 Exchanger 拒绝新订单, 撤销所有账号所有交易对的挂单并按配置平仓
+type KillSwitch struct {
+	cfg    KillConfig
+	active int32
+	report *KillReport
+	done   chan struct{}
+	peak   float64
+	lk     sync.Mutex
+}
+
+var Kill = NewKillSwitch(DefaultKill)
+
+func NewKillSwitch(cfg KillConfig) *KillSwitch {
+	return &KillSwitch{cfg: cfg, done: make(chan struct{})}
+}
+
+func (k *KillSwitch) Configure(cfg KillConfig) {
+	k.lk.Lock()
+	defer k.lk.Unlock()
+	k.cfg = cfg
+}
+
+func (k *KillSwitch) config() KillConfig {
+	k.lk.Lock()
+	defer k.lk.Unlock()
+	return k.cfg
+}
+
+// Active 已触发, 新订单被拒绝
+func (k *KillSwitch) Active() bool {
+	return atomic.LoadInt32(&k.active) == 1
+}
+
+// Trigger 触发并等待撤单及平仓完成, 重复触发时等待第一次的结果
+func (k *KillSwitch) Trigger(reason string) *KillReport {
+	if !atomic.CompareAndSwapInt32(&k.active, 0, 1) {
+		k.lk.Lock()
+		done := k.done
+		k.lk.Unlock()
+		<-done
+		return k.Report()
+	}
+	k.lk.Lock()
+	done := k.done
+	k.lk.Unlock()
+	log.Errorln(log.Stt, "======kill switch triggered======", reason)
+	report := k.run(reason)
+	k.lk.Lock()
+	k.report = report
+	k.lk.Unlock()
+	close(done)
+	return report
+}
+
+// Reset 恢复下单, 用于人工确认后继续交易
+func (k *KillSwitch) Reset() {
+	k.lk.Lock()
+	defer k.lk.Unlock()
+	if k.Active() {
+		select {
+		case <-k.done:
+		default:
+			return
+		}
+	}
+	k.done = make(chan struct{})
+	k.peak = 0
+	atomic.StoreInt32(&k.active, 0)
+	log.Warnln(log.Stt, "kill switch reset")
+}
+
+// Report 最后一次触发的结果
+func (k *KillSwitch) Report() *KillReport {
+	k.lk.Lock()
+	defer k.lk.Unlock()
+	return k.report
+}
+
+// WatchSignal 收到信号时触发
+func (k *KillSwitch) WatchSignal(sigs ...os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	go func() {
+		sig := <-ch
+		k.Trigger(fmt.Sprintf("signal %v", sig))
+	}()
+}
+
+// OnEquity 更新账号权益, 从最高点回撤超过 MaxDrawdown 时触发
+func (k *KillSwitch) OnEquity(equity float64) {
+	cfg := k.config()
+	if cfg.MaxDrawdown <= 0 || k.Active() {
+		return
+	}
+	k.lk.Lock()
+	if equity > k.peak {
+		k.peak = equity
+	}
+	peak := k.peak
+	k.lk.Unlock()
+	if dd := peak - equity; dd > cfg.MaxDrawdown {
+		go k.Trigger(fmt.Sprintf("drawdown %v from peak %v", dd, peak))
+	}
+}
+
+// watch 仓位校对告警时触发
+func (k *KillSwitch) watch(fd *Feed) {
+	fd.OnDrift("", func(d *Drift) {
+		if d.Alert && k.config().OnDrift && !k.Active() {
+			go k.Trigger(fmt.Sprintf("%s drift %s %v", d.Kind, d.Symbol, d.Diff))
+		}
+	})
+}
+
+// OpenOrderLister 连接的 REST 查询账号所有交易对的挂单, 实现时紧急停止按其结果确定撤单的交易对并确认撤单
+type OpenOrderLister interface {
+	ListOpenOrders(ctx context.Context) ([]*Order, error)
+}
+
+type killTarget struct {
+	sign   string
+	mn     *Exchanger
+	ex     Exchange
+	symbol string
+}
+
+func (k *KillSwitch) run(reason string) *KillReport {
+	cfg := k.config()
+	report := &KillReport{Reason: reason, Start: time.Now()}
+	targets, errs := k.targets(cfg)
+	report.Errors = errs
+	results := make([]*KillResult, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t *killTarget) {
+			defer wg.Done()
+			results[i] = k.stop(cfg, t)
+		}(i, t)
+	}
+	wg.Wait()
+	report.Results = results
+	report.End = time.Now()
+	for _, res := range results {
+		log.Warnf(log.Stt, "kill switch %s %s cancelled=%v open=%d position=%v errors=%v \r\n",
+			res.Sign, res.Symbol, res.Cancelled, res.OpenOrders, res.Position, res.Errors)
+	}
+	for _, e := range report.Errors {
+		log.Errorln(log.Stt, "kill switch", e)
+	}
+	log.Errorln(log.Stt, "======kill switch done======", reason, "done:", report.Done(), "cost:", report.End.Sub(report.Start))
+	return report
+}
+
+// targets 所有 Exchanger 中有挂单或仓位的交易对, 同一账号同一连接类型只处理一次,
+// 连接支持时按 REST 查询的账号挂单补充推送及 OMS 未记录的交易对
+func (k *KillSwitch) targets(cfg KillConfig) ([]*killTarget, []string) {
+	seen := map[string]bool{}
+	listed := map[string]bool{}
+	var targets []*killTarget
+	var errs []string
+	for _, mn := range AllManage.List() {
+		if mn.Ex == nil {
+			continue
+		}
+		apiSign := text.GetString(mn.Ctx, ApiSign)
+		symbols := map[string]bool{}
+		for _, s := range cfg.Symbols {
+			symbols[s] = true
+		}
+		if lister, ok := openOrderLister(rawExchange(mn.Ex)); ok && !listed[apiSign+"_"+mn.NameType] {
+			listed[apiSign+"_"+mn.NameType] = true
+			orders, err := lister.ListOpenOrders(mn.Ctx)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s list open orders error %v", mn.Sign, err))
+			}
+			for _, o := range orders {
+				symbols[o.Symbol] = true
+			}
+		}
+		if mn.OMS != nil {
+			for _, o := range mn.OMS.Orders(OMSQuery{Status: OrderOpen}) {
+				symbols[o.Symbol] = true
+			}
+		}
+		if cfg.Flatten {
+			_, positions := mn.Ex.ListAsset(mn.Ctx)
+			for s, pos := range positions {
+				if pos != nil && pos.Size != 0 {
+					// 双向持仓时键包含持仓方向
+					if pos.Symbol != "" {
+						s = pos.Symbol
+					}
+					symbols[s] = true
+				}
+			}
+		}
+		if s := text.GetString(mn.Ctx, CtxSymbol); s != "" {
+			symbols[s] = true
+		}
+		for s := range symbols {
+			key := apiSign + "_" + mn.NameType + "_" + s
+			if s == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			targets = append(targets, &killTarget{sign: mn.Sign, mn: mn, ex: rawExchange(mn.Ex), symbol: s})
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].sign+targets[i].symbol < targets[j].sign+targets[j].symbol
+	})
+	return targets, errs
+}
+
+// stop 撤单直到推送确认没有挂单, 按配置平仓直到仓位为 0
+func (k *KillSwitch) stop(cfg KillConfig, t *killTarget) *KillResult {
+	res := &KillResult{Sign: t.sign, Symbol: t.symbol}
+	ctx := WithSymbol(context.Background(), t.symbol)
+	retries := cfg.Retries
+	if retries <= 0 {
+		retries = 1
+	}
+	for i := 0; i < retries && !res.Cancelled; i++ {
+		if _, err := t.ex.CancelAllOrder(ctx, t.symbol); err != nil {
+			res.Errors = append(res.Errors, err.Error())
+		}
+		time.Sleep(cfg.Interval)
+		open, err := openOrders(ctx, t)
+		if err != nil {
+			res.Errors = append(res.Errors, err.Error())
+			continue
+		}
+		res.OpenOrders = open
+		res.Cancelled = open == 0
+	}
+	sides := []string{""}
+	if HedgeMode(t.mn.Ctx) {
+		sides = []string{PositionLong, PositionShort}
+	}
+	if size, ok := position(ctx, t, sides); ok {
+		res.Position = size
+	}
+	if !cfg.Flatten {
+		return res
+	}
+	for i := 0; i < retries && res.Position != 0; i++ {
+		for _, side := range sides {
+			pos := t.ex.GetPosition(WithPositionSide(ctx, side))
+			if pos == nil || pos.Size == 0 {
+				continue
+			}
+			req := &OrderRequest{
+				Symbol:       t.symbol,
+				Size:         -pos.Size,
+				Tif:          OrderIoc,
+				UUID:         NewClientId(),
+				ReduceOnly:   true,
+				PositionSide: side,
+			}
+			if _, err := t.ex.PlaceOrder(ctx, req); err != nil {
+				res.Errors = append(res.Errors, err.Error())
+			}
+		}
+		time.Sleep(cfg.Interval)
+		if size, ok := position(ctx, t, sides); ok {
+			res.Position = size
+		}
+	}
+	return res
+}
+
+// position 推送维护的仓位, 双向持仓时为各方向数量的绝对值之和, 没有仓位数据时 ok 为 false
+func position(ctx context.Context, t *killTarget, sides []string) (size float64, ok bool) {
+	if len(sides) == 1 {
+		if pos := t.ex.GetPosition(ctx); pos != nil {
+			return pos.Size, true
+		}
+		return 0, false
+	}
+	for _, side := range sides {
+		if pos := t.ex.GetPosition(WithPositionSide(ctx, side)); pos != nil {
+			size += math.Abs(pos.Size)
+			ok = true
+		}
+	}
+	return size, ok
+}
+
+// openOrders 挂单数, 取 REST 查询、订单推送及 OMS 中的最大值, REST 查询失败时不能确认
+func openOrders(ctx context.Context, t *killTarget) (int, error) {
+	n := 0
+	if lister, ok := openOrderLister(t.ex); ok {
+		orders, err := lister.ListOpenOrders(t.mn.Ctx)
+		if err != nil {
+			return 0, err
+		}
+		for _, o := range orders {
+			if o.Symbol == t.symbol {
+				n++
+			}
+		}
+	}
+	local := 0
+	for _, o := range t.ex.GetOrder(ctx) {
+		if o != nil && o.Status != OrderFinished {
+			local++
+		}
+	}
+	if local > n {
+		n = local
+	}
+	if t.mn.OMS != nil {
+		if open := len(t.mn.OMS.Open(t.symbol)); open > n {
+			n = open
+		}
+	}
+	return n, nil
+}
+
+// openOrderLister 连接是否支持 REST 查询挂单, OMSGate 取其包装的连接
+func openOrderLister(ex Exchange) (OpenOrderLister, bool) {
+	if g, ok := ex.(*OMSGate); ok {
+		ex = g.Exchange
+	}
+	lister, ok := ex.(OpenOrderLister)
+	return lister, ok
+}
+
+// rawExchange 去掉风控包装, 紧急停止的撤单及平仓不受拦截
+func rawExchange(ex Exchange) Exchange {
+	if g, ok := ex.(*RiskGate); ok {
+		return g.Exchange
+	}
+	return ex
+}
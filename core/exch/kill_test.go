@@ -0,0 +1,158 @@
+package exch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"high-freq-quant-go/adapter/text"
+)
+
+type killMock struct {
+	Exchange
+	lk      sync.Mutex
+	orders  map[string]*Order
+	pos     *Position
+	cancels int
+}
+
+func (m *killMock) CancelAllOrder(ctx context.Context, symbol string) ([]*Order, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.cancels++
+	// 第一次撤单推送未到
+	if m.cancels > 1 {
+		m.orders = map[string]*Order{}
+	}
+	return nil, nil
+}
+
+func (m *killMock) GetOrder(ctx context.Context) map[string]*Order {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	return m.orders
+}
+
+func (m *killMock) GetPosition(ctx context.Context) *Position {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if text.GetString(ctx, CtxSymbol) != m.pos.Symbol {
+		return nil
+	}
+	cp := *m.pos
+	return &cp
+}
+
+func (m *killMock) ListAsset(ctx context.Context) (*Balance, map[string]*Position) {
+	return nil, map[string]*Position{m.pos.Symbol: m.GetPosition(WithSymbol(ctx, m.pos.Symbol))}
+}
+
+func (m *killMock) PlaceOrder(ctx context.Context, req *OrderRequest) (*Order, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if req.Tif != OrderIoc || !req.ReduceOnly || req.Size != -m.pos.Size {
+		return nil, ErrInvalidOrder
+	}
+	m.pos.Size += req.Size
+	return req.Order(), nil
+}
+
+func TestKillSwitch(t *testing.T) {
+	for _, mn := range AllManage.List() {
+		AllManage.DelEx(mn.Sign)
+	}
+	mock := &killMock{
+		orders: map[string]*Order{"1": {Id: "1", Symbol: "BTC_USDT", Size: 1, Price: 100, Status: OrderOpen}},
+		pos:    &Position{Symbol: "ETH_USDT", Size: -2},
+	}
+	Register("killtest_"+Futures, func(ctx context.Context) Exchange { return mock })
+	ctx := context.WithValue(context.Background(), ApiSign, "acc")
+	ctx = context.WithValue(ctx, CtxExname, "killtest")
+	ctx = context.WithValue(ctx, CtxExtype, Futures)
+	mn := NewExchanger(WithSymbol(ctx, "BTC_USDT"), "k1")
+	defer Delete("acc", "k1")
+
+	Kill.Configure(KillConfig{Flatten: true, Retries: 3, Interval: time.Millisecond})
+	defer Kill.Configure(DefaultKill)
+	report := Kill.Trigger("test")
+	defer Kill.Reset()
+	if !report.Done() || len(report.Results) != 2 || mock.cancels < 2 {
+		t.Fatalf("report %+v cancels %d", report.Results, mock.cancels)
+	}
+	if mock.pos.Size != 0 {
+		t.Fatalf("position not flattened %v", mock.pos.Size)
+	}
+	_, err := mn.Ex.PlaceOrder(ctx, &OrderRequest{Symbol: "BTC_USDT", Size: 1, Price: 100})
+	var re *RiskError
+	if !errors.As(err, &re) || re.Reason != RiskKilled {
+		t.Fatalf("order after kill got %v", err)
+	}
+	if Kill.Trigger("again") != report {
+		t.Fatal("second trigger ran again")
+	}
+}
+
+// killListMock 推送及 OMS 没有记录的挂单只能通过 REST 查询发现
+type killListMock struct {
+	killMock
+	remote map[string]int
+	err    error
+}
+
+func (m *killListMock) CancelAllOrder(ctx context.Context, symbol string) ([]*Order, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.cancels++
+	delete(m.remote, symbol)
+	return nil, nil
+}
+
+func (m *killListMock) ListOpenOrders(ctx context.Context) ([]*Order, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	var orders []*Order
+	for s, n := range m.remote {
+		for i := 0; i < n; i++ {
+			orders = append(orders, &Order{Symbol: s, Status: OrderOpen})
+		}
+	}
+	return orders, nil
+}
+
+func TestKillSwitchListOrders(t *testing.T) {
+	for _, mn := range AllManage.List() {
+		AllManage.DelEx(mn.Sign)
+	}
+	mock := &killListMock{
+		killMock: killMock{pos: &Position{Symbol: "ETH_USDT"}},
+		remote:   map[string]int{"SOL_USDT": 2},
+	}
+	Register("killlisttest_"+Futures, func(ctx context.Context) Exchange { return mock })
+	ctx := context.WithValue(context.Background(), ApiSign, "acc")
+	ctx = context.WithValue(ctx, CtxExname, "killlisttest")
+	ctx = context.WithValue(ctx, CtxExtype, Futures)
+	NewExchanger(ctx, "k1")
+	defer Delete("acc", "k1")
+
+	ks := NewKillSwitch(KillConfig{Retries: 2, Interval: time.Millisecond})
+	report := ks.Trigger("test")
+	if !report.Done() || len(report.Results) != 1 || report.Results[0].Symbol != "SOL_USDT" {
+		t.Fatalf("report %+v", report)
+	}
+	if len(mock.remote) != 0 {
+		t.Fatalf("remote orders left %v", mock.remote)
+	}
+
+	// 查询失败时不能确认撤单
+	mock.remote = map[string]int{"SOL_USDT": 1}
+	mock.err = errors.New("timeout")
+	ks = NewKillSwitch(KillConfig{Retries: 2, Interval: time.Millisecond})
+	if report := ks.Trigger("test"); report.Done() || len(report.Errors) != 1 {
+		t.Fatalf("report %+v", report)
+	}
+}
@@ -0,0 +1,67 @@
+package exch
+
+import (
+	"context"
+
+	"high-freq-quant-go/adapter/queue"
+)
+
+// 连接内的消息通道, 订单薄与私有数据分开处理, 订单薄积压不会阻塞委托单及成交推送
+const (
+	LaneWss      = "wss"      //原始消息
+	LaneDepth    = "depth"    //订单薄增量, 丢弃后按 id 不连续重新拉取快照, 不应使用 Conflate
+	LaneUser     = "user"     //订单薄以外的消息, 包括委托单/成交/仓位/资金等私有数据
+	LaneBookData = "bookdata" //BookDataChannel 推送
+	LaneBookMsg  = "bookmsg"  //BookMsgChan 推送
+)
+
+// DefaultOverflow 各通道默认溢出策略, 私有数据不丢弃
+var DefaultOverflow = map[string]queue.Policy{
+	LaneWss:      queue.Block,
+	LaneDepth:    queue.DropOldest,
+	LaneUser:     queue.Block,
+	LaneBookData: queue.Block,
+	LaneBookMsg:  queue.Block,
+}
+
+// WithOverflow 为通道指定溢出策略, 在创建 Exchanger 或连接前设置
+func WithOverflow(ctx context.Context, lane string, p queue.Policy) context.Context {
+	m := map[string]queue.Policy{}
+	if ret, ok := ctx.Value(CtxQueue).(map[string]queue.Policy); ok {
+		for k, v := range ret {
+			m[k] = v
+		}
+	}
+	m[lane] = p
+	return context.WithValue(ctx, CtxQueue, m)
+}
+
+func GetOverflow(ctx context.Context, lane string) queue.Policy {
+	if ret, ok := ctx.Value(CtxQueue).(map[string]queue.Policy); ok {
+		if p, ok := ret[lane]; ok {
+			return p
+		}
+	}
+	return DefaultOverflow[lane]
+}
+
+func NewLane(ctx context.Context, lane string, size int64) *queue.Queue {
+	return queue.New(ctx, lane, size, GetOverflow(ctx, lane))
+}
+
+// NewOutLane 写入调用方传入的通道, 如 BookDataChannel
+func NewOutLane(ctx context.Context, lane string, out chan interface{}) *queue.Queue {
+	return queue.NewOut(ctx, lane, out, GetOverflow(ctx, lane))
+}
+
+// QueueStater 连接的消息通道统计, 包括积压, 丢弃及合并的消息数
+type QueueStater interface {
+	QueueStats() []queue.Stat
+}
+
+func QueueStats(ex Exchange) []queue.Stat {
+	if qs, ok := ex.(QueueStater); ok {
+		return qs.QueueStats()
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package exch
+
+import (
+	"context"
+
+	"high-freq-quant-go/adapter/text"
+)
+
+// Legacy 旧版 context 传参接口的兼容层
+// 适配器内嵌 Legacy 并指向自身的 Trader 实现, 从 context 取出参数后转为类型化调用
+type Legacy struct {
+	Trader Trader
+}
+
+func NewLegacy(t Trader) Legacy {
+	return Legacy{Trader: t}
+}
+
+func (lg Legacy) CreateOrder(ctx context.Context) (*Order, error) {
+	o := GetOrder(ctx)
+	if o == nil {
+		return nil, ErrEmptyOrder
+	}
+	return lg.Trader.PlaceOrder(ctx, NewOrderRequest(o))
+}
+
+func (lg Legacy) CreateBatchOrder(ctx context.Context) ([]*Order, error) {
+	lists := GetOrders(ctx)
+	if len(lists) == 0 {
+		return nil, ErrEmptyOrder
+	}
+	reqs := make([]*OrderRequest, 0, len(lists))
+	for _, o := range lists {
+		reqs = append(reqs, NewOrderRequest(o))
+	}
+	return lg.Trader.PlaceBatchOrder(ctx, reqs)
+}
+
+func (lg Legacy) CannelOrder(ctx context.Context) (*Order, error) {
+	o := GetOrder(ctx)
+	if o == nil {
+		return nil, ErrEmptyOrder
+	}
+	return lg.Trader.CancelOrder(ctx, &CancelRequest{Symbol: o.Symbol, Id: o.Id})
+}
+
+func (lg Legacy) CannelAllOrder(ctx context.Context) ([]*Order, error) {
+	return lg.Trader.CancelAllOrder(ctx, text.GetString(ctx, CtxSymbol))
+}
+
+func (lg Legacy) UpdateLeverage(ctx context.Context) (*Position, error) {
+	return lg.Trader.SetLeverage(ctx, text.GetString(ctx, CtxSymbol), GetLeverage(ctx))
+}
+
+func (lg Legacy) UpdateMargin(ctx context.Context) (*Position, error) {
+	return lg.Trader.SetMargin(ctx, text.GetString(ctx, CtxSymbol), text.GetFloat(ctx, CtxChange))
+}
+
+func (lg Legacy) SubTicker(ctx context.Context) error {
+	return lg.Trader.SubscribeTicker(ctx, text.GetString(ctx, CtxSymbol))
+}
+
+func (lg Legacy) SubOrderBook(ctx context.Context) error {
+	return lg.Trader.SubscribeOrderBook(ctx, text.GetString(ctx, CtxSymbol))
+}
+
+func (lg Legacy) SubOrder(ctx context.Context) error {
+	return lg.Trader.SubscribeOrder(ctx, text.GetString(ctx, CtxSymbol))
+}
+
+func (lg Legacy) SubUserTrade(ctx context.Context) error {
+	return lg.Trader.SubscribeUserTrade(ctx, text.GetString(ctx, CtxSymbol))
+}
+
+func (lg Legacy) SubPublicTrades(ctx context.Context) error {
+	return lg.Trader.SubscribePublicTrades(ctx, text.GetString(ctx, CtxSymbol))
+}
+
+func (lg Legacy) SubPosition(ctx context.Context) error {
+	return lg.Trader.SubscribePosition(ctx, text.GetString(ctx, CtxSymbol))
+}
+
+func (lg Legacy) SubBalance(ctx context.Context) error {
+	return lg.Trader.SubscribeBalance(ctx, text.GetString(ctx, CtxSymbol))
+}
+
+func (lg Legacy) UnsubTicker(ctx context.Context) error {
+	return lg.Trader.UnsubscribeTicker(ctx, text.GetString(ctx, CtxSymbol))
+}
+
+func (lg Legacy) UnsubOrderBook(ctx context.Context) error {
+	return lg.Trader.UnsubscribeOrderBook(ctx, text.GetString(ctx, CtxSymbol))
+}
+
+func (lg Legacy) UnsubOrder(ctx context.Context) error {
+	return lg.Trader.UnsubscribeOrder(ctx, text.GetString(ctx, CtxSymbol))
+}
+
+func (lg Legacy) UnsubUserTrade(ctx context.Context) error {
+	return lg.Trader.UnsubscribeUserTrade(ctx, text.GetString(ctx, CtxSymbol))
+}
+
+func (lg Legacy) UnsubPublicTrades(ctx context.Context) error {
+	return lg.Trader.UnsubscribePublicTrades(ctx, text.GetString(ctx, CtxSymbol))
+}
+
+func (lg Legacy) UnsubPosition(ctx context.Context) error {
+	return lg.Trader.UnsubscribePosition(ctx, text.GetString(ctx, CtxSymbol))
+}
@@ -0,0 +1,165 @@
+package exch
+
+import (
+	"context"
+	"testing"
+)
+
+type mockTrader struct {
+	order  *OrderRequest
+	cancel *CancelRequest
+	symbol string
+	lv     float64
+	change float64
+}
+
+func (m *mockTrader) PlaceOrder(ctx context.Context, req *OrderRequest) (*Order, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	m.order = req
+	return req.Order(), nil
+}
+
+func (m *mockTrader) PlaceBatchOrder(ctx context.Context, reqs []*OrderRequest) ([]*Order, error) {
+	res := []*Order{}
+	for _, req := range reqs {
+		res = append(res, req.Order())
+	}
+	return res, nil
+}
+
+func (m *mockTrader) CancelOrder(ctx context.Context, req *CancelRequest) (*Order, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	m.cancel = req
+	return req.Order(), nil
+}
+
+func (m *mockTrader) CancelAllOrder(ctx context.Context, symbol string) ([]*Order, error) {
+	m.symbol = symbol
+	return nil, nil
+}
+
+func (m *mockTrader) AmendOrder(ctx context.Context, req *AmendRequest) (*AmendResult, error) {
+	return nil, nil
+}
+
+func (m *mockTrader) SetLeverage(ctx context.Context, symbol string, lv float64) (*Position, error) {
+	m.symbol, m.lv = symbol, lv
+	return &Position{Symbol: symbol, Lv: lv}, nil
+}
+
+func (m *mockTrader) SetMargin(ctx context.Context, symbol string, change float64) (*Position, error) {
+	m.symbol, m.change = symbol, change
+	return &Position{Symbol: symbol}, nil
+}
+
+func (m *mockTrader) SetMarginMode(ctx context.Context, symbol, mode string) error { return nil }
+func (m *mockTrader) SetPositionMode(ctx context.Context, hedge bool) error        { return nil }
+func (m *mockTrader) SetRiskLimit(ctx context.Context, symbol string, limit float64) error {
+	return nil
+}
+func (m *mockTrader) FetchPosition(ctx context.Context, symbol string) (*Position, error) {
+	return &Position{Symbol: symbol, Lv: m.lv}, nil
+}
+
+func (m *mockTrader) SubscribeTicker(ctx context.Context, symbol string) error {
+	m.symbol = symbol
+	return nil
+}
+
+func (m *mockTrader) SubscribeOrderBook(ctx context.Context, symbol string) error {
+	m.symbol = symbol
+	return nil
+}
+
+func (m *mockTrader) SubscribeOrder(ctx context.Context, symbol string) error     { return nil }
+func (m *mockTrader) SubscribeUserTrade(ctx context.Context, symbol string) error { return nil }
+func (m *mockTrader) SubscribePosition(ctx context.Context, symbol string) error  { return nil }
+func (m *mockTrader) SubscribeBalance(ctx context.Context, symbol string) error   { return nil }
+func (m *mockTrader) SubscribePublicTrades(ctx context.Context, symbol string) error {
+	return nil
+}
+
+func (m *mockTrader) UnsubscribeTicker(ctx context.Context, symbol string) error { return nil }
+func (m *mockTrader) UnsubscribeOrderBook(ctx context.Context, symbol string) error {
+	m.symbol = symbol
+	return nil
+}
+func (m *mockTrader) UnsubscribeOrder(ctx context.Context, symbol string) error     { return nil }
+func (m *mockTrader) UnsubscribeUserTrade(ctx context.Context, symbol string) error { return nil }
+func (m *mockTrader) UnsubscribePosition(ctx context.Context, symbol string) error  { return nil }
+func (m *mockTrader) UnsubscribePublicTrades(ctx context.Context, symbol string) error {
+	return nil
+}
+
+func TestLegacyCreateOrder(t *testing.T) {
+	m := &mockTrader{}
+	lg := NewLegacy(m)
+
+	if _, err := lg.CreateOrder(context.Background()); err != ErrEmptyOrder {
+		t.Fatalf("missing order: got %v, want %v", err, ErrEmptyOrder)
+	}
+
+	ctx := context.WithValue(context.Background(), CtxOrder, &Order{Symbol: "BTC_USDT", Size: 1, Price: 100, UUID: "a1"})
+	o, err := lg.CreateOrder(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.order.Symbol != "BTC_USDT" || m.order.Size != 1 || m.order.Price != 100 || o.UUID != "a1" {
+		t.Fatalf("unexpected request %+v", m.order)
+	}
+
+	ctx = context.WithValue(context.Background(), CtxOrder, &Order{Symbol: "BTC_USDT"})
+	if _, err := lg.CreateOrder(ctx); err != ErrZeroSize {
+		t.Fatalf("zero size: got %v, want %v", err, ErrZeroSize)
+	}
+}
+
+func TestLegacyCannelOrder(t *testing.T) {
+	m := &mockTrader{}
+	lg := NewLegacy(m)
+
+	ctx := context.WithValue(context.Background(), CtxOrder, &Order{Symbol: "BTC_USDT"})
+	if _, err := lg.CannelOrder(ctx); err != ErrEmptyOrderId {
+		t.Fatalf("missing id: got %v, want %v", err, ErrEmptyOrderId)
+	}
+	ctx = context.WithValue(context.Background(), CtxOrder, &Order{Symbol: "BTC_USDT", Id: "42"})
+	if _, err := lg.CannelOrder(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if m.cancel.Id != "42" {
+		t.Fatalf("unexpected cancel %+v", m.cancel)
+	}
+}
+
+func TestLegacyUpdateLeverage(t *testing.T) {
+	for _, lv := range []interface{}{"10", 10.0, 10, int64(10)} {
+		m := &mockTrader{}
+		ctx := WithSymbol(context.Background(), "ETH_USDT")
+		ctx = context.WithValue(ctx, CtxLv, lv)
+		if _, err := NewLegacy(m).UpdateLeverage(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if m.symbol != "ETH_USDT" || m.lv != 10 {
+			t.Fatalf("lv %T: got %s %v", lv, m.symbol, m.lv)
+		}
+	}
+}
+
+func TestLegacySub(t *testing.T) {
+	m := &mockTrader{}
+	ctx := WithSymbol(context.Background(), "ETH_USDT")
+	if err := NewLegacy(m).SubOrderBook(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if m.symbol != "ETH_USDT" {
+		t.Fatalf("got symbol %s", m.symbol)
+	}
+	m.symbol = ""
+	if err := NewLegacy(m).UnsubOrderBook(ctx); err != nil || m.symbol != "ETH_USDT" {
+		t.Fatalf("unsub got %s %v", m.symbol, err)
+	}
+}
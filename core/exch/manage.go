@@ -19,6 +19,9 @@ type Exchanger struct {
 	Ctx      context.Context
 	Cancel   context.CancelFunc
 	sc       sync.Mutex
+
+	*Feed //OnBook OnTrade OnOrder OnPosition OnBalance 推送订阅
+	OMS   *OMS
 }
 
 func NewExchanger(ctx context.Context, id string) *Exchanger {
@@ -40,12 +43,17 @@ func NewExchanger(ctx context.Context, id string) *Exchanger {
 	}
 	ctx = context.WithValue(ctx, ConnSign, mn.Sign)
 	mn.Ctx, mn.Cancel = context.WithCancel(ctx)
+	mn.Feed = NewFeed(mn.Ctx)
+	mn.Ctx = context.WithValue(mn.Ctx, CtxFeed, mn.Feed)
+	mn.OMS = NewOMS(DefaultOMSHistory)
+	mn.OMS.Attach(mn.Feed)
 	conn := NewConn(mn.Ctx, mn.NameType)
 	if conn == nil {
 		mn.Cancel()
 		return nil
 	}
-	mn.Ex = conn
+	mn.Ex = NewRiskGate(mn.Ctx, NewOMSGate(conn, mn.OMS), mn.Sign, mn.Feed)
+	Kill.watch(mn.Feed)
 	AllManage.SetEx(mn.Sign, mn)
 	return mn
 }
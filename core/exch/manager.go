@@ -42,3 +42,14 @@ func (ma *AllManager) GetEx(s string) *Exchanger {
 	e := ma.ex[s]
 	return e
 }
+
+// List 所有已注册的 Exchanger
+func (ma *AllManager) List() []*Exchanger {
+	ma.lock.Lock()
+	defer ma.lock.Unlock()
+	res := make([]*Exchanger, 0, len(ma.ex))
+	for _, e := range ma.ex {
+		res = append(res, e)
+	}
+	return res
+}
@@ -2,42 +2,62 @@ package mbooker
 
 import (
 	"context"
-	"strings"
 	"sync"
 
 	"high-freq-quant-go/core/exch"
 
-	"high-freq-quant-go/adapter/convert"
-
-	"high-freq-quant-go/adapter/text"
-
 	"high-freq-quant-go/adapter/sort"
 )
 
+const BookerKey = "mbooker"
+
 type Booker struct {
-	Name, Symbol     string
-	Exname, Extype   string
+	exch.BookMeta
 	asks, bids       []float64
 	askBook, bidBook map[float64]float64
-	IsReady          bool
-	UpdateID         int64
-	ResponTime       int64
-	UpdateTime       int64
+	scale            exch.Scale
 	rw               sync.RWMutex
 }
 
 func NewBooker(ctx context.Context) *Booker {
-	bk := Booker{
-		Symbol:  strings.ToUpper(text.GetString(ctx, exch.CtxSymbol)),
-		Exname:  strings.ToLower(text.GetString(ctx, exch.CtxExname)),
-		Extype:  strings.ToLower(text.GetString(ctx, exch.CtxExtype)),
-		IsReady: false,
+	bk := &Booker{scale: exch.DecimalScale(exch.BookFloat)}
+	bk.InitMeta(ctx)
+	return bk
+}
+
+func (bk *Booker) price(p string) float64 {
+	return bk.scale.Float64(bk.scale.Parse(p))
+}
+
+// SetScale 设置价格刻度, 已有档位按新刻度重建
+func (bk *Booker) SetScale(sc exch.Scale) {
+	bk.rw.Lock()
+	old := bk.scale
+	bk.scale = sc
+	askData, bidData := map[string]float64{}, map[string]float64{}
+	for p, s := range bk.askBook {
+		askData[old.String(old.FromFloat(p))] = s
+	}
+	for p, s := range bk.bidBook {
+		bidData[old.String(old.FromFloat(p))] = s
+	}
+	isSet := bk.askBook != nil
+	bk.rw.Unlock()
+	if isSet {
+		bk.SetBook(askData, bidData)
 	}
-	bk.Name = bk.Exname + "_" + bk.Extype + "_" + bk.Symbol
-	return &bk
+}
+
+func (bk *Booker) GetScale() exch.Scale {
+	bk.rw.RLock()
+	defer bk.rw.RUnlock()
+	return bk.scale
 }
 
 func (bk *Booker) SetBook(AskData, BidData map[string]float64) {
+	bk.rw.RLock()
+	sc := bk.scale
+	bk.rw.RUnlock()
 	var asks, bids []float64
 	askBook, bidBook := map[float64]float64{}, map[float64]float64{}
 
@@ -46,22 +66,26 @@ func (bk *Booker) SetBook(AskData, BidData map[string]float64) {
 	go func(asks *[]float64, askBook map[float64]float64, AskData map[string]float64, wg *sync.WaitGroup) {
 		defer wg.Done()
 		for p, s := range AskData {
-			fp := convert.GetFloat64(p)
+			fp := sc.Float64(sc.Parse(p))
 			if fp == 0 || s == 0 {
 				continue
 			}
-			*asks = append(*asks, fp)
+			if _, ok := askBook[fp]; !ok {
+				*asks = append(*asks, fp)
+			}
 			askBook[fp] = s
 		}
 	}(&asks, askBook, AskData, &wg)
 	go func(bids *[]float64, bidBook map[float64]float64, BidData map[string]float64, wg *sync.WaitGroup) {
 		defer wg.Done()
 		for p, s := range BidData {
-			fp := convert.GetFloat64(p)
+			fp := sc.Float64(sc.Parse(p))
 			if fp == 0 || s == 0 {
 				continue
 			}
-			*bids = append(*bids, fp)
+			if _, ok := bidBook[fp]; !ok {
+				*bids = append(*bids, fp)
+			}
 			bidBook[fp] = s
 		}
 	}(&bids, bidBook, BidData, &wg)
@@ -77,7 +101,7 @@ func (bk *Booker) SetBook(AskData, BidData map[string]float64) {
 func (bk *Booker) UpdateAsk(p string, size float64) {
 	bk.rw.Lock()
 	defer bk.rw.Unlock()
-	price := convert.GetFloat64(p)
+	price := bk.price(p)
 	isIn := false
 	if _, ok := bk.askBook[price]; ok {
 		isIn = true
@@ -99,7 +123,7 @@ func (bk *Booker) UpdateAsk(p string, size float64) {
 func (bk *Booker) UpdateBid(p string, size float64) {
 	bk.rw.Lock()
 	defer bk.rw.Unlock()
-	price := convert.GetFloat64(p)
+	price := bk.price(p)
 	isIn := false
 	if _, ok := bk.bidBook[price]; ok {
 		isIn = true
@@ -123,7 +147,7 @@ func (bk *Booker) GetAsks() []float64 {
 	defer bk.rw.RUnlock()
 	asks := make([]float64, len(bk.asks))
 	copy(asks, bk.asks)
-	return bk.asks
+	return asks
 }
 
 func (bk *Booker) GetBids() []float64 {
@@ -154,13 +178,24 @@ func (bk *Booker) GetBidBook() map[float64]float64 {
 	return bidBook
 }
 
+func (bk *Booker) GetAskLen() int {
+	bk.rw.RLock()
+	defer bk.rw.RUnlock()
+	return len(bk.askBook)
+}
+
+func (bk *Booker) GetBidLen() int {
+	bk.rw.RLock()
+	defer bk.rw.RUnlock()
+	return len(bk.bidBook)
+}
+
 func (bk *Booker) GetAskMap() map[string]float64 {
 	bk.rw.RLock()
 	defer bk.rw.RUnlock()
 	askMap := map[string]float64{}
 	for p, s := range bk.askBook {
-		sp := convert.GetString(p)
-		askMap[sp] = s
+		askMap[bk.scale.String(bk.scale.FromFloat(p))] = s
 	}
 	return askMap
 }
@@ -170,8 +205,7 @@ func (bk *Booker) GetBidMap() map[string]float64 {
 	defer bk.rw.RUnlock()
 	bidMap := map[string]float64{}
 	for p, s := range bk.bidBook {
-		sp := convert.GetString(p)
-		bidMap[sp] = s
+		bidMap[bk.scale.String(bk.scale.FromFloat(p))] = s
 	}
 	return bidMap
 }
@@ -201,3 +235,23 @@ func (bk *Booker) GetBook() ([]float64, []float64, map[float64]float64, map[floa
 	copy(bids, bk.bids)
 	return asks, bids, askBook, bidBook
 }
+
+func (bk *Booker) View(fn func(asks, bids exch.Levels)) {
+	bk.rw.RLock()
+	defer bk.rw.RUnlock()
+	fn(levels(bk.asks, bk.askBook), levels(bk.bids, bk.bidBook))
+}
+
+func levels(prices []float64, book map[float64]float64) exch.Levels {
+	return func(fn func(price, size float64) bool) {
+		for _, p := range prices {
+			if !fn(p, book[p]) {
+				return
+			}
+		}
+	}
+}
+
+func init() {
+	exch.RegisterBooker(BookerKey, func(ctx context.Context) exch.Booker { return NewBooker(ctx) })
+}
@@ -0,0 +1,473 @@
+package exch
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	"high-freq-quant-go/adapter/timer"
+	"high-freq-quant-go/core/log"
+)
+
+// 订单状态先后, 乱序到达的旧状态不覆盖新状态
+var stateRank = map[string]int{
+	"":                   0,
+	StatePendingNew:      1,
+	StateNew:             2,
+	StatePartiallyFilled: 3,
+	StateFilled:          4,
+	StateCancelled:       4,
+	StateRejected:        4,
+	StateExpired:         4,
+}
+
+// DefaultOMSHistory 保留的已结束订单数
+var DefaultOMSHistory = 10000
+
+// OMSQuery 订单查询条件, 空值不过滤
+type OMSQuery struct {
+	Symbol string
+	Tag    string //策略标识, 见 ParseClientId
+	Status string //open,finished
+	State  string
+}
+
+func (q OMSQuery) match(o *Order) bool {
+	return (q.Symbol == "" || o.Symbol == q.Symbol) &&
+		(q.Tag == "" || OrderTag(o) == q.Tag) &&
+		(q.Status == "" || o.Status == q.Status) &&
+		(q.State == "" || o.State == q.State)
+}
+
+// omsOrder 订单及按成交推送累计的成交
+type omsOrder struct {
+	Order
+	trades      map[string]bool
+	tradeFilled float64
+	tradeAmount float64
+	local       bool //下单请求失败时本地标记的 rejected, 交易所推送可以覆盖
+	finished    bool //已移入历史
+}
+
+// OMS 本地订单管理, 按自定义订单 Id 及交易所订单 Id 跟踪订单从提交到终态,
+// 下单返回, 订单推送及成交推送按任意顺序到达均可合并, 重复消息不重复计算
+type OMS struct {
+	lk      sync.RWMutex
+	byUUID  map[string]*omsOrder
+	byId    map[string]*omsOrder
+	open    map[*omsOrder]bool
+	history []*omsOrder //已结束订单, 超过 limit 时删除最早的
+	limit   int
+}
+
+func NewOMS(limit int) *OMS {
+	if limit <= 0 {
+		limit = DefaultOMSHistory
+	}
+	return &OMS{
+		byUUID: map[string]*omsOrder{},
+		byId:   map[string]*omsOrder{},
+		open:   map[*omsOrder]bool{},
+		limit:  limit,
+	}
+}
+
+// Attach 阻塞订阅连接的订单及成交推送, 队列满时连接的推送协程等待 OMS 处理, 不丢消息
+func (m *OMS) Attach(fd *Feed) []*Subscription {
+	if fd == nil {
+		return nil
+	}
+	return []*Subscription{
+		fd.OnOrder("", func(o *Order) { m.OnOrder(o) }, WithBlocking()),
+		fd.OnTrade("", func(o *Order) { m.OnTrade(o) }, WithBlocking()),
+	}
+}
+
+// Submit 下单前登记订单, UUID 为空时生成, 返回登记的 UUID
+func (m *OMS) Submit(req *OrderRequest) string {
+	if req.UUID == "" {
+		req.UUID = NewClientId()
+	}
+	o := req.Order()
+	o.State = StatePendingNew
+	o.Status = OrderOpen
+	o.Left = math.Abs(o.Size)
+	o.CreateTime = timer.MicNow()
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if _, ok := m.byUUID[o.UUID]; !ok {
+		m.add(&omsOrder{Order: *o})
+	}
+	return o.UUID
+}
+
+// Reject 下单请求失败, 超时等情况订单可能已提交, 之后收到交易所推送时重新打开
+func (m *OMS) Reject(uuid string) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if oo, ok := m.byUUID[uuid]; ok && oo.State == StatePendingNew {
+		oo.State, oo.Status = StateRejected, OrderFinished
+		oo.UpdateTime = timer.MicNow()
+		oo.local = true
+		m.finish(oo)
+	}
+}
+
+// OnRest 合并下单, 撤单及查询接口返回的订单
+func (m *OMS) OnRest(o *Order) {
+	m.OnOrder(o)
+}
+
+// OnOrder 合并订单推送
+func (m *OMS) OnOrder(o *Order) {
+	if o == nil || (o.Id == "" && o.UUID == "") {
+		return
+	}
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	oo := m.lookup(o.Id, o.UUID)
+	if oo == nil {
+		oo = &omsOrder{Order: Order{Symbol: o.Symbol}}
+		m.add(oo)
+	}
+	oo = m.link(oo, o.Id, o.UUID)
+	oo.merge(o)
+	m.settle(oo)
+}
+
+// OnTrade 合并成交推送, Id 为交易所订单 Id, 按 TradeId 去重
+func (m *OMS) OnTrade(t *Order) {
+	if t == nil || t.Id == "" {
+		return
+	}
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	oo := m.lookup(t.Id, t.UUID)
+	if oo == nil {
+		oo = &omsOrder{Order: Order{Symbol: t.Symbol}}
+		m.add(oo)
+	}
+	oo = m.link(oo, t.Id, t.UUID)
+	if t.TradeId != "" {
+		if oo.trades == nil {
+			oo.trades = map[string]bool{}
+		}
+		if oo.trades[t.TradeId] {
+			return
+		}
+		oo.trades[t.TradeId] = true
+	}
+	qty := math.Abs(t.Size)
+	oo.tradeFilled += qty
+	oo.tradeAmount += qty * t.Price
+	if oo.tradeFilled > oo.FilledSize {
+		oo.FilledSize = oo.tradeFilled
+		oo.AvgPrice = oo.tradeAmount / oo.tradeFilled
+	}
+	if t.CreateTime > oo.UpdateTime {
+		oo.UpdateTime = t.CreateTime
+	}
+	if oo.Role == "" {
+		oo.Role = t.Role
+	}
+	m.settle(oo)
+}
+
+// merge 只前进不后退, 成交数量取较大值
+func (oo *omsOrder) merge(o *Order) {
+	if oo.Symbol == "" {
+		oo.Symbol = o.Symbol
+	}
+	if oo.Size == 0 {
+		oo.Size = o.Size
+	}
+	if oo.Price == 0 {
+		oo.Price = o.Price
+	}
+	if oo.Tif == "" {
+		oo.Tif = o.Tif
+	}
+	if oo.CreateTime == 0 {
+		oo.CreateTime = o.CreateTime
+	}
+	if o.UpdateTime > oo.UpdateTime {
+		oo.UpdateTime = o.UpdateTime
+	}
+	filled := o.FilledSize
+	if filled == 0 && o.Left != 0 && o.Size != 0 {
+		filled = math.Abs(o.Size) - math.Abs(o.Left)
+	}
+	if filled > oo.FilledSize {
+		oo.FilledSize = filled
+		if o.AvgPrice != 0 {
+			oo.AvgPrice = o.AvgPrice
+		} else if o.FillPrice != 0 {
+			oo.AvgPrice = o.FillPrice
+		}
+	}
+	if o.State != "" && (stateRank[o.State] > stateRank[oo.State] || oo.local) {
+		oo.State = o.State
+		oo.local = false
+	}
+}
+
+// settle 根据成交数量推进状态, 终态订单移入历史
+func (m *OMS) settle(oo *omsOrder) {
+	size := math.Abs(oo.Size)
+	if size > 0 {
+		oo.Left = math.Max(size-oo.FilledSize, 0)
+		switch {
+		case oo.Left <= size*1e-9 && !IsFinalState(oo.State):
+			oo.State = StateFilled
+		case oo.FilledSize > 0 && stateRank[oo.State] < stateRank[StatePartiallyFilled]:
+			oo.State = StatePartiallyFilled
+		}
+	} else if oo.FilledSize > 0 && oo.State == "" {
+		oo.State = StatePartiallyFilled
+	}
+	oo.Status = StateStatus(oo.State)
+	switch {
+	case oo.Status == OrderFinished && m.open[oo]:
+		m.finish(oo)
+	case oo.Status == OrderOpen && oo.finished:
+		m.open[oo] = true
+	}
+}
+
+func (m *OMS) lookup(id, uuid string) *omsOrder {
+	if oo, ok := m.byId[id]; ok && id != "" {
+		return oo
+	}
+	if oo, ok := m.byUUID[uuid]; ok && uuid != "" {
+		return oo
+	}
+	return nil
+}
+
+// link 记录另一种 Id, 先到的成交或推送只有交易所 Id, 收到 UUID 时合并到提交时登记的订单
+func (m *OMS) link(oo *omsOrder, id, uuid string) *omsOrder {
+	if id != "" && oo.Id == "" {
+		oo.Id = id
+		m.byId[id] = oo
+	}
+	if uuid == "" || oo.UUID != "" {
+		return oo
+	}
+	sub, ok := m.byUUID[uuid]
+	if !ok || sub == oo {
+		oo.UUID = uuid
+		m.byUUID[uuid] = oo
+		return oo
+	}
+	m.remove(oo)
+	sub.absorb(oo)
+	if sub.Id == "" && oo.Id != "" {
+		sub.Id = oo.Id
+	}
+	if sub.Id != "" {
+		m.byId[sub.Id] = sub
+	}
+	return sub
+}
+
+func (oo *omsOrder) absorb(other *omsOrder) {
+	oo.merge(&other.Order)
+	for id := range other.trades {
+		if oo.trades == nil {
+			oo.trades = map[string]bool{}
+		}
+		oo.trades[id] = true
+	}
+	oo.tradeFilled += other.tradeFilled
+	oo.tradeAmount += other.tradeAmount
+	if oo.tradeFilled > oo.FilledSize {
+		oo.FilledSize = oo.tradeFilled
+		oo.AvgPrice = oo.tradeAmount / oo.tradeFilled
+	}
+	if oo.Role == "" {
+		oo.Role = other.Role
+	}
+}
+
+func (m *OMS) add(oo *omsOrder) {
+	if oo.Id != "" {
+		m.byId[oo.Id] = oo
+	}
+	if oo.UUID != "" {
+		m.byUUID[oo.UUID] = oo
+	}
+	m.open[oo] = true
+}
+
+func (m *OMS) remove(oo *omsOrder) {
+	if m.byId[oo.Id] == oo {
+		delete(m.byId, oo.Id)
+	}
+	if m.byUUID[oo.UUID] == oo {
+		delete(m.byUUID, oo.UUID)
+	}
+	delete(m.open, oo)
+}
+
+func (m *OMS) finish(oo *omsOrder) {
+	delete(m.open, oo)
+	if oo.finished {
+		return
+	}
+	oo.finished = true
+	m.history = append(m.history, oo)
+	if len(m.history) <= m.limit {
+		return
+	}
+	old := m.history[0]
+	m.history[0] = nil
+	m.history = m.history[1:]
+	old.finished = false
+	if m.open[old] {
+		return
+	}
+	m.remove(old)
+	log.Debugln(log.Conn, "oms evict finished order", old.Symbol, old.Id, old.UUID)
+}
+
+// Get 按交易所订单 Id 或 UUID 查询, 返回副本
+func (m *OMS) Get(id string) (Order, bool) {
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+	if oo := m.lookup(id, id); oo != nil {
+		return oo.Order, true
+	}
+	return Order{}, false
+}
+
+// Orders 按条件查询未结束及历史订单, 返回副本
+func (m *OMS) Orders(q OMSQuery) []Order {
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+	res := []Order{}
+	if q.Status != OrderFinished {
+		for oo := range m.open {
+			if q.match(&oo.Order) {
+				res = append(res, oo.Order)
+			}
+		}
+	}
+	if q.Status != OrderOpen {
+		for _, oo := range m.history {
+			if !m.open[oo] && q.match(&oo.Order) {
+				res = append(res, oo.Order)
+			}
+		}
+	}
+	return res
+}
+
+// PlaceOrder 登记订单后下单并合并接口返回, tr 已是 OMSGate 时重复登记及合并不影响结果
+func (m *OMS) PlaceOrder(ctx context.Context, tr Trader, req *OrderRequest) (*Order, error) {
+	uuid := m.Submit(req)
+	o, err := tr.PlaceOrder(ctx, req)
+	if err != nil {
+		m.Reject(uuid)
+		return nil, err
+	}
+	if o != nil {
+		if o.UUID == "" {
+			o.UUID = uuid
+		}
+		m.OnRest(o)
+	}
+	return o, nil
+}
+
+// CancelOrder 撤单并合并接口返回
+func (m *OMS) CancelOrder(ctx context.Context, tr Trader, req *CancelRequest) (*Order, error) {
+	o, err := tr.CancelOrder(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	m.OnRest(o)
+	return o, nil
+}
+
+// Open 未结束订单
+func (m *OMS) Open(symbol string) []Order {
+	return m.Orders(OMSQuery{Symbol: symbol, Status: OrderOpen})
+}
+
+// OMSGate 记录连接的下单, 改单及撤单接口返回, 策略直接调用连接下单时 OMS 同样跟踪订单
+type OMSGate struct {
+	Exchange
+	oms *OMS
+}
+
+func NewOMSGate(ex Exchange, oms *OMS) *OMSGate {
+	return &OMSGate{Exchange: ex, oms: oms}
+}
+
+func (g *OMSGate) PlaceOrder(ctx context.Context, req *OrderRequest) (*Order, error) {
+	return g.oms.PlaceOrder(ctx, g.Exchange, req)
+}
+
+// PlaceBatchOrder 返回的订单与请求顺序一致, 缺少 UUID 时按位置补齐
+func (g *OMSGate) PlaceBatchOrder(ctx context.Context, reqs []*OrderRequest) ([]*Order, error) {
+	uuids := make([]string, len(reqs))
+	for i, req := range reqs {
+		uuids[i] = g.oms.Submit(req)
+	}
+	res, err := g.Exchange.PlaceBatchOrder(ctx, reqs)
+	if err != nil {
+		for _, uuid := range uuids {
+			g.oms.Reject(uuid)
+		}
+		return nil, err
+	}
+	for i, o := range res {
+		if o == nil {
+			continue
+		}
+		if o.UUID == "" && i < len(uuids) {
+			o.UUID = uuids[i]
+		}
+		g.oms.OnRest(o)
+	}
+	return res, nil
+}
+
+func (g *OMSGate) CancelOrder(ctx context.Context, req *CancelRequest) (*Order, error) {
+	return g.oms.CancelOrder(ctx, g.Exchange, req)
+}
+
+func (g *OMSGate) CancelAllOrder(ctx context.Context, symbol string) ([]*Order, error) {
+	res, err := g.Exchange.CancelAllOrder(ctx, symbol)
+	for _, o := range res {
+		g.oms.OnRest(o)
+	}
+	return res, err
+}
+
+// AmendOrder 模拟改单时撤单成功而下单失败同样记录被撤销的原订单
+func (g *OMSGate) AmendOrder(ctx context.Context, req *AmendRequest) (*AmendResult, error) {
+	res, err := g.Exchange.AmendOrder(ctx, req)
+	if res != nil {
+		g.oms.OnRest(res.Cancelled)
+		g.oms.OnRest(res.Order)
+	}
+	return res, err
+}
+
+// CreateOrder 旧版接口同样记录
+func (g *OMSGate) CreateOrder(ctx context.Context) (*Order, error) {
+	return NewLegacy(g).CreateOrder(ctx)
+}
+
+func (g *OMSGate) CreateBatchOrder(ctx context.Context) ([]*Order, error) {
+	return NewLegacy(g).CreateBatchOrder(ctx)
+}
+
+func (g *OMSGate) CannelOrder(ctx context.Context) (*Order, error) {
+	return NewLegacy(g).CannelOrder(ctx)
+}
+
+func (g *OMSGate) CannelAllOrder(ctx context.Context) ([]*Order, error) {
+	return NewLegacy(g).CannelAllOrder(ctx)
+}
@@ -0,0 +1,162 @@
+package exch
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestOMSMerge(t *testing.T) {
+	m := NewOMS(2)
+	g := NewClientIdGen("mm")
+	uuid := m.Submit(&OrderRequest{Symbol: "BTC_USDT", Size: 2, Price: 100, UUID: g.Next()})
+	if o, ok := m.Get(uuid); !ok || o.State != StatePendingNew {
+		t.Fatalf("submit got %+v", o)
+	}
+	// 成交先于下单返回到达, 只有交易所 Id
+	m.OnTrade(&Order{Id: "1", TradeId: "t1", Symbol: "BTC_USDT", Size: 0.5, Price: 100})
+	m.OnRest(&Order{Id: "1", UUID: uuid, Symbol: "BTC_USDT", Size: 2, Price: 100, State: StateNew})
+	m.OnTrade(&Order{Id: "1", TradeId: "t1", Symbol: "BTC_USDT", Size: 0.5, Price: 100})
+	o, _ := m.Get("1")
+	if o.UUID != uuid || o.FilledSize != 0.5 || o.State != StatePartiallyFilled || o.Left != 1.5 {
+		t.Fatalf("merge got %+v", o)
+	}
+	if open := m.Orders(OMSQuery{Tag: "mm", Status: OrderOpen}); len(open) != 1 {
+		t.Fatalf("open by tag got %d", len(open))
+	}
+	m.OnTrade(&Order{Id: "1", TradeId: "t2", Symbol: "BTC_USDT", Size: 1.5, Price: 102})
+	// 旧推送晚到不回退
+	m.OnOrder(&Order{Id: "1", UUID: uuid, Symbol: "BTC_USDT", Size: 2, Left: 1.5, FilledSize: 0.5, State: StatePartiallyFilled})
+	o, _ = m.Get(uuid)
+	if o.State != StateFilled || o.Status != OrderFinished || o.AvgPrice != 101.5 {
+		t.Fatalf("filled got %+v", o)
+	}
+	if len(m.Open("BTC_USDT")) != 0 || len(m.Orders(OMSQuery{State: StateFilled})) != 1 {
+		t.Fatal("filled order still open")
+	}
+
+	// 下单失败后交易所推送订单, 重新打开
+	lost := m.Submit(&OrderRequest{Symbol: "ETH_USDT", Size: -1, Price: 10})
+	m.Reject(lost)
+	if o, _ := m.Get(lost); o.State != StateRejected {
+		t.Fatalf("reject got %+v", o)
+	}
+	m.OnOrder(&Order{Id: "2", UUID: lost, Symbol: "ETH_USDT", Size: -1, State: StateNew})
+	if open := m.Open("ETH_USDT"); len(open) != 1 || open[0].Id != "2" {
+		t.Fatalf("reopen got %+v", open)
+	}
+	m.OnOrder(&Order{Id: "2", Symbol: "ETH_USDT", State: StateCancelled})
+
+	// 历史超过上限时删除最早的订单
+	m.OnOrder(&Order{Id: "3", Symbol: "ETH_USDT", Size: 1, State: StateExpired})
+	if _, ok := m.Get(uuid); ok {
+		t.Fatal("oldest finished order not evicted")
+	}
+	if len(m.Orders(OMSQuery{Status: OrderFinished})) != 2 {
+		t.Fatalf("history got %d", len(m.Orders(OMSQuery{Status: OrderFinished})))
+	}
+}
+
+func TestOMSAttachLossless(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fd := NewFeed(ctx)
+	m := NewOMS(0)
+	subs := m.Attach(fd)
+	// OMS 处理阻塞期间推送超过队列长度
+	n := int(MsgChannelLen) * 2
+	done := make(chan struct{})
+	m.lk.Lock()
+	go func() {
+		for i := 0; i < n; i++ {
+			fd.PubOrder(&Order{Id: strconv.Itoa(i), Symbol: "BTC_USDT", Size: 1, Price: 100, State: StateNew})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("publisher not blocked by full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+	m.lk.Unlock()
+	<-done
+	waitFor(t, func() bool { return len(m.Open("BTC_USDT")) == n })
+	if subs[0].Dropped() != 0 {
+		t.Fatalf("dropped got %d", subs[0].Dropped())
+	}
+	for _, sub := range subs {
+		sub.Unsubscribe()
+	}
+}
+
+// omsMock 不支持改单的连接, 改单时撤单后重新下单
+type omsMock struct {
+	Exchange
+	id     int
+	orders map[string]*Order
+	fail   bool
+}
+
+func (m *omsMock) PlaceOrder(ctx context.Context, req *OrderRequest) (*Order, error) {
+	if m.fail {
+		return nil, errors.New("place error")
+	}
+	m.id++
+	o := req.Order()
+	o.Id, o.State = strconv.Itoa(m.id), StateNew
+	m.orders[o.Id] = o
+	return o, nil
+}
+
+func (m *omsMock) CancelOrder(ctx context.Context, req *CancelRequest) (*Order, error) {
+	o := *m.orders[req.Id]
+	o.State, o.Left = StateCancelled, o.Size
+	return &o, nil
+}
+
+func (m *omsMock) AmendOrder(ctx context.Context, req *AmendRequest) (*AmendResult, error) {
+	return EmulateAmend(ctx, m, req)
+}
+
+func TestOMSGate(t *testing.T) {
+	m := NewOMS(0)
+	ex := &omsMock{orders: map[string]*Order{}}
+	g := NewOMSGate(ex, m)
+	// 直接调用连接下单, 不经过 OMS.PlaceOrder
+	o, err := g.PlaceOrder(context.Background(), &OrderRequest{Symbol: "BTC_USDT", Size: 1, Price: 100, Tif: OrderGtc})
+	if err != nil || o.UUID == "" {
+		t.Fatalf("place got %+v %v", o, err)
+	}
+	if open := m.Open("BTC_USDT"); len(open) != 1 || open[0].Id != "1" || open[0].UUID != o.UUID {
+		t.Fatalf("place open got %+v", open)
+	}
+	// 模拟改单的撤单及新订单都记录
+	res, err := g.AmendOrder(context.Background(), &AmendRequest{Symbol: "BTC_USDT", Id: "1", Size: 2})
+	if err != nil || res.Order == nil {
+		t.Fatalf("amend got %+v %v", res, err)
+	}
+	if old, _ := m.Get("1"); old.State != StateCancelled {
+		t.Fatalf("amend cancelled got %+v", old)
+	}
+	if open := m.Open("BTC_USDT"); len(open) != 1 || open[0].Id != "2" || open[0].Size != 2 {
+		t.Fatalf("amend open got %+v", open)
+	}
+	// 旧版接口同样记录
+	ctx := context.WithValue(context.Background(), CtxOrder, &Order{Symbol: "BTC_USDT", Id: "2"})
+	if _, err := g.CannelOrder(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Open("BTC_USDT")) != 0 {
+		t.Fatal("cancelled order still open")
+	}
+	ex.fail = true
+	ctx = context.WithValue(context.Background(), CtxOrder, &Order{Symbol: "BTC_USDT", Size: 1, Price: 100, UUID: "c1"})
+	if _, err := g.CreateOrder(ctx); err == nil {
+		t.Fatal("create want error")
+	}
+	if o, _ := m.Get("c1"); o.State != StateRejected {
+		t.Fatalf("create rejected got %+v", o)
+	}
+}
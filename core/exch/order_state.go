@@ -0,0 +1,74 @@
+package exch
+
+import (
+	"fmt"
+	"math"
+)
+
+// 订单生命周期状态
+const (
+	StatePendingNew      = "pending_new"      //已提交 交易所未确认
+	StateNew             = "new"              //已挂单 未成交
+	StatePartiallyFilled = "partially_filled" //部分成交
+	StateFilled          = "filled"           //全部成交
+	StateCancelled       = "cancelled"        //已撤销 可能有部分成交
+	StateRejected        = "rejected"         //交易所拒绝
+	StateExpired         = "expired"          //过期 ioc/fok 未成交部分
+)
+
+var orderTransitions = map[string][]string{
+	StatePendingNew:      {StateNew, StatePartiallyFilled, StateFilled, StateCancelled, StateRejected, StateExpired},
+	StateNew:             {StatePartiallyFilled, StateFilled, StateCancelled, StateExpired},
+	StatePartiallyFilled: {StatePartiallyFilled, StateFilled, StateCancelled, StateExpired},
+}
+
+// CanTransition 状态迁移是否合法, 空状态表示首次收到该订单
+func CanTransition(from, to string) bool {
+	if from == "" || (from == to && !IsFinalState(to)) {
+		return true
+	}
+	for _, s := range orderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinalState 终态不会再发生变化
+func IsFinalState(state string) bool {
+	switch state {
+	case StateFilled, StateCancelled, StateRejected, StateExpired:
+		return true
+	}
+	return false
+}
+
+// StateStatus 生命周期状态对应的 open,finished 状态
+func StateStatus(state string) string {
+	if IsFinalState(state) {
+		return OrderFinished
+	}
+	return OrderOpen
+}
+
+// SetState 校验并迁移订单状态, 同时更新 Status
+func (o *Order) SetState(state string) error {
+	if state == "" || !CanTransition(o.State, state) {
+		return fmt.Errorf("%w: %s %s -> %s", ErrInvalidTransition, o.Id, o.State, state)
+	}
+	o.State = state
+	o.Status = StateStatus(state)
+	return nil
+}
+
+// ApplyFill 累加一笔成交并重新计算成交均价, qty 取绝对值
+func (o *Order) ApplyFill(qty, price float64) {
+	qty = math.Abs(qty)
+	if qty == 0 {
+		return
+	}
+	total := o.FilledSize + qty
+	o.AvgPrice = (o.AvgPrice*o.FilledSize + price*qty) / total
+	o.FilledSize = total
+}
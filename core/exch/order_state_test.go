@@ -0,0 +1,50 @@
+package exch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOrderSetState(t *testing.T) {
+	o := &Order{Id: "1"}
+	steps := []string{StatePendingNew, StateNew, StatePartiallyFilled, StatePartiallyFilled, StateFilled}
+	for _, s := range steps {
+		if err := o.SetState(s); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+	}
+	if o.Status != OrderFinished {
+		t.Fatalf("status got %s, want %s", o.Status, OrderFinished)
+	}
+	for _, s := range []string{StateNew, StateCancelled, StateFilled} {
+		if err := o.SetState(s); !errors.Is(err, ErrInvalidTransition) {
+			t.Fatalf("filled -> %s: got %v", s, err)
+		}
+	}
+
+	o = &Order{Id: "2"}
+	if err := o.SetState(StateNew); err != nil {
+		t.Fatal(err)
+	}
+	if o.Status != OrderOpen {
+		t.Fatalf("status got %s, want %s", o.Status, OrderOpen)
+	}
+	if err := o.SetState(StateRejected); err == nil {
+		t.Fatal("new -> rejected should fail")
+	}
+	if err := o.SetState(""); err == nil {
+		t.Fatal("empty state should fail")
+	}
+}
+
+func TestOrderApplyFill(t *testing.T) {
+	o := &Order{}
+	o.ApplyFill(1, 100)
+	o.ApplyFill(-3, 104)
+	if o.FilledSize != 4 {
+		t.Fatalf("filled got %v", o.FilledSize)
+	}
+	if o.AvgPrice != 103 {
+		t.Fatalf("avg got %v", o.AvgPrice)
+	}
+}
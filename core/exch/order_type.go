@@ -4,6 +4,9 @@ const (
 	OrderMaker = "maker"
 	OrderTaker = "taker"
 
+	SideBuy  = "buy"  //主动买
+	SideSell = "sell" //主动卖
+
 	OrderOpen     = "open"
 	OrderFinished = "finished"
 
@@ -12,9 +15,14 @@ const (
 	OrderPoc = "poc" //被动委托，只挂单不吃单
 	OrderFok = "fok" //无法全部立即成交就撤销
 
-	OrderLimit  = "limit"
-	OrderMarket = "market"
-	OrderStop   = "stop"
+	OrderLimit      = "limit"
+	OrderMarket     = "market"
+	OrderStop       = "stop"        //止损条件单, 买单价格上涨到触发价时触发, 卖单下跌时触发
+	OrderTakeProfit = "take_profit" //止盈条件单, 买单价格下跌到触发价时触发, 卖单上涨时触发
+
+	TriggerLast  = "last"  //按最新成交价触发
+	TriggerMark  = "mark"  //按标记价格触发
+	TriggerIndex = "index" //按指数价格触发
 
 	MarginIsolated = "isolated" //保证金逐仓
 	MarginCrossed  = "crossed"  //保证金全仓
@@ -31,15 +39,49 @@ type Order struct {
 	UUID       string  //自定义订单Id
 	Symbol     string  //交易对
 	Status     string  //状态 open,finished
+	State      string  //生命周期状态 new,partially_filled,filled,cancelled,rejected,expired
 	Size       float64 //总数量 多正 空负
 	Price      float64 //下单价格
 	FillPrice  float64 //成交价格
 	Left       float64 //未成交数量
+	FilledSize float64 //累计成交数量
+	AvgPrice   float64 //成交均价
 	Role       string  //交易角色 maker,taker
 	Iceberg    int64   //冰山数量
 	Text       string
 	Tif        string //下单类型 gtc:未成交则挂单,ioc:立即成交或者取消，只吃单不挂单,poc:被动委托，只挂单不吃单
-	Ordertype  string //订单类型 LIMIT, MARKET, STOP
+	Ordertype  string //订单类型 limit,market,stop,take_profit
 	CreateTime int64  //创建时间
 	UpdateTime int64  //最后更新时间
+
+	TriggerPrice  float64 //触发价格, 不为 0 时为条件单
+	TriggerBy     string  //触发价格类型 last,mark,index, 为空时按最新成交价
+	ClosePosition bool    //平掉全部仓位, Size 只表示方向, 条件单为触发后平仓
+	ReduceOnly    bool    //只减仓, 成交不会使仓位反向
+	PostOnly      bool    //只挂单, 会立即成交时交易所拒单并返回 PostOnlyError, 与 Tif poc 相同
+	PositionSide  string  //双向持仓时的仓位方向 LONG,SHORT, 为空时按 HedgeSide 推断
+
+	//定点价格及数量, 通过 SetPrice SetSize 设置, 与 Price Size 一致时下单按定点值精确换算
+	FixedPrice Fixed
+	FixedSize  Fixed
+	PriceScale Scale
+	SizeScale  Scale
+}
+
+// IsPostOnly 是否为只挂单的订单
+func (o *Order) IsPostOnly() bool {
+	return o.PostOnly || o.Tif == OrderPoc
+}
+
+// IsTrigger 是否为未触发的条件单
+func (o *Order) IsTrigger() bool {
+	return o.TriggerPrice > 0
+}
+
+// TriggerRising 价格上涨到触发价时触发, 否则下跌到触发价时触发
+func (o *Order) TriggerRising() bool {
+	if o.Ordertype == OrderTakeProfit {
+		return o.Size < 0
+	}
+	return o.Size > 0
 }
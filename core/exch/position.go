@@ -0,0 +1,100 @@
+package exch
+
+import (
+	"context"
+	"strings"
+
+	"high-freq-quant-go/adapter/text"
+)
+
+// PositionKey 仓位数据的键, 单向持仓为交易对, 双向持仓为 交易对:LONG 或 交易对:SHORT
+func PositionKey(symbol, side string) string {
+	if side == "" || side == PositionBoth {
+		return symbol
+	}
+	return symbol + ":" + side
+}
+
+// SplitPositionKey 仓位数据的键拆分为交易对及持仓方向, 单向持仓方向为空
+func SplitPositionKey(key string) (symbol, side string) {
+	if i := strings.LastIndex(key, ":"); i > 0 {
+		if s := key[i+1:]; s == PositionLong || s == PositionShort {
+			return key[:i], s
+		}
+	}
+	return key, ""
+}
+
+// PositionKeys 交易对按持仓模式对应的所有仓位键
+func PositionKeys(symbol string, hedge bool) []string {
+	if !hedge {
+		return []string{symbol}
+	}
+	return []string{PositionKey(symbol, PositionLong), PositionKey(symbol, PositionShort)}
+}
+
+// NetPosition 双向持仓两个方向合并为净仓位, 用于未指定持仓方向的查询
+func NetPosition(symbol string, positions ...*Position) *Position {
+	net := &Position{Symbol: symbol, PositionMode: PositionBoth}
+	for _, p := range positions {
+		if p == nil {
+			continue
+		}
+		net.Size += p.Size
+		net.Margin += p.Margin
+		net.Pnl += p.Pnl
+		net.UnPnl += p.UnPnl
+		net.Value += p.Value
+		net.MarkPrice = p.MarkPrice
+		net.Lv = p.Lv
+		net.MarginType = p.MarginType
+		if p.LastUpdateTime > net.LastUpdateTime {
+			net.LastUpdateTime = p.LastUpdateTime
+		}
+	}
+	return net
+}
+
+// Key 仓位数据的键
+func (p *Position) Key() string {
+	return PositionKey(p.Symbol, p.PositionMode)
+}
+
+// WithHedgeMode 账号是否使用双向持仓, 连接及接口按该设置维护仓位和下单
+func WithHedgeMode(ctx context.Context, hedge bool) context.Context {
+	return context.WithValue(ctx, CtxHedgeMode, hedge)
+}
+
+func HedgeMode(ctx context.Context) bool {
+	return text.GetBool(ctx, CtxHedgeMode)
+}
+
+// WithPositionSide 双向持仓时查询仓位的方向 LONG,SHORT
+func WithPositionSide(ctx context.Context, side string) context.Context {
+	return context.WithValue(ctx, CtxPositionSide, side)
+}
+
+func PositionSide(ctx context.Context) string {
+	return text.GetString(ctx, CtxPositionSide)
+}
+
+// HedgeSide 双向持仓时订单对应的仓位方向, 未指定时按数量方向推断, 只减仓或平仓时为反方向
+func (o *Order) HedgeSide() string {
+	if o.PositionSide == PositionLong || o.PositionSide == PositionShort {
+		return o.PositionSide
+	}
+	if (o.Size > 0) != (o.ReduceOnly || o.ClosePosition) {
+		return PositionLong
+	}
+	return PositionShort
+}
+
+// HedgeClose 双向持仓时订单是否减少对应方向的仓位
+func (o *Order) HedgeClose() bool {
+	switch o.HedgeSide() {
+	case PositionLong:
+		return o.Size < 0
+	default:
+		return o.Size > 0
+	}
+}
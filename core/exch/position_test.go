@@ -0,0 +1,34 @@
+package exch
+
+import "testing"
+
+func TestPositionKey(t *testing.T) {
+	if PositionKey("BTC_USDT", PositionBoth) != "BTC_USDT" || PositionKey("BTC_USDT", "") != "BTC_USDT" {
+		t.Fatal("single mode key should be symbol")
+	}
+	symbol, side := SplitPositionKey(PositionKey("BTC_USDT", PositionShort))
+	if symbol != "BTC_USDT" || side != PositionShort {
+		t.Fatalf("split %s %s", symbol, side)
+	}
+	if symbol, side = SplitPositionKey("BTC_USDT"); symbol != "BTC_USDT" || side != "" {
+		t.Fatalf("split single %s %s", symbol, side)
+	}
+
+	// 未指定方向时开多、平多、开空、平空
+	cases := []struct {
+		o     Order
+		side  string
+		close bool
+	}{
+		{Order{Size: 1}, PositionLong, false},
+		{Order{Size: -1, ReduceOnly: true}, PositionLong, true},
+		{Order{Size: -1}, PositionShort, false},
+		{Order{Size: 1, ClosePosition: true}, PositionShort, true},
+		{Order{Size: 1, PositionSide: PositionLong, ReduceOnly: true}, PositionLong, false},
+	}
+	for _, c := range cases {
+		if c.o.HedgeSide() != c.side || c.o.HedgeClose() != c.close {
+			t.Fatalf("%+v side %s close %v", c.o, c.o.HedgeSide(), c.o.HedgeClose())
+		}
+	}
+}
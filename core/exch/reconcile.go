@@ -0,0 +1,291 @@
+package exch
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	cmap "github.com/orcaman/concurrent-map"
+
+	"high-freq-quant-go/adapter/timer"
+	"high-freq-quant-go/core/log"
+)
+
+// 偏差类型
+const (
+	DriftPosition = "position"
+	DriftBalance  = "balance"
+)
+
+// ReconcileConfig 仓位及资金校对参数, 通过 WithReconcile 设置
+type ReconcileConfig struct {
+	Interval    time.Duration //校对间隔, <= 0 不校对
+	MaxSize     float64       //仓位数量偏差超过该值时告警, <= 0 不按数量告警
+	MaxNotional float64       //仓位价值偏差超过该值时告警, <= 0 不按价值告警
+	MaxBalance  float64       //资金偏差超过该值时告警, <= 0 不告警
+}
+
+var DefaultReconcile = ReconcileConfig{
+	Interval:    30 * time.Second,
+	MaxNotional: 10,
+	MaxBalance:  10,
+}
+
+func WithReconcile(ctx context.Context, cfg ReconcileConfig) context.Context {
+	return context.WithValue(ctx, CtxReconcile, cfg)
+}
+
+func GetReconcile(ctx context.Context) ReconcileConfig {
+	if ret, ok := ctx.Value(CtxReconcile).(ReconcileConfig); ok {
+		return ret
+	}
+	return DefaultReconcile
+}
+
+// Drift 推送与 REST 不一致, 已按 REST 更正
+type Drift struct {
+	Kind          string
+	Symbol        string //仓位数据的键或资产, 双向持仓时为 交易对:方向
+	Local, Remote float64
+	Diff          float64 //Remote - Local
+	Notional      float64 //仓位偏差价值
+	Alert         bool    //超过告警阈值
+	Time          int64
+}
+
+// AssetFetcher 账户资产 REST 接口
+type AssetFetcher interface {
+	GetBalance(ctx context.Context) (map[string]*Balance, error)
+	ListPosition(ctx context.Context) (map[string]*Position, error)
+}
+
+// AssetPublisher 更正后的推送, Feed 及 Feeds 均实现
+type AssetPublisher interface {
+	PubPosition(pos *Position)
+	PubBalance(ba *Balance)
+	PubDrift(d *Drift)
+}
+
+// Reconciler 定时拉取 REST 仓位及资金与推送维护的数据比较, 不一致时更正并推送, 超过阈值时告警
+type Reconciler struct {
+	Sign      string
+	ctx       context.Context
+	cfg       ReconcileConfig
+	api       AssetFetcher
+	positions cmap.ConcurrentMap //交易对 *Position, nil 不校对仓位
+	balances  cmap.ConcurrentMap //资产 *Balance, nil 不校对资金
+	pub       AssetPublisher
+
+	lk     sync.Mutex
+	counts map[string]int64
+}
+
+// NewReconciler positions 及 balances 为连接维护的数据, 推送更新时整体替换指针
+func NewReconciler(ctx context.Context, sign string, api AssetFetcher, positions, balances cmap.ConcurrentMap, pub AssetPublisher) *Reconciler {
+	return &Reconciler{
+		Sign:      sign,
+		ctx:       ctx,
+		cfg:       GetReconcile(ctx),
+		api:       api,
+		positions: positions,
+		balances:  balances,
+		pub:       pub,
+		counts:    map[string]int64{},
+	}
+}
+
+func (r *Reconciler) Run() {
+	if r.cfg.Interval <= 0 {
+		return
+	}
+	tr := time.NewTicker(r.cfg.Interval)
+	defer tr.Stop()
+	for {
+		select {
+		case <-r.ctx.Done():
+			log.Warnln(log.Wss, r.Sign, " Reconciler return by done")
+			return
+		case <-tr.C:
+			r.Check()
+		}
+	}
+}
+
+// Check 校对一轮, 返回发现的偏差
+func (r *Reconciler) Check() []*Drift {
+	var drifts []*Drift
+	// 本地没有仓位时 REST 仍可能有, 如开仓推送丢失
+	if r.positions != nil {
+		drifts = append(drifts, r.checkPositions()...)
+	}
+	if r.balances != nil && r.balances.Count() != 0 {
+		drifts = append(drifts, r.checkBalances()...)
+	}
+	return drifts
+}
+
+func (r *Reconciler) checkPositions() []*Drift {
+	// 拉取前的本地数据, 拉取期间推送更新过的交易对本轮不更正
+	local := r.positions.Items()
+	remote, err := r.api.ListPosition(r.ctx)
+	if err != nil {
+		r.count("failed")
+		log.Warnln(log.Wss, r.Sign, "Reconciler ListPosition error", err)
+		return nil
+	}
+	var drifts []*Drift
+	for key, v := range local {
+		lpos, _ := v.(*Position)
+		rpos := remote[key]
+		if rpos == nil {
+			// 已平仓的交易对 REST 不返回
+			symbol, side := SplitPositionKey(key)
+			rpos = &Position{Symbol: symbol, PositionMode: side}
+		}
+		if d := r.fixPosition(key, lpos, rpos); d != nil {
+			drifts = append(drifts, d)
+		}
+	}
+	// 本地没有的键, 如未收到推送的交易对或双向持仓的另一方向
+	for key, rpos := range remote {
+		if _, ok := local[key]; ok || rpos == nil {
+			continue
+		}
+		if d := r.fixPosition(key, nil, rpos); d != nil {
+			drifts = append(drifts, d)
+		}
+	}
+	r.count("checked")
+	return drifts
+}
+
+// fixPosition key 为仓位数据的键, 双向持仓时包含持仓方向
+func (r *Reconciler) fixPosition(key string, lpos, rpos *Position) *Drift {
+	var lsize, lprice float64
+	if lpos != nil {
+		lsize, lprice = lpos.Size, lpos.Price
+	}
+	if sizeEqual(lsize, rpos.Size) {
+		return nil
+	}
+	cur, _ := r.positions.Get(key)
+	if cp, _ := cur.(*Position); cp != lpos {
+		return nil
+	}
+	price := rpos.MarkPrice
+	if price == 0 {
+		price = math.Max(rpos.Price, lprice)
+	}
+	d := &Drift{
+		Kind:   DriftPosition,
+		Symbol: key,
+		Local:  lsize,
+		Remote: rpos.Size,
+		Diff:   rpos.Size - lsize,
+		Time:   timer.MicNow(),
+	}
+	d.Notional = math.Abs(d.Diff) * price
+	d.Alert = (r.cfg.MaxSize > 0 && math.Abs(d.Diff) > r.cfg.MaxSize) ||
+		(r.cfg.MaxNotional > 0 && d.Notional > r.cfg.MaxNotional)
+	pos := *rpos
+	if pos.Symbol == "" {
+		pos.Symbol, _ = SplitPositionKey(key)
+	}
+	if lpos != nil {
+		// REST 未返回的设置沿用本地
+		if pos.Lv == 0 {
+			pos.Lv = lpos.Lv
+		}
+		if pos.MarginType == "" {
+			pos.MarginType = lpos.MarginType
+		}
+		if pos.PositionMode == "" {
+			pos.PositionMode = lpos.PositionMode
+		}
+	}
+	if pos.LastUpdateTime == 0 {
+		pos.LastUpdateTime = d.Time
+	}
+	r.positions.Set(key, &pos)
+	r.pub.PubPosition(&pos)
+	r.report(d)
+	return d
+}
+
+func (r *Reconciler) checkBalances() []*Drift {
+	local := r.balances.Items()
+	remote, err := r.api.GetBalance(r.ctx)
+	if err != nil {
+		r.count("failed")
+		log.Warnln(log.Wss, r.Sign, "Reconciler GetBalance error", err)
+		return nil
+	}
+	var drifts []*Drift
+	for asset, rb := range remote {
+		if rb == nil {
+			continue
+		}
+		lb, _ := local[asset].(*Balance)
+		var total float64
+		if lb != nil {
+			total = lb.Total
+		}
+		if sizeEqual(total, rb.Total) {
+			continue
+		}
+		cur, _ := r.balances.Get(asset)
+		if cb, _ := cur.(*Balance); cb != lb {
+			continue
+		}
+		d := &Drift{
+			Kind:   DriftBalance,
+			Symbol: asset,
+			Local:  total,
+			Remote: rb.Total,
+			Diff:   rb.Total - total,
+			Time:   timer.MicNow(),
+		}
+		d.Alert = r.cfg.MaxBalance > 0 && math.Abs(d.Diff) > r.cfg.MaxBalance
+		ba := *rb
+		if ba.Asset == "" {
+			ba.Asset = asset
+		}
+		r.balances.Set(asset, &ba)
+		r.pub.PubBalance(&ba)
+		r.report(d)
+		drifts = append(drifts, d)
+	}
+	r.count("checked")
+	return drifts
+}
+
+// report 所有偏差都更正并记录, 超过阈值的推送 Drift
+func (r *Reconciler) report(d *Drift) {
+	r.count(d.Kind)
+	if !d.Alert {
+		log.Infof(log.Wss, "%s %s Reconciler corrected %s local=%v remote=%v \r\n", r.Sign, d.Symbol, d.Kind, d.Local, d.Remote)
+		return
+	}
+	r.count("alert")
+	log.Warnf(log.Wss, "%s %s Reconciler drift %s local=%v remote=%v notional=%v \r\n",
+		r.Sign, d.Symbol, d.Kind, d.Local, d.Remote, d.Notional)
+	r.pub.PubDrift(d)
+}
+
+func (r *Reconciler) count(kind string) {
+	r.lk.Lock()
+	r.counts[kind]++
+	r.lk.Unlock()
+}
+
+// Stats 校对次数, 失败, 仓位及资金更正, 告警次数
+func (r *Reconciler) Stats() map[string]int64 {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	res := make(map[string]int64, len(r.counts))
+	for k, n := range r.counts {
+		res[k] = n
+	}
+	return res
+}
@@ -0,0 +1,112 @@
+package exch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cmap "github.com/orcaman/concurrent-map"
+)
+
+type mockAssets struct {
+	positions map[string]*Position
+	balances  map[string]*Balance
+	onFetch   func()
+}
+
+func (m *mockAssets) GetBalance(ctx context.Context) (map[string]*Balance, error) {
+	return m.balances, nil
+}
+
+func (m *mockAssets) ListPosition(ctx context.Context) (map[string]*Position, error) {
+	if m.onFetch != nil {
+		m.onFetch()
+	}
+	return m.positions, nil
+}
+
+func TestReconciler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = WithReconcile(ctx, ReconcileConfig{MaxSize: 1, MaxNotional: 500, MaxBalance: 5})
+	fd := NewFeed(ctx)
+	alerts := make(chan *Drift, 10)
+	fd.OnDrift("", func(d *Drift) { alerts <- d })
+
+	positions, balances := cmap.New(), cmap.New()
+	positions.Set("BTC_USDT", &Position{Symbol: "BTC_USDT", Size: 0.5, Lv: 10})
+	positions.Set("ETH_USDT", &Position{Symbol: "ETH_USDT", Size: 2})
+	positions.Set("DOGE_USDT", &Position{Symbol: "DOGE_USDT", Size: 100})
+	balances.Set("USDT", &Balance{Asset: "USDT", Total: 1000})
+	api := &mockAssets{
+		positions: map[string]*Position{
+			// BTC 数量偏差小但价值超过阈值, ETH 一致, DOGE 已平仓
+			"BTC_USDT": {Symbol: "BTC_USDT", Size: 0.6, MarkPrice: 30000},
+			"ETH_USDT": {Symbol: "ETH_USDT", Size: 2, MarkPrice: 2000},
+		},
+		balances: map[string]*Balance{"USDT": {Asset: "USDT", Total: 1002}},
+	}
+	// 拉取期间推送更新了 DOGE, 本轮不更正
+	api.onFetch = func() {
+		positions.Set("DOGE_USDT", &Position{Symbol: "DOGE_USDT", Size: 50})
+	}
+	r := NewReconciler(ctx, "test", api, positions, balances, fd)
+	drifts := r.Check()
+	if len(drifts) != 2 {
+		t.Fatalf("drifts got %d", len(drifts))
+	}
+	if pos, _ := positions.Get("BTC_USDT"); pos.(*Position).Size != 0.6 {
+		t.Fatalf("btc not corrected %+v", pos)
+	}
+	if pos, _ := positions.Get("DOGE_USDT"); pos.(*Position).Size != 50 {
+		t.Fatalf("doge overwritten %+v", pos)
+	}
+	if ba, _ := balances.Get("USDT"); ba.(*Balance).Total != 1002 {
+		t.Fatalf("balance not corrected %+v", ba)
+	}
+	select {
+	case d := <-alerts:
+		if d.Kind != DriftPosition || d.Symbol != "BTC_USDT" || !near(d.Notional, 3000) {
+			t.Fatalf("alert got %+v", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no drift alert")
+	}
+
+	// 平仓推送丢失时按 REST 置零并保留杠杆
+	api.onFetch = nil
+	api.positions = map[string]*Position{}
+	r.Check()
+	pos, _ := positions.Get("BTC_USDT")
+	if p := pos.(*Position); p.Size != 0 || p.Lv != 10 {
+		t.Fatalf("closed position got %+v", p)
+	}
+	if st := r.Stats(); st[DriftPosition] != 4 || st[DriftBalance] != 1 || st["alert"] != 3 {
+		t.Fatalf("stats got %+v", st)
+	}
+}
+
+func TestReconcilerRemoteOnly(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = WithReconcile(ctx, ReconcileConfig{MaxSize: 1})
+	positions := cmap.New()
+	api := &mockAssets{positions: map[string]*Position{
+		PositionKey("BTC_USDT", PositionShort): {Symbol: "BTC_USDT", Size: -2, PositionMode: PositionShort},
+	}}
+	r := NewReconciler(ctx, "test", api, positions, nil, NewFeed(ctx))
+	// 本地为空时也校对, 只收到多仓推送时补上空仓
+	if drifts := r.Check(); len(drifts) != 1 || drifts[0].Symbol != "BTC_USDT:SHORT" || !drifts[0].Alert {
+		t.Fatalf("empty local got %+v", drifts)
+	}
+	positions.Remove("BTC_USDT:SHORT")
+	positions.Set(PositionKey("BTC_USDT", PositionLong), &Position{Symbol: "BTC_USDT", Size: 1, PositionMode: PositionLong})
+	api.positions[PositionKey("BTC_USDT", PositionLong)] = &Position{Symbol: "BTC_USDT", Size: 1, PositionMode: PositionLong}
+	drifts := r.Check()
+	if len(drifts) != 1 || drifts[0].Symbol != "BTC_USDT:SHORT" || drifts[0].Remote != -2 {
+		t.Fatalf("missing side got %+v", drifts)
+	}
+	if pos, ok := positions.Get("BTC_USDT:SHORT"); !ok || pos.(*Position).Size != -2 {
+		t.Fatalf("short not added %+v", pos)
+	}
+}
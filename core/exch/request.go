@@ -0,0 +1,172 @@
+package exch
+
+// OrderRequest 下单请求
+type OrderRequest struct {
+	Symbol    string  //交易对
+	Size      float64 //下单数量 多正 空负
+	Price     float64 //下单价格 0为市价
+	UUID      string  //自定义订单Id
+	Iceberg   int64   //冰山数量
+	Text      string
+	Tif       string //下单类型 gtc,ioc,poc,fok
+	Ordertype string //订单类型 limit,market,stop,take_profit, 条件单为 stop 或 take_profit
+
+	TriggerPrice  float64 //触发价格, 不为 0 时下条件单, Price 为触发后的委托价格, 0为市价
+	TriggerBy     string  //触发价格类型 last,mark,index
+	ClosePosition bool    //平掉全部仓位, Size 只表示方向, 条件单为触发后平仓
+	ReduceOnly    bool    //只减仓
+	PostOnly      bool    //只挂单, 需要限价且不能为 ioc,fok
+	PositionSide  string  //双向持仓时的仓位方向 LONG,SHORT
+
+	//定点价格及数量, 通过 SetPrice SetSize 设置, 与 Price Size 一致时下单按定点值精确换算
+	FixedPrice Fixed
+	FixedSize  Fixed
+	PriceScale Scale
+	SizeScale  Scale
+}
+
+// CancelRequest 撤单请求, Id 为交易所订单Id
+type CancelRequest struct {
+	Symbol  string
+	Id      string
+	Trigger bool //撤销条件单, Id 为条件单Id
+}
+
+// AmendRequest 改单请求, Id 为交易所订单Id, Size 为包含已成交部分的新总数量 多正 空负,
+// Size 或 Price 为 0 时保持不变
+type AmendRequest struct {
+	Symbol string
+	Id     string
+	Size   float64
+	Price  float64
+	UUID   string //模拟改单时新订单的自定义Id, 为空时自动生成
+}
+
+func NewOrderRequest(o *Order) *OrderRequest {
+	if o == nil {
+		return nil
+	}
+	return &OrderRequest{
+		Symbol:    o.Symbol,
+		Size:      o.Size,
+		Price:     o.Price,
+		UUID:      o.UUID,
+		Iceberg:   o.Iceberg,
+		Text:      o.Text,
+		Tif:       o.Tif,
+		Ordertype: o.Ordertype,
+
+		TriggerPrice:  o.TriggerPrice,
+		TriggerBy:     o.TriggerBy,
+		ClosePosition: o.ClosePosition,
+		ReduceOnly:    o.ReduceOnly,
+		PostOnly:      o.PostOnly,
+		PositionSide:  o.PositionSide,
+
+		FixedPrice: o.FixedPrice,
+		FixedSize:  o.FixedSize,
+		PriceScale: o.PriceScale,
+		SizeScale:  o.SizeScale,
+	}
+}
+
+func (r *OrderRequest) Validate() error {
+	if r == nil {
+		return ErrEmptyOrder
+	}
+	if r.Symbol == "" {
+		return ErrEmptySymbol
+	}
+	if r.Size == 0 {
+		return ErrZeroSize
+	}
+	if r.Price < 0 || r.TriggerPrice < 0 {
+		return ErrInvalidPrice
+	}
+	if r.TriggerPrice == 0 && (r.Ordertype == OrderStop || r.Ordertype == OrderTakeProfit) {
+		return ErrInvalidOrder
+	}
+	if r.PostOnly && (r.Price == 0 || r.Tif == OrderIoc || r.Tif == OrderFok) {
+		return ErrInvalidOrder
+	}
+	if r.PositionSide != "" && r.PositionSide != PositionBoth && r.PositionSide != PositionLong && r.PositionSide != PositionShort {
+		return ErrInvalidOrder
+	}
+	return nil
+}
+
+// Order 转换为提交给交易所接口的订单
+func (r *OrderRequest) Order() *Order {
+	return &Order{
+		Symbol:    r.Symbol,
+		Size:      r.Size,
+		Price:     r.Price,
+		UUID:      r.UUID,
+		Iceberg:   r.Iceberg,
+		Text:      r.Text,
+		Tif:       r.Tif,
+		Ordertype: r.Ordertype,
+
+		TriggerPrice:  r.TriggerPrice,
+		TriggerBy:     r.TriggerBy,
+		ClosePosition: r.ClosePosition,
+		ReduceOnly:    r.ReduceOnly,
+		PostOnly:      r.PostOnly,
+		PositionSide:  r.PositionSide,
+
+		FixedPrice: r.FixedPrice,
+		FixedSize:  r.FixedSize,
+		PriceScale: r.PriceScale,
+		SizeScale:  r.SizeScale,
+	}
+}
+
+func (r *CancelRequest) Validate() error {
+	if r == nil {
+		return ErrEmptyOrder
+	}
+	if r.Symbol == "" {
+		return ErrEmptySymbol
+	}
+	if r.Id == "" {
+		return ErrEmptyOrderId
+	}
+	return nil
+}
+
+func (r *CancelRequest) Order() *Order {
+	return &Order{
+		Symbol: r.Symbol,
+		Id:     r.Id,
+	}
+}
+
+func (r *AmendRequest) Validate() error {
+	if r == nil {
+		return ErrEmptyOrder
+	}
+	if r.Symbol == "" {
+		return ErrEmptySymbol
+	}
+	if r.Id == "" {
+		return ErrEmptyOrderId
+	}
+	if r.Size == 0 && r.Price == 0 {
+		return ErrInvalidOrder
+	}
+	if r.Price < 0 {
+		return ErrInvalidPrice
+	}
+	return nil
+}
+
+// Order 转换为提交给交易所改单接口的订单
+func (r *AmendRequest) Order() *Order {
+	return &Order{
+		Symbol: r.Symbol,
+		Id:     r.Id,
+		Size:   r.Size,
+		Price:  r.Price,
+		UUID:   r.UUID,
+	}
+}
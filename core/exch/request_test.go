@@ -0,0 +1,32 @@
+package exch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOrderRequestPostOnly(t *testing.T) {
+	cases := []struct {
+		req OrderRequest
+		err error
+	}{
+		{OrderRequest{Symbol: "BTC_USDT", Size: 1, Price: 100, PostOnly: true}, nil},
+		{OrderRequest{Symbol: "BTC_USDT", Size: 1, PostOnly: true}, ErrInvalidOrder},
+		{OrderRequest{Symbol: "BTC_USDT", Size: 1, Price: 100, Tif: OrderIoc, PostOnly: true}, ErrInvalidOrder},
+	}
+	for _, c := range cases {
+		if err := c.req.Validate(); err != c.err {
+			t.Fatalf("%+v want %v got %v", c.req, c.err, err)
+		}
+	}
+	o := NewOrderRequest(&Order{Symbol: "BTC_USDT", Size: -1, ReduceOnly: true, ClosePosition: true}).Order()
+	if !o.ReduceOnly || !o.ClosePosition || o.IsPostOnly() {
+		t.Fatalf("flags not copied %+v", o)
+	}
+
+	var err error = &PostOnlyError{Exname: Gate, Symbol: "BTC_USDT", Detail: "ORDER_POC_IMMEDIATE"}
+	var pe *PostOnlyError
+	if !errors.Is(err, ErrPostOnlyRejected) || !errors.As(err, &pe) || pe.Exname != Gate {
+		t.Fatalf("post only error %v", err)
+	}
+}
@@ -0,0 +1,368 @@
+package exch
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	cmap "github.com/orcaman/concurrent-map"
+
+	"high-freq-quant-go/core/log"
+)
+
+// 风控拒单原因
+const (
+	RiskNotional    = "max_notional"    //单笔价值超过上限
+	RiskOpenOrders  = "max_open_orders" //交易对挂单数超过上限
+	RiskPosition    = "max_position"    //交易对仓位超过上限
+	RiskAccount     = "max_account"     //账号仓位总价值超过上限
+	RiskPriceBand   = "price_band"      //价格偏离标记价或中间价
+	RiskNoPrice     = "no_ref_price"    //没有参考价格, 无法计算市价单价值
+	RiskMinSize     = "min_size"        //数量小于最小下单数量
+	RiskMinNotional = "min_notional"    //价值小于最小下单金额
+	RiskStep        = "size_step"       //数量不是最小单位的整数倍
+	RiskTick        = "price_tick"      //价格不是最小价格单位的整数倍
+	RiskSelfTrade   = "self_trade"      //与自己的挂单成交
+	RiskKilled      = "killed"          //紧急停止已触发, 见 KillSwitch
+)
+
+// RiskError 风控拒单, errors.Is(err, ErrRiskRejected) 为 true
+type RiskError struct {
+	Reason string
+	Symbol string
+	Detail string
+}
+
+func (e *RiskError) Error() string {
+	return fmt.Sprintf("%s: %s %s %s", ErrRiskRejected, e.Symbol, e.Reason, e.Detail)
+}
+
+func (e *RiskError) Unwrap() error {
+	return ErrRiskRejected
+}
+
+// RiskConfig 下单前风控参数, 通过 WithRisk 设置, 数值 <= 0 或 false 不检查
+type RiskConfig struct {
+	MaxNotional   float64 //单笔最大价值
+	MaxOpenOrders int     //每个交易对最多挂单数
+	MaxPosition   float64 //每个交易对最大仓位数量(绝对值)
+	MaxAccount    float64 //账号所有仓位最大总价值
+	PriceBand     float64 //限价单偏离参考价的最大比例, 如 0.05
+	CheckMin      bool    //按 BaseInfo 检查最小数量、最小金额、数量及价格精度
+	SelfTrade     bool    //禁止与自己的挂单成交
+}
+
+var DefaultRisk = RiskConfig{}
+
+func WithRisk(ctx context.Context, cfg RiskConfig) context.Context {
+	return context.WithValue(ctx, CtxRisk, cfg)
+}
+
+func GetRisk(ctx context.Context) RiskConfig {
+	if ret, ok := ctx.Value(CtxRisk).(RiskConfig); ok {
+		return ret
+	}
+	return DefaultRisk
+}
+
+func (c RiskConfig) enabled() bool {
+	return c != RiskConfig{}
+}
+
+// RiskGate 包装 Exchange, 紧急停止触发后拒绝所有新订单, 下单前按 RiskConfig 检查,
+// 不通过时返回 RiskError 并记录日志, 仓位按推送维护, 用于账号总价值检查
+type RiskGate struct {
+	Exchange
+	Sign string
+
+	cfg       RiskConfig
+	hedge     bool               //双向持仓
+	positions cmap.ConcurrentMap //仓位数据的键 *Position
+	lk        sync.Mutex
+	rejected  map[string]int64
+}
+
+func NewRiskGate(ctx context.Context, ex Exchange, sign string, fd *Feed) *RiskGate {
+	g := &RiskGate{
+		Exchange:  ex,
+		Sign:      sign,
+		cfg:       GetRisk(ctx),
+		hedge:     HedgeMode(ctx),
+		positions: cmap.New(),
+		rejected:  map[string]int64{},
+	}
+	if fd != nil && g.cfg.enabled() {
+		fd.OnPosition("", func(pos *Position) { g.positions.Set(pos.Key(), pos) })
+	}
+	return g
+}
+
+func (g *RiskGate) PlaceOrder(ctx context.Context, req *OrderRequest) (*Order, error) {
+	if err := g.Check(ctx, req); err != nil {
+		return nil, err
+	}
+	return g.Exchange.PlaceOrder(ctx, req)
+}
+
+// PlaceBatchOrder 任意一笔不通过时整批拒绝
+func (g *RiskGate) PlaceBatchOrder(ctx context.Context, reqs []*OrderRequest) ([]*Order, error) {
+	for _, req := range reqs {
+		if err := g.Check(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	return g.Exchange.PlaceBatchOrder(ctx, reqs)
+}
+
+// AmendOrder 改单按新数量及价格检查, 不计入挂单数, Size 或 Price 为 0 时取推送的原订单
+func (g *RiskGate) AmendOrder(ctx context.Context, req *AmendRequest) (*AmendResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	or := &OrderRequest{Symbol: req.Symbol, Size: req.Size, Price: req.Price, UUID: req.UUID}
+	if o, ok := g.GetOrder(WithSymbol(ctx, req.Symbol))[req.Id]; ok && o != nil {
+		if or.Size == 0 {
+			or.Size = o.Size
+		}
+		if or.Price == 0 {
+			or.Price = o.Price
+		}
+	}
+	if err := g.guard(ctx, or, true); err != nil {
+		return nil, err
+	}
+	return g.Exchange.AmendOrder(ctx, req)
+}
+
+// CreateOrder 旧版接口同样经过风控
+func (g *RiskGate) CreateOrder(ctx context.Context) (*Order, error) {
+	return NewLegacy(g).CreateOrder(ctx)
+}
+
+func (g *RiskGate) CreateBatchOrder(ctx context.Context) ([]*Order, error) {
+	return NewLegacy(g).CreateBatchOrder(ctx)
+}
+
+// Check 按配置检查下单请求, 通过返回 nil
+func (g *RiskGate) Check(ctx context.Context, req *OrderRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	return g.guard(ctx, req, false)
+}
+
+func (g *RiskGate) guard(ctx context.Context, req *OrderRequest, amend bool) error {
+	if err := g.check(WithSymbol(ctx, req.Symbol), req, amend); err != nil {
+		g.lk.Lock()
+		g.rejected[err.Reason]++
+		g.lk.Unlock()
+		log.Warnf(log.Conn, "%s %s risk reject %s %s size=%v price=%v uuid=%s \r\n",
+			g.Sign, req.Symbol, err.Reason, err.Detail, req.Size, req.Price, req.UUID)
+		return err
+	}
+	return nil
+}
+
+func (g *RiskGate) check(ctx context.Context, req *OrderRequest, amend bool) *RiskError {
+	cfg := g.cfg
+	reject := func(reason, format string, args ...interface{}) *RiskError {
+		return &RiskError{Reason: reason, Symbol: req.Symbol, Detail: fmt.Sprintf(format, args...)}
+	}
+	if Kill.Active() {
+		return reject(RiskKilled, "new orders blocked")
+	}
+	// 改单未找到原订单时数量未知, 不检查
+	if !cfg.enabled() || req.Size == 0 {
+		return nil
+	}
+	info := g.GetBaseInfo(ctx)
+	ref := g.refPrice(ctx, info)
+	// 条件单不进入订单薄, 按触发价计算价值, 不检查价格偏离、挂单数及自成交
+	trigger := req.TriggerPrice > 0
+	price := req.Price
+	if price == 0 {
+		price = ref
+		if trigger {
+			price = req.TriggerPrice
+		}
+	}
+	size := math.Abs(req.Size)
+	notional := size * price
+	// 平仓单数量由交易所按仓位决定
+	if req.ClosePosition {
+		return nil
+	}
+
+	if cfg.CheckMin && info != nil {
+		min := info.MinBase
+		if min == 0 {
+			min = info.MinSizeStep
+		}
+		if min > 0 && size < min*(1-1e-9) {
+			return reject(RiskMinSize, "min %v", min)
+		}
+		if info.MinQuote > 0 && price > 0 && notional < info.MinQuote*(1-1e-9) {
+			return reject(RiskMinNotional, "notional %v min %v", notional, info.MinQuote)
+		}
+		if !onStep(size, info.MinSizeStep) {
+			return reject(RiskStep, "step %v", info.MinSizeStep)
+		}
+		if req.Price != 0 && !onStep(req.Price, info.MinPriceStep) {
+			return reject(RiskTick, "tick %v", info.MinPriceStep)
+		}
+	}
+	if cfg.PriceBand > 0 && req.Price != 0 && ref > 0 && !trigger {
+		if dev := math.Abs(req.Price-ref) / ref; dev > cfg.PriceBand {
+			return reject(RiskPriceBand, "ref %v deviation %.4f", ref, dev)
+		}
+	}
+	if cfg.MaxNotional > 0 {
+		if price == 0 {
+			return reject(RiskNoPrice, "market order")
+		}
+		if notional > cfg.MaxNotional {
+			return reject(RiskNotional, "notional %v max %v", notional, cfg.MaxNotional)
+		}
+	}
+	if (cfg.MaxOpenOrders > 0 || cfg.SelfTrade) && !trigger {
+		var open int
+		for _, o := range g.GetOrder(ctx) {
+			if o == nil || o.Status == OrderFinished {
+				continue
+			}
+			open++
+			if cfg.SelfTrade && crosses(req, o) {
+				return reject(RiskSelfTrade, "resting %s %v@%v", o.Id, o.Size, o.Price)
+			}
+		}
+		if cfg.MaxOpenOrders > 0 && !amend && open >= cfg.MaxOpenOrders {
+			return reject(RiskOpenOrders, "open %d max %d", open, cfg.MaxOpenOrders)
+		}
+	}
+	if cfg.MaxPosition > 0 || cfg.MaxAccount > 0 {
+		// 双向持仓按订单对应方向的仓位单独检查, 不与另一方向合并为净仓位
+		key, side := req.Symbol, ""
+		if g.hedge {
+			side = req.Order().HedgeSide()
+			key = PositionKey(req.Symbol, side)
+			ctx = WithPositionSide(ctx, side)
+		}
+		var cur float64
+		if pos := g.position(ctx, req.Symbol, side); pos != nil {
+			cur = pos.Size
+		}
+		next := cur + req.Size
+		// 减仓不限制
+		reduce := req.ReduceOnly || math.Abs(next) <= math.Abs(cur)
+		if g.hedge {
+			reduce = req.ReduceOnly || req.Order().HedgeClose()
+		}
+		if cfg.MaxPosition > 0 && !reduce && math.Abs(next) > cfg.MaxPosition {
+			return reject(RiskPosition, "position %v max %v", next, cfg.MaxPosition)
+		}
+		if cfg.MaxAccount > 0 && !reduce {
+			total := g.accountNotional(key) + math.Abs(next)*price
+			if total > cfg.MaxAccount {
+				return reject(RiskAccount, "account %v max %v", total, cfg.MaxAccount)
+			}
+		}
+	}
+	return nil
+}
+
+// refPrice 参考价格, 优先标记价, 其次订单薄中间价
+func (g *RiskGate) refPrice(ctx context.Context, info *BaseInfo) float64 {
+	if info != nil && info.MarkPrice > 0 {
+		return info.MarkPrice
+	}
+	bk := g.GetOrderBook(ctx)
+	if bk == nil {
+		return 0
+	}
+	var ask, bid float64
+	bk.View(func(asks, bids Levels) {
+		asks(func(price, size float64) bool { ask = price; return false })
+		bids(func(price, size float64) bool { bid = price; return false })
+	})
+	if ask > 0 && bid > 0 {
+		return (ask + bid) / 2
+	}
+	return 0
+}
+
+// position 连接维护的仓位, 没有时取推送的仓位, 双向持仓时取 side 方向, side 为空时合并为净仓位
+func (g *RiskGate) position(ctx context.Context, symbol, side string) *Position {
+	if pos := g.GetPosition(ctx); pos != nil {
+		return pos
+	}
+	if side != "" {
+		if pos, ok := g.positions.Get(PositionKey(symbol, side)); ok {
+			return pos.(*Position)
+		}
+		return nil
+	}
+	var list []*Position
+	for _, key := range PositionKeys(symbol, g.hedge) {
+		if pos, ok := g.positions.Get(key); ok {
+			list = append(list, pos.(*Position))
+		}
+	}
+	switch len(list) {
+	case 0:
+		return nil
+	case 1:
+		return list[0]
+	}
+	return NetPosition(symbol, list...)
+}
+
+// accountNotional 除 key 外其他仓位的总价值, key 为交易对, 双向持仓时为交易对及方向
+func (g *RiskGate) accountNotional(key string) float64 {
+	var total float64
+	for _, v := range g.positions.Items() {
+		pos := v.(*Position)
+		if pos.Key() == key || pos.Size == 0 {
+			continue
+		}
+		price := pos.MarkPrice
+		if price == 0 {
+			price = pos.Price
+		}
+		total += math.Abs(pos.Size) * price
+	}
+	return total
+}
+
+// Rejected 各原因的拒单次数
+func (g *RiskGate) Rejected() map[string]int64 {
+	g.lk.Lock()
+	defer g.lk.Unlock()
+	res := make(map[string]int64, len(g.rejected))
+	for k, n := range g.rejected {
+		res[k] = n
+	}
+	return res
+}
+
+// crosses 新订单是否会与反方向的挂单成交, 市价单与所有反方向挂单成交
+func crosses(req *OrderRequest, o *Order) bool {
+	if req.Size*o.Size >= 0 || o.Price == 0 {
+		return false
+	}
+	if req.Price == 0 {
+		return true
+	}
+	if req.Size > 0 {
+		return req.Price >= o.Price
+	}
+	return req.Price <= o.Price
+}
+
+func onStep(v, step float64) bool {
+	if step <= 0 {
+		return true
+	}
+	n := v / step
+	return math.Abs(n-math.Round(n)) <= 1e-6*math.Max(1, n)
+}
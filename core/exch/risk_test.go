@@ -0,0 +1,122 @@
+package exch
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type riskMock struct {
+	Exchange
+	info   *BaseInfo
+	pos    *Position
+	orders map[string]*Order
+	placed int
+}
+
+func (m *riskMock) GetBaseInfo(ctx context.Context) *BaseInfo      { return m.info }
+func (m *riskMock) GetPosition(ctx context.Context) *Position      { return m.pos }
+func (m *riskMock) GetOrder(ctx context.Context) map[string]*Order { return m.orders }
+func (m *riskMock) GetOrderBook(ctx context.Context) Booker        { return nil }
+
+func (m *riskMock) PlaceOrder(ctx context.Context, req *OrderRequest) (*Order, error) {
+	m.placed++
+	return req.Order(), nil
+}
+
+func TestRiskGate(t *testing.T) {
+	ctx := WithRisk(context.Background(), RiskConfig{
+		MaxNotional:   1000,
+		MaxOpenOrders: 2,
+		MaxPosition:   5,
+		PriceBand:     0.05,
+		CheckMin:      true,
+		SelfTrade:     true,
+	})
+	m := &riskMock{
+		info:   &BaseInfo{MarkPrice: 100, MinBase: 0.1, MinSizeStep: 0.1, MinPriceStep: 0.5, MinQuote: 5},
+		pos:    &Position{Symbol: "BTC_USDT", Size: 4},
+		orders: map[string]*Order{"1": {Id: "1", Symbol: "BTC_USDT", Size: -1, Price: 103}},
+	}
+	g := NewRiskGate(ctx, m, "test", nil)
+	cases := []struct {
+		req    OrderRequest
+		reason string
+	}{
+		{OrderRequest{Size: 0.05, Price: 100}, RiskMinSize},
+		{OrderRequest{Size: 0.15, Price: 100}, RiskStep},
+		{OrderRequest{Size: 1, Price: 100.2}, RiskTick},
+		{OrderRequest{Size: 1, Price: 110}, RiskPriceBand},
+		{OrderRequest{Size: 20, Price: 100}, RiskNotional},
+		{OrderRequest{Size: 1, Price: 103}, RiskSelfTrade},
+		{OrderRequest{Size: 1.5, Price: 100}, RiskPosition},
+		{OrderRequest{Size: 1, Price: 100}, ""},
+		// 减仓不受仓位限制
+		{OrderRequest{Size: -8, Price: 100}, ""},
+		{OrderRequest{Size: -10, Price: 100, ReduceOnly: true}, ""},
+	}
+	for _, c := range cases {
+		req := c.req
+		req.Symbol = "BTC_USDT"
+		_, err := g.PlaceOrder(ctx, &req)
+		var re *RiskError
+		if c.reason == "" {
+			if err != nil {
+				t.Fatalf("%+v got %v", req, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrRiskRejected) || !errors.As(err, &re) || re.Reason != c.reason {
+			t.Fatalf("%+v want %s got %v", req, c.reason, err)
+		}
+	}
+	m.orders["2"] = &Order{Id: "2", Symbol: "BTC_USDT", Size: 1, Price: 95}
+	if _, err := g.PlaceOrder(ctx, &OrderRequest{Symbol: "BTC_USDT", Size: 0.5, Price: 99}); !errors.Is(err, ErrRiskRejected) {
+		t.Fatalf("open orders got %v", err)
+	}
+	if m.placed != 3 || g.Rejected()[RiskSelfTrade] != 1 || g.Rejected()[RiskOpenOrders] != 1 {
+		t.Fatalf("placed %d rejected %v", m.placed, g.Rejected())
+	}
+}
+
+func TestRiskGateHedge(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = WithHedgeMode(WithRisk(ctx, RiskConfig{MaxPosition: 5}), true)
+	fd := NewFeed(ctx)
+	m := &riskMock{info: &BaseInfo{MarkPrice: 100}, orders: map[string]*Order{}}
+	g := NewRiskGate(ctx, m, "test", fd)
+	fd.PubPosition(&Position{Symbol: "BTC_USDT", Size: 5, PositionMode: PositionLong})
+	fd.PubPosition(&Position{Symbol: "BTC_USDT", Size: -5, PositionMode: PositionShort})
+	waitFor(t, func() bool { return g.positions.Count() == 2 })
+	cases := []struct {
+		req    OrderRequest
+		reason string
+	}{
+		// 净仓位为 0, 但每个方向单独检查
+		{OrderRequest{Size: 3, Price: 100, PositionSide: PositionLong}, RiskPosition},
+		{OrderRequest{Size: 3, Price: 100}, RiskPosition},
+		{OrderRequest{Size: -3, Price: 100, PositionSide: PositionShort}, RiskPosition},
+		// 平仓不受仓位限制
+		{OrderRequest{Size: -3, Price: 100, PositionSide: PositionLong}, ""},
+		{OrderRequest{Size: 3, Price: 100, ReduceOnly: true}, ""},
+	}
+	for _, c := range cases {
+		req := c.req
+		req.Symbol = "BTC_USDT"
+		_, err := g.PlaceOrder(ctx, &req)
+		var re *RiskError
+		if c.reason == "" {
+			if err != nil {
+				t.Fatalf("%+v got %v", req, err)
+			}
+			continue
+		}
+		if !errors.As(err, &re) || re.Reason != c.reason {
+			t.Fatalf("%+v want %s got %v", req, c.reason, err)
+		}
+	}
+	if pos := g.position(ctx, "BTC_USDT", ""); pos == nil || pos.Size != 0 {
+		t.Fatalf("net position got %+v", pos)
+	}
+}
@@ -0,0 +1,103 @@
+package exch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"high-freq-quant-go/core/log"
+)
+
+// AccountSetup 交易对启动时需要的账号设置, 零值的项不设置
+type AccountSetup struct {
+	Symbol       string
+	PositionMode bool          //按账号的 HedgeMode 设置单向或双向持仓
+	MarginMode   string        //保证金模式 isolated,crossed
+	RiskLimit    float64       //风险限额
+	Leverage     float64       //杠杆, 全仓时为全仓杠杆上限
+	Retries      int           //设置或确认失败时最多尝试次数
+	Interval     time.Duration //每次尝试的间隔
+}
+
+// Setup 按账号的持仓模式设置交易对并确认, 返回确认时查询的仓位
+func (mn *Exchanger) Setup(ctx context.Context, setup AccountSetup) (*Position, error) {
+	return SetupAccount(ctx, mn.Ex, HedgeMode(mn.Ctx), setup)
+}
+
+// SetupAccount 按持仓模式、保证金模式、风险限额、杠杆的顺序设置, 各接口已是目标设置时不修改,
+// 设置后 REST 查询仓位确认, 失败或不一致时重试, 返回最后一次查询的仓位
+func SetupAccount(ctx context.Context, tr Trader, hedge bool, setup AccountSetup) (*Position, error) {
+	if setup.Symbol == "" {
+		return nil, ErrEmptySymbol
+	}
+	retries := setup.Retries
+	if retries <= 0 {
+		retries = 1
+	}
+	var pos *Position
+	var err error
+	for i := 0; i < retries; i++ {
+		if i > 0 {
+			time.Sleep(setup.Interval)
+		}
+		if pos, err = setupOnce(ctx, tr, hedge, setup); err == nil {
+			log.Infoln(log.Conn, setup.Symbol, "SetupAccount success", pos.Lv, pos.MarginType, pos.PositionMode)
+			return pos, nil
+		}
+		log.Warnln(log.Conn, setup.Symbol, "SetupAccount error", i, err)
+	}
+	return pos, err
+}
+
+func setupOnce(ctx context.Context, tr Trader, hedge bool, setup AccountSetup) (*Position, error) {
+	symbol := setup.Symbol
+	if setup.PositionMode {
+		if err := tr.SetPositionMode(WithSymbol(ctx, symbol), hedge); err != nil {
+			return nil, err
+		}
+	}
+	if setup.MarginMode != "" {
+		if err := tr.SetMarginMode(ctx, symbol, setup.MarginMode); err != nil {
+			return nil, err
+		}
+	}
+	if setup.RiskLimit > 0 {
+		if err := tr.SetRiskLimit(ctx, symbol, setup.RiskLimit); err != nil {
+			return nil, err
+		}
+	}
+	if setup.Leverage > 0 {
+		if _, err := tr.SetLeverage(ctx, symbol, setup.Leverage); err != nil {
+			return nil, err
+		}
+	}
+	// 双向持仓按多仓确认, 两个方向的设置相同
+	qctx := ctx
+	if hedge {
+		qctx = WithPositionSide(ctx, PositionLong)
+	}
+	pos, err := tr.FetchPosition(qctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if pos == nil {
+		return nil, fmt.Errorf("%w: %s position not found", ErrAccountSetup, symbol)
+	}
+	return pos, verifySetup(pos, hedge, setup)
+}
+
+// verifySetup 查询的仓位与目标设置比较
+func verifySetup(pos *Position, hedge bool, setup AccountSetup) error {
+	dual := pos.PositionMode == PositionLong || pos.PositionMode == PositionShort
+	switch {
+	case setup.PositionMode && dual != hedge:
+		return fmt.Errorf("%w: %s position mode %s", ErrAccountSetup, setup.Symbol, pos.PositionMode)
+	case setup.MarginMode != "" && pos.MarginType != setup.MarginMode:
+		return fmt.Errorf("%w: %s margin mode %s", ErrAccountSetup, setup.Symbol, pos.MarginType)
+	case setup.RiskLimit > 0 && pos.RiskLimit != setup.RiskLimit:
+		return fmt.Errorf("%w: %s risk limit %v", ErrAccountSetup, setup.Symbol, pos.RiskLimit)
+	case setup.Leverage > 0 && pos.Lv != setup.Leverage:
+		return fmt.Errorf("%w: %s leverage %v", ErrAccountSetup, setup.Symbol, pos.Lv)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package exch
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type setupMock struct {
+	Exchange
+	pos   Position
+	hedge bool
+	calls int
+	maxLv float64 //超过时交易所不修改杠杆
+}
+
+func (m *setupMock) SetPositionMode(ctx context.Context, hedge bool) error {
+	m.calls++
+	m.hedge = hedge
+	return nil
+}
+
+func (m *setupMock) SetMarginMode(ctx context.Context, symbol, mode string) error {
+	m.calls++
+	m.pos.MarginType = mode
+	return nil
+}
+
+func (m *setupMock) SetRiskLimit(ctx context.Context, symbol string, limit float64) error {
+	return ErrNotSupported
+}
+
+func (m *setupMock) SetLeverage(ctx context.Context, symbol string, lv float64) (*Position, error) {
+	m.calls++
+	if lv <= m.maxLv {
+		m.pos.Lv = lv
+	}
+	return nil, nil
+}
+
+func (m *setupMock) FetchPosition(ctx context.Context, symbol string) (*Position, error) {
+	pos := m.pos
+	pos.Symbol = symbol
+	pos.PositionMode = PositionBoth
+	if m.hedge {
+		pos.PositionMode = PositionSide(ctx)
+	}
+	return &pos, nil
+}
+
+func TestSetupAccount(t *testing.T) {
+	m := &setupMock{maxLv: 20}
+	setup := AccountSetup{Symbol: "BTC_USDT", PositionMode: true, MarginMode: MarginCrossed, Leverage: 10}
+	pos, err := SetupAccount(context.Background(), m, true, setup)
+	if err != nil || pos.Lv != 10 || pos.MarginType != MarginCrossed || pos.PositionMode != PositionLong {
+		t.Fatalf("setup %+v %v", pos, err)
+	}
+
+	// 杠杆未生效时重试后返回 ErrAccountSetup
+	m.calls = 0
+	setup.Leverage, setup.Retries = 50, 2
+	if _, err = SetupAccount(context.Background(), m, true, setup); !errors.Is(err, ErrAccountSetup) || m.calls != 6 {
+		t.Fatalf("leverage mismatch got %v calls %d", err, m.calls)
+	}
+
+	setup.RiskLimit = 1000
+	if _, err = SetupAccount(context.Background(), m, false, setup); err != ErrNotSupported {
+		t.Fatalf("risk limit got %v", err)
+	}
+}
@@ -0,0 +1,327 @@
+package exch
+
+import (
+	"context"
+	"sync"
+
+	"high-freq-quant-go/adapter/text"
+	"high-freq-quant-go/core/log"
+)
+
+// 共享订阅的数据流
+const (
+	StreamBook      = "book"
+	StreamTicker    = "ticker"
+	StreamOrder     = "order"
+	StreamUserTrade = "trade"
+	StreamTrade     = "public_trade"
+	StreamPosition  = "position"
+	StreamBalance   = "balance"
+)
+
+// SharedId SubManager 创建的连接 id, 同一 ApiSign 的所有策略共用一个连接
+const SharedId = "shared"
+
+// IsPublic 行情数据流, 同一交易所类型的所有账号共用
+func IsPublic(stream string) bool {
+	return stream == StreamBook || stream == StreamTicker || stream == StreamTrade
+}
+
+type sharedStream struct {
+	apiSign      string //提供数据流的连接
+	kind, symbol string
+	owners       map[string]bool
+	ex           *Exchanger
+	ready        chan struct{} //订阅请求结束时关闭, 之后 ex 及 err 不再变化
+	err          error
+}
+
+// SubManager 多个策略共用连接订阅数据流, 行情按交易所类型复用第一个账号的连接, 账户数据按 ApiSign 复用,
+// 按数据流记录订阅的策略, 最后一个策略取消时才取消订阅, 连接没有数据流时关闭.
+// 创建连接及订阅等网络请求在锁外进行, 进行中的数据流及连接有标记, 同一数据流或连接的其他调用等待其结束
+type SubManager struct {
+	lk      sync.Mutex
+	conns   map[string]*Exchanger    //ApiSign 对应的连接
+	users   map[string]int           //ApiSign 连接上的数据流数, 包含订阅中的
+	public  map[string]string        //NameType 对应的提供行情的 ApiSign
+	streams map[string]*sharedStream //包含订阅中的数据流
+	leaving map[string]chan struct{} //取消订阅中的数据流
+	dialing map[string]chan struct{} //创建或关闭中的连接
+}
+
+var Shared = NewSubManager()
+
+func NewSubManager() *SubManager {
+	return &SubManager{
+		conns:   map[string]*Exchanger{},
+		users:   map[string]int{},
+		public:  map[string]string{},
+		streams: map[string]*sharedStream{},
+		leaving: map[string]chan struct{}{},
+		dialing: map[string]chan struct{}{},
+	}
+}
+
+func nameType(ctx context.Context) string {
+	return text.GetString(ctx, CtxExname) + "_" + text.GetString(ctx, CtxExtype)
+}
+
+// streamKey 行情按交易所类型区分, 账户数据按 ApiSign 区分
+func streamKey(ctx context.Context, kind, symbol string) string {
+	if IsPublic(kind) {
+		return nameType(ctx) + "." + kind + "." + symbol
+	}
+	return text.GetString(ctx, ApiSign) + "." + kind + "." + symbol
+}
+
+// wait 释放锁等待 ch 关闭后重新加锁
+func (m *SubManager) wait(ch chan struct{}) {
+	m.lk.Unlock()
+	<-ch
+	m.lk.Lock()
+}
+
+// Subscribe owner 为策略标识, ctx 为 ApiCtx 返回的账号参数, 返回提供该数据流的连接,
+// 行情可能由其他账号的连接提供, 通过返回连接的 Feed 及 Get* 获取数据
+func (m *SubManager) Subscribe(ctx context.Context, owner, kind, symbol string) (*Exchanger, error) {
+	if symbol == "" && kind != StreamBalance {
+		return nil, ErrEmptySymbol
+	}
+	key := streamKey(ctx, kind, symbol)
+	m.lk.Lock()
+	for {
+		if s, ok := m.streams[key]; ok {
+			s.owners[owner] = true
+			m.lk.Unlock()
+			<-s.ready
+			return s.ex, s.err
+		}
+		ch, ok := m.leaving[key]
+		if !ok {
+			break
+		}
+		m.wait(ch)
+	}
+	apiSign := text.GetString(ctx, ApiSign)
+	if IsPublic(kind) {
+		if sign, ok := m.public[nameType(ctx)]; ok {
+			apiSign = sign
+		} else {
+			m.public[nameType(ctx)] = apiSign
+		}
+	}
+	// 先登记数据流及连接引用, 同一数据流的订阅等待 ready, 连接不会被关闭
+	s := &sharedStream{
+		apiSign: apiSign,
+		kind:    kind,
+		symbol:  symbol,
+		owners:  map[string]bool{owner: true},
+		ready:   make(chan struct{}),
+	}
+	m.streams[key] = s
+	m.users[apiSign]++
+	ex := m.conn(ctx, apiSign)
+	err := ErrNoConn
+	if ex != nil {
+		m.lk.Unlock()
+		err = subscribe(ex, kind, symbol)
+		m.lk.Lock()
+	}
+	if err != nil {
+		if ex != nil {
+			log.Errorln(log.Conn, ex.Sign, symbol, "shared subscribe", kind, "error", err)
+		}
+		if m.streams[key] == s {
+			delete(m.streams, key)
+		}
+		s.err = err
+		closing := m.release(apiSign)
+		close(s.ready)
+		m.lk.Unlock()
+		closing()
+		return nil, err
+	}
+	s.ex = ex
+	close(s.ready)
+	m.lk.Unlock()
+	log.Infoln(log.Conn, ex.Sign, symbol, "shared subscribe", kind, "owner", owner)
+	return ex, nil
+}
+
+// Unsubscribe 策略取消订阅, 没有策略使用时取消数据流
+func (m *SubManager) Unsubscribe(ctx context.Context, owner, kind, symbol string) error {
+	m.lk.Lock()
+	leave := m.unref(streamKey(ctx, kind, symbol), owner)
+	m.lk.Unlock()
+	if leave == nil {
+		return nil
+	}
+	return leave()
+}
+
+// Release 取消策略的所有订阅, 策略退出时调用
+func (m *SubManager) Release(owner string) error {
+	m.lk.Lock()
+	var leaves []func() error
+	for key, s := range m.streams {
+		if !s.owners[owner] {
+			continue
+		}
+		if leave := m.unref(key, owner); leave != nil {
+			leaves = append(leaves, leave)
+		}
+	}
+	m.lk.Unlock()
+	var err error
+	for _, leave := range leaves {
+		if uerr := leave(); uerr != nil {
+			err = uerr
+		}
+	}
+	return err
+}
+
+// Owners 数据流的订阅策略数
+func (m *SubManager) Owners(ctx context.Context, kind, symbol string) int {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if s, ok := m.streams[streamKey(ctx, kind, symbol)]; ok {
+		return len(s.owners)
+	}
+	return 0
+}
+
+// unref 持有 m.lk 调用, 最后一个策略取消时返回在锁外执行的取消订阅
+func (m *SubManager) unref(key, owner string) func() error {
+	s, ok := m.streams[key]
+	if !ok || !s.owners[owner] {
+		return nil
+	}
+	delete(s.owners, owner)
+	if len(s.owners) != 0 {
+		return nil
+	}
+	delete(m.streams, key)
+	ch := make(chan struct{})
+	m.leaving[key] = ch
+	return func() error {
+		<-s.ready
+		var err error
+		// 订阅失败时订阅方已释放连接引用
+		if s.err == nil {
+			if err = unsubscribe(s.ex, s.kind, s.symbol); err != nil {
+				log.Errorln(log.Conn, s.ex.Sign, s.symbol, "shared unsubscribe", s.kind, "error", err)
+			}
+		}
+		m.lk.Lock()
+		closing := func() {}
+		if s.err == nil {
+			closing = m.release(s.apiSign)
+		}
+		delete(m.leaving, key)
+		close(ch)
+		m.lk.Unlock()
+		closing()
+		return err
+	}
+}
+
+// conn 持有 m.lk 调用, 获取或创建 ApiSign 的共用连接, 创建期间释放锁.
+// 行情由其他账号提供时 ctx 不是该账号的参数, 只能使用已有连接
+func (m *SubManager) conn(ctx context.Context, apiSign string) *Exchanger {
+	for {
+		if ex, ok := m.conns[apiSign]; ok {
+			return ex
+		}
+		ch, ok := m.dialing[apiSign]
+		if !ok {
+			break
+		}
+		m.wait(ch)
+	}
+	if apiSign != text.GetString(ctx, ApiSign) {
+		return nil
+	}
+	ch := make(chan struct{})
+	m.dialing[apiSign] = ch
+	m.lk.Unlock()
+	ex := NewExchanger(ctx, SharedId)
+	m.lk.Lock()
+	delete(m.dialing, apiSign)
+	close(ch)
+	if ex != nil {
+		m.conns[apiSign] = ex
+	}
+	return ex
+}
+
+// release 持有 m.lk 调用, 连接没有数据流时返回在锁外执行的关闭
+func (m *SubManager) release(apiSign string) func() {
+	m.users[apiSign]--
+	if m.users[apiSign] > 0 {
+		return func() {}
+	}
+	delete(m.users, apiSign)
+	for nt, sign := range m.public {
+		if sign == apiSign {
+			delete(m.public, nt)
+		}
+	}
+	ex, ok := m.conns[apiSign]
+	if !ok {
+		return func() {}
+	}
+	delete(m.conns, apiSign)
+	ch := make(chan struct{})
+	m.dialing[apiSign] = ch
+	return func() {
+		Delete(apiSign, SharedId)
+		log.Infoln(log.Conn, ex.Sign, "shared conn closed")
+		m.lk.Lock()
+		delete(m.dialing, apiSign)
+		close(ch)
+		m.lk.Unlock()
+	}
+}
+
+// subscribe 使用连接的 ctx, 重连初始化随连接结束, 不受策略 ctx 取消影响
+func subscribe(ex *Exchanger, kind, symbol string) error {
+	ctx := ex.Ctx
+	switch kind {
+	case StreamBook:
+		return ex.Ex.SubscribeOrderBook(ctx, symbol)
+	case StreamTicker:
+		return ex.Ex.SubscribeTicker(ctx, symbol)
+	case StreamOrder:
+		return ex.Ex.SubscribeOrder(ctx, symbol)
+	case StreamUserTrade:
+		return ex.Ex.SubscribeUserTrade(ctx, symbol)
+	case StreamTrade:
+		return ex.Ex.SubscribePublicTrades(ctx, symbol)
+	case StreamPosition:
+		return ex.Ex.SubscribePosition(ctx, symbol)
+	case StreamBalance:
+		return ex.Ex.SubscribeBalance(ctx, symbol)
+	}
+	return ErrNotSupported
+}
+
+// unsubscribe 资金推送不区分交易对, 连接关闭时结束
+func unsubscribe(ex *Exchanger, kind, symbol string) error {
+	ctx := ex.Ctx
+	switch kind {
+	case StreamBook:
+		return ex.Ex.UnsubscribeOrderBook(ctx, symbol)
+	case StreamTicker:
+		return ex.Ex.UnsubscribeTicker(ctx, symbol)
+	case StreamOrder:
+		return ex.Ex.UnsubscribeOrder(ctx, symbol)
+	case StreamUserTrade:
+		return ex.Ex.UnsubscribeUserTrade(ctx, symbol)
+	case StreamTrade:
+		return ex.Ex.UnsubscribePublicTrades(ctx, symbol)
+	case StreamPosition:
+		return ex.Ex.UnsubscribePosition(ctx, symbol)
+	}
+	return nil
+}
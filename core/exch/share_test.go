@@ -0,0 +1,150 @@
+package exch
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"high-freq-quant-go/adapter/text"
+)
+
+type shareMock struct {
+	Exchange
+	sign  string
+	lk    sync.Mutex
+	calls []string
+}
+
+var shareMocks = map[string]*shareMock{}
+
+func (m *shareMock) call(s string) error {
+	m.lk.Lock()
+	m.calls = append(m.calls, s)
+	m.lk.Unlock()
+	return nil
+}
+
+func (m *shareMock) SubscribeOrderBook(ctx context.Context, symbol string) error {
+	return m.call("book " + symbol)
+}
+
+func (m *shareMock) UnsubscribeOrderBook(ctx context.Context, symbol string) error {
+	return m.call("unbook " + symbol)
+}
+
+func (m *shareMock) SubscribeOrder(ctx context.Context, symbol string) error {
+	return m.call("order " + symbol)
+}
+
+func (m *shareMock) UnsubscribeOrder(ctx context.Context, symbol string) error {
+	return m.call("unorder " + symbol)
+}
+
+func shareCtx(apiSign string) context.Context {
+	ctx := context.WithValue(context.Background(), ApiSign, apiSign)
+	ctx = context.WithValue(ctx, CtxExname, "sharetest")
+	return context.WithValue(ctx, CtxExtype, Futures)
+}
+
+func TestSubManager(t *testing.T) {
+	Register("sharetest_"+Futures, func(ctx context.Context) Exchange {
+		m := &shareMock{sign: text.GetString(ctx, ApiSign)}
+		shareMocks[m.sign] = m
+		return m
+	})
+	sm := NewSubManager()
+	a, b := shareCtx("acc-a"), shareCtx("acc-b")
+
+	exa, err := sm.Subscribe(a, "s1", StreamBook, "BTC_USDT")
+	if err != nil || exa == nil {
+		t.Fatalf("subscribe got %v", err)
+	}
+	// 其他账号及策略复用同一行情连接
+	exb, _ := sm.Subscribe(b, "s2", StreamBook, "BTC_USDT")
+	if exb != exa || sm.Owners(a, StreamBook, "BTC_USDT") != 2 {
+		t.Fatalf("book not shared %v %v", exb, exa)
+	}
+	// 账户数据使用各自账号的连接
+	exo, _ := sm.Subscribe(b, "s2", StreamOrder, "BTC_USDT")
+	if exo == exa || shareMocks["acc-b"] == nil {
+		t.Fatal("private stream on other account conn")
+	}
+	if calls := shareMocks["acc-a"].calls; len(calls) != 1 {
+		t.Fatalf("acc-a calls %v", calls)
+	}
+
+	sm.Unsubscribe(a, "s1", StreamBook, "BTC_USDT")
+	if calls := shareMocks["acc-a"].calls; len(calls) != 1 {
+		t.Fatalf("unsubscribed with subscribers %v", calls)
+	}
+	sm.Release("s2")
+	if calls := shareMocks["acc-a"].calls; len(calls) != 2 || calls[1] != "unbook BTC_USDT" {
+		t.Fatalf("acc-a calls %v", calls)
+	}
+	if calls := shareMocks["acc-b"].calls; len(calls) != 2 || calls[1] != "unorder BTC_USDT" {
+		t.Fatalf("acc-b calls %v", calls)
+	}
+	// 连接没有数据流时关闭
+	if AllManage.GetEx("acc-a_"+SharedId) != nil || exa.Ctx.Err() == nil {
+		t.Fatal("unused conn not closed")
+	}
+	if ex, _ := sm.Subscribe(b, "s3", StreamBook, "BTC_USDT"); ex == exa {
+		t.Fatal("closed conn reused")
+	}
+}
+
+type slowShareMock struct {
+	shareMock
+	gate chan struct{}
+}
+
+func (m *slowShareMock) SubscribeOrderBook(ctx context.Context, symbol string) error {
+	if symbol == "SLOW" {
+		<-m.gate
+	}
+	return m.call("book " + symbol)
+}
+
+func TestSubManagerConcurrent(t *testing.T) {
+	slow := &slowShareMock{gate: make(chan struct{})}
+	Register("sharetest_"+Spot, func(ctx context.Context) Exchange {
+		slow.sign = text.GetString(ctx, ApiSign)
+		return slow
+	})
+	ctx := context.WithValue(shareCtx("acc-slow"), CtxExtype, Spot)
+	sm := NewSubManager()
+
+	var wg sync.WaitGroup
+	for _, owner := range []string{"s1", "s2", "s3"} {
+		wg.Add(1)
+		go func(owner string) {
+			defer wg.Done()
+			if _, err := sm.Subscribe(ctx, owner, StreamBook, "SLOW"); err != nil {
+				t.Error(owner, err)
+			}
+		}(owner)
+	}
+	// 其他数据流不等待进行中的订阅请求
+	done := make(chan struct{})
+	go func() {
+		sm.Subscribe(ctx, "s4", StreamBook, "BTC_USDT")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("subscribe blocked by other stream")
+	}
+	for sm.Owners(ctx, StreamBook, "SLOW") != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	close(slow.gate)
+	wg.Wait()
+	// 同一数据流只订阅一次
+	slow.lk.Lock()
+	defer slow.lk.Unlock()
+	if len(slow.calls) != 2 {
+		t.Fatalf("calls %v", slow.calls)
+	}
+}
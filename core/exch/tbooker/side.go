@@ -0,0 +1,248 @@
+package tbooker
+
+import (
+	"math/bits"
+	"sort"
+
+	"high-freq-quant-go/core/exch"
+)
+
+// side 单边档位, 最优价附近的档位按 tick 存放在数组窗口 [lo, lo+n) 内,
+// 窗口外较差的档位存放在 far 中. 最优价始终在窗口内.
+type side struct {
+	ask   bool
+	lo    exch.Fixed
+	sizes []float64
+	bits  []uint64
+	count int //窗口内档位数
+	far   map[exch.Fixed]float64
+	best  exch.Fixed
+	has   bool
+}
+
+func newSide(ask bool, n int) *side {
+	n = (n + 63) &^ 63
+	return &side{
+		ask:   ask,
+		sizes: make([]float64, n),
+		bits:  make([]uint64, n/64),
+		far:   map[exch.Fixed]float64{},
+	}
+}
+
+func (sd *side) reset() {
+	for i := range sd.sizes {
+		sd.sizes[i] = 0
+	}
+	for i := range sd.bits {
+		sd.bits[i] = 0
+	}
+	sd.count, sd.has = 0, false
+	sd.far = map[exch.Fixed]float64{}
+}
+
+// load 用快照重建, 先定位窗口再写入避免多次 recenter
+func (sd *side) load(levels map[exch.Fixed]float64) {
+	sd.reset()
+	for t := range levels {
+		if !sd.has || sd.better(t, sd.best) {
+			sd.best, sd.has = t, true
+		}
+	}
+	if !sd.has {
+		return
+	}
+	sd.recenter(sd.best)
+	for t, s := range levels {
+		sd.put(t, s)
+	}
+}
+
+func (sd *side) len() int {
+	return sd.count + len(sd.far)
+}
+
+func (sd *side) inRing(t exch.Fixed) bool {
+	return t >= sd.lo && t < sd.lo+exch.Fixed(len(sd.sizes))
+}
+
+func (sd *side) better(a, b exch.Fixed) bool {
+	if sd.ask {
+		return a < b
+	}
+	return a > b
+}
+
+// drifted 最优价离窗口前端超过一半, 需要重新定位窗口
+func (sd *side) drifted(t exch.Fixed) bool {
+	n := exch.Fixed(len(sd.sizes))
+	if sd.ask {
+		return t-sd.lo > n/2
+	}
+	return sd.lo+n-1-t > n/2
+}
+
+func (sd *side) get(t exch.Fixed) (float64, bool) {
+	if sd.inRing(t) {
+		s := sd.sizes[t-sd.lo]
+		return s, s != 0
+	}
+	s, ok := sd.far[t]
+	return s, ok
+}
+
+func (sd *side) set(t exch.Fixed, size float64) {
+	if size == 0 {
+		sd.del(t)
+		return
+	}
+	if !sd.has || sd.better(t, sd.best) {
+		sd.best, sd.has = t, true
+		if !sd.inRing(t) || sd.drifted(t) {
+			sd.recenter(t)
+		}
+	}
+	sd.put(t, size)
+}
+
+func (sd *side) put(t exch.Fixed, size float64) {
+	if !sd.inRing(t) {
+		sd.far[t] = size
+		return
+	}
+	i := int(t - sd.lo)
+	if sd.sizes[i] == 0 {
+		sd.bits[i>>6] |= 1 << uint(i&63)
+		sd.count++
+	}
+	sd.sizes[i] = size
+}
+
+func (sd *side) del(t exch.Fixed) {
+	if sd.inRing(t) {
+		i := int(t - sd.lo)
+		if sd.sizes[i] == 0 {
+			return
+		}
+		sd.sizes[i] = 0
+		sd.bits[i>>6] &^= 1 << uint(i&63)
+		sd.count--
+	} else {
+		if _, ok := sd.far[t]; !ok {
+			return
+		}
+		delete(sd.far, t)
+	}
+	if sd.has && t == sd.best {
+		sd.findBest()
+	}
+}
+
+// findBest 最优价被删除后向较差方向查找新的最优价
+func (sd *side) findBest() {
+	if sd.count > 0 {
+		sd.best = sd.lo + exch.Fixed(sd.next(int(sd.best-sd.lo)))
+		if sd.drifted(sd.best) {
+			sd.recenter(sd.best)
+		}
+		return
+	}
+	if len(sd.far) == 0 {
+		sd.has = false
+		return
+	}
+	first := true
+	for t := range sd.far {
+		if first || sd.better(t, sd.best) {
+			sd.best, first = t, false
+		}
+	}
+	sd.recenter(sd.best)
+}
+
+// next 从下标 i 开始向较差方向的第一个档位下标, 没有返回 -1
+func (sd *side) next(i int) int {
+	if i < 0 || i >= len(sd.sizes) {
+		return -1
+	}
+	w := i >> 6
+	if sd.ask {
+		word := sd.bits[w] >> uint(i&63) << uint(i&63)
+		for {
+			if word != 0 {
+				return w<<6 + bits.TrailingZeros64(word)
+			}
+			if w++; w >= len(sd.bits) {
+				return -1
+			}
+			word = sd.bits[w]
+		}
+	}
+	word := sd.bits[w] << uint(63-i&63) >> uint(63-i&63)
+	for {
+		if word != 0 {
+			return w<<6 + 63 - bits.LeadingZeros64(word)
+		}
+		if w--; w < 0 {
+			return -1
+		}
+		word = sd.bits[w]
+	}
+}
+
+// recenter 以最优价 t 重新定位窗口, 卖盘窗口向上展开, 买盘窗口向下展开
+func (sd *side) recenter(t exch.Fixed) {
+	n := exch.Fixed(len(sd.sizes))
+	lo := t - n/4
+	if !sd.ask {
+		lo = t + n/4 - n + 1
+	}
+	if sd.count > 0 {
+		for w, word := range sd.bits {
+			for word != 0 {
+				i := w<<6 + bits.TrailingZeros64(word)
+				word &= word - 1
+				sd.far[sd.lo+exch.Fixed(i)] = sd.sizes[i]
+				sd.sizes[i] = 0
+			}
+			sd.bits[w] = 0
+		}
+		sd.count = 0
+	}
+	sd.lo = lo
+	for p, s := range sd.far {
+		if sd.inRing(p) {
+			delete(sd.far, p)
+			sd.put(p, s)
+		}
+	}
+}
+
+// each 从最优价开始按价格顺序遍历, fn 返回 false 停止
+func (sd *side) each(fn func(t exch.Fixed, size float64) bool) {
+	if !sd.has {
+		return
+	}
+	dir := 1
+	if !sd.ask {
+		dir = -1
+	}
+	for i := sd.next(int(sd.best - sd.lo)); i >= 0; i = sd.next(i + dir) {
+		if !fn(sd.lo+exch.Fixed(i), sd.sizes[i]) {
+			return
+		}
+	}
+	if len(sd.far) == 0 {
+		return
+	}
+	ticks := make([]exch.Fixed, 0, len(sd.far))
+	for t := range sd.far {
+		ticks = append(ticks, t)
+	}
+	sort.Slice(ticks, func(i, j int) bool { return sd.better(ticks[i], ticks[j]) })
+	for _, t := range ticks {
+		if !fn(t, sd.far[t]) {
+			return
+		}
+	}
+}
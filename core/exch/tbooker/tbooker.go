@@ -0,0 +1,192 @@
+// Package tbooker 按 tick 下标存放档位的订单薄, 更新及最优价查询 O(1),
+// 需通过 SetScale 设置 BaseInfo 的最小变动单位.
+package tbooker
+
+import (
+	"context"
+
+	"high-freq-quant-go/core/exch"
+)
+
+const BookerKey = "tbooker"
+
+// DefaultTicks 每边数组窗口的 tick 数
+var DefaultTicks = 4096
+
+type Booker struct {
+	exch.BookMeta
+	asks, bids *side
+	scale      exch.Scale
+}
+
+func NewBooker(ctx context.Context) *Booker {
+	bk := &Booker{
+		asks:  newSide(true, DefaultTicks),
+		bids:  newSide(false, DefaultTicks),
+		scale: exch.DecimalScale(exch.BookFloat),
+	}
+	bk.InitMeta(ctx)
+	return bk
+}
+
+// SetScale 设置价格刻度, 已有档位按新刻度重建
+func (bk *Booker) SetScale(sc exch.Scale) {
+	askMap, bidMap := bk.GetAskMap(), bk.GetBidMap()
+	bk.Rw.Lock()
+	bk.scale = sc
+	bk.Rw.Unlock()
+	bk.SetBook(askMap, bidMap)
+}
+
+func (bk *Booker) GetScale() exch.Scale {
+	bk.Rw.RLock()
+	defer bk.Rw.RUnlock()
+	return bk.scale
+}
+
+func (bk *Booker) SetBook(AskMap, BidMap map[string]float64) {
+	bk.Rw.Lock()
+	defer bk.Rw.Unlock()
+	bk.asks.load(bk.levels(AskMap))
+	bk.bids.load(bk.levels(BidMap))
+}
+
+func (bk *Booker) levels(data map[string]float64) map[exch.Fixed]float64 {
+	levels := make(map[exch.Fixed]float64, len(data))
+	for p, s := range data {
+		if s == 0 {
+			continue
+		}
+		levels[bk.scale.Parse(p)] = s
+	}
+	return levels
+}
+
+// UpdateAsk 调用方需持有 Rw 写锁, 买卖两边可以并发更新
+func (bk *Booker) UpdateAsk(p string, size float64) {
+	bk.asks.set(bk.scale.Parse(p), size)
+}
+
+func (bk *Booker) UpdateBid(p string, size float64) {
+	bk.bids.set(bk.scale.Parse(p), size)
+}
+
+func (bk *Booker) UpdateAskFixed(price exch.Fixed, size float64) {
+	bk.asks.set(price, size)
+}
+
+func (bk *Booker) UpdateBidFixed(price exch.Fixed, size float64) {
+	bk.bids.set(price, size)
+}
+
+func (bk *Booker) GetAskLen() int {
+	bk.Rw.RLock()
+	defer bk.Rw.RUnlock()
+	return bk.asks.len()
+}
+
+func (bk *Booker) GetBidLen() int {
+	bk.Rw.RLock()
+	defer bk.Rw.RUnlock()
+	return bk.bids.len()
+}
+
+func (bk *Booker) GetAskMap() map[string]float64 {
+	bk.Rw.RLock()
+	defer bk.Rw.RUnlock()
+	return bk.sideMap(bk.asks)
+}
+
+func (bk *Booker) GetBidMap() map[string]float64 {
+	bk.Rw.RLock()
+	defer bk.Rw.RUnlock()
+	return bk.sideMap(bk.bids)
+}
+
+func (bk *Booker) sideMap(sd *side) map[string]float64 {
+	m := make(map[string]float64, sd.len())
+	sd.each(func(t exch.Fixed, s float64) bool {
+		m[bk.scale.String(t)] = s
+		return true
+	})
+	return m
+}
+
+// asks, bids, askBook, bidBook
+func (bk *Booker) GetBook() ([]float64, []float64, map[float64]float64, map[float64]float64) {
+	bk.Rw.RLock()
+	defer bk.Rw.RUnlock()
+	asks, askBook := bk.sideBook(bk.asks)
+	bids, bidBook := bk.sideBook(bk.bids)
+	return asks, bids, askBook, bidBook
+}
+
+func (bk *Booker) sideBook(sd *side) ([]float64, map[float64]float64) {
+	prices, book := make([]float64, 0, sd.len()), make(map[float64]float64, sd.len())
+	sd.each(func(t exch.Fixed, s float64) bool {
+		p := bk.scale.Float64(t)
+		prices = append(prices, p)
+		book[p] = s
+		return true
+	})
+	return prices, book
+}
+
+func (bk *Booker) BestAsk() (exch.BookLevel, bool) {
+	bk.Rw.RLock()
+	defer bk.Rw.RUnlock()
+	return bk.bestLevel(bk.asks)
+}
+
+func (bk *Booker) BestBid() (exch.BookLevel, bool) {
+	bk.Rw.RLock()
+	defer bk.Rw.RUnlock()
+	return bk.bestLevel(bk.bids)
+}
+
+func (bk *Booker) bestLevel(sd *side) (exch.BookLevel, bool) {
+	if !sd.has {
+		return exch.BookLevel{}, false
+	}
+	s, _ := sd.get(sd.best)
+	return exch.BookLevel{Price: bk.scale.Float64(sd.best), Size: s}, true
+}
+
+// TopN 把前 len(asks) 档卖盘及前 len(bids) 档买盘写入调用方缓冲区, 返回写入的档数.
+// 窗口内的档位不分配内存.
+func (bk *Booker) TopN(asks, bids []exch.BookLevel) (int, int) {
+	bk.Rw.RLock()
+	defer bk.Rw.RUnlock()
+	return bk.top(bk.asks, asks), bk.top(bk.bids, bids)
+}
+
+func (bk *Booker) top(sd *side, buf []exch.BookLevel) int {
+	n := 0
+	if len(buf) == 0 {
+		return 0
+	}
+	sd.each(func(t exch.Fixed, s float64) bool {
+		buf[n] = exch.BookLevel{Price: bk.scale.Float64(t), Size: s}
+		n++
+		return n < len(buf)
+	})
+	return n
+}
+
+func (bk *Booker) View(fn func(asks, bids exch.Levels)) {
+	bk.Rw.RLock()
+	defer bk.Rw.RUnlock()
+	fn(bk.sideLevels(bk.asks), bk.sideLevels(bk.bids))
+}
+
+func (bk *Booker) sideLevels(sd *side) exch.Levels {
+	return func(fn func(price, size float64) bool) {
+		sd.each(func(t exch.Fixed, s float64) bool {
+			return fn(bk.scale.Float64(t), s)
+		})
+	}
+}
+
+func init() {
+	exch.RegisterBooker(BookerKey, func(ctx context.Context) exch.Booker { return NewBooker(ctx) })
+}
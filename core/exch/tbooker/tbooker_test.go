@@ -0,0 +1,85 @@
+package tbooker
+
+import (
+	"context"
+	"testing"
+
+	"high-freq-quant-go/core/exch"
+	"high-freq-quant-go/core/exch/booktest"
+)
+
+func TestRecenter(t *testing.T) {
+	ticks := DefaultTicks
+	DefaultTicks = 64
+	defer func() { DefaultTicks = ticks }()
+
+	ctx := exch.WithSymbol(context.Background(), "BTC_USDT")
+	bk, ref := NewBooker(ctx), exch.NewMapBooker(ctx)
+	bk.SetScale(exch.NewScale(0.01))
+	ref.SetScale(exch.NewScale(0.01))
+	for i, du := range booktest.GenDepth(5000, 3) {
+		booktest.Apply(bk, du, int64(i+1))
+		booktest.Apply(ref, du, int64(i+1))
+	}
+	asks, bids, askBook, bidBook := bk.GetBook()
+	wasks, wbids, waskBook, wbidBook := ref.GetBook()
+	if len(asks) != len(wasks) || len(bids) != len(wbids) {
+		t.Fatalf("got %d %d levels, want %d %d", len(asks), len(bids), len(wasks), len(wbids))
+	}
+	for i := range asks {
+		if asks[i] != wasks[i] || askBook[asks[i]] != waskBook[wasks[i]] {
+			t.Fatalf("ask %d got %v want %v", i, asks[i], wasks[i])
+		}
+	}
+	for i := range bids {
+		if bids[i] != wbids[i] || bidBook[bids[i]] != wbidBook[wbids[i]] {
+			t.Fatalf("bid %d got %v want %v", i, bids[i], wbids[i])
+		}
+	}
+	if ba, ok := bk.BestAsk(); !ok || ba.Price != wasks[0] || ba.Size != waskBook[wasks[0]] {
+		t.Fatalf("best ask got %+v want %v", ba, wasks[0])
+	}
+	if bb, ok := bk.BestBid(); !ok || bb.Price != wbids[0] {
+		t.Fatalf("best bid got %+v want %v", bb, wbids[0])
+	}
+}
+
+func TestTopN(t *testing.T) {
+	bk := NewBooker(context.Background())
+	bk.SetScale(exch.NewScale(0.5))
+	bk.SetBook(map[string]float64{"101": 1, "100.5": 2, "102": 3}, map[string]float64{"100": 4, "99": 5})
+	asks, bids := make([]exch.BookLevel, 2), make([]exch.BookLevel, 5)
+	na, nb := bk.TopN(asks, bids)
+	if na != 2 || nb != 2 {
+		t.Fatalf("got %d %d levels", na, nb)
+	}
+	if asks[0] != (exch.BookLevel{Price: 100.5, Size: 2}) || asks[1].Price != 101 || bids[0].Price != 100 || bids[1].Price != 99 {
+		t.Fatalf("got asks %v bids %v", asks, bids[:nb])
+	}
+	if n := testing.AllocsPerRun(100, func() { bk.TopN(asks, bids) }); n != 0 {
+		t.Fatalf("TopN allocs %v", n)
+	}
+
+	bk.Rw.Lock()
+	bk.UpdateAsk("100.5", 0)
+	bk.UpdateBid("100", 0)
+	bk.Rw.Unlock()
+	if ba, _ := bk.BestAsk(); ba.Price != 101 {
+		t.Fatalf("best ask got %v", ba)
+	}
+	if bb, _ := bk.BestBid(); bb.Price != 99 {
+		t.Fatalf("best bid got %v", bb)
+	}
+}
+
+func BenchmarkTopN(b *testing.B) {
+	bk := NewBooker(context.Background())
+	bk.SetScale(exch.NewScale(0.01))
+	updates := booktest.GenDepth(1, 1)
+	booktest.Apply(bk, updates[0], 1)
+	asks, bids := make([]exch.BookLevel, 5), make([]exch.BookLevel, 5)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		bk.TopN(asks, bids)
+	}
+}
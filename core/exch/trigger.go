@@ -0,0 +1,67 @@
+package exch
+
+import "sync"
+
+// TriggerBook 按交易对维护未触发的条件单, 与普通委托单分开保存,
+// 触发、撤销或过期后删除, 触发后生成的委托单按普通订单推送
+type TriggerBook struct {
+	lk   sync.RWMutex
+	data map[string]map[string]*Order //交易对 条件单Id
+}
+
+func NewTriggerBook() *TriggerBook {
+	return &TriggerBook{data: map[string]map[string]*Order{}}
+}
+
+// Get 交易对当前的条件单, 返回副本
+func (b *TriggerBook) Get(symbol string) map[string]*Order {
+	b.lk.RLock()
+	defer b.lk.RUnlock()
+	res := make(map[string]*Order, len(b.data[symbol]))
+	for id, o := range b.data[symbol] {
+		res[id] = o
+	}
+	return res
+}
+
+// Reset 按 REST 查询结果替换交易对的条件单
+func (b *TriggerBook) Reset(symbol string, orders map[string]*Order) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	m := make(map[string]*Order, len(orders))
+	for id, o := range orders {
+		m[id] = o
+	}
+	b.data[symbol] = m
+}
+
+// Update 条件单状态变化, 结束时删除
+func (b *TriggerBook) Update(o *Order) {
+	if o == nil || o.Id == "" {
+		return
+	}
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	if o.Status == OrderFinished {
+		delete(b.data[o.Symbol], o.Id)
+		return
+	}
+	if _, ok := b.data[o.Symbol]; !ok {
+		b.data[o.Symbol] = map[string]*Order{}
+	}
+	b.data[o.Symbol][o.Id] = o
+}
+
+// Remove 删除条件单, 用于触发后转为普通委托单
+func (b *TriggerBook) Remove(symbol, id string) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	delete(b.data[symbol], id)
+}
+
+// Delete 取消订阅时删除交易对
+func (b *TriggerBook) Delete(symbol string) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	delete(b.data, symbol)
+}
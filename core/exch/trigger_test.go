@@ -0,0 +1,36 @@
+package exch
+
+import "testing"
+
+func TestTriggerOrder(t *testing.T) {
+	// 多单止损向下触发, 空单止盈向下触发
+	cases := []struct {
+		o      Order
+		rising bool
+	}{
+		{Order{Size: 1, Ordertype: OrderStop}, true},
+		{Order{Size: -1, Ordertype: OrderStop}, false},
+		{Order{Size: 1, Ordertype: OrderTakeProfit}, false},
+		{Order{Size: -1, Ordertype: OrderTakeProfit}, true},
+	}
+	for _, c := range cases {
+		if c.o.TriggerRising() != c.rising {
+			t.Fatalf("%+v rising want %v", c.o, c.rising)
+		}
+	}
+	if err := (&OrderRequest{Symbol: "BTC_USDT", Size: 1, Ordertype: OrderStop}).Validate(); err != ErrInvalidOrder {
+		t.Fatalf("stop without trigger price got %v", err)
+	}
+
+	b := NewTriggerBook()
+	b.Update(&Order{Id: "1", Symbol: "BTC_USDT", Status: OrderOpen, TriggerPrice: 90})
+	b.Update(&Order{Id: "2", Symbol: "BTC_USDT", Status: OrderOpen, TriggerPrice: 110})
+	b.Update(&Order{Id: "1", Symbol: "BTC_USDT", Status: OrderFinished})
+	if got := b.Get("BTC_USDT"); len(got) != 1 || got["2"] == nil {
+		t.Fatalf("trigger book %+v", got)
+	}
+	b.Reset("BTC_USDT", nil)
+	if len(b.Get("BTC_USDT")) != 0 {
+		t.Fatal("reset not cleared")
+	}
+}
@@ -43,10 +43,29 @@ type Position struct {
 	UnPnl          float64 //未实现盈亏
 	LiqPrice       float64 //爆仓价
 	MarkPrice      float64 //标记价
-	Lv             float64 //杠杠
+	Lv             float64 //杠杠, 全仓时为全仓杠杆上限
 	MarginType     string  //保证金类型 逐仓、全仓
-	PositionMode   string  //持仓模式 单仓、多仓
+	RiskLimit      float64 //风险限额
+	PositionMode   string  //持仓方向 单向持仓为 BOTH, 双向持仓为 LONG,SHORT
 	OpenMargin     float64 //开仓保证金
 	Value          float64 //仓位价值
 	LastUpdateTime int64   //最后更新时间
 }
+
+// Trade 公开逐笔成交, 币安为归集成交
+type Trade struct {
+	Symbol string  //交易对
+	Id     string  //成交Id, 币安为归集成交Id
+	Price  float64 //成交价格
+	Size   float64 //成交数量, 主动买为正 主动卖为负, 合约按张数换算后的数量
+	Side   string  //主动成交方向 buy,sell
+	Time   int64   //交易所成交时间, 毫秒
+}
+
+// TakerSide 按成交数量符号返回主动成交方向
+func TakerSide(size float64) string {
+	if size < 0 {
+		return SideSell
+	}
+	return SideBuy
+}
@@ -0,0 +1,298 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"high-freq-quant-go/core/exch"
+	"high-freq-quant-go/core/log"
+)
+
+// 请求类型, 各交易所按类型分别限制
+const (
+	ClassOrder  = "order"  //下单及改单
+	ClassCancel = "cancel" //撤单
+	ClassQuery  = "query"  //查询及其他
+)
+
+// Limit 令牌桶, 每 Per 时间最多 N 个权重, 同名的桶在账号内各类型共用
+type Limit struct {
+	Name string
+	N    float64
+	Per  time.Duration
+}
+
+// Rules 交易所限制规则
+type Rules struct {
+	Classes  map[string][]Limit                                     //请求类型对应的桶
+	Classify func(req *http.Request) (class string, weight float64) //请求类型及权重
+	Observe  func(l *Limiter, class string, resp *http.Response)    //按返回头校正剩余额度
+}
+
+// LimitConfig 额度不足时的处理, 通过 WithRateLimit 设置
+type LimitConfig struct {
+	Reject  bool          //直接返回 LimitError, 否则排队等待
+	MaxWait time.Duration //排队最长等待, 超过时返回 LimitError
+}
+
+var DefaultRateLimit = LimitConfig{
+	MaxWait: 3 * time.Second,
+}
+
+func WithRateLimit(ctx context.Context, cfg LimitConfig) context.Context {
+	return context.WithValue(ctx, exch.CtxRateLimit, cfg)
+}
+
+func GetRateLimit(ctx context.Context) LimitConfig {
+	if ret, ok := ctx.Value(exch.CtxRateLimit).(LimitConfig); ok {
+		return ret
+	}
+	return DefaultRateLimit
+}
+
+// LimitError 额度不足, errors.Is(err, exch.ErrRateLimit) 为 true
+type LimitError struct {
+	Sign  string
+	Class string
+	Wait  time.Duration //额度恢复还需等待的时间
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s %s %s, retry after %v", exch.ErrRateLimit, e.Sign, e.Class, e.Wait)
+}
+
+func (e *LimitError) Unwrap() error {
+	return exch.ErrRateLimit
+}
+
+type bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+	pause  time.Time //触发交易所限频后暂停到该时间
+}
+
+func (b *bucket) rate() float64 {
+	return b.limit.N / b.limit.Per.Seconds()
+}
+
+func (b *bucket) fill(now time.Time) {
+	b.tokens = math.Min(b.limit.N, b.tokens+now.Sub(b.last).Seconds()*b.rate())
+	b.last = now
+}
+
+// need 取 n 个权重还需等待的时间
+func (b *bucket) need(n float64, now time.Time) time.Duration {
+	b.fill(now)
+	var wait time.Duration
+	if n = math.Min(n, b.limit.N); b.tokens < n {
+		wait = time.Duration((n - b.tokens) / b.rate() * float64(time.Second))
+	}
+	if b.pause.Sub(now) > wait {
+		wait = b.pause.Sub(now)
+	}
+	return wait
+}
+
+// Limiter 单个账号的请求限制, 各类型请求按令牌桶扣减权重, 按交易所返回头校正
+type Limiter struct {
+	Sign string
+
+	cfg     LimitConfig
+	rules   Rules
+	buckets map[string]*bucket
+	classes map[string][]*bucket
+	lk      sync.Mutex
+
+	waited, rejected map[string]int64
+}
+
+func NewLimiter(sign string, rules Rules, cfg LimitConfig) *Limiter {
+	l := &Limiter{
+		Sign:     sign,
+		cfg:      cfg,
+		rules:    rules,
+		buckets:  map[string]*bucket{},
+		classes:  map[string][]*bucket{},
+		waited:   map[string]int64{},
+		rejected: map[string]int64{},
+	}
+	now := time.Now()
+	for class, limits := range rules.Classes {
+		for _, lt := range limits {
+			b, ok := l.buckets[lt.Name]
+			if !ok {
+				b = &bucket{limit: lt, tokens: lt.N, last: now}
+				l.buckets[lt.Name] = b
+			}
+			l.classes[class] = append(l.classes[class], b)
+		}
+	}
+	return l
+}
+
+// Acquire 扣减 class 的权重, 额度不足时按配置排队或返回 LimitError
+func (l *Limiter) Acquire(ctx context.Context, class string, weight float64) error {
+	if l == nil {
+		return nil
+	}
+	deadline := time.Now().Add(l.cfg.MaxWait)
+	counted := false
+	for {
+		l.lk.Lock()
+		now := time.Now()
+		var wait time.Duration
+		for _, b := range l.classes[class] {
+			if w := b.need(weight, now); w > wait {
+				wait = w
+			}
+		}
+		if wait == 0 {
+			for _, b := range l.classes[class] {
+				b.tokens -= math.Min(weight, b.limit.N)
+			}
+			l.lk.Unlock()
+			return nil
+		}
+		if l.cfg.Reject || now.Add(wait).After(deadline) {
+			l.rejected[class]++
+			l.lk.Unlock()
+			log.Warnln(log.Http, l.Sign, class, "rate limit reject, wait", wait)
+			return &LimitError{Sign: l.Sign, Class: class, Wait: wait}
+		}
+		if !counted {
+			l.waited[class]++
+			counted = true
+		}
+		l.lk.Unlock()
+		tm := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			tm.Stop()
+			return ctx.Err()
+		case <-tm.C:
+		}
+	}
+}
+
+// SyncRemain 交易所返回的剩余额度小于本地时按交易所校正
+func (l *Limiter) SyncRemain(name string, remain float64) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+	if b, ok := l.buckets[name]; ok {
+		b.fill(time.Now())
+		if remain < b.tokens {
+			b.tokens = math.Max(remain, 0)
+		}
+	}
+}
+
+// SyncUsed 交易所返回的已用额度, 见 binance X-MBX-USED-WEIGHT-*
+func (l *Limiter) SyncUsed(name string, used float64) {
+	l.lk.Lock()
+	b, ok := l.buckets[name]
+	l.lk.Unlock()
+	if ok {
+		l.SyncRemain(name, b.limit.N-used)
+	}
+}
+
+// Pause class 的所有桶暂停到 until, 用于 429 及 418
+func (l *Limiter) Pause(class string, until time.Time) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+	for _, b := range l.classes[class] {
+		if until.After(b.pause) {
+			b.pause = until
+		}
+	}
+}
+
+func (l *Limiter) observe(class string, resp *http.Response) {
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == 418 {
+		wait := time.Second
+		if sec, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && sec > 0 {
+			wait = time.Duration(sec) * time.Second
+		}
+		log.Errorln(log.Http, l.Sign, class, "rate limited by exchange", resp.StatusCode, "pause", wait)
+		l.Pause(class, time.Now().Add(wait))
+	}
+	if l.rules.Observe != nil {
+		l.rules.Observe(l, class, resp)
+	}
+}
+
+// Stats 各类型排队及拒绝次数
+func (l *Limiter) Stats() (waited, rejected map[string]int64) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+	waited, rejected = map[string]int64{}, map[string]int64{}
+	for k, n := range l.waited {
+		waited[k] = n
+	}
+	for k, n := range l.rejected {
+		rejected[k] = n
+	}
+	return waited, rejected
+}
+
+type transport struct {
+	l    *Limiter
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	class, weight := ClassQuery, 1.0
+	if t.l.rules.Classify != nil {
+		class, weight = t.l.rules.Classify(req)
+	}
+	if err := t.l.Acquire(req.Context(), class, weight); err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		t.l.observe(class, resp)
+	}
+	return resp, err
+}
+
+// Client 发送前按限制扣减额度的 http.Client
+func (l *Limiter) Client() *http.Client {
+	return &http.Client{Transport: &transport{l: l, base: http.DefaultTransport}}
+}
+
+// UsedHeaders 按返回头中的已用额度校正, headers 为返回头对应的桶
+func UsedHeaders(headers map[string]string) func(l *Limiter, class string, resp *http.Response) {
+	return func(l *Limiter, class string, resp *http.Response) {
+		for h, name := range headers {
+			if v := resp.Header.Get(h); v != "" {
+				if used, err := strconv.ParseFloat(v, 64); err == nil {
+					l.SyncUsed(name, used)
+				}
+			}
+		}
+	}
+}
+
+// RemainHeader 按返回头中的剩余次数校正请求类型的第一个桶
+func RemainHeader(header string) func(l *Limiter, class string, resp *http.Response) {
+	return func(l *Limiter, class string, resp *http.Response) {
+		v := resp.Header.Get(header)
+		if v == "" {
+			return
+		}
+		remain, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return
+		}
+		if limits := l.rules.Classes[class]; len(limits) != 0 {
+			l.SyncRemain(limits[0].Name, remain)
+		}
+	}
+}
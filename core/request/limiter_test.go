@@ -0,0 +1,88 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"high-freq-quant-go/core/exch"
+)
+
+func testRules() Rules {
+	return Rules{
+		Classes: map[string][]Limit{
+			ClassOrder: {{Name: ClassOrder, N: 2, Per: 100 * time.Millisecond}},
+			ClassQuery: {{Name: "weight", N: 10, Per: time.Minute}},
+		},
+		Classify: func(req *http.Request) (string, float64) {
+			if req.Method == http.MethodPost {
+				return ClassOrder, 1
+			}
+			return ClassQuery, 4
+		},
+		Observe: UsedHeaders(map[string]string{"X-MBX-USED-WEIGHT-1M": "weight"}),
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := NewLimiter("test", testRules(), LimitConfig{Reject: true})
+	for i := 0; i < 2; i++ {
+		if err := l.Acquire(ctx, ClassOrder, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := l.Acquire(ctx, ClassOrder, 1)
+	var le *LimitError
+	if !errors.Is(err, exch.ErrRateLimit) || !errors.As(err, &le) || le.Wait <= 0 {
+		t.Fatalf("reject got %v", err)
+	}
+	// 排队等待令牌恢复
+	l.cfg = LimitConfig{MaxWait: time.Second}
+	start := time.Now()
+	if err := l.Acquire(ctx, ClassOrder, 1); err != nil || time.Since(start) < 30*time.Millisecond {
+		t.Fatalf("wait got %v %v", err, time.Since(start))
+	}
+	if waited, rejected := l.Stats(); waited[ClassOrder] != 1 || rejected[ClassOrder] != 1 {
+		t.Fatalf("stats got %v %v", waited, rejected)
+	}
+}
+
+func TestLimiterTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-MBX-USED-WEIGHT-1M", "8")
+		if r.URL.Path == "/busy" {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+	l := NewLimiter("test", testRules(), LimitConfig{Reject: true})
+	cl := l.Client()
+	resp, err := cl.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	// 交易所已用 8, 剩余 2 不够 4
+	if _, err = cl.Get(srv.URL); !errors.Is(err, exch.ErrRateLimit) {
+		t.Fatalf("synced weight got %v", err)
+	}
+
+	// 429 时按 Retry-After 暂停
+	l = NewLimiter("test", testRules(), LimitConfig{Reject: true})
+	cl = l.Client()
+	resp, err = cl.Post(srv.URL+"/busy", "", nil)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("busy got %v", err)
+	}
+	resp.Body.Close()
+	err = l.Acquire(context.Background(), ClassOrder, 1)
+	var le *LimitError
+	if !errors.As(err, &le) || le.Wait < 50*time.Second {
+		t.Fatalf("pause got %v", err)
+	}
+}
@@ -136,9 +136,12 @@ func (s *CreateOrderService) createOrder(ctx context.Context, endpoint string, o
 		"symbol":           s.symbol,
 		"side":             s.side,
 		"type":             s.orderType,
-		"quantity":         s.quantity,
 		"newOrderRespType": s.newOrderRespType,
 	}
+	// closePosition 时不能传 quantity
+	if s.quantity != "" {
+		m["quantity"] = s.quantity
+	}
 	if s.positionSide != nil {
 		m["positionSide"] = *s.positionSide
 	}
@@ -487,6 +490,84 @@ type CancelOrderResponse struct {
 	PriceProtect     bool             `json:"priceProtect"`
 }
 
+// ModifyOrderService modify a limit order, only quantity and price can be changed
+type ModifyOrderService struct {
+	c                 *Client
+	symbol            string
+	orderID           *int64
+	origClientOrderID *string
+	side              SideType
+	quantity          string
+	price             string
+}
+
+// Symbol set symbol
+func (s *ModifyOrderService) Symbol(symbol string) *ModifyOrderService {
+	s.symbol = symbol
+	return s
+}
+
+// OrderID set orderID
+func (s *ModifyOrderService) OrderID(orderID int64) *ModifyOrderService {
+	s.orderID = &orderID
+	return s
+}
+
+// OrigClientOrderID set origClientOrderID
+func (s *ModifyOrderService) OrigClientOrderID(origClientOrderID string) *ModifyOrderService {
+	s.origClientOrderID = &origClientOrderID
+	return s
+}
+
+// Side set side
+func (s *ModifyOrderService) Side(side SideType) *ModifyOrderService {
+	s.side = side
+	return s
+}
+
+// Quantity set quantity
+func (s *ModifyOrderService) Quantity(quantity string) *ModifyOrderService {
+	s.quantity = quantity
+	return s
+}
+
+// Price set price
+func (s *ModifyOrderService) Price(price string) *ModifyOrderService {
+	s.price = price
+	return s
+}
+
+// Do send request
+func (s *ModifyOrderService) Do(ctx context.Context, opts ...RequestOption) (res *Order, err error) {
+	r := &request{
+		method:   http.MethodPut,
+		endpoint: "/fapi/v1/order",
+		secType:  secTypeSigned,
+	}
+	r.setFormParams(params{
+		"symbol":   s.symbol,
+		"side":     s.side,
+		"quantity": s.quantity,
+		"price":    s.price,
+	})
+	if s.orderID != nil {
+		r.setFormParam("orderId", *s.orderID)
+	}
+	if s.origClientOrderID != nil {
+		r.setFormParam("origClientOrderId", *s.origClientOrderID)
+	}
+	data, _, err := s.c.callAPI(ctx, r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res = new(Order)
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 // CancelAllOpenOrdersService cancel all open orders
 type CancelAllOpenOrdersService struct {
 	c      *Client
@@ -436,6 +436,49 @@ func (s *orderServiceTestSuite) TestCancelOrder() {
 	s.assertCancelOrderResponseEqual(e, res)
 }
 
+func (s *orderServiceTestSuite) TestModifyOrder() {
+	data := []byte(`{
+		"clientOrderId": "myOrder1",
+		"cumQty": "0",
+		"cumQuote": "0",
+		"executedQty": "0",
+		"orderId": 283194212,
+		"origQty": "12",
+		"price": "8302",
+		"reduceOnly": false,
+		"side": "BUY",
+		"status": "NEW",
+		"symbol": "BTCUSDT",
+		"timeInForce": "GTC",
+		"type": "LIMIT",
+		"updateTime": 1571110484038
+	}`)
+	s.mockDo(data, nil)
+	defer s.assertDo()
+
+	symbol := "BTCUSDT"
+	orderID := int64(283194212)
+	s.assertReq(func(r *request) {
+		e := newSignedRequest().setFormParams(params{
+			"symbol":   symbol,
+			"orderId":  orderID,
+			"side":     SideTypeBuy,
+			"quantity": "12",
+			"price":    "8302",
+		})
+		s.assertRequestEqual(e, r)
+	})
+
+	res, err := s.client.NewModifyOrderService().Symbol(symbol).OrderID(orderID).
+		Side(SideTypeBuy).Quantity("12").Price("8302").Do(newContext())
+	r := s.r()
+	r.NoError(err)
+	r.Equal(orderID, res.OrderID)
+	r.Equal("12", res.OrigQuantity)
+	r.Equal("8302", res.Price)
+	r.Equal(OrderStatusTypeNew, res.Status)
+}
+
 func (s *orderServiceTestSuite) assertCancelOrderResponseEqual(e, a *CancelOrderResponse) {
 	r := s.r()
 	r.Equal(e.ClientOrderID, a.ClientOrderID, "ClientOrderID")
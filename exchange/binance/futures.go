@@ -4,6 +4,7 @@ import (
 	"context"
 	"sync"
 
+	"high-freq-quant-go/adapter/queue"
 	"high-freq-quant-go/adapter/text"
 	"high-freq-quant-go/core/exch"
 	"high-freq-quant-go/exchange/binance/futures_api"
@@ -18,6 +19,7 @@ type Futures struct {
 	Api     *futures_api.BinaceFuturesApi
 	PubWss  *futures_wss.Futures
 	PriWss  *futures_wss.UserWss
+	exch.Legacy
 
 	Exchange, Extype string
 
@@ -33,6 +35,7 @@ func NewClient(ctx context.Context) exch.Exchange {
 		Extype:   exch.Futures,
 	}
 	ft.Api = futures_api.NewBinanceApi(ctx)
+	ft.Legacy = exch.NewLegacy(ft)
 	return ft
 }
 
@@ -76,6 +79,17 @@ func (mk *Futures) GetStatus() int {
 	return 1
 }
 
+func (mk *Futures) QueueStats() []queue.Stat {
+	var stats []queue.Stat
+	if mk.PubWss != nil {
+		stats = append(stats, mk.PubWss.QueueStats()...)
+	}
+	if mk.PriWss != nil {
+		stats = append(stats, mk.PriWss.QueueStats()...)
+	}
+	return stats
+}
+
 func (mk *Futures) GetBaseInfo(ctx context.Context) *exch.BaseInfo {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
 	http := text.GetBool(ctx, exch.CtxHttp)
@@ -127,7 +141,18 @@ func (mk *Futures) GetOrder(ctx context.Context) map[string]*exch.Order {
 	return res
 }
 
-func (mk *Futures) GetOrderBook(ctx context.Context) *exch.Booker {
+func (mk *Futures) GetTriggerOrder(ctx context.Context) map[string]*exch.Order {
+	if mk.PriWss != nil {
+		return mk.PriWss.GetTriggerOrder(ctx)
+	}
+	res, err := mk.Api.ListTriggerOrder(ctx)
+	if err != nil {
+		return nil
+	}
+	return res
+}
+
+func (mk *Futures) GetOrderBook(ctx context.Context) exch.Booker {
 	book := mk.PubWss.GetBook(ctx)
 	return book
 }
@@ -148,87 +173,239 @@ func (mk *Futures) ListAsset(ctx context.Context) (*exch.Balance, map[string]*ex
 	return bls, pos
 }
 
-func (mk *Futures) CreateOrder(ctx context.Context) (*exch.Order, error) {
-	res, err := mk.Api.CreateOrder(ctx)
-	return res, err
+func (mk *Futures) PlaceOrder(ctx context.Context, req *exch.OrderRequest) (*exch.Order, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	o, err := mk.Api.CreateOrder(ctx, req.Order())
+	if err == nil && req.TriggerPrice > 0 && mk.PriWss != nil {
+		mk.PriWss.TriggerData.Update(o)
+	}
+	return o, err
 }
 
-func (mk *Futures) CreateBatchOrder(ctx context.Context) ([]*exch.Order, error) {
-	res, err := mk.Api.CreateBatchOrder(ctx)
-	return res, err
+func (mk *Futures) PlaceBatchOrder(ctx context.Context, reqs []*exch.OrderRequest) ([]*exch.Order, error) {
+	if len(reqs) == 0 {
+		return nil, exch.ErrEmptyOrder
+	}
+	lists := make([]*exch.Order, 0, len(reqs))
+	for _, req := range reqs {
+		if err := req.Validate(); err != nil {
+			return nil, err
+		}
+		//条件单及平仓单不支持批量
+		if req.TriggerPrice > 0 || req.ClosePosition {
+			return nil, exch.ErrNotSupported
+		}
+		lists = append(lists, req.Order())
+	}
+	return mk.Api.CreateBatchOrder(ctx, lists)
 }
 
-func (mk *Futures) CannelOrder(ctx context.Context) (*exch.Order, error) {
-	res, err := mk.Api.CannelOrder(ctx)
-	return res, err
+func (mk *Futures) CancelOrder(ctx context.Context, req *exch.CancelRequest) (*exch.Order, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	//条件单与普通委托单使用相同的撤单接口
+	o, err := mk.Api.CannelOrder(ctx, req.Order())
+	if err == nil && req.Trigger && mk.PriWss != nil {
+		mk.PriWss.TriggerData.Remove(req.Symbol, req.Id)
+	}
+	return o, err
 }
 
-func (mk *Futures) CannelAllOrder(ctx context.Context) ([]*exch.Order, error) {
-	res, err := mk.Api.CannelAllOrder(ctx)
-	return res, err
+// CancelAllOrder 同时撤销交易对的条件单
+func (mk *Futures) CancelAllOrder(ctx context.Context, symbol string) ([]*exch.Order, error) {
+	if symbol == "" {
+		return nil, exch.ErrEmptySymbol
+	}
+	orders, err := mk.Api.CannelAllOrder(ctx, symbol)
+	if err == nil && mk.PriWss != nil {
+		mk.PriWss.TriggerData.Reset(symbol, nil)
+	}
+	return orders, err
 }
 
-func (mk *Futures) UpdateLeverage(ctx context.Context) (*exch.Position, error) {
-	//err := ft.Futures.UpdatePositionMode(ctx)
-	//if err != nil {
-	//	return nil, err
-	//}
-	_, err := mk.Api.UpdateMarginType(ctx)
-	if err != nil {
+// ListOpenOrders REST 查询账号所有交易对的挂单, 紧急停止以此确认撤单
+func (mk *Futures) ListOpenOrders(ctx context.Context) ([]*exch.Order, error) {
+	return mk.Api.ListOpenOrders(ctx)
+}
+
+func (mk *Futures) AmendOrder(ctx context.Context, req *exch.AmendRequest) (*exch.AmendResult, error) {
+	if err := req.Validate(); err != nil {
 		return nil, err
 	}
-	res, err := mk.Api.UpdateLeverage(ctx)
+	o, err := mk.Api.AmendOrder(ctx, req.Order())
 	if err != nil {
-		return res, err
+		return nil, err
+	}
+	return &exch.AmendResult{Order: o, Path: exch.AmendNative}, nil
+}
+
+func (mk *Futures) SetLeverage(ctx context.Context, symbol string, lv float64) (*exch.Position, error) {
+	if symbol == "" {
+		return nil, exch.ErrEmptySymbol
+	}
+	return mk.Api.UpdateLeverage(ctx, symbol, lv)
+}
+
+func (mk *Futures) SetMargin(ctx context.Context, symbol string, change float64) (*exch.Position, error) {
+	if symbol == "" {
+		return nil, exch.ErrEmptySymbol
+	}
+	return mk.Api.UpdateMargin(ctx, symbol, change)
+}
+
+func (mk *Futures) SetMarginMode(ctx context.Context, symbol, mode string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
 	}
-	return res, err
+	return mk.Api.UpdateMarginType(ctx, symbol, mode)
+}
+
+func (mk *Futures) SetPositionMode(ctx context.Context, hedge bool) error {
+	return mk.Api.UpdatePositionMode(ctx, hedge)
 }
 
-func (mk *Futures) UpdateMargin(ctx context.Context) (*exch.Position, error) {
-	res, err := mk.Api.UpdateMargin(ctx)
-	return res, err
+// SetRiskLimit 合约风险限额按杠杆分层, 不能单独设置
+func (mk *Futures) SetRiskLimit(ctx context.Context, symbol string, limit float64) error {
+	return exch.ErrNotSupported
 }
 
-func (mk *Futures) SubTicker(ctx context.Context) error {
+func (mk *Futures) FetchPosition(ctx context.Context, symbol string) (*exch.Position, error) {
+	if symbol == "" {
+		return nil, exch.ErrEmptySymbol
+	}
+	return mk.Api.GetPosition(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Futures) SubscribeTicker(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	err := mk.StartWss()
 	if err != nil {
 		return err
 	}
-	mk.PubWss.SubTicker(ctx)
+	mk.PubWss.SubTicker(exch.WithSymbol(ctx, symbol))
 	return nil
 }
-func (mk *Futures) SubOrderBook(ctx context.Context) error {
+
+func (mk *Futures) SubscribeOrderBook(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	err := mk.StartWss()
 	if err != nil {
 		return err
 	}
-	mk.PubWss.SubOrderBook(ctx)
+	mk.PubWss.SubOrderBook(exch.WithSymbol(ctx, symbol))
 	return nil
 }
 
+func (mk *Futures) SubscribePublicTrades(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	err := mk.StartWss()
+	if err != nil {
+		return err
+	}
+	return mk.PubWss.SubPublicTrades(exch.WithSymbol(ctx, symbol))
+}
+
 func (mk *Futures) GetTradeChan(ctx context.Context) *chan *exch.Order {
 	mk.StartUser()
 	return mk.PriWss.GetTradeChan(ctx)
 }
 
-func (mk *Futures) SubUserTrade(ctx context.Context) error {
+func (mk *Futures) SubscribeUserTrade(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	mk.StartUser()
-	return mk.PriWss.SubUserTrade(ctx)
+	return mk.PriWss.SubUserTrade(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *Futures) SubPosition(ctx context.Context) error {
+func (mk *Futures) SubscribePosition(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	mk.StartUser()
-	return mk.PriWss.SubPosition(ctx)
+	return mk.PriWss.SubPosition(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *Futures) SubBalance(ctx context.Context) error {
+func (mk *Futures) SubscribeBalance(ctx context.Context, symbol string) error {
 	mk.StartUser()
-	return mk.PriWss.SubBalance(ctx)
+	return mk.PriWss.SubBalance(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *Futures) SubOrder(ctx context.Context) error {
+func (mk *Futures) SubscribeOrder(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	mk.StartUser()
-	return mk.PriWss.SubOrder(ctx)
+	return mk.PriWss.SubOrder(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Futures) UnsubscribeTicker(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.PubWss == nil {
+		return nil
+	}
+	return mk.PubWss.UnsubTicker(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Futures) UnsubscribeOrderBook(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.PubWss == nil {
+		return nil
+	}
+	return mk.PubWss.UnsubOrderBook(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Futures) UnsubscribePublicTrades(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.PubWss == nil {
+		return nil
+	}
+	return mk.PubWss.UnsubPublicTrades(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Futures) UnsubscribeUserTrade(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.PriWss == nil {
+		return nil
+	}
+	return mk.PriWss.UnsubUserTrade(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Futures) UnsubscribePosition(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.PriWss == nil {
+		return nil
+	}
+	return mk.PriWss.UnsubPosition(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Futures) UnsubscribeOrder(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.PriWss == nil {
+		return nil
+	}
+	return mk.PriWss.UnsubOrder(exch.WithSymbol(ctx, symbol))
 }
 
 func init() {
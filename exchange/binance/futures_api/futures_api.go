@@ -21,7 +21,7 @@ type BinaceFuturesApi struct {
 	Ctx context.Context
 	Api *BinaceFuturesRequest
 
-	//持仓模式
+	//交易所当前持仓模式, UpdatePositionMode 查询后更新
 	DualSide bool
 }
 
@@ -29,62 +29,82 @@ func NewBinanceApi(ctx context.Context) *BinaceFuturesApi {
 	gf := &BinaceFuturesApi{
 		Ctx:      ctx,
 		Api:      NewBinaceFuturesRequest(ctx),
-		DualSide: true,
+		DualSide: exch.HedgeMode(ctx),
 	}
 	return gf
 }
 
-func (bf *BinaceFuturesApi) CreateOrder(ctx context.Context) (*exch.Order, error) {
-	o := exch.GetOrder(ctx)
+func (bf *BinaceFuturesApi) CreateOrder(ctx context.Context, o *exch.Order) (*exch.Order, error) {
 	if o == nil {
 		log.Errorln(log.Http, bf.Api.ApiSign, "BinaceFuturesApi CreateOrder get order error ")
-		return nil, nil
+		return nil, exch.ErrEmptyOrder
+	}
+	if o.TriggerPrice > 0 {
+		if _, ok := unify.TriggerWorkingType[triggerBy(o)]; !ok {
+			return nil, exch.ErrNotSupported
+		}
+	} else if o.ClosePosition {
+		//closePosition 只支持市价条件单
+		return nil, exch.ErrNotSupported
 	}
 	client := bf.Api.GetClient()
 	service := bf.CreateOrderService(client, o)
 	if service == nil {
-		return nil, nil
+		return nil, exch.ErrInvalidOrder
 	}
-	res, err := service.Do(context.Background())
+	res, err := service.Do(ctx)
 	if err != nil {
 		log.Errorln(log.Http, bf.Api.ApiSign, "BinaceFuturesApi CreateOrder error ", err)
-		return nil, err
+		return nil, unify.OrderError(err, o.Symbol)
+	}
+	//GTX 会立即成交时交易所直接过期
+	if res.TimeInForce == futures.TimeInForceTypeGTX && res.Status == futures.OrderStatusTypeExpired {
+		return nil, &exch.PostOnlyError{Exname: exch.Binance, Symbol: o.Symbol, Detail: convert.GetString(res.OrderID)}
 	}
 	status := unify.UnifyOrderStatus[res.Status]
-	size := unify.QuantityToFloat(o.Symbol, res.OrigQuantity)
-	lsize := size - unify.QuantityToFloat(o.Symbol, res.ExecutedQuantity)
+	size := unify.QuantityToFloat(exch.Futures, o.Symbol, res.OrigQuantity)
+	lsize := size - unify.QuantityToFloat(exch.Futures, o.Symbol, res.ExecutedQuantity)
 	if res.Side == futures.SideTypeSell {
 		size = -size
 		lsize = -lsize
 	}
-	price := convert.GetFloat64(unify.PriceToStr(o.Symbol, res.Price))
-	fprice := convert.GetFloat64(unify.PriceToStr(o.Symbol, res.AvgPrice))
+	price := convert.GetFloat64(unify.PriceToStr(exch.Futures, o.Symbol, res.Price))
+	fprice := convert.GetFloat64(unify.PriceToStr(exch.Futures, o.Symbol, res.AvgPrice))
 	or := &exch.Order{
 		Id:         convert.GetString(res.OrderID),
 		UUID:       res.ClientOrderID,
 		Symbol:     o.Symbol,
 		Status:     status,
+		State:      unify.UnifyOrderState[res.Status],
 		Size:       size,
 		Price:      price,
 		FillPrice:  fprice,
 		Left:       lsize,
+		Tif:        unify.UnifyOrderType[res.TimeInForce],
 		CreateTime: o.UpdateTime,
 		UpdateTime: o.UpdateTime,
+
+		ReduceOnly: res.ReduceOnly,
+		PostOnly:   res.TimeInForce == futures.TimeInForceTypeGTX,
+	}
+	if exch.HedgeMode(bf.Ctx) {
+		or.PositionSide = unify.PosMap[res.PositionSide]
 	}
+	unify.SetTrigger(or, res.Type, res.StopPrice, res.WorkingType, res.ClosePosition)
 	log.Infoln(log.Http, bf.Api.ApiSign, or.Symbol, "BinaceFuturesApi CreateOrder success:p,s ", or.Price, or.Size)
 	return or, nil
 }
 
-func (bf *BinaceFuturesApi) CreateBatchOrder(ctx context.Context) ([]*exch.Order, error) {
-	lists := exch.GetOrders(ctx)
-	if lists == nil || len(lists) == 0 {
+func (bf *BinaceFuturesApi) CreateBatchOrder(ctx context.Context, lists []*exch.Order) ([]*exch.Order, error) {
+	if len(lists) == 0 {
 		log.Errorln(log.Http, bf.Api.ApiSign, "BinaceFuturesApi  CreateBatchOrder GetOrders error ", lists)
-		return nil, nil
+		return nil, exch.ErrEmptyOrder
 	}
 	l := len(lists) - 1
 	orders := []*exch.Order{}
 	tos, os := []*exch.Order{}, []*exch.Order{}
 	var wg sync.WaitGroup
+	var mu sync.Mutex
 	for i, o := range lists {
 		os = append(os, o)
 		if (i+1)%MaxBatchOrderNum == 0 || i == l {
@@ -95,13 +115,14 @@ func (bf *BinaceFuturesApi) CreateBatchOrder(ctx context.Context) ([]*exch.Order
 			wg.Add(1)
 			go func(wg *sync.WaitGroup, tos []*exch.Order) {
 				defer wg.Done()
-				octx := context.WithValue(context.Background(), exch.CtxOrders, tos)
-				order, err := bf.CreateLimitBatchOrder(octx)
+				order, err := bf.CreateLimitBatchOrder(ctx, tos)
 				if err != nil {
 					log.Errorf(log.Http, "%s BinaceFuturesApi CreateBatchOrder CreateLimitBatchOrder error %+v %s \r\n", bf.Api.ApiSign, tos, err)
 					return
 				}
+				mu.Lock()
 				orders = append(orders, order...)
+				mu.Unlock()
 			}(&wg, tos)
 			tos = []*exch.Order{}
 		}
@@ -110,11 +131,10 @@ func (bf *BinaceFuturesApi) CreateBatchOrder(ctx context.Context) ([]*exch.Order
 	return orders, nil
 }
 
-func (bf *BinaceFuturesApi) CreateLimitBatchOrder(ctx context.Context) ([]*exch.Order, error) {
-	lists := exch.GetOrders(ctx)
-	if lists == nil || len(lists) == 0 {
+func (bf *BinaceFuturesApi) CreateLimitBatchOrder(ctx context.Context, lists []*exch.Order) ([]*exch.Order, error) {
+	if len(lists) == 0 {
 		log.Errorln(log.Http, bf.Api.ApiSign, "BinaceFuturesApi CreateLimitBatchOrder get orders error ", lists)
-		return nil, nil
+		return nil, exch.ErrEmptyOrder
 	}
 	client := bf.Api.GetClient()
 	var sers []*futures.CreateOrderService
@@ -126,9 +146,9 @@ func (bf *BinaceFuturesApi) CreateLimitBatchOrder(ctx context.Context) ([]*exch.
 		sers = append(sers, ser)
 	}
 	if len(sers) == 0 {
-		return nil, nil
+		return nil, exch.ErrInvalidOrder
 	}
-	result, err := client.NewCreateBatchOrdersService().OrderList(sers).Do(context.Background())
+	result, err := client.NewCreateBatchOrdersService().OrderList(sers).Do(ctx)
 	if err != nil {
 		log.Errorln(log.Http, bf.Api.ApiSign, "BinaceFuturesApi CreateLimitBatchOrder error", err)
 		return nil, err
@@ -136,21 +156,22 @@ func (bf *BinaceFuturesApi) CreateLimitBatchOrder(ctx context.Context) ([]*exch.
 	orders := []*exch.Order{}
 	symbol := ""
 	for _, res := range result.Orders {
-		symbol = unify.BToSymbol(res.Symbol)
+		symbol = unify.BToSymbol(exch.Futures, res.Symbol)
 		status := unify.UnifyOrderStatus[res.Status]
-		size := unify.QuantityToFloat(symbol, res.OrigQuantity)
-		lsize := size - unify.QuantityToFloat(symbol, res.ExecutedQuantity)
+		size := unify.QuantityToFloat(exch.Futures, symbol, res.OrigQuantity)
+		lsize := size - unify.QuantityToFloat(exch.Futures, symbol, res.ExecutedQuantity)
 		if res.Side == futures.SideTypeSell {
 			size = -size
 			lsize = -lsize
 		}
-		price := convert.GetFloat64(unify.PriceToStr(symbol, res.Price))
-		fprice := convert.GetFloat64(unify.PriceToStr(symbol, res.AvgPrice))
+		price := convert.GetFloat64(unify.PriceToStr(exch.Futures, symbol, res.Price))
+		fprice := convert.GetFloat64(unify.PriceToStr(exch.Futures, symbol, res.AvgPrice))
 		or := &exch.Order{
 			Id:         convert.GetString(res.OrderID),
 			UUID:       res.ClientOrderID,
 			Symbol:     symbol,
 			Status:     status,
+			State:      unify.UnifyOrderState[res.Status],
 			Size:       size,
 			Price:      price,
 			FillPrice:  fprice,
@@ -166,72 +187,138 @@ func (bf *BinaceFuturesApi) CreateLimitBatchOrder(ctx context.Context) ([]*exch.
 }
 
 func (bf *BinaceFuturesApi) CreateOrderService(client *futures.Client, o *exch.Order) *futures.CreateOrderService {
-	bsymbol := unify.SymbolToB(o.Symbol)
 	side := futures.SideTypeBuy
 	if o.Size < 0 {
 		side = futures.SideTypeSell
 	}
+	//GetBaseInfo 同时加载合约信息, 之后再换算交易所交易对
 	info, err := bf.GetBaseInfo(o.Symbol)
 	if err != nil {
 		log.Errorln(log.Http, bf.Api.ApiSign, o.Symbol, "BinaceFuturesApi GetBaseInfo error", o, err)
 		return nil
 	}
-	price := mather.FloatRound(o.Price, info.PriceFloat)
-	size := mather.FloatRound(o.Size, info.SizeFloat)
-	quantity := convert.GetString(math.Abs(size))
+	bsymbol := unify.SymbolToB(exch.Futures, o.Symbol)
+	//按合约乘数换算为交易所价格及数量
+	in := unify.Instrument(exch.Futures, o.Symbol)
+	ps, ss := info.PriceScale(), info.SizeScale()
+	var price, qty exch.Fixed
+	//乘数为 10 的整数次幂时统一价格只移动小数位, 定点值与交易所价格相同
+	if ups := in.PriceScale(ps); ups.Step == ps.Step {
+		price = o.PriceFixed(ups)
+	} else {
+		price = ps.FromFloat(in.PriceToVenue(o.Price))
+	}
+	if in.SizeToVenue(1) == 1 {
+		qty = o.SizeFixed(ss)
+	} else {
+		qty = ss.FromFloat(in.SizeToVenue(o.Size))
+	}
+	if qty < 0 {
+		qty = -qty
+	}
+	quantity := ss.String(qty)
 	service := client.NewCreateOrderService().Symbol(bsymbol).Side(side).Quantity(quantity)
 	orderType := futures.OrderTypeMarket
 	if price != 0 {
 		orderType = futures.OrderTypeLimit
 		orderTif := unify.GetOrderTif(o.Tif)
-		service = service.Price(convert.GetString(price)).TimeInForce(orderTif)
+		if o.PostOnly {
+			orderTif = futures.TimeInForceTypeGTX
+		}
+		service = service.Price(ps.String(price)).TimeInForce(orderTif)
 	} else if o.Price != 0 {
 		return nil
 	}
+	if o.TriggerPrice > 0 {
+		wt, ok := unify.TriggerWorkingType[triggerBy(o)]
+		stop := ps.FromFloat(in.PriceToVenue(o.TriggerPrice))
+		if !ok || stop <= 0 {
+			return nil
+		}
+		service = service.StopPrice(ps.String(stop)).WorkingType(wt)
+		orderType = triggerType(o.Ordertype, price != 0)
+		//平掉全部仓位只支持市价条件单, 不传数量
+		if o.ClosePosition {
+			if price != 0 {
+				return nil
+			}
+			service = service.Quantity("").ClosePosition(true)
+		}
+	}
+	if exch.HedgeMode(bf.Ctx) {
+		//双向持仓按 positionSide 区分开平仓, 不能传 reduceOnly
+		service = service.PositionSide(futures.PositionSideType(o.HedgeSide()))
+	} else if o.ReduceOnly && !o.ClosePosition {
+		service = service.ReduceOnly(true)
+	}
 	if o.UUID != "" {
-		service.NewClientOrderID(o.UUID)
+		service.NewClientOrderID(exch.FitClientId(exch.Binance, o.UUID))
 	}
 	return service.Type(orderType).NewOrderResponseType(futures.NewOrderRespTypeRESULT)
 }
 
-func (bf *BinaceFuturesApi) CannelOrder(ctx context.Context) (*exch.Order, error) {
-	o := exch.GetOrder(ctx)
+// triggerType 条件单类型, 有价格时触发后下限价单, 否则下市价单
+func triggerType(ordertype string, limit bool) futures.OrderType {
+	if ordertype == exch.OrderTakeProfit {
+		if limit {
+			return futures.OrderTypeTakeProfit
+		}
+		return futures.OrderTypeTakeProfitMarket
+	}
+	if limit {
+		return futures.OrderTypeStop
+	}
+	return futures.OrderTypeStopMarket
+}
+
+func triggerBy(o *exch.Order) string {
+	if o.TriggerBy == "" {
+		return exch.TriggerLast
+	}
+	return o.TriggerBy
+}
+
+func (bf *BinaceFuturesApi) CannelOrder(ctx context.Context, o *exch.Order) (*exch.Order, error) {
 	if o == nil {
 		log.Errorln(log.Http, bf.Api.ApiSign, "BinaceFuturesApi CannelOrder GetOrder id error")
-		return nil, nil
+		return nil, exch.ErrEmptyOrder
 	}
 	orderId := convert.GetInt64(o.Id)
-	bsymbol := unify.SymbolToB(o.Symbol)
-	res, err := bf.Api.GetClient().NewCancelOrderService().Symbol(bsymbol).OrderID(orderId).Do(context.Background())
+	bsymbol := unify.SymbolToB(exch.Futures, o.Symbol)
+	res, err := bf.Api.GetClient().NewCancelOrderService().Symbol(bsymbol).OrderID(orderId).Do(ctx)
 	if err != nil {
 		return nil, err
 	}
 	status := unify.UnifyOrderStatus[res.Status]
-	size := unify.QuantityToFloat(o.Symbol, res.OrigQuantity)
-	lsize := size - unify.QuantityToFloat(o.Symbol, res.ExecutedQuantity)
+	size := unify.QuantityToFloat(exch.Futures, o.Symbol, res.OrigQuantity)
+	lsize := size - unify.QuantityToFloat(exch.Futures, o.Symbol, res.ExecutedQuantity)
 	if res.Side == futures.SideTypeSell {
 		size = -size
 		lsize = -lsize
 	}
-	price := convert.GetFloat64(unify.PriceToStr(o.Symbol, res.Price))
+	price := convert.GetFloat64(unify.PriceToStr(exch.Futures, o.Symbol, res.Price))
 	or := &exch.Order{
 		Id:         convert.GetString(res.OrderID),
 		UUID:       res.ClientOrderID,
 		Symbol:     o.Symbol,
 		Status:     status,
+		State:      unify.UnifyOrderState[res.Status],
 		Size:       size,
 		Price:      price,
 		Left:       lsize,
+		Tif:        unify.UnifyOrderType[res.TimeInForce],
+		PostOnly:   res.TimeInForce == futures.TimeInForceTypeGTX,
+		ReduceOnly: res.ReduceOnly,
 		CreateTime: o.UpdateTime,
 		UpdateTime: o.UpdateTime,
 	}
+	unify.SetTrigger(or, res.Type, res.StopPrice, res.WorkingType, false)
 	return or, nil
 }
 
-func (bf *BinaceFuturesApi) CannelAllOrder(ctx context.Context) ([]*exch.Order, error) {
-	symbol := text.GetString(ctx, exch.CtxSymbol)
-	bsymbol := unify.SymbolToB(symbol)
-	err := bf.Api.GetClient().NewCancelAllOpenOrdersService().Symbol(bsymbol).Do(context.Background())
+func (bf *BinaceFuturesApi) CannelAllOrder(ctx context.Context, symbol string) ([]*exch.Order, error) {
+	bsymbol := unify.SymbolToB(exch.Futures, symbol)
+	err := bf.Api.GetClient().NewCancelAllOpenOrdersService().Symbol(bsymbol).Do(ctx)
 	if err != nil {
 		log.Errorln(log.Http, bf.Api.ApiSign, "BinaceFuturesApi  CannelAllOrder error", err)
 		return nil, err
@@ -240,52 +327,128 @@ func (bf *BinaceFuturesApi) CannelAllOrder(ctx context.Context) ([]*exch.Order,
 	return nil, nil
 }
 
-func (bf *BinaceFuturesApi) UpdateLeverage(ctx context.Context) (*exch.Position, error) {
-	symbol := text.GetString(ctx, exch.CtxSymbol)
-	bsymbol := unify.SymbolToB(symbol)
-	lv := convert.GetInt(text.GetString(ctx, exch.CtxLv))
-	_, err := bf.Api.GetClient().NewChangeLeverageService().Symbol(bsymbol).Leverage(lv).Do(context.Background())
+// AmendOrder 修改限价单数量及价格, 订单Id不变, 数量或价格为 0 时按交易所当前订单补全
+func (bf *BinaceFuturesApi) AmendOrder(ctx context.Context, o *exch.Order) (*exch.Order, error) {
+	if o == nil {
+		log.Errorln(log.Http, bf.Api.ApiSign, "BinaceFuturesApi AmendOrder GetOrder id error")
+		return nil, exch.ErrEmptyOrder
+	}
+	info, err := bf.GetBaseInfo(o.Symbol)
 	if err != nil {
-		log.Errorln(log.Http, bf.Api.ApiSign, symbol, "BinaceFuturesApi  UpdateLeverage error", err)
+		log.Errorln(log.Http, bf.Api.ApiSign, o.Symbol, "BinaceFuturesApi GetBaseInfo error", o, err)
+		return nil, err
 	}
-	return nil, err
+	client := bf.Api.GetClient()
+	orderId := convert.GetInt64(o.Id)
+	bsymbol := unify.SymbolToB(exch.Futures, o.Symbol)
+	size, price := o.Size, o.Price
+	if size == 0 || price == 0 {
+		cur, err := client.NewGetOrderService().Symbol(bsymbol).OrderID(orderId).Do(ctx)
+		if err != nil {
+			log.Errorln(log.Http, bf.Api.ApiSign, o.Symbol, "BinaceFuturesApi AmendOrder GetOrder error", err)
+			return nil, err
+		}
+		if size == 0 {
+			size = unify.QuantityToFloat(exch.Futures, o.Symbol, cur.OrigQuantity)
+			if cur.Side == futures.SideTypeSell {
+				size = -size
+			}
+		}
+		if price == 0 {
+			price = convert.GetFloat64(unify.PriceToStr(exch.Futures, o.Symbol, cur.Price))
+		}
+	}
+	side := futures.SideTypeBuy
+	if size < 0 {
+		side = futures.SideTypeSell
+	}
+	in := unify.Instrument(exch.Futures, o.Symbol)
+	ps, ss := info.PriceScale(), info.SizeScale()
+	quantity := ss.String(ss.FromFloat(math.Abs(in.SizeToVenue(size))))
+	res, err := client.NewModifyOrderService().Symbol(bsymbol).OrderID(orderId).Side(side).
+		Quantity(quantity).Price(ps.String(ps.FromFloat(in.PriceToVenue(price)))).Do(ctx)
+	if err != nil {
+		log.Errorln(log.Http, bf.Api.ApiSign, o.Symbol, "BinaceFuturesApi AmendOrder error", err)
+		return nil, err
+	}
+	rsize := unify.QuantityToFloat(exch.Futures, o.Symbol, res.OrigQuantity)
+	lsize := rsize - unify.QuantityToFloat(exch.Futures, o.Symbol, res.ExecutedQuantity)
+	if res.Side == futures.SideTypeSell {
+		rsize = -rsize
+		lsize = -lsize
+	}
+	or := &exch.Order{
+		Id:         convert.GetString(res.OrderID),
+		UUID:       res.ClientOrderID,
+		Symbol:     o.Symbol,
+		Status:     unify.UnifyOrderStatus[res.Status],
+		State:      unify.UnifyOrderState[res.Status],
+		Size:       rsize,
+		Price:      convert.GetFloat64(unify.PriceToStr(exch.Futures, o.Symbol, res.Price)),
+		FillPrice:  convert.GetFloat64(unify.PriceToStr(exch.Futures, o.Symbol, res.AvgPrice)),
+		Left:       lsize,
+		CreateTime: res.Time,
+		UpdateTime: res.UpdateTime,
+	}
+	log.Infoln(log.Http, bf.Api.ApiSign, or.Symbol, "BinaceFuturesApi AmendOrder success:p,s ", or.Price, or.Size)
+	return or, nil
 }
 
-func (bf *BinaceFuturesApi) UpdateMarginType(ctx context.Context) (*exch.Position, error) {
-	symbol := text.GetString(ctx, exch.CtxSymbol)
-	bsymbol := unify.SymbolToB(symbol)
-	marginType := futures.MarginTypeIsolated
-	err := bf.Api.GetClient().NewChangeMarginTypeService().Symbol(bsymbol).MarginType(marginType).Do(context.Background())
-	if err != nil {
-		log.Errorln(log.Http, bf.Api.ApiSign, symbol, "BinaceFuturesApi  UpdateMarginType error", err)
+// UpdateLeverage 杠杆按交易对设置, 双向持仓时两个方向相同
+func (bf *BinaceFuturesApi) UpdateLeverage(ctx context.Context, symbol string, lv float64) (*exch.Position, error) {
+	if lv == 0 {
+		return nil, exch.ErrZeroLeverage
 	}
-	if err == nil || err.Error() == "<APIError> code=-4046, msg=No need to change margin type." {
-		return nil, nil
+	bsymbol := unify.SymbolToB(exch.Futures, symbol)
+	_, err := bf.Api.GetClient().NewChangeLeverageService().Symbol(bsymbol).Leverage(int(lv)).Do(ctx)
+	if err != nil {
+		log.Errorln(log.Http, bf.Api.ApiSign, symbol, "BinaceFuturesApi  UpdateLeverage error", err)
 	}
 	return nil, err
 }
 
-func (bf *BinaceFuturesApi) UpdatePositionMode(ctx context.Context) error {
-	if bf.DualSide == false {
+// UpdateMarginType 设置保证金模式 isolated,crossed, 已是该模式时交易所返回 -4046 按成功处理
+func (bf *BinaceFuturesApi) UpdateMarginType(ctx context.Context, symbol, mode string) error {
+	marginType, ok := unify.MarginTypeMap[mode]
+	if !ok {
+		return exch.ErrNotSupported
+	}
+	bsymbol := unify.SymbolToB(exch.Futures, symbol)
+	err := bf.Api.GetClient().NewChangeMarginTypeService().Symbol(bsymbol).MarginType(marginType).Do(ctx)
+	if err == nil || unify.IsCode(err, unify.CodeNoNeedMarginType) {
 		return nil
 	}
-	bf.DualSide = false
-	err := bf.Api.GetClient().NewChangePositionModeService().DualSide(false).Do(context.Background())
+	log.Errorln(log.Http, bf.Api.ApiSign, symbol, "BinaceFuturesApi  UpdateMarginType error", err)
+	return err
+}
+
+// UpdatePositionMode 切换单向或双向持仓, 查询与当前模式相同时不修改
+func (bf *BinaceFuturesApi) UpdatePositionMode(ctx context.Context, hedge bool) error {
+	res, err := bf.Api.GetClient().NewGetPositionModeService().Do(ctx)
 	if err != nil {
-		bf.DualSide = true
+		log.Errorln(log.Http, bf.Api.ApiSign, "BinaceFuturesApi  GetPositionMode error", err)
+		return err
+	}
+	bf.DualSide = res.DualSidePosition
+	if bf.DualSide == hedge {
+		return nil
+	}
+	err = bf.Api.GetClient().NewChangePositionModeService().DualSide(hedge).Do(ctx)
+	if err != nil && !unify.IsCode(err, unify.CodeNoNeedPositionMode) {
 		log.Errorln(log.Http, bf.Api.ApiSign, "BinaceFuturesApi  UpdatePositionMode error", err)
+		return err
 	}
-	return err
+	bf.DualSide = hedge
+	log.Infoln(log.Http, bf.Api.ApiSign, "BinaceFuturesApi UpdatePositionMode success dual:", hedge)
+	return nil
 }
 
-func (bf *BinaceFuturesApi) UpdateMargin(ctx context.Context) (*exch.Position, error) {
-	change := text.GetFloat(ctx, exch.CtxChange)
+func (bf *BinaceFuturesApi) UpdateMargin(ctx context.Context, symbol string, change float64) (*exch.Position, error) {
 	if change == 0.0 {
 		log.Errorln(log.Http, bf.Api.ApiSign, "BinaceFuturesApi  UpdateMargin error: change is 0 ")
-		return nil, nil
+		return nil, exch.ErrZeroChange
 	}
-	symbol := text.GetString(ctx, exch.CtxSymbol)
-	bsymbol := unify.SymbolToB(symbol)
+	bsymbol := unify.SymbolToB(exch.Futures, symbol)
 
 	amount := convert.GetString(change)
 	actionType := 1
@@ -293,7 +456,16 @@ func (bf *BinaceFuturesApi) UpdateMargin(ctx context.Context) (*exch.Position, e
 		amount = convert.GetString(-change)
 		actionType = 2
 	}
-	err := bf.Api.GetClient().NewUpdatePositionMarginService().Symbol(bsymbol).Amount(amount).Type(actionType).Do(context.Background())
+	service := bf.Api.GetClient().NewUpdatePositionMarginService().Symbol(bsymbol).Amount(amount).Type(actionType)
+	if exch.HedgeMode(bf.Ctx) {
+		//双向持仓按 ctx 中的持仓方向调整保证金
+		side := exch.PositionSide(ctx)
+		if side != exch.PositionLong && side != exch.PositionShort {
+			return nil, exch.ErrPositionSide
+		}
+		service = service.PositionSide(futures.PositionSideType(side))
+	}
+	err := service.Do(ctx)
 	if err != nil {
 		log.Errorln(log.Http, bf.Api.ApiSign, "BinaceFuturesApi UpdateMargin error", err)
 	}
@@ -301,29 +473,62 @@ func (bf *BinaceFuturesApi) UpdateMargin(ctx context.Context) (*exch.Position, e
 }
 
 func (bf *BinaceFuturesApi) GetOrder(ctx context.Context) (map[string]*exch.Order, error) {
-	symbol := text.GetString(ctx, exch.CtxSymbol)
-	bsymbol := unify.SymbolToB(symbol)
-	res, err := bf.Api.GetClient().NewListOpenOrdersService().Symbol(bsymbol).Do(context.Background())
+	orders, _, err := bf.OpenOrders(ctx)
+	return orders, err
+}
+
+// ListTriggerOrder 交易对未触发的条件单
+func (bf *BinaceFuturesApi) ListTriggerOrder(ctx context.Context) (map[string]*exch.Order, error) {
+	_, triggers, err := bf.OpenOrders(ctx)
+	return triggers, err
+}
+
+// ListOpenOrders 账号所有交易对的当前委托, 包含未触发的条件单
+func (bf *BinaceFuturesApi) ListOpenOrders(ctx context.Context) ([]*exch.Order, error) {
+	orders, triggers, err := bf.OpenOrders(exch.WithSymbol(ctx, ""))
 	if err != nil {
+		log.Errorln(log.Http, bf.Api.ApiSign, "BinaceFuturesApi ListOpenOrders error", err)
 		return nil, err
 	}
-	orders := map[string]*exch.Order{}
+	lists := make([]*exch.Order, 0, len(orders)+len(triggers))
+	for _, o := range orders {
+		lists = append(lists, o)
+	}
+	for _, o := range triggers {
+		lists = append(lists, o)
+	}
+	return lists, nil
+}
+
+// OpenOrders 交易对当前委托, 按类型分为普通委托单及未触发的条件单, 未指定交易对时查询所有交易对
+func (bf *BinaceFuturesApi) OpenOrders(ctx context.Context) (map[string]*exch.Order, map[string]*exch.Order, error) {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	service := bf.Api.GetClient().NewListOpenOrdersService()
+	if symbol != "" {
+		service = service.Symbol(unify.SymbolToB(exch.Futures, symbol))
+	}
+	res, err := service.Do(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	orders, triggers := map[string]*exch.Order{}, map[string]*exch.Order{}
 	for _, o := range res {
-		symb := unify.BToSymbol(o.Symbol)
+		symb := unify.BToSymbol(exch.Futures, o.Symbol)
 		status := unify.UnifyOrderStatus[o.Status]
-		size := unify.QuantityToFloat(symb, o.OrigQuantity)
-		lsize := size - unify.QuantityToFloat(symb, o.ExecutedQuantity)
+		size := unify.QuantityToFloat(exch.Futures, symb, o.OrigQuantity)
+		lsize := size - unify.QuantityToFloat(exch.Futures, symb, o.ExecutedQuantity)
 		if o.Side == futures.SideTypeSell {
 			size = -size
 			lsize = -lsize
 		}
-		price := convert.GetFloat64(unify.PriceToStr(symb, o.Price))
-		fprice := convert.GetFloat64(unify.PriceToStr(symb, o.AvgPrice))
+		price := convert.GetFloat64(unify.PriceToStr(exch.Futures, symb, o.Price))
+		fprice := convert.GetFloat64(unify.PriceToStr(exch.Futures, symb, o.AvgPrice))
 		or := exch.Order{
 			Id:         convert.GetString(o.OrderID),
 			UUID:       o.ClientOrderID,
 			Symbol:     symb,
 			Status:     status,
+			State:      unify.UnifyOrderState[o.Status],
 			Size:       size,
 			Price:      price,
 			FillPrice:  fprice,
@@ -332,42 +537,82 @@ func (bf *BinaceFuturesApi) GetOrder(ctx context.Context) (map[string]*exch.Orde
 			CreateTime: o.UpdateTime,
 			UpdateTime: o.UpdateTime,
 		}
+		//价格按统一刻度解析为定点值, 数量不经乘数换算时同样解析为定点值
+		if info, err := bf.GetBaseInfo(symb); err == nil && info != nil {
+			or.ParsePrice(unify.PriceScale(exch.Futures, symb, info), unify.PriceToStr(exch.Futures, symb, o.Price))
+			if unify.Instrument(exch.Futures, symb).SizeToVenue(1) == 1 {
+				quantity := o.OrigQuantity
+				if o.Side == futures.SideTypeSell {
+					quantity = "-" + quantity
+				}
+				or.ParseSize(info.SizeScale(), quantity)
+			}
+		}
+		if unify.IsTrigger(o.Type) {
+			unify.SetTrigger(&or, o.Type, o.StopPrice, o.WorkingType, o.ClosePosition)
+			triggers[or.Id] = &or
+			continue
+		}
 		orders[or.Id] = &or
 	}
-	return orders, nil
+	return orders, triggers, nil
 }
 
 func (bf *BinaceFuturesApi) GetOrderBook(ctx context.Context) (*futures.DepthResponse, error) {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
-	bsymbol := unify.SymbolToB(symbol)
+	bsymbol := unify.SymbolToB(exch.Futures, symbol)
 	limit := text.GetInt64(ctx, OrderBookLimit)
-	res, err := bf.Api.GetClient().NewDepthService().Symbol(bsymbol).Limit(int(limit)).Do(context.Background())
+	res, err := bf.Api.GetClient().NewDepthService().Symbol(bsymbol).Limit(int(limit)).Do(ctx)
 	if err != nil {
 		log.Errorln(log.Http, bf.Api.ApiSign, symbol, "BinaceFuturesApi GetOrderBook error", err)
 	}
 	return res, err
 }
 
+// GetPosition 双向持仓时返回 ctx 中持仓方向的仓位, 未指定时返回净仓位
 func (bf *BinaceFuturesApi) GetPosition(ctx context.Context) (*exch.Position, error) {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
-	bsymbol := unify.SymbolToB(symbol)
-	res, err := bf.Api.GetClient().NewGetPositionRiskService().Symbol(bsymbol).Do(context.Background())
+	list, err := bf.GetPositions(ctx)
 	if err != nil {
-		log.Errorln(log.Http, bf.Api.ApiSign, symbol, "BinaceFuturesApi  GetPosition error", err)
 		return nil, err
 	}
+	side := exch.PositionSide(ctx)
+	if exch.HedgeMode(bf.Ctx) && side == "" {
+		return exch.NetPosition(symbol, list...), nil
+	}
+	if side == "" {
+		side = exch.PositionBoth
+	}
 	var pos *exch.Position
+	for _, p := range list {
+		if p.PositionMode == side {
+			pos = p
+		}
+	}
+	return pos, nil
+}
+
+// GetPositions 交易对所有方向的仓位
+func (bf *BinaceFuturesApi) GetPositions(ctx context.Context) ([]*exch.Position, error) {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	bsymbol := unify.SymbolToB(exch.Futures, symbol)
+	res, err := bf.Api.GetClient().NewGetPositionRiskService().Symbol(bsymbol).Do(ctx)
+	if err != nil {
+		log.Errorln(log.Http, bf.Api.ApiSign, symbol, "BinaceFuturesApi  GetPosition error", err)
+		return nil, err
+	}
+	list := make([]*exch.Position, 0, len(res))
 	for _, r := range res {
 		price := convert.GetFloat64(r.EntryPrice)
 		mprice := convert.GetFloat64(r.MarkPrice)
 		lprice := convert.GetFloat64(r.LiquidationPrice)
 
-		price = unify.PriceToFloat(symbol, price)
-		mprice = unify.PriceToFloat(symbol, mprice)
-		lprice = unify.PriceToFloat(symbol, lprice)
-		size := unify.QuantityToFloat(symbol, r.PositionAmt)
-		pos = &exch.Position{
-			Symbol:         r.Symbol,
+		price = unify.PriceToFloat(exch.Futures, symbol, price)
+		mprice = unify.PriceToFloat(exch.Futures, symbol, mprice)
+		lprice = unify.PriceToFloat(exch.Futures, symbol, lprice)
+		size := unify.QuantityToFloat(exch.Futures, symbol, r.PositionAmt)
+		list = append(list, &exch.Position{
+			Symbol:         symbol,
 			Price:          price,
 			Size:           size,
 			Margin:         convert.GetFloat64(r.IsolatedMargin),
@@ -375,13 +620,13 @@ func (bf *BinaceFuturesApi) GetPosition(ctx context.Context) (*exch.Position, er
 			LiqPrice:       lprice,
 			MarkPrice:      mprice,
 			Lv:             convert.GetFloat64(r.Leverage),
-			MarginType:     r.MarginType,
+			MarginType:     unify.UnifyMarginType[r.MarginType],
 			PositionMode:   r.PositionSide,
 			Value:          mprice * math.Abs(size),
 			LastUpdateTime: r.UpdateTime,
-		}
+		})
 	}
-	return pos, err
+	return list, nil
 }
 
 func (bf *BinaceFuturesApi) GetBalance(ctx context.Context) (map[string]*exch.Balance, error) {
@@ -393,7 +638,7 @@ func (bf *BinaceFuturesApi) GetBalance(ctx context.Context) (map[string]*exch.Ba
 		return InitBalance.D, nil
 	}
 	blmap := map[string]*exch.Balance{}
-	bl, err := bf.Api.GetClient().NewGetBalanceService().Do(context.Background())
+	bl, err := bf.Api.GetClient().NewGetBalanceService().Do(ctx)
 	if err != nil {
 		log.Errorln(log.Http, bf.Api.ApiSign, "BinaceFuturesApi GetBalance error", err)
 		return blmap, err
@@ -418,7 +663,7 @@ func (bf *BinaceFuturesApi) ListPosition(ctx context.Context) (map[string]*exch.
 	if isinit && ti-InitPosition.T < 2000 {
 		return InitPosition.D, nil
 	}
-	res, err := bf.Api.GetClient().NewGetAccountService().Do(context.Background())
+	res, err := bf.Api.GetClient().NewGetAccountService().Do(ctx)
 	if err != nil {
 		log.Errorln(log.Http, bf.Api.ApiSign, "BinaceFuturesApi ListPosition error", err)
 		return nil, err
@@ -433,10 +678,10 @@ func (bf *BinaceFuturesApi) ListPosition(ctx context.Context) (map[string]*exch.
 	//}
 	posList := map[string]*exch.Position{}
 	for _, p := range res.Positions {
-		symbol := unify.BToSymbol(p.Symbol)
+		symbol := unify.BToSymbol(exch.Futures, p.Symbol)
 		price := convert.GetFloat64(p.EntryPrice)
-		price = unify.PriceToFloat(symbol, price)
-		size := unify.QuantityToFloat(symbol, p.PositionAmt)
+		price = unify.PriceToFloat(exch.Futures, symbol, price)
+		size := unify.QuantityToFloat(exch.Futures, symbol, p.PositionAmt)
 		mtype := exch.MarginIsolated
 		if !p.Isolated {
 			mtype = exch.MarginCrossed
@@ -453,7 +698,8 @@ func (bf *BinaceFuturesApi) ListPosition(ctx context.Context) (map[string]*exch.
 			Value:          price * math.Abs(size),
 			LastUpdateTime: convert.GetInt64(p.UpdateTime),
 		}
-		posList[symbol] = pos
+		//双向持仓时同一交易对有两个方向, 按 交易对:方向 区分
+		posList[pos.Key()] = pos
 	}
 	InitPosition.D = posList
 	InitPosition.T = ti
@@ -470,7 +716,7 @@ func (bf *BinaceFuturesApi) GetMarketPrice(symbol string) (map[string]*exch.Base
 	infos := map[string]*exch.BaseInfo{}
 	for _, r := range res {
 		info := &exch.BaseInfo{}
-		s := unify.BToSymbol(r.Symbol)
+		s := unify.BToSymbol(exch.Futures, r.Symbol)
 		info.Symbol = s
 		info.MarkPrice = convert.GetFloat64(r.MarkPrice)
 		info.FundingRate = convert.GetFloat64(r.LastFundingRate)
@@ -499,11 +745,12 @@ func (bf *BinaceFuturesApi) GetBaseInfo(symbol string) (*exch.BaseInfo, error) {
 		return nil, err
 	}
 	//log.Warnln(log.Http, bf.Api.ApiSign, symbol, "BinaceFuturesApi GetBaseInfo success ")
+	exch.RegisterInstruments(Instruments(res.Symbols)...)
 	ti := time.Now().Unix()
 	infos := map[string]interface{}{}
 	for _, r := range res.Symbols {
 		info := &exch.BaseInfo{}
-		s := unify.BToSymbol(r.Symbol)
+		s := unify.BToSymbol(exch.Futures, r.Symbol)
 		info.Symbol = s
 		pf := r.PriceFilter()
 		sf := r.LotSizeFilter()
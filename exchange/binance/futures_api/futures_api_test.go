@@ -0,0 +1,58 @@
+package futures_api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"high-freq-quant-go/core/exch"
+)
+
+func TestCreateTriggerOrderReduceOnly(t *testing.T) {
+	cases := []struct {
+		sign  string
+		hedge bool
+		o     exch.Order
+		want  string //reduceOnly 参数, 空为不传
+		side  string
+	}{
+		{"trigger-oneway", false, exch.Order{Size: -0.01, ReduceOnly: true}, "true", ""},
+		{"trigger-oneway-open", false, exch.Order{Size: -0.01}, "", ""},
+		{"trigger-hedge", true, exch.Order{Size: -0.01, PositionSide: exch.PositionLong}, "", "LONG"},
+	}
+	for _, c := range cases {
+		var form map[string][]string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			form = r.Form
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"orderId":1,"symbol":"BTCUSDT","status":"NEW","side":"SELL","origQty":"0.010","type":"STOP_MARKET","stopPrice":"30000"}`))
+		}))
+		ctx := context.WithValue(context.Background(), exch.ApiSign, c.sign)
+		ctx = context.WithValue(ctx, exch.Key, "key")
+		ctx = context.WithValue(ctx, exch.Secret, "secret")
+		bf := NewBinanceApi(exch.WithHedgeMode(ctx, c.hedge))
+		bf.Api.GetClient().BaseURL = srv.URL
+		InitInfo.D.Set("BTC_USDT", &exch.BaseInfo{Symbol: "BTC_USDT", MinPriceStep: 0.1, MinSizeStep: 0.001})
+		o := c.o
+		o.Symbol, o.TriggerPrice = "BTC_USDT", 30000
+		_, err := bf.CreateOrder(context.Background(), &o)
+		srv.Close()
+		if err != nil {
+			t.Fatalf("%s got %v", c.sign, err)
+		}
+		get := func(k string) string {
+			if v := form[k]; len(v) != 0 {
+				return v[0]
+			}
+			return ""
+		}
+		if get("type") != "STOP_MARKET" || get("stopPrice") != "30000" || get("quantity") != "0.01" {
+			t.Fatalf("%s payload got %v", c.sign, form)
+		}
+		if get("reduceOnly") != c.want || get("positionSide") != c.side {
+			t.Fatalf("%s payload got %v", c.sign, form)
+		}
+	}
+}
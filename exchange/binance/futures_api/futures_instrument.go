@@ -0,0 +1,62 @@
+package futures_api
+
+import (
+	"context"
+	"strings"
+
+	"high-freq-quant-go/adapter/convert"
+	"high-freq-quant-go/adapter/timer"
+	"high-freq-quant-go/core/exch"
+	"high-freq-quant-go/core/log"
+	"high-freq-quant-go/exchange/binance/binanceapi/futures"
+)
+
+func init() {
+	exch.RegisterInstrumentLoader(exch.Binance, exch.Futures, LoadInstruments)
+}
+
+// LoadInstruments 用公共接口拉取交易所信息生成合约信息, 为 exch 合约信息加载函数
+func LoadInstruments() ([]*exch.Instrument, error) {
+	api := NewBinaceFuturesRequest(context.Background())
+	res, err := api.GetClient().NewExchangeInfoService().Do(context.Background())
+	if err != nil {
+		log.Errorln(log.Http, "BinaceFuturesApi LoadInstruments error", err)
+		return nil, err
+	}
+	return Instruments(res.Symbols), nil
+}
+
+// Instruments 1000SHIBUSDT 统一为 SHIB_USDT, 乘数 1000; BTCUSDT_230630 统一为 BTC_USDT_20230630
+func Instruments(symbols []futures.Symbol) []*exch.Instrument {
+	ti := timer.MicNow()
+	ins := make([]*exch.Instrument, 0, len(symbols))
+	for i := range symbols {
+		r := &symbols[i]
+		base, mul := exch.ParseMultiplier(r.BaseAsset)
+		in := &exch.Instrument{
+			Symbol:         base + "_" + r.QuoteAsset,
+			VenueSymbol:    r.Symbol,
+			Exname:         exch.Binance,
+			Extype:         exch.Futures,
+			Base:           base,
+			Quote:          r.QuoteAsset,
+			Settle:         r.MarginAsset,
+			Multiplier:     mul,
+			ContractSize:   1,
+			LastUpdateTime: ti,
+		}
+		if i := strings.Index(r.Symbol, "_"); i > 0 {
+			in.Symbol = in.Symbol + "_20" + r.Symbol[i+1:]
+			in.ExpireTime = r.DeliveryDate
+		}
+		if pf := r.PriceFilter(); pf != nil {
+			in.TickSize = convert.GetFloat64(pf.TickSize)
+		}
+		if sf := r.LotSizeFilter(); sf != nil {
+			in.StepSize = convert.GetFloat64(sf.StepSize)
+			in.MinSize = convert.GetFloat64(sf.MinQuantity)
+		}
+		ins = append(ins, in)
+	}
+	return ins
+}
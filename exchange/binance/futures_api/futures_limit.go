@@ -0,0 +1,76 @@
+package futures_api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"high-freq-quant-go/core/request"
+)
+
+const (
+	limitWeight    = "weight"
+	limitOrders10s = "orders10s"
+	limitOrders1m  = "orders1m"
+)
+
+// LimitRules binance u本位合约限频: 权重 2400/分钟, 下单及改单 300次/10秒及 1200次/分钟, 下单不计权重
+var LimitRules = request.Rules{
+	Classes: map[string][]request.Limit{
+		request.ClassOrder: {
+			{Name: limitOrders10s, N: 300, Per: 10 * time.Second},
+			{Name: limitOrders1m, N: 1200, Per: time.Minute},
+		},
+		request.ClassCancel: {{Name: limitWeight, N: 2400, Per: time.Minute}},
+		request.ClassQuery:  {{Name: limitWeight, N: 2400, Per: time.Minute}},
+	},
+	Classify: limitClass,
+	Observe: request.UsedHeaders(map[string]string{
+		"X-MBX-USED-WEIGHT-1M":  limitWeight,
+		"X-MBX-ORDER-COUNT-10S": limitOrders10s,
+		"X-MBX-ORDER-COUNT-1M":  limitOrders1m,
+	}),
+}
+
+// 查询接口权重, 未列出的为 1
+var limitWeights = map[string]float64{
+	"/fapi/v2/account":      5,
+	"/fapi/v2/balance":      5,
+	"/fapi/v2/positionRisk": 5,
+	"/fapi/v1/userTrades":   5,
+	"/fapi/v1/allOrders":    5,
+	"/fapi/v1/income":       30,
+}
+
+func limitClass(req *http.Request) (string, float64) {
+	path := req.URL.Path
+	switch path {
+	case "/fapi/v1/order", "/fapi/v1/batchOrders", "/fapi/v1/allOpenOrders":
+		switch req.Method {
+		case http.MethodDelete:
+			return request.ClassCancel, 1
+		case http.MethodPost, http.MethodPut:
+			return request.ClassOrder, 1
+		}
+	case "/fapi/v1/openOrders":
+		if req.URL.Query().Get("symbol") == "" {
+			return request.ClassQuery, 40
+		}
+	case "/fapi/v1/depth":
+		//默认 500 档
+		limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+		switch {
+		case limit > 500:
+			return request.ClassQuery, 20
+		case limit > 100 || limit == 0:
+			return request.ClassQuery, 10
+		case limit > 50:
+			return request.ClassQuery, 5
+		}
+		return request.ClassQuery, 2
+	}
+	if w, ok := limitWeights[path]; ok {
+		return request.ClassQuery, w
+	}
+	return request.ClassQuery, 1
+}
@@ -18,7 +18,7 @@ type BinaceFuturesRequest struct {
 	Ctx    context.Context
 	Client *futures.Client
 
-	Times *request.Times
+	Limiter *request.Limiter
 }
 
 func NewBinaceFuturesRequest(ctx context.Context) *BinaceFuturesRequest {
@@ -33,16 +33,16 @@ func NewBinaceFuturesRequest(ctx context.Context) *BinaceFuturesRequest {
 
 	bc := &BinaceFuturesRequest{
 		ApiSign: sign,
-		Times:   TimeLimiter,
+		Limiter: request.NewLimiter(sign, LimitRules, request.GetRateLimit(ctx)),
 		Ctx:     context.Background(),
 		Client:  binanceapi.NewFuturesClient(key, secret),
 	}
+	bc.Client.HTTPClient = bc.Limiter.Client()
 
 	InitApier.D.Set(sign, bc)
 	return bc
 }
 
 func (bc *BinaceFuturesRequest) GetClient() *futures.Client {
-	bc.Times.Update()
 	return bc.Client
 }
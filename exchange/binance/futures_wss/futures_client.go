@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"math/rand"
 	"strings"
+	"sync"
 	"time"
 
 	"high-freq-quant-go/adapter/client"
@@ -34,6 +35,7 @@ type FuturesClient struct {
 
 	//reconnect
 	ReConnectMsg []client.SubscribeData
+	rl           sync.Mutex
 }
 
 func NewFuturesClient(ctx context.Context) *FuturesClient {
@@ -52,6 +54,7 @@ func (ws *FuturesClient) NewClient() error {
 	ctx = context.WithValue(ctx, client.Keepalive, true)
 	ctx = context.WithValue(ctx, client.Timeout, WssTimeout)
 	ctx = context.WithValue(ctx, client.MsgLen, exch.WSChannelLen)
+	ctx = context.WithValue(ctx, client.Overflow, exch.GetOverflow(ws.Ctx, exch.LaneWss))
 	ctx = context.WithValue(ctx, client.Id, ws.Sign)
 	wss, err := client.NewWssSocket(ctx, ws.ReConnect)
 	if err != nil {
@@ -75,7 +78,7 @@ func (ws *FuturesClient) OrderBook(ctx context.Context) error {
 	}
 	msg := ws.SubscribeChannel(param)
 	ctx = context.WithValue(ctx, client.SendMsg, msg)
-	ws.RegisterMsg(ws.OrderBook, ctx)
+	ws.RegisterMsg(param[0], ws.OrderBook, ctx)
 	err := ws.Wss.SendMsg(ctx)
 	return err
 }
@@ -90,7 +93,7 @@ func (ws *FuturesClient) MarkPrice(ctx context.Context) error {
 	}
 	msg := ws.SubscribeChannel(param)
 	ctx = context.WithValue(ctx, client.SendMsg, msg)
-	ws.RegisterMsg(ws.MarkPrice, ctx)
+	ws.RegisterMsg(param[0], ws.MarkPrice, ctx)
 	err := ws.Wss.SendMsg(ctx)
 	return err
 }
@@ -105,20 +108,74 @@ func (ws *FuturesClient) Ticker(ctx context.Context) error {
 	}
 	msg := ws.SubscribeChannel(param)
 	ctx = context.WithValue(ctx, client.SendMsg, msg)
-	ws.RegisterMsg(ws.Ticker, ctx)
+	ws.RegisterMsg(param[0], ws.Ticker, ctx)
 	err := ws.Wss.SendMsg(ctx)
 	return err
 }
 
+// AggTrade 归集成交, 同一价格同一主动方向的逐笔成交合并推送
+func (ws *FuturesClient) AggTrade(ctx context.Context) error {
+	if ws.Wss == nil {
+		ws.NewClient()
+	}
+	symbol := WssSymbol(ctx)
+	param := []string{
+		symbol + "@aggTrade",
+	}
+	msg := ws.SubscribeChannel(param)
+	ctx = context.WithValue(ctx, client.SendMsg, msg)
+	ws.RegisterMsg(param[0], ws.AggTrade, ctx)
+	err := ws.Wss.SendMsg(ctx)
+	return err
+}
+
+func (ws *FuturesClient) UnOrderBook(ctx context.Context) error {
+	return ws.Unsubscribe(WssSymbol(ctx) + "@depth@100ms")
+}
+
+func (ws *FuturesClient) UnMarkPrice(ctx context.Context) error {
+	return ws.Unsubscribe(WssSymbol(ctx) + "@markPrice@1s")
+}
+
+func (ws *FuturesClient) UnTicker(ctx context.Context) error {
+	return ws.Unsubscribe(WssSymbol(ctx) + "@ticker")
+}
+
+func (ws *FuturesClient) UnAggTrade(ctx context.Context) error {
+	return ws.Unsubscribe(WssSymbol(ctx) + "@aggTrade")
+}
+
 func (ws *FuturesClient) SubscribeChannel(param []string) []byte {
+	return channelMsg("SUBSCRIBE", param)
+}
+
+func (ws *FuturesClient) UnSubscribeChannel(param []string) []byte {
+	return channelMsg("UNSUBSCRIBE", param)
+}
+
+func channelMsg(method string, param []string) []byte {
 	request := make(map[string]interface{})
-	request["method"] = "SUBSCRIBE"
+	request["method"] = method
 	request["params"] = param
 	request["id"] = rand.Int()
 	msg, _ := json.Marshal(request)
 	return msg
 }
 
+// Unsubscribe 从重连列表删除并发送取消订阅, Key 为订阅的 stream 名称
+func (ws *FuturesClient) Unsubscribe(param ...string) error {
+	ws.rl.Lock()
+	for _, key := range param {
+		ws.ReConnectMsg = client.RemoveSubscribe(ws.ReConnectMsg, key)
+	}
+	ws.rl.Unlock()
+	if ws.Wss == nil {
+		return nil
+	}
+	ctx := context.WithValue(ws.Ctx, client.SendMsg, ws.UnSubscribeChannel(param))
+	return ws.Wss.CancelMsg(ctx)
+}
+
 func (ws *FuturesClient) ReceivedMsg() {
 	for {
 		select {
@@ -137,17 +194,20 @@ func (ws *FuturesClient) ReceivedMsg() {
 	}
 }
 
-func (ws *FuturesClient) RegisterMsg(action func(ctx context.Context) error, param context.Context) {
+// RegisterMsg 记录重连后需要重新订阅的 stream, 相同 Key 只保留最新一条
+func (ws *FuturesClient) RegisterMsg(key string, action func(ctx context.Context) error, param context.Context) {
 	if text.GetBool(param, client.IsReconnect) {
 		return
 	}
 	param = context.WithValue(param, client.IsReconnect, true)
 	method := client.SubscribeData{
+		Key:    key,
 		Action: &action,
 		Param:  param,
 	}
-	ws.ReConnectMsg = append(ws.ReConnectMsg, method)
-	return
+	ws.rl.Lock()
+	ws.ReConnectMsg = append(client.RemoveSubscribe(ws.ReConnectMsg, key), method)
+	ws.rl.Unlock()
 }
 
 func (ws *FuturesClient) ReConnect(err error) error {
@@ -161,7 +221,10 @@ func (ws *FuturesClient) ReConnect(err error) error {
 		return conErr
 	}
 	log.Warnln(log.Wss, ws.Sign, ws.Wss.Id, "binance wss start reconnect")
-	for _, m := range ws.ReConnectMsg {
+	ws.rl.Lock()
+	msgs := append([]client.SubscribeData{}, ws.ReConnectMsg...)
+	ws.rl.Unlock()
+	for _, m := range msgs {
 		fun := *m.Action
 		ctx := m.Param
 		err := fun(ctx)
@@ -181,6 +244,6 @@ func (ws *FuturesClient) ReConnect(err error) error {
 
 func WssSymbol(ctx context.Context) string {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
-	symbol = unify.SymbolToB(symbol)
+	symbol = unify.SymbolToB(exch.Futures, symbol)
 	return strings.ToLower(symbol)
 }
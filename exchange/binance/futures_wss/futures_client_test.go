@@ -0,0 +1,122 @@
+package futures_wss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"high-freq-quant-go/adapter/client"
+	"high-freq-quant-go/core/exch"
+
+	"github.com/gorilla/websocket"
+	cmap "github.com/orcaman/concurrent-map"
+)
+
+// subServer 按 方法 stream 记录收到的订阅及取消订阅
+func subServer(t *testing.T) (*httptest.Server, chan string) {
+	msgs := make(chan string, 100)
+	up := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := up.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		for {
+			_, b, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg struct {
+				Method string   `json:"method"`
+				Params []string `json:"params"`
+			}
+			if err := json.Unmarshal(b, &msg); err != nil {
+				t.Errorf("bad msg %s", b)
+				return
+			}
+			for _, p := range msg.Params {
+				msgs <- msg.Method + " " + p
+			}
+		}
+	}))
+	return srv, msgs
+}
+
+// collect 读取 n 条消息并排序, 之后没有多余消息
+func collect(t *testing.T, msgs chan string, n int) []string {
+	var res []string
+	for len(res) < n {
+		select {
+		case m := <-msgs:
+			res = append(res, m)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("got %v want %d msgs", res, n)
+		}
+	}
+	select {
+	case m := <-msgs:
+		t.Fatalf("unexpected msg %s after %v", m, res)
+	case <-time.After(100 * time.Millisecond):
+	}
+	sort.Strings(res)
+	return res
+}
+
+// 取消订阅的交易对重连后不再订阅, 且删除该交易对的订单薄
+func TestUnsubscribeReconnect(t *testing.T) {
+	srv, msgs := subServer(t)
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q := make(exch.MsgQueue, 10)
+	cl := &FuturesClient{Sign: "test", Ctx: ctx, MsgQueue: &q}
+	wctx := context.WithValue(ctx, client.WssUrl, "ws"+strings.TrimPrefix(srv.URL, "http"))
+	wctx = context.WithValue(wctx, client.MsgLen, int64(10))
+	// 不启动读取协程, 由测试直接调用重连
+	cl.Wss = &client.WssSocket{Ctx: wctx, ErrorHandle: cl.ReConnect}
+	cl.Wss.Init()
+	if err := cl.Wss.Connent(); err != nil {
+		t.Fatal(err)
+	}
+	ws := &Futures{Sign: "test", Ctx: ctx, Client: cl, Bookers: cmap.New(), BookKinds: cmap.New(), PublicTrade: cmap.New()}
+	for _, symbol := range []string{"BTC_USDT", "ETH_USDT"} {
+		sctx := exch.WithSymbol(ctx, symbol)
+		if err := ws.SubOrderBook(sctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := ws.SubPublicTrades(sctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	collect(t, msgs, 4)
+
+	bctx := exch.WithSymbol(ctx, "BTC_USDT")
+	if err := ws.UnsubOrderBook(bctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.UnsubPublicTrades(bctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"UNSUBSCRIBE btcusdt@aggTrade", "UNSUBSCRIBE btcusdt@depth@100ms"}
+	if got := collect(t, msgs, 2); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("unsubscribe got %v", got)
+	}
+	if ws.Bookers.Has("BTC_USDT") || ws.PublicTrade.Has("BTC_USDT") || !ws.Bookers.Has("ETH_USDT") {
+		t.Fatal("BTC_USDT data not removed")
+	}
+
+	if err := cl.ReConnect(errors.New("closed")); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"SUBSCRIBE ethusdt@aggTrade", "SUBSCRIBE ethusdt@depth@100ms"}
+	if got := collect(t, msgs, 2); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("resubscribe got %v", got)
+	}
+}
@@ -8,8 +8,11 @@ import (
 
 	cmap "github.com/orcaman/concurrent-map"
 
+	"high-freq-quant-go/adapter/convert"
+	"high-freq-quant-go/adapter/queue"
 	"high-freq-quant-go/adapter/text"
 	"high-freq-quant-go/core/exch"
+	_ "high-freq-quant-go/core/exch/tbooker"
 	"high-freq-quant-go/core/log"
 	"high-freq-quant-go/exchange/binance/binanceapi"
 	"high-freq-quant-go/exchange/binance/futures_api"
@@ -22,38 +25,54 @@ type Futures struct {
 	//param
 	Ctx context.Context
 	//return data
-	Bookers  cmap.ConcurrentMap
-	BaseData cmap.ConcurrentMap
+	Bookers     cmap.ConcurrentMap
+	BookKinds   cmap.ConcurrentMap //交易对订单薄实现
+	BaseData    cmap.ConcurrentMap
+	PublicTrade cmap.ConcurrentMap //已订阅公开成交的交易对
 
 	//wss client
 	Client *FuturesClient
 
 	//struct msg chan
-	OrderBookQueue *chan *DepthEvent
-	BaseDataQueue  *chan interface{}
+	OrderBookQueue *queue.Queue //订单薄增量 exch.LaneDepth
+	BaseDataQueue  *queue.Queue //其他消息 exch.LaneUser
 
 	//struct msg to public
 	BookDataChannel *chan interface{}
 	BookMsgChan     *chan interface{}
+	Feed            *exch.Feed
+
+	bookData, bookMsg *queue.Queue
+
+	//订单薄异常检查及 REST 快照校验, 异常时通过 resync 在 OrderBookEvent 中重新拉取快照
+	Monitor  *exch.BookMonitor
+	Verifier *exch.BookVerifier
+	resync   chan string
 }
 
 func NewFutures(ctx context.Context) *Futures {
-	bq := make(chan *DepthEvent, exch.MsgChannelLen)
-	uq := make(chan interface{}, exch.MsgChannelLen)
 	sign := text.GetString(ctx, exch.ConnSign)
 	cl := &Futures{
 		Sign:           sign,
 		Ctx:            ctx,
 		Bookers:        cmap.New(),
+		BookKinds:      cmap.New(),
 		BaseData:       cmap.New(),
-		OrderBookQueue: &bq,
-		BaseDataQueue:  &uq,
+		PublicTrade:    cmap.New(),
+		OrderBookQueue: exch.NewLane(ctx, exch.LaneDepth, exch.MsgChannelLen),
+		BaseDataQueue:  exch.NewLane(ctx, exch.LaneUser, exch.MsgChannelLen),
+		Feed:           exch.GetFeed(ctx),
+		resync:         make(chan string, exch.MsgChannelLen),
 	}
 
 	cl.SetPubChannel()
+	cl.Monitor = exch.NewBookMonitor(ctx, cl.Sign, cl.Bookers, cl.Resync)
+	cl.Verifier = exch.NewBookVerifier(ctx, cl.Sign, cl.Bookers, cl.Snapshot, cl.Resync)
 	cl.Client = NewFuturesClient(ctx)
 	go cl.MsgEvent()
 	go cl.OrderBookEvent()
+	go cl.Monitor.Run()
+	go cl.Verifier.Run()
 	go cl.BaseDataEvent()
 	return cl
 }
@@ -70,10 +89,10 @@ func (ws *Futures) GetBaseinfo(ctx context.Context) *exch.BaseInfo {
 	return nil
 }
 
-func (ws *Futures) GetBook(ctx context.Context) *exch.Booker {
+func (ws *Futures) GetBook(ctx context.Context) exch.Booker {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
 	if res, ok := ws.Bookers.Get(symbol); ok {
-		return res.(*exch.Booker)
+		return res.(exch.Booker)
 	}
 	return nil
 }
@@ -81,9 +100,11 @@ func (ws *Futures) GetBook(ctx context.Context) *exch.Booker {
 func (ws *Futures) SetPubChannel() {
 	if ret, ok := ws.Ctx.Value(exch.BookDataChannel).(*chan interface{}); ok {
 		ws.BookDataChannel = ret
+		ws.bookData = exch.NewOutLane(ws.Ctx, exch.LaneBookData, *ret)
 	}
 	if ret, ok := ws.Ctx.Value(exch.BookMsgChan).(*chan interface{}); ok {
 		ws.BookMsgChan = ret
+		ws.bookMsg = exch.NewOutLane(ws.Ctx, exch.LaneBookMsg, *ret)
 	}
 }
 
@@ -95,7 +116,10 @@ func (ws *Futures) SubOrderBook(ctx context.Context) error {
 	if _, ok := ws.Bookers.Get(symbol); ok {
 		return nil
 	}
-	books := exch.NewBooker(ws.Ctx)
+	if kind := text.GetString(ctx, exch.CtxBooker); kind != "" {
+		ws.BookKinds.Set(symbol, kind)
+	}
+	books := exch.NewBooker(ws.bookCtx(symbol))
 	ws.Bookers.Set(symbol, books)
 	err := ws.Client.OrderBook(ctx)
 	return err
@@ -116,6 +140,52 @@ func (ws *Futures) SubTicker(ctx context.Context) error {
 	return err
 }
 
+func (ws *Futures) UnsubOrderBook(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if !ws.Bookers.Has(symbol) {
+		return nil
+	}
+	ws.Bookers.Remove(symbol)
+	ws.BookKinds.Remove(symbol)
+	return ws.Client.UnOrderBook(ctx)
+}
+
+func (ws *Futures) UnsubTicker(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if !ws.BaseData.Has(symbol) {
+		return nil
+	}
+	ws.BaseData.Remove(symbol)
+	if err := ws.Client.UnMarkPrice(ctx); err != nil {
+		return err
+	}
+	return ws.Client.UnTicker(ctx)
+}
+
+// SubPublicTrades 归集成交通过 Feed.OnPublicTrade 推送
+func (ws *Futures) SubPublicTrades(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if symbol == "" || ws.PublicTrade.Has(symbol) {
+		return nil
+	}
+	ws.PublicTrade.Set(symbol, true)
+	err := ws.Client.AggTrade(ctx)
+	if err != nil {
+		// 订阅失败时清除标记, 之后可以重新订阅
+		ws.PublicTrade.Remove(symbol)
+	}
+	return err
+}
+
+func (ws *Futures) UnsubPublicTrades(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if !ws.PublicTrade.Has(symbol) {
+		return nil
+	}
+	ws.PublicTrade.Remove(symbol)
+	return ws.Client.UnAggTrade(ctx)
+}
+
 func (ws *Futures) InitTicker(ctx context.Context) error {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
 	res, err := futures_api.NewBinanceApi(ws.Ctx).GetBaseInfo(symbol)
@@ -136,133 +206,193 @@ func (ws *Futures) MsgEvent() {
 			switch msg.(type) {
 			case *DepthEvent: // handle order book update
 				//m.DepthUpdateEventHandler(rtype)
-				*ws.OrderBookQueue <- msg.(*DepthEvent)
+				ws.OrderBookQueue.Push(msg.(*DepthEvent).Symbol, msg)
 			default:
-				*ws.BaseDataQueue <- msg
+				ws.BaseDataQueue.Push("", msg)
 			}
 		}
 	}
 }
 
+// bookCtx 订阅时可通过 exch.CtxBooker 为交易对指定订单薄实现, 如 tbooker.BookerKey
+func (ws *Futures) bookCtx(symbol string) context.Context {
+	ctx := exch.WithSymbol(ws.Ctx, symbol)
+	if kind, ok := ws.BookKinds.Get(symbol); ok {
+		ctx = context.WithValue(ctx, exch.CtxBooker, kind)
+	}
+	return ctx
+}
+
 func (ws *Futures) OrderBookEvent() {
 	for {
 		select {
 		case <-ws.Ctx.Done():
 			log.Warnln(log.Wss, ws.Sign, " Binance Futures OrderBookEvent return by done")
 			return
-		case msg := <-*ws.OrderBookQueue:
+		case symbol := <-ws.resync:
+			ws.InitOrderbook(symbol)
+			if booki, ok := ws.Bookers.Get(symbol); ok && ws.BookMsgChan != nil {
+				ws.bookMsg.Push(symbol, booki)
+			}
+		case m := <-ws.OrderBookQueue.C:
+			msg := m.(*DepthEvent)
 			//st := time.Now().UnixNano() / 1000000
-			symbol := unify.BToSymbol(msg.Symbol)
-			var book *exch.Booker
+			symbol := unify.BToSymbol(exch.Futures, msg.Symbol)
+			var book exch.Booker
 			if booki, ok := ws.Bookers.Get(symbol); !ok {
 				log.Warnln(log.Wss, ws.Sign, symbol, "no symbolbook")
 				continue
 			} else {
-				book = booki.(*exch.Booker)
+				book = booki.(exch.Booker)
 			}
-			if book.UpdateID == 0 || (book.IsReady && msg.PU != book.UpdateID) {
+			bm := book.Meta()
+			if bm.UpdateID == 0 || (bm.IsReady && msg.PU != bm.UpdateID) {
 				ws.InitOrderbook(symbol)
 				if booki, ok := ws.Bookers.Get(symbol); !ok {
 					continue
 				} else {
-					book = booki.(*exch.Booker)
+					book = booki.(exch.Booker)
 				}
+				bm = book.Meta()
 				if ws.BookMsgChan != nil {
-					*ws.BookMsgChan <- book
+					ws.bookMsg.Push(symbol, book)
 				}
 			}
 			if book.GetAskLen() == 0 || book.GetBidLen() == 0 {
 				log.Warnln(log.Wss, ws.Sign, symbol, "binance init Orderbook error asks bids:", book.GetAskLen(), book.GetBidLen())
 				continue
 			}
-			if msg.UpdateID < book.UpdateID {
+			if msg.UpdateID < bm.UpdateID {
 				continue
 			}
-			if !book.IsReady && msg.FirstUpdateID <= book.UpdateID && book.UpdateID <= msg.UpdateID {
+			if !bm.IsReady && msg.FirstUpdateID <= bm.UpdateID && bm.UpdateID <= msg.UpdateID {
 				log.Debugf(log.Global, "%s %s binance init Orderbook wss msg is isready id: %d \r\n", ws.Sign, symbol, msg.UpdateID)
-				book.IsReady = true
+				bm.IsReady = true
 			}
-			if !book.IsReady {
+			if !bm.IsReady {
 				continue
 			}
-			book.Rw.Lock()
+			in := unify.Instrument(exch.Futures, symbol)
+			bm.Rw.Lock()
 			var wg sync.WaitGroup
 			wg.Add(2)
-			//todo SHIB price quantity
-			go func(result []Bid, book *exch.Booker, wg *sync.WaitGroup) {
+			go func(result []Bid, book exch.Booker, wg *sync.WaitGroup) {
 				defer wg.Done()
 				for _, bid := range result {
-					quantity := unify.QuantityToFloat(book.Symbol, bid.Quantity)
-					price := unify.PriceToStr(book.Symbol, bid.Price)
+					quantity := in.SizeFromVenue(convert.GetFloat64(bid.Quantity))
+					price := in.PriceStr(bid.Price)
 					book.UpdateBid(price, quantity)
 				}
 			}(msg.Bids, book, &wg)
-			go func(result []Ask, book *exch.Booker, wg *sync.WaitGroup) {
+			go func(result []Ask, book exch.Booker, wg *sync.WaitGroup) {
 				defer wg.Done()
 				for _, ask := range result {
-					quantity := unify.QuantityToFloat(book.Symbol, ask.Quantity)
-					price := unify.PriceToStr(book.Symbol, ask.Price)
+					quantity := in.SizeFromVenue(convert.GetFloat64(ask.Quantity))
+					price := in.PriceStr(ask.Price)
 					book.UpdateAsk(price, quantity)
 				}
 			}(msg.Asks, book, &wg)
 			wg.Wait()
-			book.UpdateID = msg.UpdateID
-			book.ResponTime = msg.Time
-			book.UpdateTime = time.Now().UnixNano() / 1000000
-			book.Rw.Unlock()
+			bm.UpdateID = msg.UpdateID
+			bm.ResponTime = msg.Time
+			bm.UpdateTime = time.Now().UnixNano() / 1000000
+			bm.Rw.Unlock()
+			if bm.Hooked() {
+				// 合约增量按 pu 连续, U 可能跳号
+				u := &exch.BookUpdate{FirstID: msg.PU + 1, LastID: msg.UpdateID,
+					Asks: make(map[string]float64, len(msg.Asks)), Bids: make(map[string]float64, len(msg.Bids))}
+				for _, ask := range msg.Asks {
+					u.Asks[in.PriceStr(ask.Price)] = in.SizeFromVenue(convert.GetFloat64(ask.Quantity))
+				}
+				for _, bid := range msg.Bids {
+					u.Bids[in.PriceStr(bid.Price)] = in.SizeFromVenue(convert.GetFloat64(bid.Quantity))
+				}
+				bm.RunHooks(book, u)
+			}
 			if ws.BookMsgChan != nil {
-				*ws.BookMsgChan <- msg
+				ws.bookMsg.Push(symbol, msg)
 			}
 			if ws.BookDataChannel != nil {
-				*ws.BookDataChannel <- book
+				ws.bookData.Push(symbol, book)
 			}
+			ws.Feed.PubBook(symbol, book)
 		}
 	}
 }
 
+// Resync 请求 OrderBookEvent 重新拉取交易对快照
+func (ws *Futures) Resync(symbol string) {
+	select {
+	case ws.resync <- symbol:
+	default:
+	}
+}
+
 func (ws *Futures) InitOrderbook(symbol string) {
-	book := exch.NewBooker(ws.Ctx)
-	ctx := context.WithValue(context.Background(), exch.CtxSymbol, symbol)
-	ctx = context.WithValue(ctx, futures_api.OrderBookLimit, InitOrderBookLimit)
-	res, err := futures_api.NewBinanceApi(ws.Ctx).GetOrderBook(ctx)
+	snap, err := ws.Snapshot(symbol)
 	if err != nil {
 		log.Errorln(log.Global, ws.Sign, symbol, "binance InitOrderbook error ", err)
 		return
 	}
-	book.Symbol = symbol
-	book.IsReady = false
+	book := exch.NewBooker(ws.bookCtx(symbol))
+	bm := book.Meta()
+	bm.Symbol = symbol
+	bm.IsReady = false
+	api := futures_api.NewBinanceApi(ws.Ctx)
+	if info, err := api.GetBaseInfo(symbol); err == nil && info != nil {
+		book.SetScale(unify.PriceScale(exch.Futures, symbol, info))
+	}
+	book.SetBook(snap.Asks, snap.Bids)
+	bm.UpdateID = snap.UpdateID
+	bm.ResponTime = snap.Time
+	bm.UpdateTime = timer.MicNow()
+	ws.Bookers.Set(symbol, book)
+	log.Debugf(log.Wss, "%s %s binace init Orderbook success [ResponTime=%d] [UpdateTime=%d][bookName=%s][lastid=%d] \r\n", ws.Sign, symbol, bm.ResponTime, bm.UpdateTime, bm.Name, bm.UpdateID)
+}
+
+// Snapshot REST 深度快照, 供 BookVerifier 校验
+func (ws *Futures) Snapshot(symbol string) (*exch.BookSnapshot, error) {
+	ctx := context.WithValue(context.Background(), exch.CtxSymbol, symbol)
+	ctx = context.WithValue(ctx, futures_api.OrderBookLimit, InitOrderBookLimit)
+	api := futures_api.NewBinanceApi(ws.Ctx)
+	res, err := api.GetOrderBook(ctx)
+	if err != nil {
+		return nil, err
+	}
+	in := unify.Instrument(exch.Futures, symbol)
 	askMap, bidMap := map[string]float64{}, map[string]float64{}
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func(bids []binanceapi.Bid, wg *sync.WaitGroup) {
 		defer wg.Done()
 		for _, bid := range bids {
-			quantity := unify.QuantityToFloat(symbol, bid.Quantity)
+			quantity := in.SizeFromVenue(convert.GetFloat64(bid.Quantity))
 			if quantity == 0 {
 				continue
 			}
-			price := unify.PriceToStr(symbol, bid.Price)
+			price := in.PriceStr(bid.Price)
 			bidMap[price] = quantity
 		}
 	}(res.Bids, &wg)
 	go func(asks []binanceapi.Ask, wg *sync.WaitGroup) {
 		defer wg.Done()
 		for _, ask := range asks {
-			quantity := unify.QuantityToFloat(symbol, ask.Quantity)
+			quantity := in.SizeFromVenue(convert.GetFloat64(ask.Quantity))
 			if quantity == 0 {
 				continue
 			}
-			price := unify.PriceToStr(symbol, ask.Price)
+			price := in.PriceStr(ask.Price)
 			askMap[price] = quantity
 		}
 	}(res.Asks, &wg)
 	wg.Wait()
-	book.SetBook(askMap, bidMap)
-	book.UpdateID = res.LastUpdateID
-	book.ResponTime = res.Time
-	book.UpdateTime = timer.MicNow()
-	ws.Bookers.Set(symbol, book)
-	log.Debugf(log.Wss, "%s %s binace init Orderbook success [ResponTime=%d] [UpdateTime=%d][bookName=%s][lastid=%d] \r\n", ws.Sign, symbol, book.ResponTime, book.UpdateTime, book.Name, book.UpdateID)
+	return &exch.BookSnapshot{
+		Symbol:   symbol,
+		UpdateID: res.LastUpdateID,
+		Time:     res.Time,
+		Asks:     askMap,
+		Bids:     bidMap,
+	}, nil
 }
 
 func (ws *Futures) BaseDataEvent() {
@@ -271,13 +401,13 @@ func (ws *Futures) BaseDataEvent() {
 		case <-ws.Ctx.Done():
 			log.Warnln(log.Wss, ws.Sign, " Binance Futures BaseDataQueue return by done")
 			return
-		case msg := <-*ws.BaseDataQueue:
+		case msg := <-ws.BaseDataQueue.C:
 			switch msg.(type) {
 			case *MarkPriceEvent:
 				d := msg.(*MarkPriceEvent)
-				symbol := unify.BToSymbol(d.Symbol)
-				mprice := unify.PriceToFloat(symbol, d.MarkPrice)
-				iprice := unify.PriceToFloat(symbol, d.IndexPrice)
+				symbol := unify.BToSymbol(exch.Futures, d.Symbol)
+				mprice := unify.PriceToFloat(exch.Futures, symbol, d.MarkPrice)
+				iprice := unify.PriceToFloat(exch.Futures, symbol, d.IndexPrice)
 				if infoi, ok := ws.BaseData.Get(symbol); ok {
 					info := infoi.(*exch.BaseInfo)
 					info.Symbol = symbol
@@ -288,16 +418,50 @@ func (ws *Futures) BaseDataEvent() {
 				}
 			case *TickerEvent:
 				d := msg.(*TickerEvent)
-				symbol := unify.BToSymbol(d.Symbol)
+				symbol := unify.BToSymbol(exch.Futures, d.Symbol)
 				if infoi, ok := ws.BaseData.Get(symbol); ok {
 					info := infoi.(*exch.BaseInfo)
 					info.Symbol = symbol
 					info.ChangeRate = d.PriceChangePercent
 					info.DayVolume = d.QuoteVolume
 				}
+			case *AggTrade:
+				ws.UpdateTrades(msg.(*AggTrade))
 			default:
 				log.Errorln(log.Wss, ws.Sign, "------unknow BaseDataQueue msg type----", msg)
 			}
 		}
 	}
 }
+
+// UpdateTrades 买方为 maker 时主动方为卖方, 数量为负
+func (ws *Futures) UpdateTrades(d *AggTrade) {
+	symbol := unify.BToSymbol(exch.Futures, d.Symbol)
+	in := unify.Instrument(exch.Futures, symbol)
+	size := in.SizeFromVenue(convert.GetFloat64(d.Volume))
+	if d.Maker {
+		size = -size
+	}
+	ws.Feed.PubPublicTrade(&exch.Trade{
+		Symbol: symbol,
+		Id:     convert.GetString(d.CollectionId),
+		Price:  in.PriceFromVenue(convert.GetFloat64(d.Price)),
+		Size:   size,
+		Side:   exch.TakerSide(size),
+		Time:   d.VTime,
+	})
+}
+
+// QueueStats 原始消息, 订单薄, 其他消息及推送通道的积压及丢弃统计
+func (ws *Futures) QueueStats() []queue.Stat {
+	var stats []queue.Stat
+	if ws.Client.Wss != nil {
+		stats = append(stats, ws.Client.Wss.Stat())
+	}
+	for _, q := range []*queue.Queue{ws.OrderBookQueue, ws.BaseDataQueue, ws.bookData, ws.bookMsg} {
+		if q != nil {
+			stats = append(stats, q.Stat())
+		}
+	}
+	return stats
+}
@@ -12,6 +12,7 @@ import (
 
 	"high-freq-quant-go/adapter/client"
 	"high-freq-quant-go/adapter/convert"
+	"high-freq-quant-go/adapter/queue"
 	"high-freq-quant-go/adapter/text"
 	"high-freq-quant-go/adapter/timer"
 	"high-freq-quant-go/core/exch"
@@ -30,6 +31,7 @@ type UserWss struct {
 	//return data
 	TradeData    map[string]*chan *exch.Order
 	OrderData    map[string]map[string]*exch.Order
+	TriggerData  *exch.TriggerBook //未触发的条件单
 	PositionData cmap.ConcurrentMap
 	BalanceData  cmap.ConcurrentMap
 
@@ -51,6 +53,12 @@ type UserWss struct {
 
 	//reconnect
 	ReConnectMsg []client.SubscribeData
+	rl           sync.Mutex
+
+	//同一 ApiSign 的 Exchanger 共用连接, 推送给所有 Exchanger
+	Feeds exch.Feeds
+	//定时按 REST 校对仓位及资金, 推送丢失时更正
+	Reconciler *exch.Reconciler
 
 	lk, tdl, odl sync.RWMutex
 	pdl, bal     sync.Mutex
@@ -65,14 +73,19 @@ func NewFuturesUser(ctx context.Context) *UserWss {
 		Ctx:          ctx,
 		TradeData:    map[string]*chan *exch.Order{},
 		OrderData:    map[string]map[string]*exch.Order{},
+		TriggerData:  exch.NewTriggerBook(),
 		PositionData: cmap.New(),
 		BalanceData:  cmap.New(),
 	}
 	BinaceUserWss.lock.Lock()
 	defer BinaceUserWss.lock.Unlock()
 	if fu, ok := BinaceUserWss.Wss[apiSign]; ok {
+		fu.Feeds.Add(exch.GetFeed(ctx))
 		return fu
 	}
+	wss.Feeds.Add(exch.GetFeed(ctx))
+	wss.Reconciler = exch.NewReconciler(ctx, wss.Sign, futures_api.NewBinanceApi(ctx), wss.PositionData, wss.BalanceData, &wss.Feeds)
+	go wss.Reconciler.Run()
 	BinaceUserWss.Wss[apiSign] = wss
 	return wss
 }
@@ -91,18 +104,44 @@ func (ws *UserWss) GetOrder(ctx context.Context) map[string]*exch.Order {
 	return nil
 }
 
+func (ws *UserWss) GetTriggerOrder(ctx context.Context) map[string]*exch.Order {
+	return ws.TriggerData.Get(text.GetString(ctx, exch.CtxSymbol))
+}
+
+// GetPosition 双向持仓时返回 ctx 中持仓方向的仓位, 未指定时返回两个方向合并的净仓位
 func (ws *UserWss) GetPosition(ctx context.Context) *exch.Position {
 	if ws.PositionData.Count() == 0 {
 		return nil
 	}
 	symbol := text.GetString(ctx, exch.CtxSymbol)
+	side := exch.PositionSide(ctx)
+	if exch.HedgeMode(ws.Ctx) && side == "" {
+		var list []*exch.Position
+		for _, key := range exch.PositionKeys(symbol, true) {
+			if posi, ok := ws.PositionData.Get(key); ok {
+				list = append(list, posi.(*exch.Position))
+			}
+		}
+		return exch.NetPosition(symbol, list...)
+	}
 	pos := &exch.Position{}
-	if posi, ok := ws.PositionData.Get(symbol); ok {
+	if posi, ok := ws.PositionData.Get(exch.PositionKey(symbol, side)); ok {
 		pos = posi.(*exch.Position)
 	}
 	return pos
 }
 
+// initPosition 交易对没有仓位时按持仓模式设置空仓位
+func (ws *UserWss) initPosition(symbol string) {
+	hedge := exch.HedgeMode(ws.Ctx)
+	for _, key := range exch.PositionKeys(symbol, hedge) {
+		if _, ok := ws.PositionData.Get(key); !ok {
+			_, side := exch.SplitPositionKey(key)
+			ws.PositionData.Set(key, &exch.Position{Symbol: symbol, PositionMode: side})
+		}
+	}
+}
+
 func (ws *UserWss) GetBalance(ctx context.Context) *exch.Balance {
 	if ws.BalanceData.Count() == 0 {
 		return nil
@@ -152,6 +191,7 @@ func (ws *UserWss) NewClient(ListenKey string) error {
 	ctx = context.WithValue(ctx, client.Keepalive, true)
 	ctx = context.WithValue(ctx, client.Timeout, WssTimeout)
 	ctx = context.WithValue(ctx, client.MsgLen, exch.WSChannelLen)
+	ctx = context.WithValue(ctx, client.Overflow, exch.GetOverflow(ws.Ctx, exch.LaneUser))
 	ctx = context.WithValue(ctx, client.Id, ws.Sign)
 	pUrl := text.GetString(ws.Ctx, client.ProxyUrl)
 	ctx = context.WithValue(ctx, client.ProxyUrl, pUrl)
@@ -187,7 +227,7 @@ func (ws *UserWss) SubOrder(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	ws.RegisterMsg(ws.InitOrder, ctx)
+	ws.RegisterMsg(client.SubscribeKey(orderKey, symbol), ws.InitOrder, ctx)
 	err = ws.Create()
 	return err
 }
@@ -212,9 +252,7 @@ func (ws *UserWss) SubPosition(ctx context.Context) error {
 	defer ws.pdl.Unlock()
 	symbol := text.GetString(ctx, exch.CtxSymbol)
 	if ws.PositionData.Count() != 0 {
-		if _, ok := ws.PositionData.Get(symbol); !ok {
-			ws.PositionData.Set(symbol, &exch.Position{})
-		}
+		ws.initPosition(symbol)
 		return nil
 	}
 	err := ws.InitPosition(ctx)
@@ -222,7 +260,7 @@ func (ws *UserWss) SubPosition(ctx context.Context) error {
 		log.Errorln(log.Wss, ws.Sign, symbol, "binance user wss SubPosition error ", err)
 		return err
 	}
-	ws.RegisterMsg(ws.InitPosition, ctx)
+	ws.RegisterMsg(positionKey, ws.InitPosition, ctx)
 	err = ws.Create()
 	return err
 }
@@ -246,7 +284,7 @@ func (ws *UserWss) SubBalance(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	ws.RegisterMsg(ws.InitBalance, ctx)
+	ws.RegisterMsg(balanceKey, ws.InitBalance, ctx)
 	err = ws.Create()
 	return err
 }
@@ -275,7 +313,7 @@ func (ws *UserWss) InitBalance(ctx context.Context) error {
 
 func (ws *UserWss) InitOrder(ctx context.Context) error {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
-	res, err := futures_api.NewBinanceApi(ws.Ctx).GetOrder(ctx)
+	res, triggers, err := futures_api.NewBinanceApi(ws.Ctx).OpenOrders(ctx)
 	if err != nil {
 		log.Errorln(log.Wss, ws.Sign, "binance user wss InitOrder error ", err)
 		return err
@@ -283,6 +321,7 @@ func (ws *UserWss) InitOrder(ctx context.Context) error {
 	ws.odl.Lock()
 	ws.OrderData[symbol] = res
 	ws.odl.Unlock()
+	ws.TriggerData.Reset(symbol, triggers)
 	log.Infof(log.Wss, "%s %s binance user wss InitOrder %+v \r\n", ws.Sign, symbol, ws.OrderData[symbol])
 	return nil
 }
@@ -297,10 +336,7 @@ func (ws *UserWss) InitPosition(ctx context.Context) error {
 	for k, v := range res {
 		ws.PositionData.Set(k, v)
 	}
-	pos := &exch.Position{}
-	if _, ok := ws.PositionData.Get(symbol); !ok {
-		ws.PositionData.Set(symbol, pos)
-	}
+	ws.initPosition(symbol)
 	log.Infof(log.Wss, "%s %s binance user wss InitPosition success %+v \r\n", ws.Sign, symbol, res)
 	return nil
 }
@@ -362,16 +398,18 @@ func (ws *UserWss) AccountUpdate(data futures.WsUserDataEvent) {
 			Avative: convert.GetFloat64(res.CrossWalletBalance),
 		}
 		assets[res.Asset] = &ba
+		ws.Feeds.PubBalance(&ba)
 	}
 	ws.BalanceData.MSet(assets)
 	log.Debugf(log.Wss, " %s binance user wss AccountUpdate Balances %+v \r\n", ws.Sign, ws.BalanceData)
 	//todo
 	for _, res := range o.Positions {
-		symbol := unify.BToSymbol(res.Symbol)
-		size := unify.QuantityToFloat(symbol, res.Amount)
+		symbol := unify.BToSymbol(exch.Futures, res.Symbol)
+		side := unify.PosMap[res.Side]
+		size := unify.QuantityToFloat(exch.Futures, symbol, res.Amount)
 		price := convert.GetFloat64(res.EntryPrice)
 		mprice := convert.GetFloat64(res.MarkPrice)
-		mprice = unify.PriceToFloat(symbol, mprice)
+		mprice = unify.PriceToFloat(exch.Futures, symbol, mprice)
 		pos := &exch.Position{
 			Symbol:         symbol,
 			Price:          price,
@@ -379,33 +417,40 @@ func (ws *UserWss) AccountUpdate(data futures.WsUserDataEvent) {
 			Margin:         convert.GetFloat64(res.IsolatedWallet),
 			UnPnl:          convert.GetFloat64(res.UnrealizedPnL),
 			MarkPrice:      mprice,
+			PositionMode:   side,
 			LastUpdateTime: data.Time,
 		}
-		if posi, ok := ws.PositionData.Get(symbol); ok {
+		//双向持仓时按 交易对:方向 保存
+		key := exch.PositionKey(symbol, side)
+		if posi, ok := ws.PositionData.Get(key); ok {
 			opos := posi.(*exch.Position)
 			pos.Lv = opos.Lv
 			pos.MarginType = opos.MarginType
-			pos.PositionMode = opos.PositionMode
+			if pos.PositionMode == "" {
+				pos.PositionMode = opos.PositionMode
+			}
 		}
-		ws.PositionData.Set(symbol, pos)
+		ws.PositionData.Set(key, pos)
+		ws.Feeds.PubPosition(pos)
 		log.Infof(log.Wss, "%s %s binance user wss  AccountUpdate Positions %+v \r\n", ws.Sign, symbol, pos)
 	}
 }
 
 func (ws *UserWss) OrderTradeUpdate(data futures.WsUserDataEvent) {
 	o := data.OrderTradeUpdate
-	symbol := unify.BToSymbol(o.Symbol)
+	symbol := unify.BToSymbol(exch.Futures, o.Symbol)
 	status := unify.UnifyOrderStatus[o.Status]
-	size := unify.QuantityToFloat(symbol, o.OriginalQty)
-	tsize := unify.QuantityToFloat(symbol, o.LastFilledQty)
-	lsize := size - unify.QuantityToFloat(symbol, o.AccumulatedFilledQty)
+	size := unify.QuantityToFloat(exch.Futures, symbol, o.OriginalQty)
+	tsize := unify.QuantityToFloat(exch.Futures, symbol, o.LastFilledQty)
+	fsize := unify.QuantityToFloat(exch.Futures, symbol, o.AccumulatedFilledQty)
+	lsize := size - fsize
 	lsize = math.Abs(lsize)
 	if o.Side == futures.SideTypeSell {
 		size = -size
 		tsize = -tsize
 	}
-	price := convert.GetFloat64(unify.PriceToStr(symbol, o.OriginalPrice))
-	fprice := convert.GetFloat64(unify.PriceToStr(symbol, o.AveragePrice))
+	price := convert.GetFloat64(unify.PriceToStr(exch.Futures, symbol, o.OriginalPrice))
+	fprice := convert.GetFloat64(unify.PriceToStr(exch.Futures, symbol, o.AveragePrice))
 	role := exch.OrderTaker
 	if o.IsMaker {
 		role = exch.OrderMaker
@@ -419,31 +464,60 @@ func (ws *UserWss) OrderTradeUpdate(data futures.WsUserDataEvent) {
 		Price:      price,
 		FillPrice:  fprice,
 		Left:       lsize,
+		FilledSize: fsize,
+		AvgPrice:   fprice,
 		Role:       role,
 		Tif:        unify.UnifyOrderType[o.TimeInForce],
 		CreateTime: o.TradeTime,
 		UpdateTime: o.TradeTime,
+
+		ReduceOnly: o.IsReduceOnly,
+		PostOnly:   o.TimeInForce == futures.TimeInForceTypeGTX,
+	}
+	if exch.HedgeMode(ws.Ctx) {
+		or.PositionSide = unify.PosMap[o.PositionSide]
+	}
+	//条件单未触发前单独保存, 触发后订单Id不变, 类型变为 LIMIT 或 MARKET 按普通委托单处理
+	if unify.IsTrigger(o.Type) {
+		or.State = unify.UnifyOrderState[o.Status]
+		unify.SetTrigger(&or, o.Type, o.StopPrice, o.WorkingType, o.IsClosingPosition)
+		ws.TriggerData.Update(&or)
+		log.Infof(log.Wss, "%s %s binance user wss OrderTradeUpdate trigger order %+v \r\n", ws.Sign, symbol, or)
+		return
+	}
+	if unify.IsTrigger(o.OriginalType) {
+		ws.TriggerData.Remove(symbol, or.Id)
 	}
 	ws.odl.Lock()
 	if _, ok := ws.OrderData[symbol]; !ok {
 		ws.OrderData[symbol] = map[string]*exch.Order{}
 	}
+	if old, ok := ws.OrderData[symbol][or.Id]; ok {
+		or.State = old.State
+	}
+	if err := or.SetState(unify.UnifyOrderState[o.Status]); err != nil {
+		ws.odl.Unlock()
+		log.Warnln(log.Wss, ws.Sign, symbol, "binance user wss OrderTradeUpdate skip", err)
+		return
+	}
 	ws.OrderData[symbol][or.Id] = &or
 	if or.Status == exch.OrderFinished {
 		delete(ws.OrderData[symbol], or.Id)
 	}
 	ws.odl.Unlock()
-	log.Debugf(log.Wss, "%s $s binance user wss  OrderTradeUpdate result %+v \r\n", ws.Sign, symbol, ws.OrderData)
+	ws.Feeds.PubOrder(&or)
+	log.Debugf(log.Wss, "%s %s binance user wss  OrderTradeUpdate result %+v \r\n", ws.Sign, symbol, ws.OrderData)
 
 	//只要成交 不要下单
 	if o.ExecutionType == futures.OrderExecutionTypeTrade {
 		log.Infof(log.Global, "%s %s binance user wss OrderTradeUpdate old order %+v \r\n", ws.Sign, symbol, o)
-		tprice := convert.GetFloat64(unify.PriceToStr(symbol, o.LastFilledPrice))
+		tprice := convert.GetFloat64(unify.PriceToStr(exch.Futures, symbol, o.LastFilledPrice))
 		to := exch.Order{
 			Id:         convert.GetString(o.ID),
 			UUID:       o.ClientOrderID,
 			Symbol:     symbol,
 			Status:     status,
+			State:      or.State,
 			Size:       tsize,
 			Price:      tprice,
 			Role:       role,
@@ -456,21 +530,68 @@ func (ws *UserWss) OrderTradeUpdate(data futures.WsUserDataEvent) {
 			*ws.TradeData[symbol] <- &to
 		}
 		ws.tdl.Unlock()
+		ws.Feeds.PubTrade(&to)
 		log.Infof(log.Global, "%s %s binance user wss OrderTradeUpdate push order %+v \r\n", ws.Sign, symbol, or)
 	}
 }
 
-func (ws *UserWss) RegisterMsg(action func(ctx context.Context) error, param context.Context) {
+// 重连列表 Key, 用户数据流不区分交易对, 只有订单按交易对初始化
+const (
+	orderKey    = "order"
+	positionKey = "position"
+	balanceKey  = "balance"
+)
+
+// RegisterMsg 记录重连后需要重新初始化的数据, 相同 Key 只保留最新一条
+func (ws *UserWss) RegisterMsg(key string, action func(ctx context.Context) error, param context.Context) {
 	if text.GetBool(param, client.IsReconnect) {
 		return
 	}
 	param = context.WithValue(param, client.IsReconnect, true)
 	method := client.SubscribeData{
+		Key:    key,
 		Action: &action,
 		Param:  param,
 	}
-	ws.ReConnectMsg = append(ws.ReConnectMsg, method)
-	return
+	ws.rl.Lock()
+	ws.ReConnectMsg = append(client.RemoveSubscribe(ws.ReConnectMsg, key), method)
+	ws.rl.Unlock()
+}
+
+func (ws *UserWss) unregister(key string) {
+	ws.rl.Lock()
+	ws.ReConnectMsg = client.RemoveSubscribe(ws.ReConnectMsg, key)
+	ws.rl.Unlock()
+}
+
+// UnsubOrder 用户数据流仍保持连接, 只删除本地订单及重连初始化
+func (ws *UserWss) UnsubOrder(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	ws.unregister(client.SubscribeKey(orderKey, symbol))
+	ws.odl.Lock()
+	delete(ws.OrderData, symbol)
+	ws.odl.Unlock()
+	ws.TriggerData.Delete(symbol)
+	return nil
+}
+
+func (ws *UserWss) UnsubUserTrade(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	ws.tdl.Lock()
+	delete(ws.TradeData, symbol)
+	ws.tdl.Unlock()
+	return nil
+}
+
+// UnsubPosition 仓位初始化拉取账户全部仓位, 由所有交易对共用, 只删除本地仓位
+func (ws *UserWss) UnsubPosition(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	ws.pdl.Lock()
+	for _, key := range exch.PositionKeys(symbol, exch.HedgeMode(ws.Ctx)) {
+		ws.PositionData.Remove(key)
+	}
+	ws.pdl.Unlock()
+	return nil
 }
 
 func (ws *UserWss) ReConnect(err error) error {
@@ -484,7 +605,10 @@ func (ws *UserWss) ReConnect(err error) error {
 		return conErr
 	}
 	log.Warnln(log.Wss, ws.Sign, "binance user wss start reconnect")
-	for _, m := range ws.ReConnectMsg {
+	ws.rl.Lock()
+	msgs := append([]client.SubscribeData{}, ws.ReConnectMsg...)
+	ws.rl.Unlock()
+	for _, m := range msgs {
 		fun := *m.Action
 		ctx := m.Param
 		nerr := fun(ctx)
@@ -501,3 +625,11 @@ func (ws *UserWss) ReConnect(err error) error {
 	ws.ConnectTime = timer.MicNow()
 	return nil
 }
+
+// QueueStats 私有连接只有原始消息队列
+func (ws *UserWss) QueueStats() []queue.Stat {
+	if ws.Wss == nil {
+		return nil
+	}
+	return []queue.Stat{ws.Wss.Stat()}
+}
@@ -0,0 +1,47 @@
+package futures_wss
+
+import (
+	"context"
+	"testing"
+
+	"high-freq-quant-go/core/exch"
+
+	cmap "github.com/orcaman/concurrent-map"
+)
+
+// 双向持仓的多空仓位分开保存, 只推送一个方向时另一方向不变
+func TestAccountUpdateHedge(t *testing.T) {
+	ws := &UserWss{
+		ApiSign:      "test",
+		Sign:         "test",
+		Ctx:          exch.WithHedgeMode(context.Background(), true),
+		PositionData: cmap.New(),
+		BalanceData:  cmap.New(),
+	}
+	// 查询接口返回的杠杆及保证金模式, 推送中没有时保留
+	ws.PositionData.Set(exch.PositionKey("BTC_USDT", exch.PositionLong),
+		&exch.Position{Symbol: "BTC_USDT", PositionMode: exch.PositionLong, Lv: 10, MarginType: exch.MarginCrossed})
+	msg := []byte(`{"e":"ACCOUNT_UPDATE","E":1564745798939,"T":1564745798938,"a":{"m":"ORDER",` +
+		`"B":[{"a":"USDT","wb":"122624.12","cw":"100.12","bc":"0"}],"P":[` +
+		`{"s":"BTCUSDT","pa":"5","ep":"61000","cr":"0","up":"0","mt":"cross","iw":"0","ps":"LONG"},` +
+		`{"s":"BTCUSDT","pa":"-3","ep":"62000","cr":"0","up":"0","mt":"cross","iw":"0","ps":"SHORT"}]}}`)
+	ws.ReadUserMessage(&msg)
+	msg = []byte(`{"e":"ACCOUNT_UPDATE","E":1564745799939,"T":1564745799938,"a":{"m":"ORDER","B":[],"P":[` +
+		`{"s":"BTCUSDT","pa":"4","ep":"61000","cr":"0","up":"0","mt":"cross","iw":"0","ps":"LONG"}]}}`)
+	ws.ReadUserMessage(&msg)
+	if ws.PositionData.Count() != 2 {
+		t.Fatalf("positions got %v", ws.PositionData.Keys())
+	}
+	ctx := exch.WithSymbol(context.Background(), "BTC_USDT")
+	long := ws.GetPosition(exch.WithPositionSide(ctx, exch.PositionLong))
+	short := ws.GetPosition(exch.WithPositionSide(ctx, exch.PositionShort))
+	if long.Size != 4 || long.PositionMode != exch.PositionLong || long.Price != 61000 || long.Lv != 10 {
+		t.Fatalf("long got %+v", long)
+	}
+	if short.Size != -3 || short.PositionMode != exch.PositionShort || short.Price != 62000 {
+		t.Fatalf("short got %+v", short)
+	}
+	if net := ws.GetPosition(ctx); net.Size != 1 {
+		t.Fatalf("net got %+v", net)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"high-freq-quant-go/exchange/binance/spot_api"
 	"high-freq-quant-go/exchange/binance/spot_wss"
 
+	"high-freq-quant-go/adapter/queue"
 	"high-freq-quant-go/adapter/text"
 	"high-freq-quant-go/core/exch"
 )
@@ -19,6 +20,7 @@ type SpotClient struct {
 	Api     *spot_api.BinaceSpotApi
 	PubWss  *spot_wss.Futures
 	PriWss  *spot_wss.UserWss
+	exch.Legacy
 
 	Exchange, Extype string
 
@@ -34,6 +36,7 @@ func NewSpotClient(ctx context.Context) exch.Exchange {
 		Extype:   exch.Spot,
 	}
 	ft.Api = spot_api.NewBinanceApi(ctx)
+	ft.Legacy = exch.NewLegacy(ft)
 	return ft
 }
 
@@ -77,6 +80,17 @@ func (mk *SpotClient) GetStatus() int {
 	return 1
 }
 
+func (mk *SpotClient) QueueStats() []queue.Stat {
+	var stats []queue.Stat
+	if mk.PubWss != nil {
+		stats = append(stats, mk.PubWss.QueueStats()...)
+	}
+	if mk.PriWss != nil {
+		stats = append(stats, mk.PriWss.QueueStats()...)
+	}
+	return stats
+}
+
 func (mk *SpotClient) GetBaseInfo(ctx context.Context) *exch.BaseInfo {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
 	http := text.GetBool(ctx, exch.CtxHttp)
@@ -124,7 +138,12 @@ func (mk *SpotClient) GetOrder(ctx context.Context) map[string]*exch.Order {
 	return res
 }
 
-func (mk *SpotClient) GetOrderBook(ctx context.Context) *exch.Booker {
+// GetTriggerOrder 现货暂不支持条件单
+func (mk *SpotClient) GetTriggerOrder(ctx context.Context) map[string]*exch.Order {
+	return map[string]*exch.Order{}
+}
+
+func (mk *SpotClient) GetOrderBook(ctx context.Context) exch.Booker {
 	book := mk.PubWss.GetBook(ctx)
 	return book
 }
@@ -145,74 +164,209 @@ func (mk *SpotClient) ListAsset(ctx context.Context) (*exch.Balance, map[string]
 	return pos, res
 }
 
-func (mk *SpotClient) CreateOrder(ctx context.Context) (*exch.Order, error) {
-	res, err := mk.Api.CreateOrder(ctx)
-	return res, err
+// PlaceOrder 现货没有仓位方向, ReduceOnly 不需要处理, 不支持条件单及 ClosePosition
+func (mk *SpotClient) PlaceOrder(ctx context.Context, req *exch.OrderRequest) (*exch.Order, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if req.TriggerPrice > 0 || req.ClosePosition {
+		return nil, exch.ErrNotSupported
+	}
+	return mk.Api.CreateOrder(ctx, req.Order())
+}
+
+func (mk *SpotClient) PlaceBatchOrder(ctx context.Context, reqs []*exch.OrderRequest) ([]*exch.Order, error) {
+	if len(reqs) == 0 {
+		return nil, exch.ErrEmptyOrder
+	}
+	lists := make([]*exch.Order, 0, len(reqs))
+	for _, req := range reqs {
+		if err := req.Validate(); err != nil {
+			return nil, err
+		}
+		if req.TriggerPrice > 0 || req.ClosePosition {
+			return nil, exch.ErrNotSupported
+		}
+		lists = append(lists, req.Order())
+	}
+	return mk.Api.CreateBatchOrder(ctx, lists)
+}
+
+func (mk *SpotClient) CancelOrder(ctx context.Context, req *exch.CancelRequest) (*exch.Order, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return mk.Api.CannelOrder(ctx, req.Order())
+}
+
+func (mk *SpotClient) CancelAllOrder(ctx context.Context, symbol string) ([]*exch.Order, error) {
+	if symbol == "" {
+		return nil, exch.ErrEmptySymbol
+	}
+	return mk.Api.CannelAllOrder(ctx, symbol)
 }
 
-func (mk *SpotClient) CreateBatchOrder(ctx context.Context) ([]*exch.Order, error) {
-	res, err := mk.Api.CreateBatchOrder(ctx)
-	return res, err
+// ListOpenOrders REST 查询账号所有交易对的挂单, 紧急停止以此确认撤单
+func (mk *SpotClient) ListOpenOrders(ctx context.Context) ([]*exch.Order, error) {
+	return mk.Api.ListOpenOrders(ctx)
 }
 
-func (mk *SpotClient) CannelOrder(ctx context.Context) (*exch.Order, error) {
-	res, err := mk.Api.CannelOrder(ctx)
-	return res, err
+// AmendOrder 现货不支持改单, 撤单后重新下单
+func (mk *SpotClient) AmendOrder(ctx context.Context, req *exch.AmendRequest) (*exch.AmendResult, error) {
+	return exch.EmulateAmend(ctx, mk, req)
 }
 
-func (mk *SpotClient) CannelAllOrder(ctx context.Context) ([]*exch.Order, error) {
-	res, err := mk.Api.CannelAllOrder(ctx)
-	return res, err
+func (mk *SpotClient) SetLeverage(ctx context.Context, symbol string, lv float64) (*exch.Position, error) {
+	return nil, exch.ErrNotSupported
 }
 
-func (mk *SpotClient) UpdateLeverage(ctx context.Context) (*exch.Position, error) {
-	return nil, nil
+func (mk *SpotClient) SetMargin(ctx context.Context, symbol string, change float64) (*exch.Position, error) {
+	return nil, exch.ErrNotSupported
 }
 
-func (mk *SpotClient) UpdateMargin(ctx context.Context) (*exch.Position, error) {
-	return nil, nil
+func (mk *SpotClient) SetMarginMode(ctx context.Context, symbol, mode string) error {
+	return exch.ErrNotSupported
 }
 
-func (mk *SpotClient) SubTicker(ctx context.Context) error {
+func (mk *SpotClient) SetPositionMode(ctx context.Context, hedge bool) error {
+	return exch.ErrNotSupported
+}
+
+func (mk *SpotClient) SetRiskLimit(ctx context.Context, symbol string, limit float64) error {
+	return exch.ErrNotSupported
+}
+
+func (mk *SpotClient) FetchPosition(ctx context.Context, symbol string) (*exch.Position, error) {
+	return nil, exch.ErrNotSupported
+}
+
+func (mk *SpotClient) SubscribeTicker(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	err := mk.StartWss()
 	if err != nil {
 		return err
 	}
-	mk.PubWss.SubTicker(ctx)
+	mk.PubWss.SubTicker(exch.WithSymbol(ctx, symbol))
 	return nil
 }
-func (mk *SpotClient) SubOrderBook(ctx context.Context) error {
+
+func (mk *SpotClient) SubscribeOrderBook(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	err := mk.StartWss()
 	if err != nil {
 		return err
 	}
-	mk.PubWss.SubOrderBook(ctx)
+	mk.PubWss.SubOrderBook(exch.WithSymbol(ctx, symbol))
 	return nil
 }
 
+func (mk *SpotClient) SubscribePublicTrades(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	err := mk.StartWss()
+	if err != nil {
+		return err
+	}
+	return mk.PubWss.SubPublicTrades(exch.WithSymbol(ctx, symbol))
+}
+
 func (mk *SpotClient) GetTradeChan(ctx context.Context) *chan *exch.Order {
 	mk.StartUser()
 	return mk.PriWss.GetTradeChan(ctx)
 }
 
-func (mk *SpotClient) SubUserTrade(ctx context.Context) error {
+func (mk *SpotClient) SubscribeUserTrade(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	mk.StartUser()
-	return mk.PriWss.SubUserTrade(ctx)
+	return mk.PriWss.SubUserTrade(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *SpotClient) SubPosition(ctx context.Context) error {
+func (mk *SpotClient) SubscribePosition(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	mk.StartUser()
-	return mk.PriWss.SubPosition(ctx)
+	return mk.PriWss.SubPosition(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *SpotClient) SubBalance(ctx context.Context) error {
+func (mk *SpotClient) SubscribeBalance(ctx context.Context, symbol string) error {
 	mk.StartUser()
-	return mk.PriWss.SubBalance(ctx)
+	return mk.PriWss.SubBalance(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *SpotClient) SubOrder(ctx context.Context) error {
+func (mk *SpotClient) SubscribeOrder(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	mk.StartUser()
-	return mk.PriWss.SubOrder(ctx)
+	return mk.PriWss.SubOrder(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *SpotClient) UnsubscribeTicker(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.PubWss == nil {
+		return nil
+	}
+	return mk.PubWss.UnsubTicker(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *SpotClient) UnsubscribeOrderBook(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.PubWss == nil {
+		return nil
+	}
+	return mk.PubWss.UnsubOrderBook(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *SpotClient) UnsubscribePublicTrades(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.PubWss == nil {
+		return nil
+	}
+	return mk.PubWss.UnsubPublicTrades(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *SpotClient) UnsubscribeUserTrade(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.PriWss == nil {
+		return nil
+	}
+	return mk.PriWss.UnsubUserTrade(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *SpotClient) UnsubscribePosition(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.PriWss == nil {
+		return nil
+	}
+	return mk.PriWss.UnsubPosition(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *SpotClient) UnsubscribeOrder(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.PriWss == nil {
+		return nil
+	}
+	return mk.PriWss.UnsubOrder(exch.WithSymbol(ctx, symbol))
 }
 
 func init() {
@@ -34,25 +34,24 @@ func NewBinanceApi(ctx context.Context) *BinaceSpotApi {
 	return gf
 }
 
-func (bs *BinaceSpotApi) CreateOrder(ctx context.Context) (*exch.Order, error) {
-	o := exch.GetOrder(ctx)
+func (bs *BinaceSpotApi) CreateOrder(ctx context.Context, o *exch.Order) (*exch.Order, error) {
 	if o == nil {
 		log.Errorln(log.Http, bs.Api.ApiSign, "BinaceSpotApi CreateOrder get order error ")
-		return nil, nil
+		return nil, exch.ErrEmptyOrder
 	}
 	client := bs.Api.GetClient()
 	service := bs.CreateOrderService(client, o)
 	if service == nil {
-		return nil, nil
+		return nil, exch.ErrInvalidOrder
 	}
-	res, err := service.Do(context.Background())
+	res, err := service.Do(ctx)
 	if err != nil {
 		log.Errorln(log.Http, bs.Api.ApiSign, "BinaceSpotApi CreateOrder error ", err)
-		return nil, err
+		return nil, unify.OrderError(err, o.Symbol)
 	}
-	size := unify.QuantityToFloat(o.Symbol, res.ExecutedQuantity)
-	lsize := unify.QuantityToFloat(o.Symbol, res.OrigQuantity) - size
-	price := convert.GetFloat64(unify.PriceToStr(o.Symbol, res.Price))
+	size := unify.QuantityToFloat(exch.Spot, o.Symbol, res.ExecutedQuantity)
+	lsize := unify.QuantityToFloat(exch.Spot, o.Symbol, res.OrigQuantity) - size
+	price := convert.GetFloat64(unify.PriceToStr(exch.Spot, o.Symbol, res.Price))
 	if res.Side == binanceapi.SideTypeSell {
 		size = -size
 	}
@@ -70,47 +69,56 @@ func (bs *BinaceSpotApi) CreateOrder(ctx context.Context) (*exch.Order, error) {
 	return or, nil
 }
 
-func (bs *BinaceSpotApi) CreateBatchOrder(ctx context.Context) ([]*exch.Order, error) {
-	lists := exch.GetOrders(ctx)
-	if lists == nil || len(lists) == 0 {
+func (bs *BinaceSpotApi) CreateBatchOrder(ctx context.Context, lists []*exch.Order) ([]*exch.Order, error) {
+	if len(lists) == 0 {
 		log.Errorln(log.Http, bs.Api.ApiSign, "BinaceSpotApi  CreateBatchOrder GetOrders error ", lists)
-		return nil, nil
+		return nil, exch.ErrEmptyOrder
 	}
 	orders := []*exch.Order{}
 	var wg sync.WaitGroup
+	var mu sync.Mutex
 	for _, o := range lists {
 		wg.Add(1)
-		go func(wg *sync.WaitGroup, o *exch.Order, orders []*exch.Order) {
+		go func(wg *sync.WaitGroup, o *exch.Order) {
 			defer wg.Done()
-			octx := context.WithValue(context.Background(), exch.CtxOrder, o)
-			order, err := bs.CreateOrder(octx)
+			order, err := bs.CreateOrder(ctx, o)
 			if err != nil {
-				log.Errorf(log.Http, "%s BinaceSpotApi CreateBatchOrder CreateOrder error %+v %s \r\n", bs.Api.ApiSign, err)
+				log.Errorf(log.Http, "%s BinaceSpotApi CreateBatchOrder CreateOrder error %+v %s \r\n", bs.Api.ApiSign, o, err)
 				return
 			}
+			mu.Lock()
 			orders = append(orders, order)
-		}(&wg, o, orders)
+			mu.Unlock()
+		}(&wg, o)
 	}
 	wg.Wait()
 	return orders, nil
 }
 
 func (bs *BinaceSpotApi) CreateOrderService(client *binanceapi.Client, o *exch.Order) *binanceapi.CreateOrderService {
-	bsymbol := unify.SymbolToB(o.Symbol)
+	bsymbol := unify.SymbolToB(exch.Spot, o.Symbol)
 	info, err := bs.GetBaseInfo(o.Symbol)
 	if err != nil {
 		log.Errorln(log.Http, bs.Api.ApiSign, o.Symbol, "BinaceSpotApi GetBaseInfo error", o, err)
 		return nil
 	}
-	price := mather.FloatRound(o.Price, info.PriceFloat)
-	size := mather.FloatRound(o.Size, info.SizeFloat)
-	quantity := convert.GetString(math.Abs(size))
+	ps, ss := info.PriceScale(), info.SizeScale()
+	price := o.PriceFixed(ps)
+	size := o.SizeFixed(ss)
+	quantity := ss.String(size)
+	if size < 0 {
+		quantity = ss.String(-size)
+	}
 	service := client.NewCreateOrderService().Symbol(bsymbol).Quantity(quantity)
 	orderType := binanceapi.OrderTypeMarket
-	if price != 0 {
+	if price != 0 && o.IsPostOnly() {
+		//只挂单为 LIMIT_MAKER, 不传 timeInForce
+		orderType = binanceapi.OrderTypeLimitMaker
+		service = service.Price(ps.String(price))
+	} else if price != 0 {
 		orderType = binanceapi.OrderTypeLimit
 		//todo tif no do
-		service = service.TimeInForce(binanceapi.TimeInForceTypeGTC).Price(convert.GetString(price))
+		service = service.TimeInForce(binanceapi.TimeInForceTypeGTC).Price(ps.String(price))
 	} else if o.Price != 0 {
 		return nil
 	}
@@ -123,20 +131,19 @@ func (bs *BinaceSpotApi) CreateOrderService(client *binanceapi.Client, o *exch.O
 		return nil
 	}
 	if o.UUID != "" {
-		service.NewClientOrderID(o.UUID)
+		service.NewClientOrderID(exch.FitClientId(exch.Binance, o.UUID))
 	}
 	return service
 }
 
-func (bs *BinaceSpotApi) CannelOrder(ctx context.Context) (*exch.Order, error) {
-	o := exch.GetOrder(ctx)
+func (bs *BinaceSpotApi) CannelOrder(ctx context.Context, o *exch.Order) (*exch.Order, error) {
 	if o == nil {
 		log.Errorln(log.Http, bs.Api.ApiSign, "BinaceSpotApi CannelOrder GetOrder id error")
-		return nil, nil
+		return nil, exch.ErrEmptyOrder
 	}
 	orderId := convert.GetInt64(o.Id)
-	bsymbol := unify.SymbolToB(o.Symbol)
-	res, err := bs.Api.GetClient().NewCancelOrderService().Symbol(bsymbol).OrderID(orderId).Do(context.Background())
+	bsymbol := unify.SymbolToB(exch.Spot, o.Symbol)
+	res, err := bs.Api.GetClient().NewCancelOrderService().Symbol(bsymbol).OrderID(orderId).Do(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -146,25 +153,27 @@ func (bs *BinaceSpotApi) CannelOrder(ctx context.Context) (*exch.Order, error) {
 	if res.Side == binanceapi.SideTypeSell {
 		size = -size
 	}
+	tif := unify.SpotTif(res.Type, res.TimeInForce)
 	or := &exch.Order{
 		Id:         convert.GetString(res.OrderID),
 		UUID:       res.ClientOrderID,
 		Symbol:     o.Symbol,
-		Price:      o.Price,
+		Price:      convert.GetFloat64(res.Price),
 		Size:       size,
 		Left:       lsize,
 		Status:     unify.SpotOrderStatus[res.Status],
+		Tif:        tif,
+		PostOnly:   tif == exch.OrderPoc,
 		CreateTime: o.CreateTime,
 		UpdateTime: o.UpdateTime,
 	}
 	return or, nil
 }
 
-func (bs *BinaceSpotApi) CannelAllOrder(ctx context.Context) ([]*exch.Order, error) {
-	symbol := text.GetString(ctx, exch.CtxSymbol)
-	bsymbol := unify.SymbolToB(symbol)
+func (bs *BinaceSpotApi) CannelAllOrder(ctx context.Context, symbol string) ([]*exch.Order, error) {
+	bsymbol := unify.SymbolToB(exch.Spot, symbol)
 	//todo no result
-	_, err := bs.Api.GetClient().NewCancelOpenOrdersService().Symbol(bsymbol).Do(context.Background())
+	_, err := bs.Api.GetClient().NewCancelOpenOrdersService().Symbol(bsymbol).Do(ctx)
 	if err != nil {
 		log.Errorln(log.Http, bs.Api.ApiSign, "BinaceSpotApi  CannelAllOrder error", err)
 		return nil, err
@@ -173,31 +182,57 @@ func (bs *BinaceSpotApi) CannelAllOrder(ctx context.Context) ([]*exch.Order, err
 	return nil, nil
 }
 
+// ListOpenOrders 账号所有交易对的当前委托
+func (bs *BinaceSpotApi) ListOpenOrders(ctx context.Context) ([]*exch.Order, error) {
+	orders, err := bs.GetOrder(exch.WithSymbol(ctx, ""))
+	if err != nil {
+		log.Errorln(log.Http, bs.Api.ApiSign, "BinaceSpotApi ListOpenOrders error", err)
+		return nil, err
+	}
+	lists := make([]*exch.Order, 0, len(orders))
+	for _, o := range orders {
+		lists = append(lists, o)
+	}
+	return lists, nil
+}
+
+// GetOrder 交易对当前委托, 未指定交易对时查询所有交易对
 func (bs *BinaceSpotApi) GetOrder(ctx context.Context) (map[string]*exch.Order, error) {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
-	bsymbol := unify.SymbolToB(symbol)
-	res, err := bs.Api.GetClient().NewListOpenOrdersService().Symbol(bsymbol).Do(context.Background())
+	service := bs.Api.GetClient().NewListOpenOrdersService()
+	if symbol != "" {
+		service = service.Symbol(unify.SymbolToB(exch.Spot, symbol))
+	}
+	res, err := service.Do(ctx)
 	if err != nil {
 		return nil, err
 	}
 	orders := map[string]*exch.Order{}
 	for _, o := range res {
 		status := unify.SpotOrderStatus[o.Status]
-		size := convert.GetFloat64(o.OrigQuantity)
-		if o.Side == binanceapi.SideTypeSell {
-			size = -size
-		}
-		left := size - convert.GetFloat64(o.ExecutedQuantity)
+		tif := unify.SpotTif(o.Type, o.TimeInForce)
 		or := exch.Order{
 			Id:         convert.GetString(o.OrderID),
 			UUID:       o.ClientOrderID,
-			Price:      convert.GetFloat64(o.Price),
-			Size:       size,
-			Left:       left,
+			Symbol:     unify.BToSymbol(exch.Spot, o.Symbol),
 			Status:     status,
+			Tif:        tif,
+			PostOnly:   tif == exch.OrderPoc,
 			CreateTime: o.Time,
 			UpdateTime: o.UpdateTime,
 		}
+		//价格及数量按交易对刻度解析为定点值
+		var ps, ss exch.Scale
+		if info, err := bs.GetBaseInfo(or.Symbol); err == nil && info != nil {
+			ps, ss = info.PriceScale(), info.SizeScale()
+		}
+		quantity := o.OrigQuantity
+		if o.Side == binanceapi.SideTypeSell {
+			quantity = "-" + quantity
+		}
+		or.ParsePrice(ps, o.Price)
+		or.ParseSize(ss, quantity)
+		or.Left = or.Size - convert.GetFloat64(o.ExecutedQuantity)
 		orders[or.Id] = &or
 	}
 	return orders, nil
@@ -205,9 +240,9 @@ func (bs *BinaceSpotApi) GetOrder(ctx context.Context) (map[string]*exch.Order,
 
 func (bs *BinaceSpotApi) GetOrderBook(ctx context.Context) (*binanceapi.DepthResponse, error) {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
-	bsymbol := unify.SymbolToB(symbol)
+	bsymbol := unify.SymbolToB(exch.Spot, symbol)
 	limit := text.GetInt64(ctx, OrderBookLimit)
-	res, err := bs.Api.GetClient().NewDepthService().Symbol(bsymbol).Limit(int(limit)).Do(context.Background())
+	res, err := bs.Api.GetClient().NewDepthService().Symbol(bsymbol).Limit(int(limit)).Do(ctx)
 	if err != nil {
 		log.Errorln(log.Http, bs.Api.ApiSign, symbol, "BinaceSpotApi GetOrderBook error", err)
 	}
@@ -244,7 +279,7 @@ func (bs *BinaceSpotApi) GetBalance(ctx context.Context) (map[string]*exch.Balan
 	if isinit && ti-InitBalance.T < 5000 {
 		return InitBalance.D, nil
 	}
-	res, err := bs.Api.GetClient().NewGetAccountService().Do(context.Background())
+	res, err := bs.Api.GetClient().NewGetAccountService().Do(ctx)
 	if err != nil {
 		log.Errorln(log.Http, bs.Api.ApiSign, "BinaceSpotApi GetBalance error", err)
 		return nil, err
@@ -316,12 +351,12 @@ func (bs *BinaceSpotApi) GetBaseInfo(symbol string) (*exch.BaseInfo, error) {
 		return nil, err
 	}
 	//log.Warnln(log.Http, bf.Api.ApiSign, symbol, "BinaceSpotApi GetBaseInfo success ")
+	exch.RegisterInstruments(Instruments(res.Symbols)...)
 	ti := time.Now().Unix()
 	infos := map[string]interface{}{}
 	for _, r := range res.Symbols {
 		info := &exch.BaseInfo{}
-		s := unify.BToSymbol(r.Symbol)
-		//todo ETHBTC
+		s := unify.BToSymbol(exch.Spot, r.Symbol)
 		info.Symbol = s
 		pf := r.PriceFilter()
 		sf := r.LotSizeFilter()
@@ -0,0 +1,56 @@
+package spot_api
+
+import (
+	"context"
+
+	"high-freq-quant-go/adapter/convert"
+	"high-freq-quant-go/adapter/timer"
+	"high-freq-quant-go/core/exch"
+	"high-freq-quant-go/core/log"
+	"high-freq-quant-go/exchange/binance/binanceapi"
+)
+
+func init() {
+	exch.RegisterInstrumentLoader(exch.Binance, exch.Spot, LoadInstruments)
+}
+
+// LoadInstruments 用公共接口拉取交易所信息生成合约信息, 为 exch 合约信息加载函数
+func LoadInstruments() ([]*exch.Instrument, error) {
+	api := NewBinaceRequest(context.Background())
+	res, err := api.GetClient().NewExchangeInfoService().Do(context.Background())
+	if err != nil {
+		log.Errorln(log.Http, "BinaceSpotApi LoadInstruments error", err)
+		return nil, err
+	}
+	return Instruments(res.Symbols), nil
+}
+
+// Instruments 现货资产名即交易币, 1000SATS 等不拆分乘数
+func Instruments(symbols []binanceapi.Symbol) []*exch.Instrument {
+	ti := timer.MicNow()
+	ins := make([]*exch.Instrument, 0, len(symbols))
+	for i := range symbols {
+		r := &symbols[i]
+		in := &exch.Instrument{
+			Symbol:         r.BaseAsset + "_" + r.QuoteAsset,
+			VenueSymbol:    r.Symbol,
+			Exname:         exch.Binance,
+			Extype:         exch.Spot,
+			Base:           r.BaseAsset,
+			Quote:          r.QuoteAsset,
+			Settle:         r.QuoteAsset,
+			Multiplier:     1,
+			ContractSize:   1,
+			LastUpdateTime: ti,
+		}
+		if pf := r.PriceFilter(); pf != nil {
+			in.TickSize = convert.GetFloat64(pf.TickSize)
+		}
+		if sf := r.LotSizeFilter(); sf != nil {
+			in.StepSize = convert.GetFloat64(sf.StepSize)
+			in.MinSize = convert.GetFloat64(sf.MinQuantity)
+		}
+		ins = append(ins, in)
+	}
+	return ins
+}
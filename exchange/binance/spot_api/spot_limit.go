@@ -0,0 +1,79 @@
+package spot_api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"high-freq-quant-go/core/request"
+)
+
+const (
+	limitWeight    = "weight"
+	limitOrders10s = "orders10s"
+	limitOrders1d  = "orders1d"
+)
+
+// LimitRules binance 现货限频: 权重 6000/分钟, 下单 100次/10秒及 200000次/天, 下单同时计 1 权重
+var LimitRules = request.Rules{
+	Classes: map[string][]request.Limit{
+		request.ClassOrder: {
+			{Name: limitOrders10s, N: 100, Per: 10 * time.Second},
+			{Name: limitOrders1d, N: 200000, Per: 24 * time.Hour},
+			{Name: limitWeight, N: 6000, Per: time.Minute},
+		},
+		request.ClassCancel: {{Name: limitWeight, N: 6000, Per: time.Minute}},
+		request.ClassQuery:  {{Name: limitWeight, N: 6000, Per: time.Minute}},
+	},
+	Classify: limitClass,
+	Observe: request.UsedHeaders(map[string]string{
+		"X-MBX-USED-WEIGHT-1M":  limitWeight,
+		"X-MBX-ORDER-COUNT-10S": limitOrders10s,
+		"X-MBX-ORDER-COUNT-1D":  limitOrders1d,
+	}),
+}
+
+// 查询接口权重, 未列出的为 1
+var limitWeights = map[string]float64{
+	"/api/v3/account":      20,
+	"/api/v3/myTrades":     20,
+	"/api/v3/allOrders":    20,
+	"/api/v3/exchangeInfo": 20,
+	"/api/v3/order":        4,
+}
+
+func limitClass(req *http.Request) (string, float64) {
+	path := req.URL.Path
+	switch path {
+	case "/api/v3/order", "/api/v3/openOrders":
+		switch req.Method {
+		case http.MethodDelete:
+			return request.ClassCancel, 1
+		case http.MethodPost:
+			return request.ClassOrder, 1
+		}
+		if path == "/api/v3/openOrders" {
+			if req.URL.Query().Get("symbol") == "" {
+				return request.ClassQuery, 80
+			}
+			return request.ClassQuery, 6
+		}
+	case "/api/v3/order/cancelReplace":
+		return request.ClassOrder, 1
+	case "/api/v3/depth":
+		limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+		switch {
+		case limit > 1000:
+			return request.ClassQuery, 250
+		case limit > 500:
+			return request.ClassQuery, 50
+		case limit > 100:
+			return request.ClassQuery, 25
+		}
+		return request.ClassQuery, 5
+	}
+	if w, ok := limitWeights[path]; ok {
+		return request.ClassQuery, w
+	}
+	return request.ClassQuery, 1
+}
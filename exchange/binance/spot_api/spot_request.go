@@ -16,7 +16,7 @@ type BinaceSpotRequest struct {
 	Ctx    context.Context
 	Client *binanceapi.Client
 
-	Times *request.Times
+	Limiter *request.Limiter
 }
 
 func NewBinaceRequest(ctx context.Context) *BinaceSpotRequest {
@@ -31,15 +31,15 @@ func NewBinaceRequest(ctx context.Context) *BinaceSpotRequest {
 
 	bc := &BinaceSpotRequest{
 		ApiSign: sign,
-		Times:   TimeLimiter,
+		Limiter: request.NewLimiter(sign, LimitRules, request.GetRateLimit(ctx)),
 		Ctx:     context.Background(),
 		Client:  binanceapi.NewClient(key, secret),
 	}
+	bc.Client.HTTPClient = bc.Limiter.Client()
 	InitApier.D.Set(sign, bc)
 	return bc
 }
 
 func (bc *BinaceSpotRequest) GetClient() *binanceapi.Client {
-	bc.Times.Update()
 	return bc.Client
 }
@@ -5,12 +5,9 @@ import (
 	"sync"
 
 	"high-freq-quant-go/core/exch"
-	"high-freq-quant-go/core/request"
 )
 
 var (
-	TimeLimiter = request.NewTimes()
-
 	//apisign
 	InitApier = &InitApiersType{
 		D: cmap.New(),
@@ -12,6 +12,7 @@ import (
 
 	"high-freq-quant-go/exchange/binance/spot_api"
 
+	"high-freq-quant-go/adapter/queue"
 	"high-freq-quant-go/adapter/text"
 	"high-freq-quant-go/core/exch"
 	"high-freq-quant-go/core/log"
@@ -25,38 +26,52 @@ type Futures struct {
 	//param
 	Ctx context.Context
 	//return data
-	Bookers  cmap.ConcurrentMap
-	BaseData cmap.ConcurrentMap
+	Bookers     cmap.ConcurrentMap
+	BaseData    cmap.ConcurrentMap
+	PublicTrade cmap.ConcurrentMap //已订阅公开成交的交易对
 
 	//wss client
 	Client *FuturesClient
 
 	//struct msg chan
-	OrderBookQueue *chan *DepthEvent
-	BaseDataQueue  *chan interface{}
+	OrderBookQueue *queue.Queue //订单薄增量 exch.LaneDepth
+	BaseDataQueue  *queue.Queue //其他消息 exch.LaneUser
 
 	//struct msg to public
 	BookDataChannel *chan interface{}
 	BookMsgChan     *chan interface{}
+	Feed            *exch.Feed
+
+	bookData, bookMsg *queue.Queue
+
+	//订单薄异常检查及 REST 快照校验, 异常时通过 resync 在 OrderBookEvent 中重新拉取快照
+	Monitor  *exch.BookMonitor
+	Verifier *exch.BookVerifier
+	resync   chan string
 }
 
 func NewFutures(ctx context.Context) *Futures {
-	bq := make(chan *DepthEvent, exch.MsgChannelLen)
-	uq := make(chan interface{}, exch.MsgChannelLen)
 	sign := text.GetString(ctx, exch.ConnSign)
 	cl := &Futures{
 		Sign:           sign,
 		Ctx:            ctx,
 		Bookers:        cmap.New(),
 		BaseData:       cmap.New(),
-		OrderBookQueue: &bq,
-		BaseDataQueue:  &uq,
+		PublicTrade:    cmap.New(),
+		OrderBookQueue: exch.NewLane(ctx, exch.LaneDepth, exch.MsgChannelLen),
+		BaseDataQueue:  exch.NewLane(ctx, exch.LaneUser, exch.MsgChannelLen),
+		Feed:           exch.GetFeed(ctx),
+		resync:         make(chan string, exch.MsgChannelLen),
 	}
 
 	cl.SetPubChannel()
+	cl.Monitor = exch.NewBookMonitor(ctx, cl.Sign, cl.Bookers, cl.Resync)
+	cl.Verifier = exch.NewBookVerifier(ctx, cl.Sign, cl.Bookers, cl.Snapshot, cl.Resync)
 	cl.Client = NewFuturesClient(ctx)
 	go cl.MsgEvent()
 	go cl.OrderBookEvent()
+	go cl.Monitor.Run()
+	go cl.Verifier.Run()
 	go cl.BaseDataEvent()
 	return cl
 }
@@ -73,10 +88,10 @@ func (ws *Futures) GetBaseinfo(ctx context.Context) *exch.BaseInfo {
 	return nil
 }
 
-func (ws *Futures) GetBook(ctx context.Context) *exch.Booker {
+func (ws *Futures) GetBook(ctx context.Context) exch.Booker {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
 	if res, ok := ws.Bookers.Get(symbol); ok {
-		return res.(*exch.Booker)
+		return res.(exch.Booker)
 	}
 	return nil
 }
@@ -84,9 +99,11 @@ func (ws *Futures) GetBook(ctx context.Context) *exch.Booker {
 func (ws *Futures) SetPubChannel() {
 	if ret, ok := ws.Ctx.Value(exch.BookDataChannel).(*chan interface{}); ok {
 		ws.BookDataChannel = ret
+		ws.bookData = exch.NewOutLane(ws.Ctx, exch.LaneBookData, *ret)
 	}
 	if ret, ok := ws.Ctx.Value(exch.BookMsgChan).(*chan interface{}); ok {
 		ws.BookMsgChan = ret
+		ws.bookMsg = exch.NewOutLane(ws.Ctx, exch.LaneBookMsg, *ret)
 	}
 }
 
@@ -98,7 +115,7 @@ func (ws *Futures) SubOrderBook(ctx context.Context) error {
 	if _, ok := ws.Bookers.Get(symbol); ok {
 		return nil
 	}
-	books := exch.NewBooker(ws.Ctx)
+	books := exch.NewBooker(exch.WithSymbol(ws.Ctx, symbol))
 	ws.Bookers.Set(symbol, books)
 	err := ws.Client.OrderBook(ctx)
 	return err
@@ -114,6 +131,48 @@ func (ws *Futures) SubTicker(ctx context.Context) error {
 	return err
 }
 
+func (ws *Futures) UnsubOrderBook(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if !ws.Bookers.Has(symbol) {
+		return nil
+	}
+	ws.Bookers.Remove(symbol)
+	return ws.Client.UnOrderBook(ctx)
+}
+
+func (ws *Futures) UnsubTicker(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if !ws.BaseData.Has(symbol) {
+		return nil
+	}
+	ws.BaseData.Remove(symbol)
+	return ws.Client.UnTicker(ctx)
+}
+
+// SubPublicTrades 归集成交通过 Feed.OnPublicTrade 推送
+func (ws *Futures) SubPublicTrades(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if symbol == "" || ws.PublicTrade.Has(symbol) {
+		return nil
+	}
+	ws.PublicTrade.Set(symbol, true)
+	err := ws.Client.AggTrade(ctx)
+	if err != nil {
+		// 订阅失败时清除标记, 之后可以重新订阅
+		ws.PublicTrade.Remove(symbol)
+	}
+	return err
+}
+
+func (ws *Futures) UnsubPublicTrades(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if !ws.PublicTrade.Has(symbol) {
+		return nil
+	}
+	ws.PublicTrade.Remove(symbol)
+	return ws.Client.UnAggTrade(ctx)
+}
+
 func (ws *Futures) InitTicker(ctx context.Context) error {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
 	res, err := spot_api.NewBinanceApi(ws.Ctx).GetBaseInfo(symbol)
@@ -135,9 +194,9 @@ func (ws *Futures) MsgEvent() {
 			switch msg.(type) {
 			case *DepthEvent: // handle order book update
 				//m.DepthUpdateEventHandler(rtype)
-				*ws.OrderBookQueue <- msg.(*DepthEvent)
+				ws.OrderBookQueue.Push(msg.(*DepthEvent).Symbol, msg)
 			default:
-				*ws.BaseDataQueue <- msg
+				ws.BaseDataQueue.Push("", msg)
 			}
 		}
 	}
@@ -149,45 +208,53 @@ func (ws *Futures) OrderBookEvent() {
 		case <-ws.Ctx.Done():
 			log.Warnln(log.Wss, ws.Sign, " Binance Futures OrderBookEvent return by done")
 			return
-		case msg := <-*ws.OrderBookQueue:
+		case symbol := <-ws.resync:
+			ws.InitOrderbook(symbol)
+			if booki, ok := ws.Bookers.Get(symbol); ok && ws.BookMsgChan != nil {
+				ws.bookMsg.Push(symbol, booki)
+			}
+		case m := <-ws.OrderBookQueue.C:
+			msg := m.(*DepthEvent)
 			//st := time.Now().UnixNano() / 1000000
-			symbol := unify.BToSymbol(msg.Symbol)
-			var book *exch.Booker
+			symbol := unify.BToSymbol(exch.Spot, msg.Symbol)
+			var book exch.Booker
 			if booki, ok := ws.Bookers.Get(symbol); !ok {
 				log.Warnln(log.Wss, ws.Sign, symbol, "no symbolbook")
 				continue
 			} else {
-				book = booki.(*exch.Booker)
+				book = booki.(exch.Booker)
 			}
-			if book.UpdateID == 0 || (book.IsReady && msg.FirstUpdateID != book.UpdateID+1) {
+			bm := book.Meta()
+			if bm.UpdateID == 0 || (bm.IsReady && msg.FirstUpdateID != bm.UpdateID+1) {
 				ws.InitOrderbook(symbol)
 				if booki, ok := ws.Bookers.Get(symbol); !ok {
 					continue
 				} else {
-					book = booki.(*exch.Booker)
+					book = booki.(exch.Booker)
 				}
+				bm = book.Meta()
 				if ws.BookMsgChan != nil {
-					*ws.BookMsgChan <- book
+					ws.bookMsg.Push(symbol, book)
 				}
 			}
 			if book.GetAskLen() == 0 || book.GetBidLen() == 0 {
 				log.Warnln(log.Wss, ws.Sign, symbol, "binance init Orderbook error asks bids:", book.GetAskLen(), book.GetBidLen())
 				continue
 			}
-			if msg.UpdateID < book.UpdateID {
+			if msg.UpdateID < bm.UpdateID {
 				continue
 			}
-			if !book.IsReady && msg.FirstUpdateID <= book.UpdateID && book.UpdateID <= msg.UpdateID {
+			if !bm.IsReady && msg.FirstUpdateID <= bm.UpdateID && bm.UpdateID <= msg.UpdateID {
 				log.Debugf(log.Global, "%s %s binance init Orderbook wss msg is isready id: %d \r\n", ws.Sign, symbol, msg.UpdateID)
-				book.IsReady = true
+				bm.IsReady = true
 			}
-			if !book.IsReady {
+			if !bm.IsReady {
 				continue
 			}
-			book.Rw.Lock()
+			bm.Rw.Lock()
 			var wg sync.WaitGroup
 			wg.Add(2)
-			go func(result []Bid, book *exch.Booker, wg *sync.WaitGroup) {
+			go func(result []Bid, book exch.Booker, wg *sync.WaitGroup) {
 				defer wg.Done()
 				for _, bid := range result {
 					quantity := convert.GetFloat64(bid.Quantity)
@@ -195,7 +262,7 @@ func (ws *Futures) OrderBookEvent() {
 					book.UpdateBid(price, quantity)
 				}
 			}(msg.Bids, book, &wg)
-			go func(result []Ask, book *exch.Booker, wg *sync.WaitGroup) {
+			go func(result []Ask, book exch.Booker, wg *sync.WaitGroup) {
 				defer wg.Done()
 				for _, ask := range result {
 					quantity := convert.GetFloat64(ask.Quantity)
@@ -204,32 +271,72 @@ func (ws *Futures) OrderBookEvent() {
 				}
 			}(msg.Asks, book, &wg)
 			wg.Wait()
-			book.UpdateID = msg.UpdateID
-			book.ResponTime = msg.Time
-			book.UpdateTime = time.Now().UnixNano() / 1000000
-			book.Rw.Unlock()
+			bm.UpdateID = msg.UpdateID
+			bm.ResponTime = msg.Time
+			bm.UpdateTime = time.Now().UnixNano() / 1000000
+			bm.Rw.Unlock()
+			if bm.Hooked() {
+				u := &exch.BookUpdate{FirstID: msg.FirstUpdateID, LastID: msg.UpdateID,
+					Asks: make(map[string]float64, len(msg.Asks)), Bids: make(map[string]float64, len(msg.Bids))}
+				for _, ask := range msg.Asks {
+					u.Asks[unify.FloatZore(ask.Price)] = convert.GetFloat64(ask.Quantity)
+				}
+				for _, bid := range msg.Bids {
+					u.Bids[unify.FloatZore(bid.Price)] = convert.GetFloat64(bid.Quantity)
+				}
+				bm.RunHooks(book, u)
+			}
 			if ws.BookMsgChan != nil {
-				*ws.BookMsgChan <- msg
+				ws.bookMsg.Push(symbol, msg)
 			}
 			if ws.BookDataChannel != nil {
-				*ws.BookDataChannel <- book
+				ws.bookData.Push(symbol, book)
 			}
+			ws.Feed.PubBook(symbol, book)
 		}
 	}
 }
 
+// Resync 请求 OrderBookEvent 重新拉取交易对快照
+func (ws *Futures) Resync(symbol string) {
+	select {
+	case ws.resync <- symbol:
+	default:
+	}
+}
+
 func (ws *Futures) InitOrderbook(symbol string) {
-	book := exch.NewBooker(ws.Ctx)
+	snap, err := ws.Snapshot(symbol)
+	if err != nil {
+		log.Errorln(log.Global, ws.Sign, symbol, "binance InitOrderbook error ", err)
+		return
+	}
+	book := exch.NewBooker(exch.WithSymbol(ws.Ctx, symbol))
+	bm := book.Meta()
+	bm.Symbol = symbol
+	bm.IsReady = false
+	api := spot_api.NewBinanceApi(ws.Ctx)
+	if info, err := api.GetBaseInfo(symbol); err == nil && info != nil {
+		book.SetScale(unify.PriceScale(exch.Spot, symbol, info))
+	}
+	book.SetBook(snap.Asks, snap.Bids)
+	bm.UpdateID = snap.UpdateID
+	bm.ResponTime = snap.Time
+	bm.UpdateTime = timer.MicNow()
+	ws.Bookers.Set(symbol, book)
+	log.Debugf(log.Wss, "%s %s binace init Orderbook success [ResponTime=%d] [UpdateTime=%d][bookName=%s][lastid=%d] \r\n", ws.Sign, symbol, bm.ResponTime, bm.UpdateTime, bm.Name, bm.UpdateID)
+}
+
+// Snapshot REST 深度快照, 现货接口不返回时间, Time 为请求时间
+func (ws *Futures) Snapshot(symbol string) (*exch.BookSnapshot, error) {
 	ctx := context.WithValue(context.Background(), exch.CtxSymbol, symbol)
 	ctx = context.WithValue(ctx, spot_api.OrderBookLimit, InitOrderBookLimit)
 	ResponTime := timer.MicNow()
-	res, err := spot_api.NewBinanceApi(ws.Ctx).GetOrderBook(ctx)
+	api := spot_api.NewBinanceApi(ws.Ctx)
+	res, err := api.GetOrderBook(ctx)
 	if err != nil {
-		log.Errorln(log.Global, ws.Sign, symbol, "binance InitOrderbook error ", err)
-		return
+		return nil, err
 	}
-	book.Symbol = symbol
-	book.IsReady = false
 	askMap, bidMap := map[string]float64{}, map[string]float64{}
 	var wg sync.WaitGroup
 	wg.Add(2)
@@ -256,12 +363,13 @@ func (ws *Futures) InitOrderbook(symbol string) {
 		}
 	}(res.Asks, &wg)
 	wg.Wait()
-	book.SetBook(askMap, bidMap)
-	book.UpdateID = res.LastUpdateID
-	book.ResponTime = ResponTime
-	book.UpdateTime = timer.MicNow()
-	ws.Bookers.Set(symbol, book)
-	log.Debugf(log.Wss, "%s %s binace init Orderbook success [ResponTime=%d] [UpdateTime=%d][bookName=%s][lastid=%d] \r\n", ws.Sign, symbol, book.ResponTime, book.UpdateTime, book.Name, book.UpdateID)
+	return &exch.BookSnapshot{
+		Symbol:   symbol,
+		UpdateID: res.LastUpdateID,
+		Time:     ResponTime,
+		Asks:     askMap,
+		Bids:     bidMap,
+	}, nil
 }
 
 func (ws *Futures) BaseDataEvent() {
@@ -270,19 +378,53 @@ func (ws *Futures) BaseDataEvent() {
 		case <-ws.Ctx.Done():
 			log.Warnln(log.Wss, ws.Sign, " Binance Futures BaseDataQueue return by done")
 			return
-		case msg := <-*ws.BaseDataQueue:
+		case msg := <-ws.BaseDataQueue.C:
 			switch msg.(type) {
 			case *TickerEvent:
 				d := msg.(*TickerEvent)
-				symbol := unify.BToSymbol(d.Symbol)
+				symbol := unify.BToSymbol(exch.Spot, d.Symbol)
 				if infoi, ok := ws.BaseData.Get(symbol); ok {
 					info := infoi.(*exch.BaseInfo)
 					info.Symbol = symbol
 					info.MarkPrice = d.ClosePrice
 				}
+			case *AggTrade:
+				ws.UpdateTrades(msg.(*AggTrade))
 			default:
 				log.Errorln(log.Wss, ws.Sign, "------unknow BaseDataQueue msg type----", msg)
 			}
 		}
 	}
 }
+
+// UpdateTrades 买方为 maker 时主动方为卖方, 数量为负
+func (ws *Futures) UpdateTrades(d *AggTrade) {
+	symbol := unify.BToSymbol(exch.Spot, d.Symbol)
+	in := unify.Instrument(exch.Spot, symbol)
+	size := in.SizeFromVenue(convert.GetFloat64(d.Volume))
+	if d.Maker {
+		size = -size
+	}
+	ws.Feed.PubPublicTrade(&exch.Trade{
+		Symbol: symbol,
+		Id:     convert.GetString(d.CollectionId),
+		Price:  in.PriceFromVenue(convert.GetFloat64(d.Price)),
+		Size:   size,
+		Side:   exch.TakerSide(size),
+		Time:   d.VTime,
+	})
+}
+
+// QueueStats 原始消息, 订单薄, 其他消息及推送通道的积压及丢弃统计
+func (ws *Futures) QueueStats() []queue.Stat {
+	var stats []queue.Stat
+	if ws.Client.Wss != nil {
+		stats = append(stats, ws.Client.Wss.Stat())
+	}
+	for _, q := range []*queue.Queue{ws.OrderBookQueue, ws.BaseDataQueue, ws.bookData, ws.bookMsg} {
+		if q != nil {
+			stats = append(stats, q.Stat())
+		}
+	}
+	return stats
+}
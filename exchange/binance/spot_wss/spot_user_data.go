@@ -13,6 +13,7 @@ import (
 	"high-freq-quant-go/exchange/binance/unify"
 
 	"high-freq-quant-go/adapter/convert"
+	"high-freq-quant-go/adapter/queue"
 
 	"high-freq-quant-go/exchange/binance/binanceapi"
 	"high-freq-quant-go/exchange/binance/spot_api"
@@ -54,6 +55,12 @@ type UserWss struct {
 
 	//reconnect
 	ReConnectMsg []client.SubscribeData
+	rl           sync.Mutex
+
+	//同一 ApiSign 的 Exchanger 共用连接, 推送给所有 Exchanger
+	Feeds exch.Feeds
+	//定时按 REST 校对仓位及资金, 推送丢失时更正
+	Reconciler *exch.Reconciler
 
 	lk, tdl, odl sync.RWMutex
 	pdl, bdl     sync.Mutex
@@ -74,8 +81,13 @@ func NewFuturesUser(ctx context.Context) *UserWss {
 	BinaceUserWss.lock.Lock()
 	defer BinaceUserWss.lock.Unlock()
 	if fu, ok := BinaceUserWss.Wss[apiSign]; ok {
+		fu.Feeds.Add(exch.GetFeed(ctx))
 		return fu
 	}
+	wss.Feeds.Add(exch.GetFeed(ctx))
+	//现货仓位为本地按成交计算, 只校对资金
+	wss.Reconciler = exch.NewReconciler(ctx, wss.Sign, spot_api.NewBinanceApi(ctx), nil, wss.BalanceData, &wss.Feeds)
+	go wss.Reconciler.Run()
 	BinaceUserWss.Wss[apiSign] = wss
 	return wss
 }
@@ -155,6 +167,7 @@ func (ws *UserWss) NewClient(ListenKey string) error {
 	ctx = context.WithValue(ctx, client.Keepalive, true)
 	ctx = context.WithValue(ctx, client.Timeout, WssTimeout)
 	ctx = context.WithValue(ctx, client.MsgLen, exch.WSChannelLen)
+	ctx = context.WithValue(ctx, client.Overflow, exch.GetOverflow(ws.Ctx, exch.LaneUser))
 	ctx = context.WithValue(ctx, client.Id, ws.Sign)
 	pUrl := text.GetString(ws.Ctx, client.ProxyUrl)
 	ctx = context.WithValue(ctx, client.ProxyUrl, pUrl)
@@ -190,7 +203,7 @@ func (ws *UserWss) SubOrder(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	ws.RegisterMsg(ws.InitOrder, ctx)
+	ws.RegisterMsg(client.SubscribeKey(orderKey, symbol), ws.InitOrder, ctx)
 	err = ws.Create()
 	return err
 }
@@ -229,7 +242,7 @@ func (ws *UserWss) SubBalance(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	ws.RegisterMsg(ws.InitBalance, ctx)
+	ws.RegisterMsg(balanceKey, ws.InitBalance, ctx)
 	err = ws.Create()
 	return err
 }
@@ -249,7 +262,7 @@ func (ws *UserWss) SubPosition(ctx context.Context) error {
 		log.Errorln(log.Wss, ws.Sign, symbol, "binance user wss SubPosition error ", err)
 		return err
 	}
-	ws.RegisterMsg(ws.InitPosition, ctx)
+	ws.RegisterMsg(positionKey, ws.InitPosition, ctx)
 	err = ws.Create()
 	return err
 }
@@ -369,18 +382,24 @@ func (ws *UserWss) AccountUpdate(data binanceapi.WsUserDataEvent) {
 			Avative: free,
 		}
 		ws.BalanceData.Set(d.Asset, bl)
+		ws.Feeds.PubBalance(bl)
 		log.Infof(log.Wss, " %s binance user wss AccountUpdate Balances %s %+v \r\n", ws.Sign, d.Asset, bl)
 	}
 }
 
 func (ws *UserWss) OrderTradeUpdate(data binanceapi.WsUserDataEvent) {
 	o := data.OrderUpdate
-	symbol := unify.BToSymbol(o.Symbol)
+	symbol := unify.BToSymbol(exch.Spot, o.Symbol)
 	status := unify.SpotOrderStatus[binanceapi.OrderStatusType(o.Status)]
 	size := convert.GetFloat64(o.Volume)
 	tsize := convert.GetFloat64(o.LatestVolume)
-	lsize := size - convert.GetFloat64(o.FilledVolume)
+	fsize := convert.GetFloat64(o.FilledVolume)
+	lsize := size - fsize
 	lsize = math.Abs(lsize)
+	aprice := 0.0
+	if fsize != 0 {
+		aprice = convert.GetFloat64(o.FilledQuoteVolume) / fsize
+	}
 	if o.Side == string(binanceapi.SideTypeSell) {
 		size = -size
 		tsize = -tsize
@@ -400,6 +419,8 @@ func (ws *UserWss) OrderTradeUpdate(data binanceapi.WsUserDataEvent) {
 		Price:      price,
 		FillPrice:  fprice,
 		Left:       lsize,
+		FilledSize: fsize,
+		AvgPrice:   aprice,
 		Role:       role,
 		CreateTime: o.CreateTime,
 		UpdateTime: o.TransactionTime,
@@ -408,11 +429,20 @@ func (ws *UserWss) OrderTradeUpdate(data binanceapi.WsUserDataEvent) {
 	if _, ok := ws.OrderData[symbol]; !ok {
 		ws.OrderData[symbol] = map[string]*exch.Order{}
 	}
+	if old, ok := ws.OrderData[symbol][or.Id]; ok {
+		or.State = old.State
+	}
+	if err := or.SetState(unify.SpotState(binanceapi.OrderStatusType(o.Status), fsize)); err != nil {
+		ws.odl.Unlock()
+		log.Warnln(log.Wss, ws.Sign, symbol, "binance user wss OrderTradeUpdate skip", err)
+		return
+	}
 	ws.OrderData[symbol][or.Id] = &or
 	if or.Status == exch.OrderFinished {
 		delete(ws.OrderData[symbol], or.Id)
 	}
 	ws.odl.Unlock()
+	ws.Feeds.PubOrder(&or)
 	log.Debugf(log.Wss, "%s $s binance user wss  OrderTradeUpdate result %+v \r\n", ws.Sign, symbol, ws.OrderData)
 
 	//只要成交 不要下单
@@ -424,6 +454,7 @@ func (ws *UserWss) OrderTradeUpdate(data binanceapi.WsUserDataEvent) {
 			UUID:       o.ClientOrderId,
 			Symbol:     symbol,
 			Status:     status,
+			State:      or.State,
 			Size:       tsize,
 			Price:      tprice,
 			Role:       role,
@@ -435,6 +466,7 @@ func (ws *UserWss) OrderTradeUpdate(data binanceapi.WsUserDataEvent) {
 			*ws.TradeData[symbol] <- &to
 		}
 		ws.tdl.Unlock()
+		ws.Feeds.PubTrade(&to)
 
 		pos := &exch.Position{}
 		if posi, ok := ws.PositionData.Get(symbol); ok {
@@ -447,21 +479,65 @@ func (ws *UserWss) OrderTradeUpdate(data binanceapi.WsUserDataEvent) {
 		pos.Pnl = pos.Pnl + pnl
 		pos.UnPnl = (or.Price - pos.Price) * pos.Size
 		ws.PositionData.Set(symbol, pos)
+		ws.Feeds.PubPosition(pos)
 		log.Infof(log.Global, "%s %s binance user wss OrderTradeUpdate push order %+v \r\n", ws.Sign, symbol, or)
 	}
 }
 
-func (ws *UserWss) RegisterMsg(action func(ctx context.Context) error, param context.Context) {
+// 重连列表 Key, 用户数据流不区分交易对, 只有订单按交易对初始化
+const (
+	orderKey    = "order"
+	positionKey = "position"
+	balanceKey  = "balance"
+)
+
+// RegisterMsg 记录重连后需要重新初始化的数据, 相同 Key 只保留最新一条
+func (ws *UserWss) RegisterMsg(key string, action func(ctx context.Context) error, param context.Context) {
 	if text.GetBool(param, client.IsReconnect) {
 		return
 	}
 	param = context.WithValue(param, client.IsReconnect, true)
 	method := client.SubscribeData{
+		Key:    key,
 		Action: &action,
 		Param:  param,
 	}
-	ws.ReConnectMsg = append(ws.ReConnectMsg, method)
-	return
+	ws.rl.Lock()
+	ws.ReConnectMsg = append(client.RemoveSubscribe(ws.ReConnectMsg, key), method)
+	ws.rl.Unlock()
+}
+
+func (ws *UserWss) unregister(key string) {
+	ws.rl.Lock()
+	ws.ReConnectMsg = client.RemoveSubscribe(ws.ReConnectMsg, key)
+	ws.rl.Unlock()
+}
+
+// UnsubOrder 用户数据流仍保持连接, 只删除本地订单及重连初始化
+func (ws *UserWss) UnsubOrder(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	ws.unregister(client.SubscribeKey(orderKey, symbol))
+	ws.odl.Lock()
+	delete(ws.OrderData, symbol)
+	ws.odl.Unlock()
+	return nil
+}
+
+func (ws *UserWss) UnsubUserTrade(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	ws.tdl.Lock()
+	delete(ws.TradeData, symbol)
+	ws.tdl.Unlock()
+	return nil
+}
+
+// UnsubPosition 仓位初始化拉取账户全部仓位, 由所有交易对共用, 只删除本地仓位
+func (ws *UserWss) UnsubPosition(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	ws.pdl.Lock()
+	ws.PositionData.Remove(symbol)
+	ws.pdl.Unlock()
+	return nil
 }
 
 func (ws *UserWss) ReConnect(err error) error {
@@ -475,7 +551,10 @@ func (ws *UserWss) ReConnect(err error) error {
 		return conErr
 	}
 	log.Warnln(log.Wss, ws.Sign, "binance user wss start reconnect")
-	for _, m := range ws.ReConnectMsg {
+	ws.rl.Lock()
+	msgs := append([]client.SubscribeData{}, ws.ReConnectMsg...)
+	ws.rl.Unlock()
+	for _, m := range msgs {
 		fun := *m.Action
 		ctx := m.Param
 		nerr := fun(ctx)
@@ -492,3 +571,11 @@ func (ws *UserWss) ReConnect(err error) error {
 	ws.ConnectTime = timer.MicNow()
 	return nil
 }
+
+// QueueStats 私有连接只有原始消息队列
+func (ws *UserWss) QueueStats() []queue.Stat {
+	if ws.Wss == nil {
+		return nil
+	}
+	return []queue.Stat{ws.Wss.Stat()}
+}
@@ -4,73 +4,95 @@ import (
 	"strconv"
 	"strings"
 
+	"high-freq-quant-go/core/exch"
+
+	"high-freq-quant-go/exchange/binance/binanceapi"
+	"high-freq-quant-go/exchange/binance/binanceapi/common"
 	"high-freq-quant-go/exchange/binance/binanceapi/futures"
 
 	"high-freq-quant-go/adapter/convert"
 )
 
-func SymbolToB(symbol string) string {
-	baseQuote := strings.SplitN(symbol, "_", 3)
-	if len(baseQuote) > 1 && !strings.Contains(baseQuote[0], "USDT") {
-		symbol = strings.ToUpper(baseQuote[0] + baseQuote[1])
+// Instrument 合约信息, 统一交易对及交易所交易对均可查询, 未加载时返回 nil, 换算按 1 倍处理
+func Instrument(extype, symbol string) *exch.Instrument {
+	if isVenue(symbol) {
+		return exch.VenueInstrument(exch.Binance, extype, strings.ToUpper(symbol))
+	}
+	return exch.GetInstrument(exch.Binance, extype, symbol)
+}
+
+// isVenue 交易所交易对 BTCUSDT BTCUSDT_230630, 统一交易对 BTC_USDT BTC_USDT_20230630
+func isVenue(symbol string) bool {
+	pq := strings.SplitN(symbol, "_", 3)
+	if len(pq) == 1 {
+		return true
+	}
+	_, err := strconv.Atoi(pq[1])
+	return err == nil
+}
+
+func SymbolToB(extype, symbol string) string {
+	if isVenue(symbol) {
+		return strings.ToUpper(symbol)
 	}
-	if symbol == "SHIBUSDT" {
-		symbol = "1000SHIBUSDT"
+	if in := exch.GetInstrument(exch.Binance, extype, symbol); in != nil {
+		return in.VenueSymbol
 	}
+	baseQuote := strings.SplitN(symbol, "_", 3)
+	symbol = baseQuote[0] + baseQuote[1]
 	if len(baseQuote) == 3 {
-		symbol = symbol + "_" + baseQuote[2][2:]
+		symbol = symbol + "_" + strings.TrimPrefix(baseQuote[2], "20")
 	}
 	return strings.ToUpper(symbol)
 }
 
-func BToSymbol(symbol string) string {
+func BToSymbol(extype, symbol string) string {
 	symbol = strings.ToUpper(symbol)
-	symbols := strings.Split(symbol, "USDT")
-	if len(symbols) == 2 {
-		symbol = symbols[0] + "_USDT"
+	if in := exch.VenueInstrument(exch.Binance, extype, symbol); in != nil {
+		return in.Symbol
 	}
-	if symbol == "1000SHIB_USDT" {
-		symbol = "SHIB_USDT"
+	symbols := strings.Split(symbol, "USDT")
+	if len(symbols) != 2 {
+		return symbol
 	}
-	if len(symbols) == 2 {
-		exDay := strings.Split(symbols[1], "_")
-		if len(exDay) == 2 {
-			symbol = symbol + "_20" + exDay[1]
-		}
+	res := symbols[0] + "_USDT"
+	if exDay := strings.Split(symbols[1], "_"); len(exDay) == 2 {
+		res = res + "_20" + exDay[1]
 	}
-	return symbol
+	return res
 }
 
-func PriceToStr(symbol, price string) string {
-	if symbol == "1000SHIBUSDT" || symbol == "SHIB_USDT" {
-		p := convert.GetFloat64(price)
-		price = strconv.FormatFloat(p/1000, 'f', 9, 64)
-	}
-	price = FloatZore(price)
-	return price
+// PriceToStr 交易所价格按合约乘数换算, 如 1000SHIB 按十进制左移三位, 不经过浮点运算
+func PriceToStr(extype, symbol, price string) string {
+	return Instrument(extype, symbol).PriceStr(price)
+}
+
+// PriceScale 统一价格刻度, 如 1000SHIB 的最小变动单位同样缩小 1000 倍
+func PriceScale(extype, symbol string, info *exch.BaseInfo) exch.Scale {
+	return Instrument(extype, symbol).PriceScale(info.PriceScale())
 }
 
 func FloatZore(price string) string {
-	if strings.Contains(price, ".") {
-		price = strings.TrimRight(price, "0")
-		price = strings.TrimRight(price, ".")
-	}
-	return price
+	return exch.TrimZero(price)
 }
 
-func PriceToFloat(symbol string, price float64) float64 {
-	if symbol == "1000SHIBUSDT" || symbol == "SHIB_USDT" {
-		price = price / 1000
-	}
-	return price
+func PriceToFloat(extype, symbol string, price float64) float64 {
+	return Instrument(extype, symbol).PriceFromVenue(price)
+}
+
+func QuantityToFloat(extype, symbol string, quantity string) float64 {
+	return Instrument(extype, symbol).SizeFromVenue(convert.GetFloat64(quantity))
 }
 
-func QuantityToFloat(symbol string, quantity string) float64 {
-	num := convert.GetFloat64(quantity)
-	if symbol == "1000SHIBUSDT" || symbol == "SHIB_USDT" {
-		num = 1000 * num
+// SpotTif 现货只挂单为 LIMIT_MAKER, 不返回 timeInForce, 市价单返回为空
+func SpotTif(ot binanceapi.OrderType, tif binanceapi.TimeInForceType) string {
+	if ot == binanceapi.OrderTypeLimitMaker {
+		return exch.OrderPoc
+	}
+	if ot == binanceapi.OrderTypeMarket {
+		return ""
 	}
-	return num
+	return SpotOrderType[tif]
 }
 
 func IsDelive(symbol string) bool {
@@ -90,3 +112,42 @@ func GetOrderTif(str string) futures.TimeInForceType {
 	}
 	return futures.TimeInForceTypeGTC
 }
+
+// IsTrigger 合约订单类型是否为未触发的条件单
+func IsTrigger(ot futures.OrderType) bool {
+	_, ok := TriggerOrderType[ot]
+	return ok
+}
+
+// SetTrigger 条件单补充类型, 触发价格及触发价格类型
+func SetTrigger(or *exch.Order, ot futures.OrderType, stopPrice string, wt futures.WorkingType, closePosition bool) {
+	t, ok := TriggerOrderType[ot]
+	if !ok {
+		return
+	}
+	or.Ordertype = t
+	or.TriggerPrice = convert.GetFloat64(PriceToStr(exch.Futures, or.Symbol, stopPrice))
+	or.TriggerBy = exch.TriggerLast
+	if wt == futures.WorkingTypeMarkPrice {
+		or.TriggerBy = exch.TriggerMark
+	}
+	or.ClosePosition = closePosition
+}
+
+// IsCode 交易所返回的错误码
+func IsCode(err error, code int64) bool {
+	e, ok := err.(*common.APIError)
+	return ok && e.Code == code
+}
+
+// OrderError 只挂单的订单会立即成交时转换为 exch.PostOnlyError, 其他错误原样返回
+func OrderError(err error, symbol string) error {
+	e, ok := err.(*common.APIError)
+	if !ok {
+		return err
+	}
+	if e.Code == CodePostOnly || (e.Code == CodeOrderRejected && strings.Contains(e.Message, MsgWouldTake)) {
+		return &exch.PostOnlyError{Exname: exch.Binance, Symbol: symbol, Detail: e.Message}
+	}
+	return err
+}
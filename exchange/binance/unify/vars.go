@@ -6,23 +6,56 @@ import (
 	fs "high-freq-quant-go/exchange/binance/binanceapi/futures"
 )
 
+// 只挂单的订单会立即成交时的错误, 合约为 -5022, 现货 LIMIT_MAKER 为 -2010 及对应信息
+const (
+	CodePostOnly      int64 = -5022
+	CodeOrderRejected int64 = -2010
+	MsgWouldTake            = "immediately match"
+)
+
+// 设置与当前相同时的错误, 按已设置处理
+const (
+	CodeNoNeedMarginType   int64 = -4046
+	CodeNoNeedPositionMode int64 = -4059
+)
+
 var (
 	SpotOrderStatus = map[ba.OrderStatusType]string{
 		ba.OrderStatusTypeNew:             exch.OrderOpen,
 		ba.OrderStatusTypePartiallyFilled: exch.OrderOpen,
 		ba.OrderStatusTypeFilled:          exch.OrderFinished,
 		ba.OrderStatusTypeCanceled:        exch.OrderFinished,
-		ba.OrderStatusTypePendingCancel:   exch.OrderFinished,
+		ba.OrderStatusTypePendingCancel:   exch.OrderOpen, //撤单中仍可能成交
 		ba.OrderStatusTypeRejected:        exch.OrderFinished,
 		ba.OrderStatusTypeExpired:         exch.OrderFinished,
 	}
 
+	SpotOrderState = map[ba.OrderStatusType]string{
+		ba.OrderStatusTypeNew:             exch.StateNew,
+		ba.OrderStatusTypePartiallyFilled: exch.StatePartiallyFilled,
+		ba.OrderStatusTypeFilled:          exch.StateFilled,
+		ba.OrderStatusTypeCanceled:        exch.StateCancelled,
+		ba.OrderStatusTypePendingCancel:   exch.StateNew, //有成交时见 SpotState
+		ba.OrderStatusTypeRejected:        exch.StateRejected,
+		ba.OrderStatusTypeExpired:         exch.StateExpired,
+	}
+
 	SpotOrderType = map[ba.TimeInForceType]string{
 		ba.TimeInForceTypeGTC: exch.OrderGtc,
 		ba.TimeInForceTypeIOC: exch.OrderIoc,
 		ba.TimeInForceTypeFOK: exch.OrderFok,
 	}
 
+	//保证金模式, 仓位查询返回小写
+	MarginTypeMap = map[string]fs.MarginType{
+		exch.MarginIsolated: fs.MarginTypeIsolated,
+		exch.MarginCrossed:  fs.MarginTypeCrossed,
+	}
+	UnifyMarginType = map[string]string{
+		"isolated": exch.MarginIsolated,
+		"cross":    exch.MarginCrossed,
+	}
+
 	PosMap = map[fs.PositionSideType]string{
 		fs.PositionSideTypeBoth:  exch.PositionBoth,
 		fs.PositionSideTypeLong:  exch.PositionLong,
@@ -40,6 +73,32 @@ var (
 		fs.OrderStatusTypeNewADL:          exch.OrderFinished,
 	}
 
+	//强平及自动减仓单由交易所直接成交
+	UnifyOrderState = map[fs.OrderStatusType]string{
+		fs.OrderStatusTypeNew:             exch.StateNew,
+		fs.OrderStatusTypePartiallyFilled: exch.StatePartiallyFilled,
+		fs.OrderStatusTypeFilled:          exch.StateFilled,
+		fs.OrderStatusTypeCanceled:        exch.StateCancelled,
+		fs.OrderStatusTypeRejected:        exch.StateRejected,
+		fs.OrderStatusTypeExpired:         exch.StateExpired,
+		fs.OrderStatusTypeNewInsurance:    exch.StateFilled,
+		fs.OrderStatusTypeNewADL:          exch.StateFilled,
+	}
+
+	//合约条件单类型, 触发后推送的类型变为 LIMIT 或 MARKET, 原类型不变
+	TriggerOrderType = map[fs.OrderType]string{
+		fs.OrderTypeStop:             exch.OrderStop,
+		fs.OrderTypeStopMarket:       exch.OrderStop,
+		fs.OrderTypeTakeProfit:       exch.OrderTakeProfit,
+		fs.OrderTypeTakeProfitMarket: exch.OrderTakeProfit,
+	}
+
+	//合约条件单触发价格类型, 不支持指数价格
+	TriggerWorkingType = map[string]fs.WorkingType{
+		exch.TriggerLast: fs.WorkingTypeContractPrice,
+		exch.TriggerMark: fs.WorkingTypeMarkPrice,
+	}
+
 	UnifyOrderType = map[fs.TimeInForceType]string{
 		fs.TimeInForceTypeGTC: exch.OrderGtc,
 		fs.TimeInForceTypeIOC: exch.OrderIoc,
@@ -47,3 +106,11 @@ var (
 		fs.TimeInForceTypeGTX: exch.OrderPoc,
 	}
 )
+
+// SpotState 订单生命周期状态, 撤单中的订单未结束, 有成交时为部分成交
+func SpotState(status ba.OrderStatusType, filled float64) string {
+	if status == ba.OrderStatusTypePendingCancel && filled > 0 {
+		return exch.StatePartiallyFilled
+	}
+	return SpotOrderState[status]
+}
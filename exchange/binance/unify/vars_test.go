@@ -0,0 +1,27 @@
+package unify
+
+import (
+	"testing"
+
+	"high-freq-quant-go/core/exch"
+	ba "high-freq-quant-go/exchange/binance/binanceapi"
+)
+
+func TestSpotPendingCancel(t *testing.T) {
+	if SpotOrderStatus[ba.OrderStatusTypePendingCancel] != exch.OrderOpen {
+		t.Fatal("pending cancel finished")
+	}
+	if s := SpotState(ba.OrderStatusTypePendingCancel, 0); s != exch.StateNew || exch.IsFinalState(s) {
+		t.Fatalf("pending cancel got %s", s)
+	}
+	if s := SpotState(ba.OrderStatusTypePendingCancel, 0.5); s != exch.StatePartiallyFilled {
+		t.Fatalf("pending cancel filled got %s", s)
+	}
+	// 撤单中之后仍可成交或撤销
+	o := exch.Order{State: exch.StateNew}
+	for _, s := range []string{SpotState(ba.OrderStatusTypePendingCancel, 0), exch.StatePartiallyFilled, SpotState(ba.OrderStatusTypeCanceled, 1)} {
+		if err := o.SetState(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
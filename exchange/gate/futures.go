@@ -2,10 +2,10 @@ package gate
 
 import (
 	"context"
-	"errors"
 
 	"high-freq-quant-go/core/log"
 
+	"high-freq-quant-go/adapter/queue"
 	"high-freq-quant-go/adapter/text"
 
 	"high-freq-quant-go/core/exch"
@@ -20,6 +20,7 @@ type Futures struct {
 	Ctx     context.Context
 	Wss     *futures_wss.Futures
 	Api     *futures_api.GateFuturesApi
+	exch.Legacy
 
 	Exchange, Extype string
 }
@@ -33,6 +34,7 @@ func NewFuturesClient(ctx context.Context) exch.Exchange {
 		Extype:   exch.Futures,
 	}
 	ft.Api = futures_api.NewGateFuturesApi(ctx)
+	ft.Legacy = exch.NewLegacy(ft)
 	return ft
 }
 
@@ -41,7 +43,7 @@ func (mk *Futures) WssStart() error {
 		mk.Wss = futures_wss.NewGateFuturesWss(mk.Ctx)
 	}
 	if mk.Wss == nil {
-		return errors.New("wss start error")
+		return exch.ErrWssNotStarted
 	}
 	return nil
 }
@@ -50,6 +52,13 @@ func (mk *Futures) GetStatus() int {
 	return mk.Wss.GetStatus()
 }
 
+func (mk *Futures) QueueStats() []queue.Stat {
+	if mk.Wss == nil {
+		return nil
+	}
+	return mk.Wss.QueueStats()
+}
+
 func (mk *Futures) GetApiSign() string {
 	return mk.ApiSign
 }
@@ -90,7 +99,18 @@ func (mk *Futures) GetOrder(ctx context.Context) map[string]*exch.Order {
 	return res
 }
 
-func (mk *Futures) GetOrderBook(ctx context.Context) *exch.Booker {
+func (mk *Futures) GetTriggerOrder(ctx context.Context) map[string]*exch.Order {
+	if mk.Wss != nil {
+		return mk.Wss.GetTriggerOrders(ctx)
+	}
+	res, err := mk.Api.ListTriggerOrder(ctx, text.GetString(ctx, exch.CtxSymbol))
+	if err != nil {
+		return nil
+	}
+	return res
+}
+
+func (mk *Futures) GetOrderBook(ctx context.Context) exch.Booker {
 	return mk.Wss.GetBook(ctx)
 }
 
@@ -119,65 +139,176 @@ func (mk *Futures) ListAsset(ctx context.Context) (*exch.Balance, map[string]*ex
 	return bl, res
 }
 
-func (mk *Futures) CreateOrder(ctx context.Context) (*exch.Order, error) {
-	res, err := mk.Api.CreateOrder(ctx)
-	return res, err
+func (mk *Futures) PlaceOrder(ctx context.Context, req *exch.OrderRequest) (*exch.Order, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if req.TriggerPrice > 0 {
+		o, err := mk.Api.CreateTriggerOrder(ctx, req.Order())
+		if err == nil && mk.Wss != nil {
+			mk.Wss.TriggerData.Update(o)
+		}
+		return o, err
+	}
+	return mk.Api.CreateOrder(ctx, req.Order())
 }
 
-func (mk *Futures) CreateBatchOrder(ctx context.Context) ([]*exch.Order, error) {
-	res, err := mk.Api.CreateBatchOrder(ctx)
-	return res, err
+func (mk *Futures) PlaceBatchOrder(ctx context.Context, reqs []*exch.OrderRequest) ([]*exch.Order, error) {
+	if len(reqs) == 0 {
+		return nil, exch.ErrEmptyOrder
+	}
+	lists := make([]*exch.Order, 0, len(reqs))
+	for _, req := range reqs {
+		if err := req.Validate(); err != nil {
+			return nil, err
+		}
+		//条件单不支持批量
+		if req.TriggerPrice > 0 {
+			return nil, exch.ErrNotSupported
+		}
+		lists = append(lists, req.Order())
+	}
+	return mk.Api.CreateBatchOrder(ctx, lists)
 }
 
-func (mk *Futures) CannelOrder(ctx context.Context) (*exch.Order, error) {
-	res, err := mk.Api.CannelOrder(ctx)
-	return res, err
+func (mk *Futures) CancelOrder(ctx context.Context, req *exch.CancelRequest) (*exch.Order, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if req.Trigger {
+		o, err := mk.Api.CancelTriggerOrder(ctx, req.Order())
+		if err == nil && mk.Wss != nil {
+			mk.Wss.TriggerData.Remove(req.Symbol, req.Id)
+		}
+		return o, err
+	}
+	return mk.Api.CannelOrder(ctx, req.Order())
 }
 
-func (mk *Futures) CannelAllOrder(ctx context.Context) ([]*exch.Order, error) {
-	res, err := mk.Api.CannelAllOrder(ctx)
-	return res, err
+// CancelAllOrder 同时撤销交易对的条件单
+func (mk *Futures) CancelAllOrder(ctx context.Context, symbol string) ([]*exch.Order, error) {
+	if symbol == "" {
+		return nil, exch.ErrEmptySymbol
+	}
+	orders, err := mk.Api.CannelAllOrder(ctx, symbol)
+	triggers, terr := mk.Api.CancelAllTriggerOrder(ctx, symbol)
+	if terr == nil && mk.Wss != nil {
+		mk.Wss.TriggerData.Reset(symbol, nil)
+	}
+	if err == nil {
+		err = terr
+	}
+	return append(orders, triggers...), err
+}
+
+// ListOpenOrders REST 查询账号所有交易对的挂单, 紧急停止以此确认撤单
+func (mk *Futures) ListOpenOrders(ctx context.Context) ([]*exch.Order, error) {
+	return mk.Api.ListOpenOrders(ctx)
+}
+
+func (mk *Futures) AmendOrder(ctx context.Context, req *exch.AmendRequest) (*exch.AmendResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	o, err := mk.Api.AmendOrder(ctx, req.Order())
+	if err != nil {
+		return nil, err
+	}
+	return &exch.AmendResult{Order: o, Path: exch.AmendNative}, nil
+}
+
+func (mk *Futures) SetLeverage(ctx context.Context, symbol string, lv float64) (*exch.Position, error) {
+	if symbol == "" {
+		return nil, exch.ErrEmptySymbol
+	}
+	return mk.Api.UpdateLeverage(ctx, symbol, lv)
+}
+
+func (mk *Futures) SetMargin(ctx context.Context, symbol string, change float64) (*exch.Position, error) {
+	if symbol == "" {
+		return nil, exch.ErrEmptySymbol
+	}
+	return mk.Api.UpdateMargin(ctx, symbol, change)
+}
+
+func (mk *Futures) SetMarginMode(ctx context.Context, symbol, mode string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	return mk.Api.UpdateMarginMode(ctx, symbol, mode)
+}
+
+func (mk *Futures) SetPositionMode(ctx context.Context, hedge bool) error {
+	return mk.Api.UpdatePositionMode(ctx, hedge)
+}
+
+func (mk *Futures) SetRiskLimit(ctx context.Context, symbol string, limit float64) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	return mk.Api.UpdateRiskLimit(ctx, symbol, limit)
 }
 
-func (mk *Futures) UpdateLeverage(ctx context.Context) (*exch.Position, error) {
-	res, err := mk.Api.UpdateLeverage(ctx)
-	return res, err
+func (mk *Futures) FetchPosition(ctx context.Context, symbol string) (*exch.Position, error) {
+	if symbol == "" {
+		return nil, exch.ErrEmptySymbol
+	}
+	return mk.Api.GetPosition(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *Futures) UpdateMargin(ctx context.Context) (*exch.Position, error) {
-	res, err := mk.Api.UpdateMargin(ctx)
-	return res, err
+func (mk *Futures) SubscribeTicker(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	err := mk.WssStart()
+	if err != nil {
+		return err
+	}
+	return mk.Wss.SubTicker(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *Futures) SubTicker(ctx context.Context) error {
+func (mk *Futures) SubscribeOrderBook(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	err := mk.WssStart()
 	if err != nil {
 		return err
 	}
-	return mk.Wss.SubTicker(ctx)
+	return mk.Wss.SubOrderBook(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *Futures) SubOrderBook(ctx context.Context) error {
+func (mk *Futures) SubscribeUserTrade(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	err := mk.WssStart()
 	if err != nil {
 		return err
 	}
-	return mk.Wss.SubOrderBook(ctx)
+	return mk.Wss.SubUserTrade(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *Futures) SubUserTrade(ctx context.Context) error {
+func (mk *Futures) SubscribePublicTrades(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	err := mk.WssStart()
 	if err != nil {
 		return err
 	}
-	return mk.Wss.SubUserTrade(ctx)
+	return mk.Wss.SubPublicTrades(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *Futures) SubPosition(ctx context.Context) error {
+func (mk *Futures) SubscribePosition(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	err := mk.WssStart()
 	if err != nil {
 		return err
 	}
+	ctx = exch.WithSymbol(ctx, symbol)
 	err = mk.Wss.SubTicker(ctx)
 	if err != nil {
 		return err
@@ -185,20 +316,83 @@ func (mk *Futures) SubPosition(ctx context.Context) error {
 	return mk.Wss.SubPosition(ctx)
 }
 
-func (mk *Futures) SubBalance(ctx context.Context) error {
+func (mk *Futures) SubscribeBalance(ctx context.Context, symbol string) error {
 	err := mk.WssStart()
 	if err != nil {
 		return err
 	}
-	return mk.Wss.SubBalance(ctx)
+	return mk.Wss.SubBalance(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *Futures) SubOrder(ctx context.Context) error {
+func (mk *Futures) SubscribeOrder(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	err := mk.WssStart()
 	if err != nil {
 		return err
 	}
-	return mk.Wss.SubOrder(ctx)
+	return mk.Wss.SubOrder(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Futures) UnsubscribeTicker(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.Wss == nil {
+		return nil
+	}
+	return mk.Wss.UnsubTicker(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Futures) UnsubscribeOrderBook(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.Wss == nil {
+		return nil
+	}
+	return mk.Wss.UnsubOrderBook(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Futures) UnsubscribeUserTrade(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.Wss == nil {
+		return nil
+	}
+	return mk.Wss.UnsubUserTrade(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Futures) UnsubscribePublicTrades(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.Wss == nil {
+		return nil
+	}
+	return mk.Wss.UnsubPublicTrades(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Futures) UnsubscribePosition(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.Wss == nil {
+		return nil
+	}
+	return mk.Wss.UnsubPosition(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Futures) UnsubscribeOrder(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.Wss == nil {
+		return nil
+	}
+	return mk.Wss.UnsubOrder(exch.WithSymbol(ctx, symbol))
 }
 
 func init() {
@@ -36,72 +36,105 @@ func NewGateFuturesApi(ctx context.Context) *GateFuturesApi {
 	return gf
 }
 
-func (gf *GateFuturesApi) CreateOrder(ctx context.Context) (*exch.Order, error) {
-	o := exch.GetOrder(ctx)
+func (gf *GateFuturesApi) CreateOrder(ctx context.Context, o *exch.Order) (*exch.Order, error) {
 	if o == nil {
 		log.Errorln(log.Http, gf.Api.ApiSign, "GateFuturesApi CreateOrder get order error ")
-		return nil, nil
+		return nil, exch.ErrEmptyOrder
 	}
 	settle := unify.Settle(o.Symbol)
-	unit := gf.GetUnit(o.Symbol)
-	pn := gf.GetPriceFloat(o.Symbol)
-	isize := int64(o.Size / unit)
-	//todo too min size
-	if o.Size != 0 && isize == 0 {
-		isize = int64(o.Size / math.Abs(o.Size))
+	in := gf.Instrument(o.Symbol)
+	if in == nil {
+		return nil, exch.ErrNoInstrument
+	}
+	ps := gf.GetPriceScale(o.Symbol)
+	var isize int64
+	if !o.ClosePosition {
+		var err error
+		if isize, err = Contracts(in, o.Size); err != nil {
+			log.Errorln(log.Http, gf.Api.ApiSign, o.Symbol, "GateFuturesApi CreateOrder size error", o.Size, err)
+			return nil, err
+		}
 	}
 	futuresOrder := gateapi.FuturesOrder{
 		Contract: o.Symbol,
 		Size:     isize,
 	}
-	price := mather.FloatRound(o.Price, pn)
+	price := o.PriceFixed(ps)
 	if price > 0 {
-		futuresOrder.Price = convert.GetString(price)
+		futuresOrder.Price = ps.String(price)
 	} else {
 		if o.Price != 0 {
-			return nil, nil
+			return nil, exch.ErrInvalidPrice
 		}
 	}
 	if o.Tif != "" {
 		futuresOrder.Tif = o.Tif
 	}
+	if o.PostOnly {
+		futuresOrder.Tif = exch.OrderPoc
+	}
 	if o.Tif == exch.OrderIoc {
 		futuresOrder.Price = "0"
 	}
+	//平掉全部仓位时数量为 0
+	if o.ClosePosition {
+		futuresOrder.Size = 0
+		futuresOrder.Close = true
+	}
+	futuresOrder.ReduceOnly = o.ReduceOnly
+	hedge := exch.HedgeMode(gf.Ctx)
+	if hedge {
+		//双向持仓按数量方向及只减仓区分开平仓, 全部平仓时按方向指定 auto_size
+		futuresOrder.ReduceOnly = o.HedgeClose()
+		if o.ClosePosition {
+			futuresOrder.Close = false
+			futuresOrder.ReduceOnly = true
+			futuresOrder.AutoSize = unify.AutoSize[o.HedgeSide()]
+		}
+	}
 	if o.UUID != "" {
-		futuresOrder.Text = OrderPre + o.UUID
+		futuresOrder.Text = OrderPre + exch.FitClientId(exch.Gate, o.UUID)
 	}
 	if o.Iceberg != 0 {
 		futuresOrder.Iceberg = o.Iceberg
 	}
-	res, _, err := gf.Api.GetClient().FuturesApi.CreateFuturesOrder(gf.Api.Ctx, settle, futuresOrder)
+	res, _, err := gf.Api.GetClient().FuturesApi.CreateFuturesOrder(gf.Api.WithCtx(ctx), settle, futuresOrder)
 	if err != nil {
 		log.Errorf(log.Http, "%s gate GateFuturesApi CreateOrder error %s %+v \r\n", gf.Api.ApiSign, err, futuresOrder)
-		return nil, err
+		return nil, unify.OrderError(err, o.Symbol)
 	}
-	fsize := unify.UnitSize(float64(res.Size), unit)
-	fleft := unify.UnitSize(float64(res.Left), unit)
+	fsize := in.SizeFromVenue(float64(res.Size))
+	fleft := in.SizeFromVenue(float64(res.Left))
 	ro := &exch.Order{
 		Id:         convert.GetString(res.Id),
-		UUID:       res.Text,
+		UUID:       unify.ClientId(res.Text),
 		Symbol:     res.Contract,
 		Status:     res.Status,
+		State:      unify.OrderState(res.Status, res.FinishAs, fsize, fleft),
 		Size:       fsize,
 		Price:      convert.GetFloat64(res.Price),
 		FillPrice:  convert.GetFloat64(res.FillPrice),
 		Left:       fleft,
+		Tif:        res.Tif,
 		CreateTime: int64(res.CreateTime * 1000),
 		UpdateTime: int64(res.FinishTime * 1000),
+
+		ClosePosition: res.IsClose,
+		ReduceOnly:    res.IsReduceOnly,
+		PostOnly:      res.Tif == exch.OrderPoc,
+	}
+	if hedge {
+		ro.ClosePosition = o.ClosePosition
+		ro.PositionSide = o.HedgeSide()
 	}
 	log.Infoln(log.Http, gf.Api.ApiSign, o.Symbol, "GateFuturesApi CreateOrder success:p,s", ro.Price, ro.Size)
 	return ro, nil
 }
 
-func (gf *GateFuturesApi) CreateBatchOrder(ctx context.Context) ([]*exch.Order, error) {
-	lists := exch.GetOrders(ctx)
-	if lists == nil || len(lists) == 0 {
+func (gf *GateFuturesApi) CreateBatchOrder(ctx context.Context, lists []*exch.Order) ([]*exch.Order, error) {
+	if len(lists) == 0 {
 		log.Errorln(log.Http, gf.Api.ApiSign, "gate CreateBatchOrder GetOrders error ", lists)
-		return nil, nil
+		return nil, exch.ErrEmptyOrder
 	}
 	orders := []*exch.Order{}
 	var wg sync.WaitGroup
@@ -111,12 +144,13 @@ func (gf *GateFuturesApi) CreateBatchOrder(ctx context.Context) ([]*exch.Order,
 		wg.Add(1)
 		go func(o *exch.Order) {
 			defer wg.Done()
-			octx := context.WithValue(context.Background(), exch.CtxOrder, o)
-			order, err := gf.CreateOrder(octx)
+			order, err := gf.CreateOrder(ctx, o)
 			if err != nil {
 				return
 			}
+			gf.lock.Lock()
 			orders = append(orders, order)
+			gf.lock.Unlock()
 		}(o)
 	}
 	wg.Wait()
@@ -124,54 +158,106 @@ func (gf *GateFuturesApi) CreateBatchOrder(ctx context.Context) ([]*exch.Order,
 	return orders, nil
 }
 
-func (gf *GateFuturesApi) CannelOrder(ctx context.Context) (*exch.Order, error) {
-	o := exch.GetOrder(ctx)
+func (gf *GateFuturesApi) CannelOrder(ctx context.Context, o *exch.Order) (*exch.Order, error) {
 	if o == nil {
 		log.Errorln(log.Http, gf.Api.ApiSign, "GateFuturesApi CannelOrder  GetOrder id error")
-		return nil, nil
+		return nil, exch.ErrEmptyOrder
 	}
 	settle := unify.Settle(o.Symbol)
-	res, _, err := gf.Api.GetClient().FuturesApi.CancelFuturesOrder(gf.Api.Ctx, settle, o.Id)
+	res, _, err := gf.Api.GetClient().FuturesApi.CancelFuturesOrder(gf.Api.WithCtx(ctx), settle, o.Id)
 	if err != nil {
 		log.Errorln(log.Http, gf.Api.ApiSign, "GateFuturesApi CannelOrder error ", err)
 		return nil, err
 	}
-	unit := gf.GetUnit(o.Symbol)
-	fsize := float64(res.Size) * unit
-	fleft := float64(res.Left) * unit
+	in := gf.Instrument(o.Symbol)
+	fsize := in.SizeFromVenue(float64(res.Size))
+	fleft := in.SizeFromVenue(float64(res.Left))
 	ro := &exch.Order{
 		Id:         convert.GetString(res.Id),
-		UUID:       res.Text,
+		UUID:       unify.ClientId(res.Text),
 		Symbol:     res.Contract,
 		Status:     res.Status,
+		State:      unify.OrderState(res.Status, res.FinishAs, fsize, fleft),
 		Size:       fsize,
 		Price:      convert.GetFloat64(res.Price),
 		FillPrice:  convert.GetFloat64(res.FillPrice),
 		Left:       fleft,
+		Tif:        res.Tif,
+		PostOnly:   res.Tif == exch.OrderPoc,
+		ReduceOnly: res.IsReduceOnly,
 		CreateTime: int64(res.CreateTime),
 		UpdateTime: int64(res.FinishTime),
 	}
 	return ro, nil
 }
 
-func (gf *GateFuturesApi) CannelAllOrder(ctx context.Context) ([]*exch.Order, error) {
-	symbol := text.GetString(ctx, exch.CtxSymbol)
+// AmendOrder 修改挂单数量及价格, 订单Id不变, Size 为包含已成交部分的新总数量, 为 0 时不修改
+func (gf *GateFuturesApi) AmendOrder(ctx context.Context, o *exch.Order) (*exch.Order, error) {
+	if o == nil {
+		log.Errorln(log.Http, gf.Api.ApiSign, "GateFuturesApi AmendOrder GetOrder id error")
+		return nil, exch.ErrEmptyOrder
+	}
+	settle := unify.Settle(o.Symbol)
+	in := gf.Instrument(o.Symbol)
+	if in == nil {
+		return nil, exch.ErrNoInstrument
+	}
+	amend := gateapi.FuturesOrderAmendment{}
+	if o.Size != 0 {
+		size, err := Contracts(in, o.Size)
+		if err != nil {
+			log.Errorln(log.Http, gf.Api.ApiSign, o.Symbol, "GateFuturesApi AmendOrder size error", o.Size, err)
+			return nil, err
+		}
+		amend.Size = size
+	}
+	if o.Price != 0 {
+		ps := gf.GetPriceScale(o.Symbol)
+		amend.Price = ps.String(o.PriceFixed(ps))
+	}
+	res, _, err := gf.Api.GetClient().FuturesApi.AmendFuturesOrder(gf.Api.WithCtx(ctx), settle, o.Id, amend)
+	if err != nil {
+		log.Errorf(log.Http, "%s gate GateFuturesApi AmendOrder error %s %s %+v \r\n", gf.Api.ApiSign, err, o.Id, amend)
+		return nil, err
+	}
+	fsize := in.SizeFromVenue(float64(res.Size))
+	fleft := in.SizeFromVenue(float64(res.Left))
+	ro := &exch.Order{
+		Id:         convert.GetString(res.Id),
+		UUID:       unify.ClientId(res.Text),
+		Symbol:     res.Contract,
+		Status:     res.Status,
+		State:      unify.OrderState(res.Status, res.FinishAs, fsize, fleft),
+		Size:       fsize,
+		Price:      convert.GetFloat64(res.Price),
+		FillPrice:  convert.GetFloat64(res.FillPrice),
+		Left:       fleft,
+		Tif:        res.Tif,
+		CreateTime: int64(res.CreateTime * 1000),
+		UpdateTime: int64(res.FinishTime * 1000),
+	}
+	log.Infoln(log.Http, gf.Api.ApiSign, o.Symbol, "GateFuturesApi AmendOrder success:p,s", ro.Price, ro.Size)
+	return ro, nil
+}
+
+func (gf *GateFuturesApi) CannelAllOrder(ctx context.Context, symbol string) ([]*exch.Order, error) {
 	settle := unify.Settle(symbol)
-	res, _, err := gf.Api.GetClient().FuturesApi.CancelFuturesOrders(gf.Api.Ctx, settle, symbol, &gateapi.CancelFuturesOrdersOpts{})
+	res, _, err := gf.Api.GetClient().FuturesApi.CancelFuturesOrders(gf.Api.WithCtx(ctx), settle, symbol, &gateapi.CancelFuturesOrdersOpts{})
 	if err != nil {
 		log.Errorln(log.Http, gf.Api.ApiSign, symbol, "GateFuturesApi CannelAllOrder error ", err)
 		return nil, err
 	}
-	unit := gf.GetUnit(symbol)
+	in := gf.Instrument(symbol)
 	lists := []*exch.Order{}
 	for _, s := range res {
-		fsize := float64(s.Size) * unit
-		fleft := float64(s.Left) * unit
+		fsize := in.SizeFromVenue(float64(s.Size))
+		fleft := in.SizeFromVenue(float64(s.Left))
 		ro := &exch.Order{
 			Id:         convert.GetString(s.Id),
-			UUID:       s.Text,
+			UUID:       unify.ClientId(s.Text),
 			Symbol:     s.Contract,
 			Status:     s.Status,
+			State:      unify.OrderState(s.Status, s.FinishAs, fsize, fleft),
 			Size:       fsize,
 			Price:      convert.GetFloat64(s.Price),
 			FillPrice:  convert.GetFloat64(s.FillPrice),
@@ -185,116 +271,209 @@ func (gf *GateFuturesApi) CannelAllOrder(ctx context.Context) ([]*exch.Order, er
 	return lists, nil
 }
 
-func (gf *GateFuturesApi) UpdateLeverage(ctx context.Context) (*exch.Position, error) {
-	symbol := text.GetString(ctx, exch.CtxSymbol)
-	lv := text.GetString(ctx, exch.CtxLv)
+// UpdateLeverage 杠杆与当前相同时不修改, 全仓时修改全仓杠杆上限, 保证金模式不变.
+// 双向持仓时两个方向同时更新, 返回 ctx 中持仓方向的仓位, 未指定时返回净仓位
+func (gf *GateFuturesApi) UpdateLeverage(ctx context.Context, symbol string, lv float64) (*exch.Position, error) {
+	if lv == 0 {
+		return nil, exch.ErrZeroLeverage
+	}
+	cur, err := gf.GetPosition(exch.WithSymbol(ctx, symbol))
+	if err != nil {
+		return nil, err
+	}
+	if cur.Lv == lv {
+		return cur, nil
+	}
 	settle := unify.Settle(symbol)
-	res, _, err := gf.Api.GetClient().FuturesApi.UpdatePositionLeverage(gf.Api.Ctx, settle, symbol, lv, &gateapi.UpdatePositionLeverageOpts{})
+	crossed := cur.MarginType == exch.MarginCrossed
+	if exch.HedgeMode(gf.Ctx) {
+		//双向持仓接口不支持修改全仓杠杆上限
+		if crossed {
+			return nil, exch.ErrNotSupported
+		}
+		res, _, err := gf.Api.GetClient().FuturesApi.UpdateDualModePositionLeverage(gf.Api.WithCtx(ctx), settle, symbol, convert.GetString(lv))
+		if err != nil {
+			log.Errorln(log.Http, gf.Api.ApiSign, "GateFuturesApi UpdateLeverage dual mode error ", err)
+			return nil, err
+		}
+		return gf.pickPosition(symbol, exch.PositionSide(ctx), res), nil
+	}
+	leverage, opts := convert.GetString(lv), &gateapi.UpdatePositionLeverageOpts{}
+	if crossed {
+		leverage, opts.CrossLeverageLimit = "0", optional.NewString(convert.GetString(lv))
+	}
+	res, _, err := gf.Api.GetClient().FuturesApi.UpdatePositionLeverage(gf.Api.WithCtx(ctx), settle, symbol, leverage, opts)
 	if err != nil {
 		log.Errorln(log.Http, gf.Api.ApiSign, "GateFuturesApi UpdateLeverage error ", err)
 		return nil, err
 	}
-	ti := timer.MicNow()
-	mtype := exch.MarginIsolated
-	if res.Leverage == "0" {
-		mtype = exch.MarginCrossed
+	return gf.Position(res, timer.MicNow()), nil
+}
+
+// UpdateMarginMode 逐仓杠杆为 0 时为全仓, 切换时沿用当前杠杆, 已是该模式时不修改
+func (gf *GateFuturesApi) UpdateMarginMode(ctx context.Context, symbol, mode string) error {
+	if mode != exch.MarginIsolated && mode != exch.MarginCrossed {
+		return exch.ErrNotSupported
 	}
-	pos := &exch.Position{
-		Symbol:         res.Contract,
-		Price:          convert.GetFloat64(res.EntryPrice),
-		Size:           convert.GetFloat64(res.Size),
-		Margin:         convert.GetFloat64(res.Margin),
-		UnPnl:          convert.GetFloat64(res.UnrealisedPnl),
-		LiqPrice:       convert.GetFloat64(res.LiqPrice),
-		MarkPrice:      convert.GetFloat64(res.MarkPrice),
-		Lv:             convert.GetFloat64(res.Leverage),
-		MarginType:     mtype,
-		PositionMode:   unify.PositionMap[res.Mode],
-		Value:          convert.GetFloat64(res.Value),
-		LastUpdateTime: ti,
+	cur, err := gf.GetPosition(exch.WithSymbol(ctx, symbol))
+	if err != nil {
+		return err
+	}
+	if cur.MarginType == mode {
+		return nil
 	}
-	return pos, nil
+	settle := unify.Settle(symbol)
+	leverage, opts := convert.GetString(cur.Lv), &gateapi.UpdatePositionLeverageOpts{}
+	if mode == exch.MarginCrossed {
+		leverage, opts.CrossLeverageLimit = "0", optional.NewString(convert.GetString(cur.Lv))
+	}
+	if exch.HedgeMode(gf.Ctx) {
+		_, _, err = gf.Api.GetClient().FuturesApi.UpdateDualModePositionLeverage(gf.Api.WithCtx(ctx), settle, symbol, leverage)
+	} else {
+		_, _, err = gf.Api.GetClient().FuturesApi.UpdatePositionLeverage(gf.Api.WithCtx(ctx), settle, symbol, leverage, opts)
+	}
+	if err != nil {
+		log.Errorln(log.Http, gf.Api.ApiSign, symbol, "GateFuturesApi UpdateMarginMode error ", mode, err)
+		return err
+	}
+	log.Infoln(log.Http, gf.Api.ApiSign, symbol, "GateFuturesApi UpdateMarginMode success", cur.MarginType, "->", mode)
+	return nil
 }
 
-func (gf *GateFuturesApi) UpdatePositionMode(ctx context.Context) error {
-	symbol := text.GetString(ctx, exch.CtxSymbol)
+// UpdateRiskLimit 与当前风险限额相同时不修改
+func (gf *GateFuturesApi) UpdateRiskLimit(ctx context.Context, symbol string, limit float64) error {
+	cur, err := gf.GetPosition(exch.WithSymbol(ctx, symbol))
+	if err != nil {
+		return err
+	}
+	if cur.RiskLimit == limit {
+		return nil
+	}
 	settle := unify.Settle(symbol)
-	_, _, err := gf.Api.GetClient().FuturesApi.SetDualMode(gf.Api.Ctx, settle, false)
+	if exch.HedgeMode(gf.Ctx) {
+		_, _, err = gf.Api.GetClient().FuturesApi.UpdateDualModePositionRiskLimit(gf.Api.WithCtx(ctx), settle, symbol, convert.GetString(limit))
+	} else {
+		_, _, err = gf.Api.GetClient().FuturesApi.UpdatePositionRiskLimit(gf.Api.WithCtx(ctx), settle, symbol, convert.GetString(limit))
+	}
 	if err != nil {
-		log.Errorln(log.Http, gf.Api.ApiSign, "GateFuturesApi UpdateLeverage error ", err)
+		log.Errorln(log.Http, gf.Api.ApiSign, symbol, "GateFuturesApi UpdateRiskLimit error ", limit, err)
+		return err
 	}
 	return nil
 }
 
-func (gf *GateFuturesApi) UpdateMargin(ctx context.Context) (*exch.Position, error) {
+// UpdatePositionMode 切换单向或双向持仓, 已是该模式时不修改, 有仓位或挂单时交易所拒绝
+func (gf *GateFuturesApi) UpdatePositionMode(ctx context.Context, hedge bool) error {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
-	change := text.GetFloat(ctx, exch.CtxChange)
+	settle := unify.Settle(symbol)
+	acc, _, err := gf.Api.GetClient().FuturesApi.ListFuturesAccounts(gf.Api.WithCtx(ctx), settle)
+	if err != nil {
+		log.Errorln(log.Http, gf.Api.ApiSign, "GateFuturesApi ListFuturesAccounts error ", err)
+		return err
+	}
+	if acc.InDualMode == hedge {
+		return nil
+	}
+	_, _, err = gf.Api.GetClient().FuturesApi.SetDualMode(gf.Api.WithCtx(ctx), settle, hedge)
+	if err != nil {
+		log.Errorln(log.Http, gf.Api.ApiSign, "GateFuturesApi UpdatePositionMode error ", err)
+		return err
+	}
+	log.Infoln(log.Http, gf.Api.ApiSign, "GateFuturesApi UpdatePositionMode success dual:", hedge)
+	return nil
+}
+
+func (gf *GateFuturesApi) UpdateMargin(ctx context.Context, symbol string, change float64) (*exch.Position, error) {
 	if change == 0.0 {
 		log.Errorln(log.Http, "GateFuturesApi UpdateMargin error: change is 0 ")
-		return nil, nil
+		return nil, exch.ErrZeroChange
 	}
 	ch := convert.GetString(change)
 	settle := unify.Settle(symbol)
-	res, _, err := gf.Api.GetClient().FuturesApi.UpdatePositionMargin(gf.Api.Ctx, settle, symbol, ch)
+	if exch.HedgeMode(gf.Ctx) {
+		//双向持仓按 ctx 中的持仓方向调整保证金
+		side := exch.PositionSide(ctx)
+		dual, ok := unify.DualSide[side]
+		if !ok {
+			return nil, exch.ErrPositionSide
+		}
+		res, _, err := gf.Api.GetClient().FuturesApi.UpdateDualModePositionMargin(gf.Api.WithCtx(ctx), settle, symbol, ch, dual)
+		if err != nil {
+			log.Errorln(log.Http, gf.Api.ApiSign, "GateFuturesApi UpdateMargin dual mode error ", err)
+			return nil, err
+		}
+		return gf.pickPosition(symbol, side, res), nil
+	}
+	res, _, err := gf.Api.GetClient().FuturesApi.UpdatePositionMargin(gf.Api.WithCtx(ctx), settle, symbol, ch)
 	if err != nil {
 		log.Errorln(log.Http, gf.Api.ApiSign, "GateFuturesApi UpdateMargin error ", err)
 		return nil, err
 	}
-	ti := time.Now().Unix()
-	mtype := exch.MarginIsolated
-	if res.Leverage == "0" {
-		mtype = exch.MarginCrossed
-	}
-	pos := &exch.Position{
-		Symbol:         res.Contract,
-		Price:          convert.GetFloat64(res.EntryPrice),
-		Size:           convert.GetFloat64(res.Size),
-		Margin:         convert.GetFloat64(res.Margin),
-		UnPnl:          convert.GetFloat64(res.UnrealisedPnl),
-		LiqPrice:       convert.GetFloat64(res.LiqPrice),
-		MarkPrice:      convert.GetFloat64(res.MarkPrice),
-		Lv:             convert.GetFloat64(res.Leverage),
-		MarginType:     mtype,
-		PositionMode:   unify.PositionMap[res.Mode],
-		Value:          convert.GetFloat64(res.Value),
-		LastUpdateTime: ti,
-	}
-	return pos, nil
+	return gf.Position(res, timer.MicNow()), nil
 }
 
 func (gf *GateFuturesApi) GetOrder(ctx context.Context) (map[string]*exch.Order, error) {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
 	settle := unify.Settle(symbol)
-	res, _, err := gf.Api.GetClient().FuturesApi.ListFuturesOrders(gf.Api.Ctx, settle, symbol, OpenOrder, nil)
+	res, _, err := gf.Api.GetClient().FuturesApi.ListFuturesOrders(gf.Api.WithCtx(ctx), settle, symbol, OpenOrder, nil)
 	if err != nil {
 		log.Errorln(log.Http, gf.Api.ApiSign, "GateFuturesApi GetOrder error ", err)
 		return nil, err
 	}
-	unit := gf.GetUnit(symbol)
 	ti := time.Now().Unix()
 	orders := map[string]*exch.Order{}
 	for _, r := range res {
-		size := unify.UnitSize(float64(r.Size), unit)
-		left := unify.UnitSize(float64(r.Left), unit)
-		order := &exch.Order{
-			Id:         convert.GetString(r.Id),
-			UUID:       r.Text,
-			Symbol:     r.Contract,
-			Status:     r.Status,
-			Size:       size,
-			Price:      convert.GetFloat64(r.Price),
-			FillPrice:  convert.GetFloat64(r.FillPrice),
-			Left:       left,
-			Iceberg:    r.Iceberg,
-			Tif:        r.Tif,
-			CreateTime: int64(r.CreateTime),
-			UpdateTime: ti,
-		}
+		order := gf.Order(r, ti)
 		orders[order.Id] = order
 	}
 	return orders, nil
 }
 
+// ListOpenOrders 账号所有合约的挂单, 包含未触发的条件单
+func (gf *GateFuturesApi) ListOpenOrders(ctx context.Context) ([]*exch.Order, error) {
+	res, _, err := gf.Api.GetClient().FuturesApi.ListFuturesOrders(gf.Api.WithCtx(ctx), UsdtUrl, "", OpenOrder, nil)
+	if err != nil {
+		log.Errorln(log.Http, gf.Api.ApiSign, "GateFuturesApi ListOpenOrders error ", err)
+		return nil, err
+	}
+	triggers, _, err := gf.Api.GetClient().FuturesApi.ListPriceTriggeredOrders(gf.Api.WithCtx(ctx), UsdtUrl, unify.OrderOpen, nil)
+	if err != nil {
+		log.Errorln(log.Http, gf.Api.ApiSign, "GateFuturesApi ListOpenOrders trigger error ", err)
+		return nil, err
+	}
+	ti := time.Now().Unix()
+	lists := make([]*exch.Order, 0, len(res)+len(triggers))
+	for _, r := range res {
+		lists = append(lists, gf.Order(r, ti))
+	}
+	for _, r := range triggers {
+		lists = append(lists, gf.TriggerOrder(r))
+	}
+	return lists, nil
+}
+
+// Order 委托单转换为订单, 数量按合约乘数换算, 价格及数量为定点值, 数量刻度为一张
+func (gf *GateFuturesApi) Order(r gateapi.FuturesOrder, ti int64) *exch.Order {
+	in := gf.Instrument(r.Contract)
+	left := in.SizeFromVenue(float64(r.Left))
+	order := &exch.Order{
+		Id:         convert.GetString(r.Id),
+		UUID:       unify.ClientId(r.Text),
+		Symbol:     r.Contract,
+		Status:     r.Status,
+		FillPrice:  convert.GetFloat64(r.FillPrice),
+		Left:       left,
+		Iceberg:    r.Iceberg,
+		Tif:        r.Tif,
+		CreateTime: int64(r.CreateTime),
+		UpdateTime: ti,
+	}
+	order.SetSize(exch.NewScale(in.SizeFromVenue(1)), exch.Fixed(r.Size))
+	order.ParsePrice(gf.GetPriceScale(r.Contract), r.Price)
+	order.State = unify.OrderState(r.Status, r.FinishAs, order.Size, left)
+	return order
+}
+
 func (gf *GateFuturesApi) GetOrderBook(ctx context.Context) (*gateapi.FuturesOrderBook, error) {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
 	limit := text.GetString(ctx, OrderBookLimit)
@@ -304,7 +483,7 @@ func (gf *GateFuturesApi) GetOrderBook(ctx context.Context) (*gateapi.FuturesOrd
 		Limit:  optional.NewInt32(limit32),
 		WithId: optional.NewBool(true),
 	}
-	result, _, err := gf.Api.GetClient().FuturesApi.ListFuturesOrderBook(gf.Api.Ctx, settle, symbol, localVarOptionals)
+	result, _, err := gf.Api.GetClient().FuturesApi.ListFuturesOrderBook(gf.Api.WithCtx(ctx), settle, symbol, localVarOptionals)
 	if err != nil || result.Asks == nil || len(result.Asks) == 0 {
 		if e, ok := err.(gateapi.GateAPIError); ok {
 			log.Errorln(log.Http, "GateFuturesApi GetOrderBook error ", e.Error())
@@ -316,36 +495,56 @@ func (gf *GateFuturesApi) GetOrderBook(ctx context.Context) (*gateapi.FuturesOrd
 	return &result, nil
 }
 
+// GetPosition 双向持仓时返回 ctx 中持仓方向的仓位, 未指定时返回净仓位
 func (gf *GateFuturesApi) GetPosition(ctx context.Context) (*exch.Position, error) {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if exch.HedgeMode(gf.Ctx) {
+		list, err := gf.GetPositions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		side := exch.PositionSide(ctx)
+		if side == "" {
+			return exch.NetPosition(symbol, list...), nil
+		}
+		for _, pos := range list {
+			if pos.PositionMode == side {
+				return pos, nil
+			}
+		}
+		return &exch.Position{Symbol: symbol, PositionMode: side}, nil
+	}
 	settle := unify.Settle(symbol)
-	res, _, err := gf.Api.GetClient().FuturesApi.GetPosition(gf.Api.Ctx, settle, symbol)
+	res, _, err := gf.Api.GetClient().FuturesApi.GetPosition(gf.Api.WithCtx(ctx), settle, symbol)
 	if err != nil {
 		log.Errorln(log.Http, "GateFuturesApi GetPosition error ", err)
 		return nil, err
 	}
-	ti := timer.MicNow()
-	unit := gf.GetUnit(symbol)
-	size := unify.UnitSize(float64(res.Size), unit)
-	mtype := exch.MarginIsolated
-	if res.Leverage == "0" {
-		mtype = exch.MarginCrossed
+	return gf.Position(res, timer.MicNow()), nil
+}
+
+// GetPositions 交易对所有方向的仓位, 单向持仓只有一个
+func (gf *GateFuturesApi) GetPositions(ctx context.Context) ([]*exch.Position, error) {
+	if !exch.HedgeMode(gf.Ctx) {
+		pos, err := gf.GetPosition(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []*exch.Position{pos}, nil
 	}
-	pos := &exch.Position{
-		Symbol:         res.Contract,
-		Price:          convert.GetFloat64(res.EntryPrice),
-		Size:           size,
-		Margin:         convert.GetFloat64(res.Margin),
-		UnPnl:          convert.GetFloat64(res.UnrealisedPnl),
-		LiqPrice:       convert.GetFloat64(res.LiqPrice),
-		MarkPrice:      convert.GetFloat64(res.MarkPrice),
-		Lv:             convert.GetFloat64(res.Leverage),
-		MarginType:     mtype,
-		PositionMode:   unify.PositionMap[res.Mode],
-		Value:          convert.GetFloat64(res.Value),
-		LastUpdateTime: ti,
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	settle := unify.Settle(symbol)
+	res, _, err := gf.Api.GetClient().FuturesApi.GetDualModePosition(gf.Api.WithCtx(ctx), settle, symbol)
+	if err != nil {
+		log.Errorln(log.Http, "GateFuturesApi GetDualModePosition error ", err)
+		return nil, err
+	}
+	ti := timer.MicNow()
+	list := make([]*exch.Position, 0, len(res))
+	for _, r := range res {
+		list = append(list, gf.Position(r, ti))
 	}
-	return pos, nil
+	return list, nil
 }
 
 func (gf *GateFuturesApi) GetBalance(ctx context.Context) (map[string]*exch.Balance, error) {
@@ -357,7 +556,7 @@ func (gf *GateFuturesApi) GetBalance(ctx context.Context) (map[string]*exch.Bala
 		return InitBalance.D, nil
 	}
 	blmap := map[string]*exch.Balance{}
-	res, _, err := gf.Api.GetClient().WalletApi.GetTotalBalance(gf.Api.Ctx, nil)
+	res, _, err := gf.Api.GetClient().WalletApi.GetTotalBalance(gf.Api.WithCtx(ctx), nil)
 	if err != nil {
 		log.Errorln(log.Http, "GateFuturesApi GetBalance error ", err)
 		return blmap, err
@@ -383,40 +582,67 @@ func (gf *GateFuturesApi) ListPosition(ctx context.Context) (map[string]*exch.Po
 	if isinit && ti-InitPosition.T < 5000 {
 		return InitPosition.D, nil
 	}
-	res, _, err := gf.Api.GetClient().FuturesApi.ListPositions(gf.Api.Ctx, UsdtUrl)
+	res, _, err := gf.Api.GetClient().FuturesApi.ListPositions(gf.Api.WithCtx(ctx), UsdtUrl)
 	if err != nil {
 		log.Errorln(log.Http, "GateFuturesApi ListPosition error ", err)
 		return nil, err
 	}
+	//双向持仓时同一交易对有两个方向, 按 交易对:方向 区分
 	posList := map[string]*exch.Position{}
 	for _, s := range res {
-		unit := gf.GetUnit(s.Contract)
-		size := unify.UnitSize(float64(s.Size), unit)
-		mtype := exch.MarginIsolated
-		if s.Leverage == "0" {
-			mtype = exch.MarginCrossed
-		}
-		pos := &exch.Position{
-			Symbol:         s.Contract,
-			Price:          convert.GetFloat64(s.EntryPrice),
-			Size:           size,
-			Margin:         convert.GetFloat64(s.Margin),
-			UnPnl:          convert.GetFloat64(s.UnrealisedPnl),
-			LiqPrice:       convert.GetFloat64(s.LiqPrice),
-			MarkPrice:      convert.GetFloat64(s.MarkPrice),
-			Lv:             convert.GetFloat64(s.Leverage),
-			MarginType:     mtype,
-			PositionMode:   unify.PositionMap[s.Mode],
-			Value:          convert.GetFloat64(s.Value),
-			LastUpdateTime: ti,
-		}
-		posList[s.Contract] = pos
+		pos := gf.Position(s, ti)
+		posList[pos.Key()] = pos
 	}
 	InitPosition.D = posList
 	InitPosition.T = ti
 	return posList, nil
 }
 
+// Position 仓位转换, 数量按合约面值换算
+func (gf *GateFuturesApi) Position(res gateapi.Position, ti int64) *exch.Position {
+	size := float64(res.Size)
+	if in := gf.Instrument(res.Contract); in != nil {
+		size = in.SizeFromVenue(size)
+	}
+	//逐仓杠杆为 0 时为全仓, 杠杆取全仓杠杆上限
+	mtype, lv := exch.MarginIsolated, convert.GetFloat64(res.Leverage)
+	if res.Leverage == "0" {
+		mtype, lv = exch.MarginCrossed, convert.GetFloat64(res.CrossLeverageLimit)
+	}
+	return &exch.Position{
+		Symbol:         res.Contract,
+		Price:          convert.GetFloat64(res.EntryPrice),
+		Size:           size,
+		Margin:         convert.GetFloat64(res.Margin),
+		UnPnl:          convert.GetFloat64(res.UnrealisedPnl),
+		LiqPrice:       convert.GetFloat64(res.LiqPrice),
+		MarkPrice:      convert.GetFloat64(res.MarkPrice),
+		Lv:             lv,
+		MarginType:     mtype,
+		RiskLimit:      convert.GetFloat64(res.RiskLimit),
+		PositionMode:   unify.PositionMap[res.Mode],
+		Value:          convert.GetFloat64(res.Value),
+		LastUpdateTime: ti,
+	}
+}
+
+// pickPosition 双向持仓接口返回两个方向, 按持仓方向选取, 未指定时合并为净仓位
+func (gf *GateFuturesApi) pickPosition(symbol, side string, res []gateapi.Position) *exch.Position {
+	ti := timer.MicNow()
+	list := make([]*exch.Position, 0, len(res))
+	for _, r := range res {
+		pos := gf.Position(r, ti)
+		if side != "" && pos.PositionMode == side {
+			return pos
+		}
+		list = append(list, pos)
+	}
+	if side != "" {
+		return &exch.Position{Symbol: symbol, PositionMode: side, LastUpdateTime: ti}
+	}
+	return exch.NetPosition(symbol, list...)
+}
+
 func (gf *GateFuturesApi) GetBaseInfo(symbol string) (*exch.BaseInfo, error) {
 	InitInfo.sc.Lock()
 	defer InitInfo.sc.Unlock()
@@ -431,6 +657,7 @@ func (gf *GateFuturesApi) GetBaseInfo(symbol string) (*exch.BaseInfo, error) {
 		return nil, err
 	}
 	//log.Warnln(log.Http, "GateFuturesApi GetBaseInfo success ", symbol)
+	exch.RegisterInstruments(Instruments(settle, res)...)
 	ti := timer.MicNow()
 	infos := map[string]interface{}{}
 	for _, i := range res {
@@ -470,20 +697,33 @@ func (gf *GateFuturesApi) GetSignSymbol(symbol string) string {
 	return gf.Api.ApiSign + "-" + symbol
 }
 
-func (gf *GateFuturesApi) GetUnit(symbol string) float64 {
-	info, err := gf.GetBaseInfo(symbol)
-	if err != nil || info == nil {
-		log.Errorln(log.Http, "GateFuturesApi GetUnit error ")
-		return 0
+// Contracts 统一数量换算为合约张数, 不足一张或不是整数张时返回 ErrInvalidSize, 不会调整数量
+func Contracts(in *exch.Instrument, size float64) (int64, error) {
+	if size == 0 {
+		return 0, exch.ErrZeroSize
+	}
+	v := in.SizeToVenue(size)
+	n := math.Round(v)
+	if n == 0 || v != n {
+		return 0, exch.ErrInvalidSize
+	}
+	return int64(n), nil
+}
+
+// Instrument 合约信息, 数量按 quanto_multiplier 在张数与交易币数量之间换算, 未找到时等待加载, 仍未找到返回 nil
+func (gf *GateFuturesApi) Instrument(symbol string) *exch.Instrument {
+	in := exch.WaitInstrument(exch.Gate, exch.Futures, symbol)
+	if in == nil {
+		log.Errorln(log.Http, "GateFuturesApi Instrument error ", symbol)
 	}
-	return info.Unit
+	return in
 }
 
-func (gf *GateFuturesApi) GetPriceFloat(symbol string) int {
+func (gf *GateFuturesApi) GetPriceScale(symbol string) exch.Scale {
 	info, err := gf.GetBaseInfo(symbol)
 	if err != nil || info == nil {
-		log.Errorln(log.Http, "GateFuturesApi GetUnit error ")
-		return 0
+		log.Errorln(log.Http, "GateFuturesApi GetPriceScale error ")
+		return exch.Scale{}
 	}
-	return info.PriceFloat
+	return info.PriceScale()
 }
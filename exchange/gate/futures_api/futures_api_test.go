@@ -0,0 +1,53 @@
+package futures_api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"high-freq-quant-go/core/exch"
+)
+
+func TestContracts(t *testing.T) {
+	in := &exch.Instrument{ContractSize: 0.0001}
+	cases := []struct {
+		size float64
+		want int64
+		err  error
+	}{
+		{0.0003, 3, nil},
+		{-0.0102, -102, nil},
+		{0.00005, 0, exch.ErrInvalidSize},
+		{0.00015, 0, exch.ErrInvalidSize},
+		{0, 0, exch.ErrZeroSize},
+	}
+	for _, c := range cases {
+		if got, err := Contracts(in, c.size); got != c.want || err != c.err {
+			t.Fatalf("%v got %v %v", c.size, got, err)
+		}
+	}
+}
+
+func TestListOpenOrders(t *testing.T) {
+	gf := testApi(t, "list-open", false, func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["contract"]; ok {
+			t.Errorf("%s contract set %s", r.URL.Path, r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/futures/usdt/orders":
+			w.Write([]byte(`[{"id":1,"contract":"BTC_USDT","size":100,"left":100,"status":"open"}]`))
+		case "/futures/usdt/price_orders":
+			w.Write([]byte(`[{"id":2,"initial":{"contract":"BTC_USDT","size":-100},"status":"open"}]`))
+		default:
+			t.Errorf("path got %s", r.URL.Path)
+		}
+	})
+	orders, err := gf.ListOpenOrders(context.Background())
+	if err != nil || len(orders) != 2 {
+		t.Fatalf("got %v %v", orders, err)
+	}
+	if orders[0].Symbol != "BTC_USDT" || orders[0].Size != 0.01 || orders[1].Size != -0.01 {
+		t.Fatalf("orders %+v %+v", orders[0], orders[1])
+	}
+}
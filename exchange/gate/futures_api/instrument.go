@@ -0,0 +1,67 @@
+package futures_api
+
+import (
+	"context"
+	"strings"
+
+	"high-freq-quant-go/adapter/convert"
+	"high-freq-quant-go/adapter/timer"
+	"high-freq-quant-go/core/exch"
+	"high-freq-quant-go/core/log"
+	"high-freq-quant-go/exchange/gate/gateapi"
+)
+
+// Settles 加载合约信息的结算货币
+var Settles = []string{"usdt", "btc"}
+
+func init() {
+	exch.RegisterInstrumentLoader(exch.Gate, exch.Futures, LoadInstruments)
+}
+
+// LoadInstruments 用公共接口拉取各结算货币的合约列表, 为 exch 合约信息加载函数
+func LoadInstruments() ([]*exch.Instrument, error) {
+	api := NewGateApiClient(context.Background())
+	var ins []*exch.Instrument
+	var lastErr error
+	for _, settle := range Settles {
+		res, _, err := api.GetClient().FuturesApi.ListFuturesContracts(api.Ctx, settle)
+		if err != nil {
+			log.Errorln(log.Http, "GateFuturesApi LoadInstruments error ", settle, err)
+			lastErr = err
+			continue
+		}
+		ins = append(ins, Instruments(settle, res)...)
+	}
+	if len(ins) == 0 {
+		return nil, lastErr
+	}
+	return ins, nil
+}
+
+// Instruments 合约名即统一交易对, 下单及推送数量为张数, 一张为 quanto_multiplier 个交易币
+func Instruments(settle string, contracts []gateapi.Contract) []*exch.Instrument {
+	ti := timer.MicNow()
+	ins := make([]*exch.Instrument, 0, len(contracts))
+	for _, c := range contracts {
+		pq := strings.SplitN(c.Name, "_", 2)
+		if len(pq) != 2 {
+			continue
+		}
+		ins = append(ins, &exch.Instrument{
+			Symbol:         c.Name,
+			VenueSymbol:    c.Name,
+			Exname:         exch.Gate,
+			Extype:         exch.Futures,
+			Base:           pq[0],
+			Quote:          pq[1],
+			Settle:         strings.ToUpper(settle),
+			Multiplier:     1,
+			ContractSize:   convert.GetFloat64(c.QuantoMultiplier),
+			TickSize:       convert.GetFloat64(c.OrderPriceRound),
+			StepSize:       1,
+			MinSize:        float64(c.OrderSizeMin),
+			LastUpdateTime: ti,
+		})
+	}
+	return ins
+}
@@ -0,0 +1,176 @@
+package futures_api
+
+import (
+	"context"
+
+	"github.com/antihax/optional"
+
+	"high-freq-quant-go/adapter/convert"
+	"high-freq-quant-go/core/exch"
+	"high-freq-quant-go/core/log"
+	"high-freq-quant-go/exchange/gate/gateapi"
+	"high-freq-quant-go/exchange/gate/unify"
+)
+
+const (
+	ruleGte int32 = 1 //价格大于等于触发价
+	ruleLte int32 = 2 //价格小于等于触发价
+)
+
+// CreateTriggerOrder 下价格条件单, 触发后按 Price 下单, Price 为 0 时按市价 ioc,
+// ClosePosition 时触发后平掉全部仓位
+func (gf *GateFuturesApi) CreateTriggerOrder(ctx context.Context, o *exch.Order) (*exch.Order, error) {
+	if o == nil {
+		log.Errorln(log.Http, gf.Api.ApiSign, "GateFuturesApi CreateTriggerOrder get order error ")
+		return nil, exch.ErrEmptyOrder
+	}
+	in := gf.Instrument(o.Symbol)
+	if in == nil {
+		return nil, exch.ErrNoInstrument
+	}
+	by := o.TriggerBy
+	if by == "" {
+		by = exch.TriggerLast
+	}
+	priceType, ok := unify.TriggerPriceType[by]
+	if !ok {
+		return nil, exch.ErrNotSupported
+	}
+	ps := gf.GetPriceScale(o.Symbol)
+	trigger := ps.FromFloat(o.TriggerPrice)
+	if trigger <= 0 {
+		return nil, exch.ErrInvalidPrice
+	}
+	rule := ruleLte
+	if o.TriggerRising() {
+		rule = ruleGte
+	}
+	initial := gateapi.FuturesInitialOrder{
+		Contract: o.Symbol,
+		Price:    "0",
+		Tif:      exch.OrderIoc,
+	}
+	hedge := exch.HedgeMode(gf.Ctx)
+	if o.ClosePosition {
+		//双向持仓的条件单不支持触发后全部平仓
+		if hedge {
+			return nil, exch.ErrNotSupported
+		}
+		initial.Close = true
+	} else {
+		size, err := Contracts(in, o.Size)
+		if err != nil {
+			log.Errorln(log.Http, gf.Api.ApiSign, o.Symbol, "GateFuturesApi CreateTriggerOrder size error", o.Size, err)
+			return nil, err
+		}
+		initial.Size = size
+		initial.ReduceOnly = o.ReduceOnly || (hedge && o.HedgeClose())
+	}
+	if o.Price != 0 {
+		price := o.PriceFixed(ps)
+		if price <= 0 {
+			return nil, exch.ErrInvalidPrice
+		}
+		initial.Price = ps.String(price)
+		if o.Tif != exch.OrderIoc {
+			initial.Tif = exch.OrderGtc
+		}
+	}
+	req := gateapi.FuturesPriceTriggeredOrder{
+		Initial: initial,
+		Trigger: gateapi.FuturesPriceTrigger{
+			PriceType: priceType,
+			Price:     ps.String(trigger),
+			Rule:      rule,
+		},
+	}
+	settle := unify.Settle(o.Symbol)
+	res, _, err := gf.Api.GetClient().FuturesApi.CreatePriceTriggeredOrder(gf.Api.WithCtx(ctx), settle, req)
+	if err != nil {
+		log.Errorf(log.Http, "%s gate GateFuturesApi CreateTriggerOrder error %s %+v \r\n", gf.Api.ApiSign, err, req)
+		return nil, err
+	}
+	req.Id = res.Id
+	req.Status = unify.OrderOpen
+	ro := gf.TriggerOrder(req)
+	ro.UUID = o.UUID
+	log.Infoln(log.Http, gf.Api.ApiSign, o.Symbol, "GateFuturesApi CreateTriggerOrder success:t,p,s", ro.TriggerPrice, ro.Price, ro.Size)
+	return ro, nil
+}
+
+func (gf *GateFuturesApi) CancelTriggerOrder(ctx context.Context, o *exch.Order) (*exch.Order, error) {
+	if o == nil {
+		log.Errorln(log.Http, gf.Api.ApiSign, "GateFuturesApi CancelTriggerOrder GetOrder id error")
+		return nil, exch.ErrEmptyOrder
+	}
+	settle := unify.Settle(o.Symbol)
+	res, _, err := gf.Api.GetClient().FuturesApi.CancelPriceTriggeredOrder(gf.Api.WithCtx(ctx), settle, o.Id)
+	if err != nil {
+		log.Errorln(log.Http, gf.Api.ApiSign, o.Symbol, "GateFuturesApi CancelTriggerOrder error ", err)
+		return nil, err
+	}
+	return gf.TriggerOrder(res), nil
+}
+
+func (gf *GateFuturesApi) CancelAllTriggerOrder(ctx context.Context, symbol string) ([]*exch.Order, error) {
+	settle := unify.Settle(symbol)
+	res, _, err := gf.Api.GetClient().FuturesApi.CancelPriceTriggeredOrderList(gf.Api.WithCtx(ctx), settle, symbol)
+	if err != nil {
+		log.Errorln(log.Http, gf.Api.ApiSign, symbol, "GateFuturesApi CancelAllTriggerOrder error ", err)
+		return nil, err
+	}
+	lists := make([]*exch.Order, 0, len(res))
+	for _, r := range res {
+		lists = append(lists, gf.TriggerOrder(r))
+	}
+	return lists, nil
+}
+
+// ListTriggerOrder 交易对未触发的条件单
+func (gf *GateFuturesApi) ListTriggerOrder(ctx context.Context, symbol string) (map[string]*exch.Order, error) {
+	settle := unify.Settle(symbol)
+	res, _, err := gf.Api.GetClient().FuturesApi.ListPriceTriggeredOrders(gf.Api.WithCtx(ctx), settle, unify.OrderOpen, &gateapi.ListPriceTriggeredOrdersOpts{
+		Contract: optional.NewString(symbol),
+	})
+	if err != nil {
+		log.Errorln(log.Http, gf.Api.ApiSign, symbol, "GateFuturesApi ListTriggerOrder error ", err)
+		return nil, err
+	}
+	orders := map[string]*exch.Order{}
+	for _, r := range res {
+		o := gf.TriggerOrder(r)
+		orders[o.Id] = o
+	}
+	return orders, nil
+}
+
+// TriggerOrder 条件单转换为订单, 类型按触发方向及数量方向区分止损和止盈
+func (gf *GateFuturesApi) TriggerOrder(res gateapi.FuturesPriceTriggeredOrder) *exch.Order {
+	symbol := res.Initial.Contract
+	size := float64(res.Initial.Size)
+	if in := gf.Instrument(symbol); in != nil {
+		size = in.SizeFromVenue(size)
+	}
+	status, state := unify.TriggerState(res.Status, res.FinishAs)
+	otype := exch.OrderStop
+	if (res.Trigger.Rule == ruleGte) != (size > 0) && size != 0 {
+		otype = exch.OrderTakeProfit
+	}
+	return &exch.Order{
+		Id:            convert.GetString(res.Id),
+		TradeId:       convert.GetString(res.TradeId),
+		Symbol:        symbol,
+		Status:        status,
+		State:         state,
+		Size:          size,
+		Price:         convert.GetFloat64(res.Initial.Price),
+		Tif:           res.Initial.Tif,
+		Text:          res.Reason,
+		Ordertype:     otype,
+		TriggerPrice:  convert.GetFloat64(res.Trigger.Price),
+		TriggerBy:     unify.TriggerBy(res.Trigger.PriceType),
+		ClosePosition: res.Initial.Close || res.Initial.IsClose,
+		CreateTime:    int64(res.CreateTime * 1000),
+		UpdateTime:    int64(res.FinishTime * 1000),
+	}
+}
@@ -0,0 +1,62 @@
+package futures_api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"high-freq-quant-go/core/exch"
+	"high-freq-quant-go/exchange/gate/gateapi"
+)
+
+// testApi 请求发到本地服务, 合约信息直接写入缓存
+func testApi(t *testing.T, sign string, hedge bool, handler http.HandlerFunc) *GateFuturesApi {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	ctx := context.WithValue(context.Background(), exch.ApiSign, sign)
+	ctx = context.WithValue(ctx, exch.Key, "key")
+	ctx = context.WithValue(ctx, exch.Secret, "secret")
+	ctx = exch.WithHedgeMode(ctx, hedge)
+	gf := NewGateFuturesApi(ctx)
+	gf.Api.GetClient().GetConfig().BasePath = srv.URL
+	exch.RegisterInstruments(&exch.Instrument{
+		Symbol: "BTC_USDT", VenueSymbol: "BTC_USDT", Exname: exch.Gate, Extype: exch.Futures,
+		Base: "BTC", Quote: "USDT", Settle: "USDT", ContractSize: 0.0001, TickSize: 0.1, StepSize: 1, MinSize: 1,
+	})
+	InitInfo.D.Set(gf.GetSignSymbol("BTC_USDT"), &exch.BaseInfo{Symbol: "BTC_USDT", MinPriceStep: 0.1})
+	return gf
+}
+
+func TestCreateTriggerOrderReduceOnly(t *testing.T) {
+	cases := []struct {
+		sign  string
+		hedge bool
+		o     exch.Order
+		want  bool
+	}{
+		{"trigger-oneway", false, exch.Order{Size: -0.01, ReduceOnly: true}, true},
+		{"trigger-oneway-open", false, exch.Order{Size: -0.01}, false},
+		{"trigger-hedge", true, exch.Order{Size: -0.01, PositionSide: exch.PositionLong}, true},
+	}
+	for _, c := range cases {
+		var got gateapi.FuturesPriceTriggeredOrder
+		gf := testApi(t, c.sign, c.hedge, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/futures/usdt/price_orders" {
+				t.Errorf("%s path got %s", c.sign, r.URL.Path)
+			}
+			json.NewDecoder(r.Body).Decode(&got)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":1}`))
+		})
+		o := c.o
+		o.Symbol, o.TriggerPrice = "BTC_USDT", 30000
+		if _, err := gf.CreateTriggerOrder(context.Background(), &o); err != nil {
+			t.Fatalf("%s got %v", c.sign, err)
+		}
+		if got.Initial.ReduceOnly != c.want || got.Initial.Size != -100 {
+			t.Fatalf("%s payload got %+v", c.sign, got.Initial)
+		}
+	}
+}
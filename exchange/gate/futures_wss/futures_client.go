@@ -3,6 +3,7 @@ package futures_wss
 import (
 	"context"
 	"encoding/json"
+	"sync"
 	"time"
 
 	"high-freq-quant-go/adapter/client"
@@ -32,6 +33,7 @@ type FuturesClient struct {
 
 	//reconnect
 	ReConnectMsg []client.SubscribeData
+	rl           sync.Mutex
 }
 
 func NewFuturesClient(ctx context.Context) (*FuturesClient, error) {
@@ -54,6 +56,7 @@ func (ws *FuturesClient) NewClient() error {
 	ctx = context.WithValue(ctx, client.Keepalive, true)
 	ctx = context.WithValue(ctx, client.Timeout, WssTimeout)
 	ctx = context.WithValue(ctx, client.MsgLen, exch.WSChannelLen)
+	ctx = context.WithValue(ctx, client.Overflow, exch.GetOverflow(ws.Ctx, exch.LaneWss))
 	ctx = context.WithValue(ctx, client.Id, ws.Sign)
 	wss, err := client.NewWssSocket(ctx, ws.ReConnect)
 	if err != nil {
@@ -74,7 +77,7 @@ func (ws *FuturesClient) Tickers(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	ws.RegisterMsg(ws.Tickers, ctx)
+	ws.RegisterMsg(client.SubscribeKey(ChannelTickers, symbol), ws.Tickers, ctx)
 	ctx = context.WithValue(ctx, client.SendMsg, msg)
 	err = ws.Wss.SendMsg(ctx)
 	return err
@@ -82,15 +85,12 @@ func (ws *FuturesClient) Tickers(ctx context.Context) error {
 
 func (ws *FuturesClient) OrderBook(ctx context.Context) error {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
-	frequency := "100ms"  // 100ms, 1000ms
-	level := OrderBookNum // 20,10,5
-	params := []string{symbol, frequency, level}
-	msg, err := ws.SubscribeChannel(ChannelDepthUpdate, params)
+	msg, err := ws.SubscribeChannel(ChannelDepthUpdate, bookParams(symbol))
 	if err != nil {
 		return err
 	}
 	//todo error no do
-	ws.RegisterMsg(ws.OrderBook, ctx)
+	ws.RegisterMsg(client.SubscribeKey(ChannelDepthUpdate, symbol), ws.OrderBook, ctx)
 	ctx = context.WithValue(ctx, client.SendMsg, msg)
 	err = ws.Wss.SendMsg(ctx)
 	return err
@@ -103,7 +103,7 @@ func (ws *FuturesClient) UserTrades(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	ws.RegisterMsg(ws.UserTrades, ctx)
+	ws.RegisterMsg(client.SubscribeKey(ChannelUserTrade, symbol), ws.UserTrades, ctx)
 	ctx = context.WithValue(ctx, client.SendMsg, msg)
 	err = ws.Wss.SendMsg(ctx)
 	return err
@@ -116,7 +116,20 @@ func (ws *FuturesClient) Order(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	ws.RegisterMsg(ws.Order, ctx)
+	ws.RegisterMsg(client.SubscribeKey(ChannelOrders, symbol), ws.Order, ctx)
+	ctx = context.WithValue(ctx, client.SendMsg, msg)
+	err = ws.Wss.SendMsg(ctx)
+	return err
+}
+
+func (ws *FuturesClient) AutoOrder(ctx context.Context) error {
+	uid := text.GetString(ws.Ctx, exch.Uid)
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	msg, err := ws.SubscribeChannel(ChannelAutoOrders, []string{uid, symbol})
+	if err != nil {
+		return err
+	}
+	ws.RegisterMsg(client.SubscribeKey(ChannelAutoOrders, symbol), ws.AutoOrder, ctx)
 	ctx = context.WithValue(ctx, client.SendMsg, msg)
 	err = ws.Wss.SendMsg(ctx)
 	return err
@@ -129,7 +142,7 @@ func (ws *FuturesClient) Position(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	ws.RegisterMsg(ws.Position, ctx)
+	ws.RegisterMsg(client.SubscribeKey(ChannelPositions, symbol), ws.Position, ctx)
 	ctx = context.WithValue(ctx, client.SendMsg, msg)
 	err = ws.Wss.SendMsg(ctx)
 	return err
@@ -141,14 +154,61 @@ func (ws *FuturesClient) Balance(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	ws.RegisterMsg(ws.Balance, ctx)
+	ws.RegisterMsg(client.SubscribeKey(ChannelBalances, ""), ws.Balance, ctx)
 	ctx = context.WithValue(ctx, client.SendMsg, msg)
 	err = ws.Wss.SendMsg(ctx)
 	return err
 }
 
-func (ws *FuturesClient) Trades() error {
-	return nil
+func bookParams(symbol string) []string {
+	frequency := "100ms"  // 100ms, 1000ms
+	level := OrderBookNum // 20,10,5
+	return []string{symbol, frequency, level}
+}
+
+func (ws *FuturesClient) UnTickers(symbol string) error {
+	return ws.Unsubscribe(ChannelTickers, symbol, []string{symbol})
+}
+
+func (ws *FuturesClient) UnOrderBook(symbol string) error {
+	return ws.Unsubscribe(ChannelDepthUpdate, symbol, bookParams(symbol))
+}
+
+func (ws *FuturesClient) UnUserTrades(symbol string) error {
+	uid := text.GetString(ws.Ctx, exch.Uid)
+	return ws.Unsubscribe(ChannelUserTrade, symbol, []string{uid, symbol})
+}
+
+func (ws *FuturesClient) UnOrder(symbol string) error {
+	uid := text.GetString(ws.Ctx, exch.Uid)
+	return ws.Unsubscribe(ChannelOrders, symbol, []string{uid, symbol})
+}
+
+func (ws *FuturesClient) UnAutoOrder(symbol string) error {
+	uid := text.GetString(ws.Ctx, exch.Uid)
+	return ws.Unsubscribe(ChannelAutoOrders, symbol, []string{uid, symbol})
+}
+
+func (ws *FuturesClient) UnPosition(symbol string) error {
+	uid := text.GetString(ws.Ctx, exch.Uid)
+	return ws.Unsubscribe(ChannelPositions, symbol, []string{uid, symbol})
+}
+
+// Trades 公开逐笔成交
+func (ws *FuturesClient) Trades(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	msg, err := ws.SubscribeChannel(ChannelTrade, []string{symbol})
+	if err != nil {
+		return err
+	}
+	ws.RegisterMsg(client.SubscribeKey(ChannelTrade, symbol), ws.Trades, ctx)
+	ctx = context.WithValue(ctx, client.SendMsg, msg)
+	err = ws.Wss.SendMsg(ctx)
+	return err
+}
+
+func (ws *FuturesClient) UnTrades(symbol string) error {
+	return ws.Unsubscribe(ChannelTrade, symbol, []string{symbol})
 }
 
 func (ws *FuturesClient) ReceivedMsg() {
@@ -194,21 +254,40 @@ func (ws *FuturesClient) SignMsg(subtype, channel string, payload []string) ([]b
 	return msgByte, nil
 }
 
-func (ws *FuturesClient) RegisterMsg(action func(ctx context.Context) error, param context.Context) {
+// RegisterMsg 记录重连后需要重新订阅的频道, 相同 Key 只保留最新一条
+func (ws *FuturesClient) RegisterMsg(key string, action func(ctx context.Context) error, param context.Context) {
 	if text.GetBool(param, client.IsReconnect) {
 		ch := exch.GetChan(param)
 		if ch != nil {
-			*ch <- 1
+			select {
+			case *ch <- 1:
+			case <-param.Done():
+			}
 		}
 		return
 	}
 	param = context.WithValue(param, client.IsReconnect, true)
 	method := client.SubscribeData{
+		Key:    key,
 		Action: &action,
 		Param:  param,
 	}
-	ws.ReConnectMsg = append(ws.ReConnectMsg, method)
-	return
+	ws.rl.Lock()
+	ws.ReConnectMsg = append(client.RemoveSubscribe(ws.ReConnectMsg, key), method)
+	ws.rl.Unlock()
+}
+
+// Unsubscribe 发送取消订阅并从重连列表删除
+func (ws *FuturesClient) Unsubscribe(channel, symbol string, payload []string) error {
+	ws.rl.Lock()
+	ws.ReConnectMsg = client.RemoveSubscribe(ws.ReConnectMsg, client.SubscribeKey(channel, symbol))
+	ws.rl.Unlock()
+	msg, err := ws.UnSubscribeChannel(channel, payload)
+	if err != nil {
+		return err
+	}
+	ctx := context.WithValue(ws.Ctx, client.SendMsg, msg)
+	return ws.Wss.CancelMsg(ctx)
 }
 
 func (ws *FuturesClient) ReConnect(err error) error {
@@ -222,7 +301,10 @@ func (ws *FuturesClient) ReConnect(err error) error {
 		return conErr
 	}
 	log.Warnln(log.Wss, ws.Sign, "gate wss FuturesClient start reconnect")
-	for _, m := range ws.ReConnectMsg {
+	ws.rl.Lock()
+	msgs := append([]client.SubscribeData{}, ws.ReConnectMsg...)
+	ws.rl.Unlock()
+	for _, m := range msgs {
 		fun := *m.Action
 		ctx := m.Param
 		err := fun(ctx)
@@ -0,0 +1,145 @@
+package futures_wss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"high-freq-quant-go/adapter/client"
+	"high-freq-quant-go/core/exch"
+
+	"github.com/gorilla/websocket"
+	cmap "github.com/orcaman/concurrent-map"
+)
+
+// subServer 按 事件 频道.交易对 记录收到的订阅及取消订阅
+func subServer(t *testing.T) (*httptest.Server, chan string) {
+	msgs := make(chan string, 100)
+	up := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := up.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		for {
+			_, b, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg Msg
+			if err := json.Unmarshal(b, &msg); err != nil {
+				t.Errorf("bad msg %s", b)
+				return
+			}
+			// 用户频道第一个参数为 uid, 测试中为空
+			symbol := msg.Payload[0]
+			if symbol == "" && len(msg.Payload) > 1 {
+				symbol = msg.Payload[1]
+			}
+			msgs <- msg.Event + " " + client.SubscribeKey(msg.Channel, symbol)
+		}
+	}))
+	return srv, msgs
+}
+
+// collect 读取 n 条消息并排序, 之后没有多余消息
+func collect(t *testing.T, msgs chan string, n int) []string {
+	var res []string
+	for len(res) < n {
+		select {
+		case m := <-msgs:
+			res = append(res, m)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("got %v want %d msgs", res, n)
+		}
+	}
+	select {
+	case m := <-msgs:
+		t.Fatalf("unexpected msg %s after %v", m, res)
+	case <-time.After(100 * time.Millisecond):
+	}
+	sort.Strings(res)
+	return res
+}
+
+// 取消订阅的交易对重连后不再订阅, 且删除该交易对的订单薄, 成交及委托单数据
+func TestUnsubscribeReconnect(t *testing.T) {
+	srv, msgs := subServer(t)
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q := make(exch.MsgQueue, 10)
+	cl := &FuturesClient{Sign: "test", Ctx: ctx, MsgQueue: &q}
+	wctx := context.WithValue(ctx, client.WssUrl, "ws"+strings.TrimPrefix(srv.URL, "http"))
+	wctx = context.WithValue(wctx, client.MsgLen, int64(10))
+	// 不启动读取协程, 由测试直接调用重连
+	cl.Wss = &client.WssSocket{Ctx: wctx, ErrorHandle: cl.ReConnect}
+	cl.Wss.Init()
+	if err := cl.Wss.Connent(); err != nil {
+		t.Fatal(err)
+	}
+	ws := testWss()
+	ws.Ctx, ws.Cl = ctx, cl
+	ws.Instruments = map[string]*exch.Instrument{}
+	ws.Bookers, ws.BookKinds, ws.PublicTrade = cmap.New(), cmap.New(), cmap.New()
+	ws.PositionData, ws.cancels = cmap.New(), cmap.New()
+	ws.OrderData = map[string]map[string]*exch.Order{}
+	ws.TriggerData = exch.NewTriggerBook()
+	for _, symbol := range []string{"BTC_USDT", "ETH_USDT"} {
+		ws.Instruments[symbol] = &exch.Instrument{Symbol: symbol}
+		sctx := exch.WithSymbol(ctx, symbol)
+		if err := ws.SubOrderBook(sctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := ws.SubUserTrade(sctx); err != nil {
+			t.Fatal(err)
+		}
+		ws.OrderData[symbol] = map[string]*exch.Order{}
+		if err := cl.Order(sctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	collect(t, msgs, 6)
+
+	bctx := exch.WithSymbol(ctx, "BTC_USDT")
+	for _, unsub := range []func(context.Context) error{ws.UnsubOrderBook, ws.UnsubUserTrade, ws.UnsubOrder} {
+		if err := unsub(bctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{
+		"unsubscribe futures.autoorders.BTC_USDT",
+		"unsubscribe futures.order_book_update.BTC_USDT",
+		"unsubscribe futures.orders.BTC_USDT",
+		"unsubscribe futures.usertrades.BTC_USDT",
+	}
+	if got := collect(t, msgs, 4); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("unsubscribe got %v", got)
+	}
+	if ws.Bookers.Has("BTC_USDT") || ws.BookKinds.Has("BTC_USDT") || ws.TradeData["BTC_USDT"] != nil ||
+		ws.OrderData["BTC_USDT"] != nil || ws.Instruments["BTC_USDT"] != nil {
+		t.Fatal("BTC_USDT data not removed")
+	}
+	if !ws.Bookers.Has("ETH_USDT") || ws.TradeData["ETH_USDT"] == nil || ws.OrderData["ETH_USDT"] == nil {
+		t.Fatal("ETH_USDT data removed")
+	}
+
+	if err := cl.ReConnect(errors.New("closed")); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		"subscribe futures.order_book_update.ETH_USDT",
+		"subscribe futures.orders.ETH_USDT",
+		"subscribe futures.usertrades.ETH_USDT",
+	}
+	if got := collect(t, msgs, 3); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("resubscribe got %v", got)
+	}
+}
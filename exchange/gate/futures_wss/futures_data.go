@@ -2,15 +2,19 @@ package futures_wss
 
 import (
 	"context"
+	"math"
 	"strings"
 	"sync"
 	"time"
 
 	cmap "github.com/orcaman/concurrent-map"
 
+	"high-freq-quant-go/adapter/client"
 	"high-freq-quant-go/adapter/convert"
+	"high-freq-quant-go/adapter/queue"
 	"high-freq-quant-go/adapter/text"
 	"high-freq-quant-go/core/exch"
+	_ "high-freq-quant-go/core/exch/tbooker"
 	"high-freq-quant-go/core/log"
 	"high-freq-quant-go/exchange/gate/futures_api"
 	"high-freq-quant-go/exchange/gate/unify"
@@ -24,12 +28,15 @@ type Futures struct {
 	//param
 	Ctx context.Context
 
-	Units map[string]float64
+	Instruments map[string]*exch.Instrument //交易对合约信息, 数量按张换算
 	//return data
 	BaseData     cmap.ConcurrentMap
 	Bookers      cmap.ConcurrentMap
+	BookKinds    cmap.ConcurrentMap //交易对订单薄实现
 	TradeData    map[string]*chan *exch.Order
+	PublicTrade  cmap.ConcurrentMap //已订阅公开成交的交易对
 	OrderData    map[string]map[string]*exch.Order
+	TriggerData  *exch.TriggerBook //未触发的价格条件单
 	PositionData cmap.ConcurrentMap
 	BalanceData  cmap.ConcurrentMap
 
@@ -37,38 +44,59 @@ type Futures struct {
 	Cl *FuturesClient
 
 	//struct msg chan
-	OrderBookQueue *chan *DepthUpdateAllEvent
-	UserDataQueue  *chan interface{}
+	OrderBookQueue *queue.Queue //订单薄增量 exch.LaneDepth
+	UserDataQueue  *queue.Queue //其他消息 exch.LaneUser
 
 	//struct msg to public
 	BookDataChannel *chan interface{}
 	BookMsgChan     *chan interface{}
+	Feed            *exch.Feed
+
+	bookData, bookMsg *queue.Queue
+
+	//订单薄异常检查及 REST 快照校验, 异常时通过 resync 在 OrderBookEvent 中重新拉取快照
+	Monitor  *exch.BookMonitor
+	Verifier *exch.BookVerifier
+	resync   chan string
+
+	//定时按 REST 校对仓位及资金, 推送丢失时更正
+	Reconciler *exch.Reconciler
+
+	cancels cmap.ConcurrentMap //订阅 Key 对应的重连初始化协程, 取消订阅时结束
 
 	ul, bal, bdl, bl, tdl, odl, pdl sync.RWMutex
 }
 
 func NewGateFuturesWss(ctx context.Context) *Futures {
 	apiSign := text.GetString(ctx, exch.ApiSign)
-	bq := make(chan *DepthUpdateAllEvent, exch.MsgChannelLen)
-	uq := make(chan interface{}, exch.MsgChannelLen)
 	sg := text.GetString(ctx, exch.ConnSign)
 	ft := &Futures{
-		ApiSign: apiSign,
-		Sign:    sg,
-		Units:   map[string]float64{},
-		Ctx:     ctx,
+		ApiSign:     apiSign,
+		Sign:        sg,
+		Instruments: map[string]*exch.Instrument{},
+		Ctx:         ctx,
 
 		BaseData:     cmap.New(),
 		Bookers:      cmap.New(),
+		BookKinds:    cmap.New(),
 		TradeData:    map[string]*chan *exch.Order{},
+		PublicTrade:  cmap.New(),
 		OrderData:    map[string]map[string]*exch.Order{},
+		TriggerData:  exch.NewTriggerBook(),
 		PositionData: cmap.New(),
 		BalanceData:  cmap.New(),
 
-		OrderBookQueue: &bq,
-		UserDataQueue:  &uq,
+		OrderBookQueue: exch.NewLane(ctx, exch.LaneDepth, exch.MsgChannelLen),
+		UserDataQueue:  exch.NewLane(ctx, exch.LaneUser, exch.MsgChannelLen),
+		Feed:           exch.GetFeed(ctx),
+		resync:         make(chan string, exch.MsgChannelLen),
+		cancels:        cmap.New(),
 	}
 	ft.SetPubChannel()
+	ft.Monitor = exch.NewBookMonitor(ctx, ft.Sign, ft.Bookers, ft.Resync)
+	ft.Verifier = exch.NewBookVerifier(ctx, ft.Sign, ft.Bookers, ft.Snapshot, ft.Resync)
+	ft.Api = futures_api.NewGateFuturesApi(ctx)
+	ft.Reconciler = exch.NewReconciler(ctx, ft.Sign, ft.Api, ft.PositionData, ft.BalanceData, ft.Feed)
 	cl, err := NewFuturesClient(ctx)
 	if err != nil {
 		return nil
@@ -76,6 +104,9 @@ func NewGateFuturesWss(ctx context.Context) *Futures {
 	ft.Cl = cl
 	go ft.MsgEvent()
 	go ft.OrderBookEvent()
+	go ft.Monitor.Run()
+	go ft.Verifier.Run()
+	go ft.Reconciler.Run()
 	go ft.UserDataMsgEvent()
 	return ft
 }
@@ -92,10 +123,10 @@ func (ws *Futures) GetBaseInfo(ctx context.Context) *exch.BaseInfo {
 	return nil
 }
 
-func (ws *Futures) GetBook(ctx context.Context) *exch.Booker {
+func (ws *Futures) GetBook(ctx context.Context) exch.Booker {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
 	if res, ok := ws.Bookers.Get(symbol); ok {
-		return res.(*exch.Booker)
+		return res.(exch.Booker)
 	}
 	return nil
 }
@@ -110,13 +141,28 @@ func (ws *Futures) GetOrders(ctx context.Context) map[string]*exch.Order {
 	return map[string]*exch.Order{}
 }
 
+func (ws *Futures) GetTriggerOrders(ctx context.Context) map[string]*exch.Order {
+	return ws.TriggerData.Get(text.GetString(ctx, exch.CtxSymbol))
+}
+
+// GetPosition 双向持仓时返回 ctx 中持仓方向的仓位, 未指定时返回两个方向合并的净仓位
 func (ws *Futures) GetPosition(ctx context.Context) *exch.Position {
 	if ws.PositionData.Count() == 0 {
 		return nil
 	}
 	symbol := text.GetString(ctx, exch.CtxSymbol)
+	side := exch.PositionSide(ctx)
+	if exch.HedgeMode(ws.Ctx) && side == "" {
+		var list []*exch.Position
+		for _, key := range exch.PositionKeys(symbol, true) {
+			if res, ok := ws.PositionData.Get(key); ok {
+				list = append(list, res.(*exch.Position))
+			}
+		}
+		return exch.NetPosition(symbol, list...)
+	}
 	pos := &exch.Position{}
-	if res, ok := ws.PositionData.Get(symbol); ok {
+	if res, ok := ws.PositionData.Get(exch.PositionKey(symbol, side)); ok {
 		pos = res.(*exch.Position)
 	}
 	return pos
@@ -147,24 +193,32 @@ func (ws *Futures) GetTradeChan(ctx context.Context) *chan *exch.Order {
 func (ws *Futures) SetPubChannel() {
 	if ret, ok := ws.Ctx.Value(exch.BookDataChannel).(*chan interface{}); ok {
 		ws.BookDataChannel = ret
+		ws.bookData = exch.NewOutLane(ws.Ctx, exch.LaneBookData, *ret)
 	}
 	if ret, ok := ws.Ctx.Value(exch.BookMsgChan).(*chan interface{}); ok {
 		ws.BookMsgChan = ret
+		ws.bookMsg = exch.NewOutLane(ws.Ctx, exch.LaneBookMsg, *ret)
 	}
 }
 
-func (ws *Futures) SetUnit(ctx context.Context) {
+func (ws *Futures) SetInstrument(ctx context.Context) {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
 	ws.ul.Lock()
 	defer ws.ul.Unlock()
-	if u, ok := ws.Units[symbol]; ok && u != 0 {
+	if in, ok := ws.Instruments[symbol]; ok && in != nil {
 		return
 	}
 	if ws.Api == nil {
 		ws.Api = futures_api.NewGateFuturesApi(ws.Ctx)
 	}
-	unit := ws.Api.GetUnit(symbol)
-	ws.Units[symbol] = unit
+	ws.Instruments[symbol] = ws.Api.Instrument(symbol)
+}
+
+// instrument 未订阅过的交易对返回 nil, 数量按 1 换算
+func (ws *Futures) instrument(symbol string) *exch.Instrument {
+	ws.ul.RLock()
+	defer ws.ul.RUnlock()
+	return ws.Instruments[symbol]
 }
 
 func (ws *Futures) SubTicker(ctx context.Context) error {
@@ -194,7 +248,7 @@ func (ws *Futures) InitTickers(ctx context.Context) error {
 }
 
 func (ws *Futures) SubOrderBook(ctx context.Context) error {
-	ws.SetUnit(ctx)
+	ws.SetInstrument(ctx)
 	symbol := text.GetString(ctx, exch.CtxSymbol)
 	if symbol == "" {
 		return nil
@@ -202,7 +256,10 @@ func (ws *Futures) SubOrderBook(ctx context.Context) error {
 	if _, ok := ws.Bookers.Get(symbol); ok {
 		return nil
 	}
-	books := exch.NewBooker(ws.Ctx)
+	if kind := text.GetString(ctx, exch.CtxBooker); kind != "" {
+		ws.BookKinds.Set(symbol, kind)
+	}
+	books := exch.NewBooker(ws.bookCtx(symbol))
 	ws.Bookers.Set(symbol, books)
 	err := ws.Cl.OrderBook(ctx)
 	return err
@@ -219,22 +276,39 @@ func (ws *Futures) SubUserTrade(ctx context.Context) error {
 		ws.TradeData[symbol] = &oq
 	}
 	ws.tdl.Unlock()
-	ws.SetUnit(ctx)
+	ws.SetInstrument(ctx)
 	err := ws.Cl.UserTrades(ctx)
 	return err
 }
 
+// SubPublicTrades 公开逐笔成交通过 Feed.OnPublicTrade 推送
+func (ws *Futures) SubPublicTrades(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if symbol == "" || ws.PublicTrade.Has(symbol) {
+		return nil
+	}
+	ws.SetInstrument(ctx)
+	ws.PublicTrade.Set(symbol, true)
+	err := ws.Cl.Trades(ctx)
+	if err != nil {
+		// 订阅失败时清除标记, 之后可以重新订阅
+		ws.PublicTrade.Remove(symbol)
+	}
+	return err
+}
+
 func (ws *Futures) SubPosition(ctx context.Context) error {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
-	if _, ok := ws.PositionData.Get(symbol); ok {
+	if ws.hasPosition(symbol) {
 		return nil
 	}
-	ws.SetUnit(ctx)
+	ws.SetInstrument(ctx)
 	err := ws.InitPosition(ctx)
 	if err != nil {
 		return err
 	}
 	pc := make(exch.PubChan)
+	ctx = ws.watch(client.SubscribeKey(ChannelPositions, symbol), ctx)
 	go ws.ReConnect(ws.InitPosition, ctx, &pc)
 	ctx = context.WithValue(ctx, exch.CtxChan, &pc)
 	err = ws.Cl.Position(ctx)
@@ -246,18 +320,37 @@ func (ws *Futures) InitPosition(ctx context.Context) error {
 		ws.Api = futures_api.NewGateFuturesApi(ws.Ctx)
 	}
 	symbol := text.GetString(ctx, exch.CtxSymbol)
-	result, err := ws.Api.GetPosition(ctx)
+	hedge := exch.HedgeMode(ws.Ctx)
+	result, err := ws.Api.GetPositions(ctx)
 	if err != nil {
-		ws.PositionData.Remove(symbol)
+		for _, key := range exch.PositionKeys(symbol, hedge) {
+			ws.PositionData.Remove(key)
+		}
 		return err
 	}
-	log.Infof(log.Wss, "%s gate wss InitPosition %+v \r\n", ws.Sign, result)
-	ws.PositionData.Set(symbol, result)
+	for _, pos := range result {
+		log.Infof(log.Wss, "%s gate wss InitPosition %+v \r\n", ws.Sign, pos)
+		key := symbol
+		if hedge {
+			key = exch.PositionKey(symbol, pos.PositionMode)
+		}
+		ws.PositionData.Set(key, pos)
+	}
 	return err
 }
 
+// hasPosition 交易对已订阅仓位, 双向持仓时任一方向存在即可
+func (ws *Futures) hasPosition(symbol string) bool {
+	for _, key := range exch.PositionKeys(symbol, exch.HedgeMode(ws.Ctx)) {
+		if ws.PositionData.Has(key) {
+			return true
+		}
+	}
+	return false
+}
+
 func (ws *Futures) SubBalance(ctx context.Context) error {
-	ws.SetUnit(ctx)
+	ws.SetInstrument(ctx)
 	err := ws.InitBalance(ctx)
 	if err != nil {
 		return err
@@ -284,16 +377,44 @@ func (ws *Futures) InitBalance(ctx context.Context) error {
 }
 
 func (ws *Futures) SubOrder(ctx context.Context) error {
-	ws.SetUnit(ctx)
+	ws.SetInstrument(ctx)
 	err := ws.InitOrders(ctx)
 	if err != nil {
 		return err
 	}
 	pc := make(exch.PubChan)
-	go ws.ReConnect(ws.InitOrders, ctx, &pc)
+	octx := ws.watch(client.SubscribeKey(ChannelOrders, text.GetString(ctx, exch.CtxSymbol)), ctx)
+	go ws.ReConnect(ws.InitOrders, octx, &pc)
+	octx = context.WithValue(octx, exch.CtxChan, &pc)
+	err = ws.Cl.Order(octx)
+	if err != nil {
+		return err
+	}
+	return ws.SubAutoOrder(ctx)
+}
+
+// SubAutoOrder 订阅价格条件单, 与委托单分开保存
+func (ws *Futures) SubAutoOrder(ctx context.Context) error {
+	err := ws.InitTriggerOrders(ctx)
+	if err != nil {
+		return err
+	}
+	pc := make(exch.PubChan)
+	ctx = ws.watch(client.SubscribeKey(ChannelAutoOrders, text.GetString(ctx, exch.CtxSymbol)), ctx)
+	go ws.ReConnect(ws.InitTriggerOrders, ctx, &pc)
 	ctx = context.WithValue(ctx, exch.CtxChan, &pc)
-	err = ws.Cl.Order(ctx)
-	return err
+	return ws.Cl.AutoOrder(ctx)
+}
+
+func (ws *Futures) InitTriggerOrders(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	result, err := ws.Api.ListTriggerOrder(ctx, symbol)
+	if err != nil {
+		return err
+	}
+	log.Infof(log.Wss, " %s gate wss InitTriggerOrders %+v \r\n", ws.Sign, result)
+	ws.TriggerData.Reset(symbol, result)
+	return nil
 }
 
 func (ws *Futures) InitOrders(ctx context.Context) error {
@@ -322,104 +443,160 @@ func (ws *Futures) MsgEvent() {
 			switch msg.(type) {
 			case *DepthUpdateAllEvent: // handle order book update
 				//m.DepthUpdateEventHandler(rtype)
-				*ws.OrderBookQueue <- msg.(*DepthUpdateAllEvent)
+				ws.OrderBookQueue.Push(msg.(*DepthUpdateAllEvent).Result.Symbol, msg)
 			default:
-				*ws.UserDataQueue <- msg
+				ws.UserDataQueue.Push("", msg)
 			}
 		}
 	}
 }
 
+// bookCtx 订阅时可通过 exch.CtxBooker 为交易对指定订单薄实现, 如 tbooker.BookerKey
+func (ws *Futures) bookCtx(symbol string) context.Context {
+	ctx := exch.WithSymbol(ws.Ctx, symbol)
+	if kind, ok := ws.BookKinds.Get(symbol); ok {
+		ctx = context.WithValue(ctx, exch.CtxBooker, kind)
+	}
+	return ctx
+}
+
 func (ws *Futures) OrderBookEvent() {
 	for {
 		select {
 		case <-ws.Ctx.Done():
 			log.Warnln(log.Wss, ws.Sign, " GateFutures OrderBookEvent return by done")
 			return
-		case msg := <-*ws.OrderBookQueue:
+		case symbol := <-ws.resync:
+			ws.InitOrderbook(symbol)
+			if booki, ok := ws.Bookers.Get(symbol); ok && ws.BookMsgChan != nil {
+				ws.bookMsg.Push(symbol, booki)
+			}
+		case m := <-ws.OrderBookQueue.C:
+			msg := m.(*DepthUpdateAllEvent)
 			//st := time.Now().UnixNano() / 1000000
 			symbol := strings.ToUpper(msg.Result.Symbol)
-			var book *exch.Booker
+			var book exch.Booker
 			if booki, ok := ws.Bookers.Get(symbol); !ok {
 				log.Warnln(log.Wss, ws.Sign, symbol, "no symbolbook")
 				continue
 			} else {
-				book = booki.(*exch.Booker)
+				book = booki.(exch.Booker)
 			}
-			if u, ok := ws.Units[symbol]; !ok || u == 0 {
-				log.Warnln(log.Wss, ws.Sign, symbol, "symbol ws.Units is error", u)
+			bm := book.Meta()
+			in := ws.instrument(symbol)
+			if in == nil {
+				log.Warnln(log.Wss, ws.Sign, symbol, "symbol ws.Instruments is error")
 				continue
 			}
-			unit := ws.Units[symbol]
-			if book.UpdateID == 0 || msg.Result.FirstId > book.UpdateID+1 {
-				log.Debugf(log.Wss, ws.Sign, symbol, "gate init Orderbook start FirstId LastId LastUpdateID ", msg.Result.FirstId, msg.Result.LastId, book.UpdateID)
-				ws.InitOrderbook(symbol, unit)
+			if bm.UpdateID == 0 || msg.Result.FirstId > bm.UpdateID+1 {
+				log.Debugf(log.Wss, ws.Sign, symbol, "gate init Orderbook start FirstId LastId LastUpdateID ", msg.Result.FirstId, msg.Result.LastId, bm.UpdateID)
+				ws.InitOrderbook(symbol)
 				if booki, ok := ws.Bookers.Get(symbol); !ok {
 					continue
 				} else {
-					book = booki.(*exch.Booker)
+					book = booki.(exch.Booker)
 				}
+				bm = book.Meta()
 				if ws.BookMsgChan != nil {
-					*ws.BookMsgChan <- book
+					ws.bookMsg.Push(symbol, book)
 				}
 			}
 			if book.GetAskLen() == 0 || book.GetBidLen() == 0 {
 				log.Warnln(log.Wss, ws.Sign, symbol, "gate init Orderbook error asks bids:", book.GetAskLen(), book.GetBidLen())
 				continue
 			}
-			if msg.Result.LastId < book.UpdateID {
+			if msg.Result.LastId < bm.UpdateID {
 				continue
 			}
-			book.Rw.Lock()
-			if !book.IsReady {
-				log.Infoln(log.Wss, ws.Sign, symbol, "gate orderBook msg IsReading  FirstId LastId LastUpdateID ", msg.Result.FirstId, msg.Result.LastId, book.UpdateID)
-				book.IsReady = true
+			bm.Rw.Lock()
+			if !bm.IsReady {
+				log.Infoln(log.Wss, ws.Sign, symbol, "gate orderBook msg IsReading  FirstId LastId LastUpdateID ", msg.Result.FirstId, msg.Result.LastId, bm.UpdateID)
+				bm.IsReady = true
 			}
 			var wg sync.WaitGroup
 			wg.Add(2)
 			//todo Quantity
-			go func(result DepthUpdateResult, book *exch.Booker, wg *sync.WaitGroup) {
+			go func(result DepthUpdateResult, book exch.Booker, wg *sync.WaitGroup) {
 				defer wg.Done()
 				for _, bid := range result.Bids {
-					quantity := unify.UnitSize(bid.Quantity, unit)
+					quantity := in.SizeFromVenue(bid.Quantity)
 					book.UpdateBid(bid.Price, quantity)
 				}
 			}(msg.Result, book, &wg)
-			go func(result DepthUpdateResult, book *exch.Booker, wg *sync.WaitGroup) {
+			go func(result DepthUpdateResult, book exch.Booker, wg *sync.WaitGroup) {
 				defer wg.Done()
 				for _, ask := range result.Asks {
-					quantity := unify.UnitSize(ask.Quantity, unit)
+					quantity := in.SizeFromVenue(ask.Quantity)
 					book.UpdateAsk(ask.Price, quantity)
 				}
 			}(msg.Result, book, &wg)
 			wg.Wait()
-			book.UpdateID = msg.Result.LastId
-			book.ResponTime = msg.Result.Time
-			book.UpdateTime = time.Now().UnixNano() / 1000000
-			book.Rw.Unlock()
+			bm.UpdateID = msg.Result.LastId
+			bm.ResponTime = msg.Result.Time
+			bm.UpdateTime = time.Now().UnixNano() / 1000000
+			bm.Rw.Unlock()
+			if bm.Hooked() {
+				u := &exch.BookUpdate{FirstID: msg.Result.FirstId, LastID: msg.Result.LastId,
+					Asks: make(map[string]float64, len(msg.Result.Asks)), Bids: make(map[string]float64, len(msg.Result.Bids))}
+				for _, ask := range msg.Result.Asks {
+					u.Asks[ask.Price] = in.SizeFromVenue(ask.Quantity)
+				}
+				for _, bid := range msg.Result.Bids {
+					u.Bids[bid.Price] = in.SizeFromVenue(bid.Quantity)
+				}
+				bm.RunHooks(book, u)
+			}
 			if ws.BookMsgChan != nil {
-				*ws.BookMsgChan <- msg
+				ws.bookMsg.Push(symbol, msg)
 			}
 			if ws.BookDataChannel != nil {
-				*ws.BookDataChannel <- book
+				ws.bookData.Push(symbol, book)
 			}
+			ws.Feed.PubBook(symbol, book)
 		}
 	}
 }
 
-func (ws *Futures) InitOrderbook(symbol string, unit float64) {
+// Resync 请求 OrderBookEvent 重新拉取交易对快照
+func (ws *Futures) Resync(symbol string) {
+	select {
+	case ws.resync <- symbol:
+	default:
+	}
+}
+
+func (ws *Futures) InitOrderbook(symbol string) {
+	snap, err := ws.Snapshot(symbol)
+	if err != nil {
+		return
+	}
+	book := exch.NewBooker(ws.bookCtx(symbol))
+	bm := book.Meta()
+	bm.Symbol = symbol
+	bm.IsReady = false
+	if info, err := ws.Api.GetBaseInfo(symbol); err == nil && info != nil {
+		book.SetScale(info.PriceScale())
+	}
+	book.SetBook(snap.Asks, snap.Bids)
+	bm.UpdateID = snap.UpdateID
+	bm.ResponTime = snap.Time
+	bm.UpdateTime = time.Now().UnixNano() / 1000000
+	ws.Bookers.Set(symbol, book)
+	log.Debugf(log.Wss, "%s %s gate init Orderbook success [ResponTime=%d] [UpdateTime=%d][bookName=%s][lastid=%d] \r\n", ws.Sign, symbol, bm.ResponTime, bm.UpdateTime, bm.Name, bm.UpdateID)
+}
+
+// Snapshot REST 深度快照, 数量按合约信息换算, 供 BookVerifier 校验
+func (ws *Futures) Snapshot(symbol string) (*exch.BookSnapshot, error) {
+	in := ws.instrument(symbol)
 	if ws.Api == nil {
 		ws.Api = futures_api.NewGateFuturesApi(ws.Ctx)
 	}
 	ctx := context.WithValue(context.Background(), exch.CtxSymbol, symbol)
 	ctx = context.WithValue(ctx, futures_api.OrderBookLimit, OrderBookNum)
-	result, _ := ws.Api.GetOrderBook(ctx)
+	result, err := ws.Api.GetOrderBook(ctx)
 	if result == nil {
-		return
+		return nil, err
 	}
-	book := exch.NewBooker(ws.Ctx)
-	book.Symbol = symbol
-	book.IsReady = false
 	askMap, bidMap := map[string]float64{}, map[string]float64{}
 	var wg sync.WaitGroup
 	wg.Add(2)
@@ -429,7 +606,7 @@ func (ws *Futures) InitOrderbook(symbol string, unit float64) {
 			if bid.S == 0 {
 				continue
 			}
-			bidMap[bid.P] = unify.UnitSize(float64(bid.S), unit)
+			bidMap[bid.P] = in.SizeFromVenue(float64(bid.S))
 		}
 	}(bidMap, &wg)
 	go func(askMap map[string]float64, wg *sync.WaitGroup) {
@@ -438,16 +615,17 @@ func (ws *Futures) InitOrderbook(symbol string, unit float64) {
 			if ask.S == 0 {
 				continue
 			}
-			askMap[ask.P] = unify.UnitSize(float64(ask.S), unit)
+			askMap[ask.P] = in.SizeFromVenue(float64(ask.S))
 		}
 	}(askMap, &wg)
 	wg.Wait()
-	book.SetBook(askMap, bidMap)
-	book.UpdateID = result.Id
-	book.ResponTime = int64(result.Current * 1000)
-	book.UpdateTime = time.Now().UnixNano() / 1000000
-	ws.Bookers.Set(symbol, book)
-	log.Debugf(log.Wss, "%s %s gate init Orderbook success [ResponTime=%d] [UpdateTime=%d][bookName=%s][lastid=%d] \r\n", ws.Sign, symbol, book.ResponTime, book.UpdateTime, book.Name, book.UpdateID)
+	return &exch.BookSnapshot{
+		Symbol:   symbol,
+		UpdateID: result.Id,
+		Time:     int64(result.Current * 1000),
+		Asks:     askMap,
+		Bids:     bidMap,
+	}, nil
 }
 
 func (ws *Futures) UserDataMsgEvent() {
@@ -456,10 +634,12 @@ func (ws *Futures) UserDataMsgEvent() {
 		case <-ws.Ctx.Done():
 			log.Warnln(log.Wss, ws.Sign, " GateFutures UserTradeMsgEvent return by done")
 			return
-		case msg := <-*ws.UserDataQueue:
+		case msg := <-ws.UserDataQueue.C:
 			switch msg.(type) {
 			case *UserTradeEvent:
 				ws.UpdateUserTrade(msg.(*UserTradeEvent))
+			case *TradeEvent:
+				ws.UpdateTrades(msg.(*TradeEvent))
 			case *TickersEvent:
 				ws.UpdateTickers(msg.(*TickersEvent))
 			case *PositionsEvent:
@@ -468,6 +648,8 @@ func (ws *Futures) UserDataMsgEvent() {
 				ws.UpdateBalances(msg.(*BalancesEvent))
 			case *OrdersEvent:
 				ws.UpdateOrders(msg.(*OrdersEvent))
+			case *AutoOrdersEvent:
+				ws.UpdateAutoOrders(msg.(*AutoOrdersEvent))
 			default:
 				log.Errorln(log.Wss, ws.Sign, "gate unknown msg type", msg)
 			}
@@ -476,6 +658,7 @@ func (ws *Futures) UserDataMsgEvent() {
 }
 
 func (ws *Futures) UpdateTickers(data *TickersEvent) {
+	hedge := exch.HedgeMode(ws.Ctx)
 	for _, v := range data.Result {
 		baseinfo := &exch.BaseInfo{}
 		if info, ok := ws.BaseData.Get(v.Symbol); ok {
@@ -488,11 +671,13 @@ func (ws *Futures) UpdateTickers(data *TickersEvent) {
 		baseinfo.DayVolume = v.Volume24hSettle
 		baseinfo.LastUpdateTime = data.Time
 		ws.BaseData.Set(v.Symbol, baseinfo)
-		if posi, ok := ws.PositionData.Get(v.Symbol); ok {
-			pos := posi.(*exch.Position)
-			size := pos.Size
-			pos.MarkPrice = v.MarkPrice
-			pos.Value = v.MarkPrice * size
+		for _, key := range exch.PositionKeys(v.Symbol, hedge) {
+			if posi, ok := ws.PositionData.Get(key); ok {
+				pos := posi.(*exch.Position)
+				size := pos.Size
+				pos.MarkPrice = v.MarkPrice
+				pos.Value = v.MarkPrice * size
+			}
 		}
 		//log.Debugf(log.Wss, "gate wss UpdateTickers  %+v %d \r\n", baseinfo, ws.Cl.ClientId)
 	}
@@ -501,12 +686,12 @@ func (ws *Futures) UpdateTickers(data *TickersEvent) {
 
 func (ws *Futures) UpdateUserTrade(data *UserTradeEvent) {
 	for _, v := range data.Result {
-		unit := ws.Units[v.Symbol]
-		size := unify.UnitSize(float64(v.Size), unit)
+		size := ws.instrument(v.Symbol).SizeFromVenue(float64(v.Size))
 		or := exch.Order{
 			ApiSign:    ws.ApiSign,
 			Id:         v.OrderId,
 			TradeId:    v.Id,
+			UUID:       unify.ClientId(v.Text),
 			Symbol:     v.Symbol,
 			Price:      convert.GetFloat64(v.Price),
 			Size:       size,
@@ -520,17 +705,33 @@ func (ws *Futures) UpdateUserTrade(data *UserTradeEvent) {
 		}
 		*ws.TradeData[v.Symbol] <- &or
 		ws.tdl.Unlock()
+		ws.Feed.PubTrade(&or)
 		log.Infof(log.Wss, " %s gate user wss  UpdateUserTrade ClientId %d result %+v \r\n", ws.Sign, ws.Cl.ClientId, or)
 	}
 }
 
+// UpdateTrades 成交数量为负时主动方为卖方
+func (ws *Futures) UpdateTrades(data *TradeEvent) {
+	for _, v := range data.Result {
+		size := ws.instrument(v.Symbol).SizeFromVenue(v.Size)
+		ws.Feed.PubPublicTrade(&exch.Trade{
+			Symbol: v.Symbol,
+			Id:     convert.GetString(v.Id),
+			Price:  convert.GetFloat64(v.Price),
+			Size:   size,
+			Side:   exch.TakerSide(size),
+			Time:   v.CreateTimeMs,
+		})
+	}
+}
+
 func (ws *Futures) UpdatePositions(data *PositionsEvent) {
 	for _, res := range data.Result {
-		unit := ws.Units[res.Symbol]
-		size := unify.UnitSize(float64(res.Size), unit)
-		mtype := exch.MarginIsolated
+		size := ws.instrument(res.Symbol).SizeFromVenue(float64(res.Size))
+		//逐仓杠杆为 0 时为全仓, 杠杆取全仓杠杆上限
+		mtype, lv := exch.MarginIsolated, float64(res.Leverage)
 		if res.Leverage == 0 {
-			mtype = exch.MarginCrossed
+			mtype, lv = exch.MarginCrossed, res.CrossLeverageLimit
 		}
 		pos := &exch.Position{
 			Symbol:         res.Symbol,
@@ -539,12 +740,15 @@ func (ws *Futures) UpdatePositions(data *PositionsEvent) {
 			Margin:         convert.GetFloat64(res.Margin),
 			UnPnl:          convert.GetFloat64(res.RealisedPnl),
 			LiqPrice:       convert.GetFloat64(res.LiqPrice),
-			Lv:             convert.GetFloat64(res.Leverage),
+			Lv:             lv,
 			MarginType:     mtype,
+			RiskLimit:      float64(res.RiskLimit),
 			PositionMode:   unify.PositionMap[res.Mode],
 			LastUpdateTime: res.TimeMs,
 		}
-		ws.PositionData.Set(res.Symbol, pos)
+		//双向持仓时按 交易对:方向 保存
+		ws.PositionData.Set(pos.Key(), pos)
+		ws.Feed.PubPosition(pos)
 		log.Infof(log.Wss, " %s %s gate user wss  UpdatePositions result %+v \r\n", ws.Sign, res.Symbol, pos)
 	}
 }
@@ -552,55 +756,211 @@ func (ws *Futures) UpdatePositions(data *PositionsEvent) {
 func (ws *Futures) UpdateBalances(data *BalancesEvent) {
 	bls := map[string]interface{}{}
 	for _, res := range data.Result {
-		bls[futures_api.AssetUsdt] = &exch.Balance{
+		ba := &exch.Balance{
 			ApiSign: ws.ApiSign,
 			Asset:   futures_api.AssetUsdt,
 			Avative: res.Balance,
 			Total:   res.Balance,
 		}
+		bls[futures_api.AssetUsdt] = ba
+		ws.Feed.PubBalance(ba)
 	}
 	ws.BalanceData.MSet(bls)
 }
 
 func (ws *Futures) UpdateOrders(data *OrdersEvent) {
 	for _, res := range data.Result {
-		unit := ws.Units[res.Symbol]
-		size := unify.UnitSize(float64(res.Size), unit)
-		left := unify.UnitSize(float64(res.Left), unit)
+		in := ws.instrument(res.Symbol)
+		size := in.SizeFromVenue(float64(res.Size))
+		left := in.SizeFromVenue(float64(res.Left))
 		o := exch.Order{
 			Id:         convert.GetString(res.Id),
-			UUID:       res.Text,
+			UUID:       unify.ClientId(res.Text),
 			Symbol:     res.Symbol,
 			Status:     res.Status,
 			Size:       size,
 			Price:      convert.GetFloat64(res.Price),
 			FillPrice:  convert.GetFloat64(res.FillPrice),
 			Left:       left,
+			FilledSize: math.Abs(size - left),
+			AvgPrice:   res.FillPrice,
 			Iceberg:    res.Iceberg,
 			Tif:        res.Tif,
 			CreateTime: res.CreateTimeMs,
 			UpdateTime: res.FinishTimeMs,
+
+			ClosePosition: res.IsClose,
+			ReduceOnly:    res.IsReduceOnly,
+			PostOnly:      res.Tif == exch.OrderPoc,
+		}
+		if exch.HedgeMode(ws.Ctx) {
+			o.PositionSide = o.HedgeSide()
 		}
+		state := unify.OrderState(res.Status, res.FinishAs, size, left)
 		ws.odl.Lock()
 		if _, ok := ws.OrderData[res.Symbol]; !ok {
 			ws.OrderData[res.Symbol] = map[string]*exch.Order{}
 		}
+		if old, ok := ws.OrderData[res.Symbol][o.Id]; ok {
+			o.State = old.State
+		}
+		if err := o.SetState(state); err != nil {
+			ws.odl.Unlock()
+			log.Warnln(log.Wss, ws.Sign, res.Symbol, "gate user wss UpdateOrders skip", err)
+			continue
+		}
 		ws.OrderData[res.Symbol][o.Id] = &o
 		if o.Status == exch.OrderFinished {
 			delete(ws.OrderData[res.Symbol], o.Id)
 		}
 		ws.odl.Unlock()
+		ws.Feed.PubOrder(&o)
 		log.Debugf(log.Wss, " %s gate user wss  UpdateOrders result %+v \r\n", ws.Sign, ws.OrderData)
 	}
 }
 
+// UpdateAutoOrders 条件单触发后生成的委托单通过 futures.orders 推送
+func (ws *Futures) UpdateAutoOrders(data *AutoOrdersEvent) {
+	for _, res := range data.Result {
+		o := ws.Api.TriggerOrder(res)
+		ws.TriggerData.Update(o)
+		log.Infof(log.Wss, " %s gate user wss UpdateAutoOrders %+v \r\n", ws.Sign, o)
+	}
+}
+
+// watch 订阅的重连初始化协程在取消订阅时结束
+func (ws *Futures) watch(key string, ctx context.Context) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+	if old, ok := ws.cancels.Get(key); ok {
+		old.(context.CancelFunc)()
+	}
+	ws.cancels.Set(key, cancel)
+	return ctx
+}
+
+func (ws *Futures) unwatch(key string) {
+	if cancel, ok := ws.cancels.Pop(key); ok {
+		cancel.(context.CancelFunc)()
+	}
+}
+
+func (ws *Futures) UnsubTicker(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if !ws.BaseData.Has(symbol) {
+		return nil
+	}
+	ws.BaseData.Remove(symbol)
+	return ws.Cl.UnTickers(symbol)
+}
+
+func (ws *Futures) UnsubOrderBook(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if !ws.Bookers.Has(symbol) {
+		return nil
+	}
+	ws.Bookers.Remove(symbol)
+	ws.BookKinds.Remove(symbol)
+	ws.release(symbol)
+	return ws.Cl.UnOrderBook(symbol)
+}
+
+func (ws *Futures) UnsubUserTrade(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	ws.tdl.Lock()
+	_, ok := ws.TradeData[symbol]
+	delete(ws.TradeData, symbol)
+	ws.tdl.Unlock()
+	if !ok {
+		return nil
+	}
+	ws.release(symbol)
+	return ws.Cl.UnUserTrades(symbol)
+}
+
+func (ws *Futures) UnsubPublicTrades(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if !ws.PublicTrade.Has(symbol) {
+		return nil
+	}
+	ws.PublicTrade.Remove(symbol)
+	ws.release(symbol)
+	return ws.Cl.UnTrades(symbol)
+}
+
+func (ws *Futures) UnsubOrder(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	ws.unwatch(client.SubscribeKey(ChannelOrders, symbol))
+	ws.unwatch(client.SubscribeKey(ChannelAutoOrders, symbol))
+	ws.odl.Lock()
+	_, ok := ws.OrderData[symbol]
+	delete(ws.OrderData, symbol)
+	ws.odl.Unlock()
+	ws.TriggerData.Delete(symbol)
+	if !ok {
+		return nil
+	}
+	ws.release(symbol)
+	if err := ws.Cl.UnAutoOrder(symbol); err != nil {
+		return err
+	}
+	return ws.Cl.UnOrder(symbol)
+}
+
+func (ws *Futures) UnsubPosition(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	ws.unwatch(client.SubscribeKey(ChannelPositions, symbol))
+	if !ws.hasPosition(symbol) {
+		return nil
+	}
+	for _, key := range exch.PositionKeys(symbol, exch.HedgeMode(ws.Ctx)) {
+		ws.PositionData.Remove(key)
+	}
+	ws.release(symbol)
+	return ws.Cl.UnPosition(symbol)
+}
+
+// release 交易对没有任何订阅时删除合约信息
+func (ws *Futures) release(symbol string) {
+	if ws.Bookers.Has(symbol) || ws.PublicTrade.Has(symbol) || ws.hasPosition(symbol) {
+		return
+	}
+	ws.tdl.RLock()
+	_, trade := ws.TradeData[symbol]
+	ws.tdl.RUnlock()
+	ws.odl.RLock()
+	_, order := ws.OrderData[symbol]
+	ws.odl.RUnlock()
+	if trade || order {
+		return
+	}
+	ws.ul.Lock()
+	delete(ws.Instruments, symbol)
+	ws.ul.Unlock()
+}
+
 func (ws *Futures) ReConnect(call func(ctx context.Context) error, ctx context.Context, pc *exch.PubChan) {
 	for {
 		select {
 		case <-ws.Ctx.Done():
 			return
+		case <-ctx.Done():
+			return
 		case <-*pc:
 			call(ctx)
 		}
 	}
 }
+
+// QueueStats 原始消息, 订单薄, 其他消息及推送通道的积压及丢弃统计
+func (ws *Futures) QueueStats() []queue.Stat {
+	var stats []queue.Stat
+	if ws.Cl.Wss != nil {
+		stats = append(stats, ws.Cl.Wss.Stat())
+	}
+	for _, q := range []*queue.Queue{ws.OrderBookQueue, ws.UserDataQueue, ws.bookData, ws.bookMsg} {
+		if q != nil {
+			stats = append(stats, q.Stat())
+		}
+	}
+	return stats
+}
@@ -0,0 +1,77 @@
+package futures_wss
+
+import (
+	"context"
+	"testing"
+
+	"high-freq-quant-go/core/exch"
+
+	cmap "github.com/orcaman/concurrent-map"
+)
+
+// testWss 只初始化推送处理需要的字段
+func testWss() *Futures {
+	return &Futures{
+		ApiSign:   "test",
+		Sign:      "test",
+		Cl:        &FuturesClient{},
+		TradeData: map[string]*chan *exch.Order{},
+		Feed:      exch.NewFeed(context.Background()),
+	}
+}
+
+// readEvent 按连接收到的原始消息解析
+func readEvent(t *testing.T, msg string) interface{} {
+	q := make(exch.MsgQueue, 1)
+	b := []byte(msg)
+	NewMsgHandler().ReadMessage(&b, &q)
+	select {
+	case ev := <-q:
+		return ev
+	default:
+		t.Fatalf("message not parsed %s", msg)
+		return nil
+	}
+}
+
+func TestUpdateUserTradeClientId(t *testing.T) {
+	uuid := exch.NewClientIdGen("mm1").Next()
+	ws := testWss()
+	ev := readEvent(t, `{"time":1637052099,"channel":"futures.usertrades","event":"update","result":[`+
+		`{"id":"3335259","create_time":1637052099,"create_time_ms":1637052099044,"contract":"BTC_USDT",`+
+		`"order_id":"93682328194","size":-1,"price":"61022.2","role":"taker","text":"t-`+uuid+`"}]}`)
+	ws.UpdateUserTrade(ev.(*UserTradeEvent))
+	or := <-*ws.TradeData["BTC_USDT"]
+	if or.UUID != uuid || exch.OrderTag(or) != "mm1" {
+		t.Fatalf("fill uuid %q tag %q", or.UUID, exch.OrderTag(or))
+	}
+}
+
+// 双向持仓的多空仓位分开保存, 只推送一个方向时另一方向不变
+func TestUpdatePositionsHedge(t *testing.T) {
+	ws := testWss()
+	ws.Ctx = exch.WithHedgeMode(context.Background(), true)
+	ws.PositionData = cmap.New()
+	ev := readEvent(t, `{"time":1637052099,"channel":"futures.positions","event":"update","result":[`+
+		`{"contract":"BTC_USDT","entry_price":61000,"mode":"dual_long","size":5,"time_ms":1637052099044},`+
+		`{"contract":"BTC_USDT","entry_price":62000,"mode":"dual_short","size":-3,"time_ms":1637052099044}]}`)
+	ws.UpdatePositions(ev.(*PositionsEvent))
+	ev = readEvent(t, `{"time":1637052100,"channel":"futures.positions","event":"update","result":[`+
+		`{"contract":"BTC_USDT","entry_price":61000,"mode":"dual_long","size":4,"time_ms":1637052100044}]}`)
+	ws.UpdatePositions(ev.(*PositionsEvent))
+	if ws.PositionData.Count() != 2 {
+		t.Fatalf("positions got %v", ws.PositionData.Keys())
+	}
+	ctx := exch.WithSymbol(context.Background(), "BTC_USDT")
+	long := ws.GetPosition(exch.WithPositionSide(ctx, exch.PositionLong))
+	short := ws.GetPosition(exch.WithPositionSide(ctx, exch.PositionShort))
+	if long.Size != 4 || long.PositionMode != exch.PositionLong || long.Price != 61000 {
+		t.Fatalf("long got %+v", long)
+	}
+	if short.Size != -3 || short.PositionMode != exch.PositionShort || short.Price != 62000 {
+		t.Fatalf("short got %+v", short)
+	}
+	if net := ws.GetPosition(ctx); net.Size != 1 {
+		t.Fatalf("net got %+v", net)
+	}
+}
@@ -53,6 +53,15 @@ func (mh *MsgHandler) ReadMessage(message *[]byte, WsQueue *exch.MsgQueue) {
 			return
 		}
 		*WsQueue <- &event
+	case ChannelAutoOrders:
+		log.Debugln(log.Wss, "gate ChannelAutoOrders msg", string(*message))
+		var event AutoOrdersEvent
+		err := json.Unmarshal(*message, &event)
+		if err != nil {
+			log.Warnln(log.Wss, " json error", string(*message), err)
+			return
+		}
+		*WsQueue <- &event
 	case ChannelPositions:
 		log.Debugln(log.Wss, "gate ChannelPositions msg", string(*message))
 		var event PositionsEvent
@@ -6,6 +6,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+
+	"high-freq-quant-go/exchange/gate/gateapi"
 )
 
 const (
@@ -27,6 +29,7 @@ const (
 	ChannelTrade       = "futures.trades"
 	ChannelUserTrade   = "futures.usertrades"
 	ChannelOrders      = "futures.orders"
+	ChannelAutoOrders  = "futures.autoorders"
 	ChannelPositions   = "futures.positions"
 	ChannelBalances    = "futures.balances"
 
@@ -216,6 +219,7 @@ type UserTrade struct {
 	CreateTime   int64  `json:"create_time"`
 	CreateTimeMs int64  `json:"create_time_ms"`
 	Price        string `json:"price"`
+	Text         string `json:"text"` //下单时的自定义Id, 带 t- 前缀
 }
 
 /**
@@ -254,6 +258,14 @@ type UserTrade struct {
     ]
 }
 */
+// AutoOrdersEvent 价格条件单推送, 结构与 REST 条件单相同
+type AutoOrdersEvent struct {
+	Channel string                               `json:"channel"`
+	Event   string                               `json:"event"`
+	Time    int                                  `json:"time"`
+	Result  []gateapi.FuturesPriceTriggeredOrder `json:"result"`
+}
+
 type OrdersEvent struct {
 	Channel string   `json:"channel"`
 	Event   string   `json:"event"`
@@ -2,8 +2,8 @@ package gate
 
 import (
 	"context"
-	"errors"
 
+	"high-freq-quant-go/adapter/queue"
 	"high-freq-quant-go/adapter/text"
 	"high-freq-quant-go/core/log"
 
@@ -19,6 +19,7 @@ type Spot struct {
 	Ctx     context.Context
 	Wss     *spot_wss.SpotWss
 	Api     *spot_api.GateSpotApi
+	exch.Legacy
 
 	Exchange, Extype string
 }
@@ -32,6 +33,7 @@ func NewSpotClient(ctx context.Context) exch.Exchange {
 		Extype:   exch.Spot,
 	}
 	ft.Api = spot_api.NewGateSpotApi(ctx)
+	ft.Legacy = exch.NewLegacy(ft)
 	return ft
 }
 
@@ -40,7 +42,7 @@ func (mk *Spot) WssStart() error {
 		mk.Wss = spot_wss.NewGateSpotWss(mk.Ctx)
 	}
 	if mk.Wss == nil {
-		return errors.New("wss start error")
+		return exch.ErrWssNotStarted
 	}
 	return nil
 }
@@ -61,6 +63,13 @@ func (mk *Spot) GetStatus() int {
 	return mk.Wss.GetStatus()
 }
 
+func (mk *Spot) QueueStats() []queue.Stat {
+	if mk.Wss == nil {
+		return nil
+	}
+	return mk.Wss.QueueStats()
+}
+
 func (mk *Spot) GetBaseInfo(ctx context.Context) *exch.BaseInfo {
 	if mk.Wss == nil {
 		symbol := text.GetString(ctx, exch.CtxSymbol)
@@ -90,7 +99,18 @@ func (mk *Spot) GetOrder(ctx context.Context) map[string]*exch.Order {
 	return res
 }
 
-func (mk *Spot) GetOrderBook(ctx context.Context) *exch.Booker {
+func (mk *Spot) GetTriggerOrder(ctx context.Context) map[string]*exch.Order {
+	if mk.Wss != nil {
+		return mk.Wss.GetTriggerOrders(ctx)
+	}
+	res, err := mk.Api.ListTriggerOrder(ctx, text.GetString(ctx, exch.CtxSymbol))
+	if err != nil {
+		return nil
+	}
+	return res
+}
+
+func (mk *Spot) GetOrderBook(ctx context.Context) exch.Booker {
 	return mk.Wss.GetBook(ctx)
 }
 
@@ -118,80 +138,238 @@ func (mk *Spot) ListAsset(ctx context.Context) (*exch.Balance, map[string]*exch.
 	return pos, res
 }
 
-func (mk *Spot) CreateOrder(ctx context.Context) (*exch.Order, error) {
-	res, err := mk.Api.CreateOrder(ctx)
-	return res, err
+// PlaceOrder 现货没有仓位方向, ReduceOnly 不需要处理, 不支持 ClosePosition
+func (mk *Spot) PlaceOrder(ctx context.Context, req *exch.OrderRequest) (*exch.Order, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if req.ClosePosition {
+		return nil, exch.ErrNotSupported
+	}
+	if req.TriggerPrice > 0 {
+		o, err := mk.Api.CreateTriggerOrder(ctx, req.Order())
+		if err == nil && mk.Wss != nil {
+			mk.Wss.TriggerData.Update(o)
+		}
+		return o, err
+	}
+	return mk.Api.CreateOrder(ctx, req.Order())
+}
+
+func (mk *Spot) PlaceBatchOrder(ctx context.Context, reqs []*exch.OrderRequest) ([]*exch.Order, error) {
+	if len(reqs) == 0 {
+		return nil, exch.ErrEmptyOrder
+	}
+	lists := make([]*exch.Order, 0, len(reqs))
+	for _, req := range reqs {
+		if err := req.Validate(); err != nil {
+			return nil, err
+		}
+		//条件单不支持批量
+		if req.TriggerPrice > 0 || req.ClosePosition {
+			return nil, exch.ErrNotSupported
+		}
+		lists = append(lists, req.Order())
+	}
+	return mk.Api.CreateBatchOrder(ctx, lists)
+}
+
+func (mk *Spot) CancelOrder(ctx context.Context, req *exch.CancelRequest) (*exch.Order, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if req.Trigger {
+		o, err := mk.Api.CancelTriggerOrder(ctx, req.Order())
+		if err == nil && mk.Wss != nil {
+			mk.Wss.TriggerData.Remove(req.Symbol, req.Id)
+		}
+		return o, err
+	}
+	return mk.Api.CannelOrder(ctx, req.Order())
+}
+
+// CancelAllOrder 同时撤销交易对的条件单
+func (mk *Spot) CancelAllOrder(ctx context.Context, symbol string) ([]*exch.Order, error) {
+	if symbol == "" {
+		return nil, exch.ErrEmptySymbol
+	}
+	orders, err := mk.Api.CannelAllOrder(ctx, symbol)
+	triggers, terr := mk.Api.CancelAllTriggerOrder(ctx, symbol)
+	if terr == nil && mk.Wss != nil {
+		mk.Wss.TriggerData.Reset(symbol, nil)
+	}
+	if err == nil {
+		err = terr
+	}
+	return append(orders, triggers...), err
+}
+
+// ListOpenOrders REST 查询账号所有交易对的挂单, 紧急停止以此确认撤单
+func (mk *Spot) ListOpenOrders(ctx context.Context) ([]*exch.Order, error) {
+	return mk.Api.ListOpenOrders(ctx)
+}
+
+// AmendOrder 现货不支持改单, 撤单后重新下单
+func (mk *Spot) AmendOrder(ctx context.Context, req *exch.AmendRequest) (*exch.AmendResult, error) {
+	return exch.EmulateAmend(ctx, mk, req)
+}
+
+func (mk *Spot) SetLeverage(ctx context.Context, symbol string, lv float64) (*exch.Position, error) {
+	return nil, exch.ErrNotSupported
 }
 
-func (mk *Spot) CreateBatchOrder(ctx context.Context) ([]*exch.Order, error) {
-	res, err := mk.Api.CreateBatchOrder(ctx)
-	return res, err
+func (mk *Spot) SetMargin(ctx context.Context, symbol string, change float64) (*exch.Position, error) {
+	return nil, exch.ErrNotSupported
 }
 
-func (mk *Spot) CannelOrder(ctx context.Context) (*exch.Order, error) {
-	res, err := mk.Api.CannelOrder(ctx)
-	return res, err
+func (mk *Spot) SetMarginMode(ctx context.Context, symbol, mode string) error {
+	return exch.ErrNotSupported
 }
 
-func (mk *Spot) CannelAllOrder(ctx context.Context) ([]*exch.Order, error) {
-	res, err := mk.Api.CannelAllOrder(ctx)
-	return res, err
+func (mk *Spot) SetPositionMode(ctx context.Context, hedge bool) error {
+	return exch.ErrNotSupported
 }
 
-func (mk *Spot) UpdateLeverage(ctx context.Context) (*exch.Position, error) {
-	return nil, nil
+func (mk *Spot) SetRiskLimit(ctx context.Context, symbol string, limit float64) error {
+	return exch.ErrNotSupported
 }
 
-func (mk *Spot) UpdateMargin(ctx context.Context) (*exch.Position, error) {
-	return nil, nil
+func (mk *Spot) FetchPosition(ctx context.Context, symbol string) (*exch.Position, error) {
+	return nil, exch.ErrNotSupported
 }
 
-func (mk *Spot) SubTicker(ctx context.Context) error {
+func (mk *Spot) SubscribeTicker(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	err := mk.WssStart()
 	if err != nil {
 		return err
 	}
-	return mk.Wss.SubTicker(ctx)
+	return mk.Wss.SubTicker(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *Spot) SubOrderBook(ctx context.Context) error {
+func (mk *Spot) SubscribeOrderBook(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	err := mk.WssStart()
 	if err != nil {
 		return err
 	}
-	return mk.Wss.SubOrderBook(ctx)
+	return mk.Wss.SubOrderBook(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *Spot) SubUserTrade(ctx context.Context) error {
+func (mk *Spot) SubscribeUserTrade(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	err := mk.WssStart()
 	if err != nil {
 		return err
 	}
-	return mk.Wss.SubUserTrade(ctx)
+	return mk.Wss.SubUserTrade(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *Spot) SubPosition(ctx context.Context) error {
+func (mk *Spot) SubscribePublicTrades(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	err := mk.WssStart()
 	if err != nil {
 		return err
 	}
-	return mk.Wss.SubPosition(ctx)
+	return mk.Wss.SubPublicTrades(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *Spot) SubBalance(ctx context.Context) error {
+func (mk *Spot) SubscribePosition(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
 	err := mk.WssStart()
 	if err != nil {
 		return err
 	}
-	return mk.Wss.SubBalance(ctx)
+	return mk.Wss.SubPosition(exch.WithSymbol(ctx, symbol))
 }
 
-func (mk *Spot) SubOrder(ctx context.Context) error {
+func (mk *Spot) SubscribeBalance(ctx context.Context, symbol string) error {
 	err := mk.WssStart()
 	if err != nil {
 		return err
 	}
-	return mk.Wss.SubOrder(ctx)
+	return mk.Wss.SubBalance(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Spot) SubscribeOrder(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	err := mk.WssStart()
+	if err != nil {
+		return err
+	}
+	return mk.Wss.SubOrder(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Spot) UnsubscribeTicker(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.Wss == nil {
+		return nil
+	}
+	return mk.Wss.UnsubTicker(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Spot) UnsubscribeOrderBook(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.Wss == nil {
+		return nil
+	}
+	return mk.Wss.UnsubOrderBook(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Spot) UnsubscribeUserTrade(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.Wss == nil {
+		return nil
+	}
+	return mk.Wss.UnsubUserTrade(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Spot) UnsubscribePublicTrades(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.Wss == nil {
+		return nil
+	}
+	return mk.Wss.UnsubPublicTrades(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Spot) UnsubscribePosition(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.Wss == nil {
+		return nil
+	}
+	return mk.Wss.UnsubPosition(exch.WithSymbol(ctx, symbol))
+}
+
+func (mk *Spot) UnsubscribeOrder(ctx context.Context, symbol string) error {
+	if symbol == "" {
+		return exch.ErrEmptySymbol
+	}
+	if mk.Wss == nil {
+		return nil
+	}
+	return mk.Wss.UnsubOrder(exch.WithSymbol(ctx, symbol))
 }
 
 func init() {
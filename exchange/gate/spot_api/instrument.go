@@ -0,0 +1,50 @@
+package spot_api
+
+import (
+	"context"
+	"math"
+
+	"high-freq-quant-go/adapter/convert"
+	"high-freq-quant-go/adapter/timer"
+	"high-freq-quant-go/core/exch"
+	"high-freq-quant-go/core/log"
+	"high-freq-quant-go/exchange/gate/gateapi"
+)
+
+func init() {
+	exch.RegisterInstrumentLoader(exch.Gate, exch.Spot, LoadInstruments)
+}
+
+// LoadInstruments 用公共接口拉取现货交易对列表, 为 exch 合约信息加载函数
+func LoadInstruments() ([]*exch.Instrument, error) {
+	api := NewGateApiClient(context.Background())
+	res, _, err := api.GetSpotClient().ListCurrencyPairs(api.Ctx)
+	if err != nil {
+		log.Errorln(log.Http, "GateSpotApi LoadInstruments error ", err)
+		return nil, err
+	}
+	return Instruments(res), nil
+}
+
+func Instruments(pairs []gateapi.CurrencyPair) []*exch.Instrument {
+	ti := timer.MicNow()
+	ins := make([]*exch.Instrument, 0, len(pairs))
+	for _, r := range pairs {
+		ins = append(ins, &exch.Instrument{
+			Symbol:         r.Id,
+			VenueSymbol:    r.Id,
+			Exname:         exch.Gate,
+			Extype:         exch.Spot,
+			Base:           r.Base,
+			Quote:          r.Quote,
+			Settle:         r.Quote,
+			Multiplier:     1,
+			ContractSize:   1,
+			TickSize:       math.Pow10(-int(r.Precision)),
+			StepSize:       math.Pow10(-int(r.AmountPrecision)),
+			MinSize:        convert.GetFloat64(r.MinBaseAmount),
+			LastUpdateTime: ti,
+		})
+	}
+	return ins
+}
@@ -0,0 +1,19 @@
+package spot_api
+
+import (
+	"time"
+
+	"high-freq-quant-go/core/request"
+	"high-freq-quant-go/exchange/gate/unify"
+)
+
+// LimitRules gate 现货限频: 下单及改单每个交易对 10次/秒, 按账号限制, 撤单 200次/秒, 其他 200次/10秒
+var LimitRules = request.Rules{
+	Classes: map[string][]request.Limit{
+		request.ClassOrder:  {{Name: request.ClassOrder, N: 10, Per: time.Second}},
+		request.ClassCancel: {{Name: request.ClassCancel, N: 200, Per: time.Second}},
+		request.ClassQuery:  {{Name: request.ClassQuery, N: 200, Per: 10 * time.Second}},
+	},
+	Classify: unify.LimitClass,
+	Observe:  request.RemainHeader(unify.HeaderRemain),
+}
@@ -15,7 +15,7 @@ type GateApiRequest struct {
 	Ctx    context.Context
 	Client *gateapi.APIClient
 
-	Times *request.Times
+	Limiter *request.Limiter
 }
 
 func NewGateApiClient(ctx context.Context) *GateApiRequest {
@@ -27,10 +27,12 @@ func NewGateApiClient(ctx context.Context) *GateApiRequest {
 	}
 	gc := &GateApiRequest{
 		ApiSign: sign,
-		Times:   request.NewTimes(),
+		Limiter: request.NewLimiter(sign, LimitRules, request.GetRateLimit(ctx)),
 		Ctx:     context.Background(),
-		Client:  gateapi.NewAPIClient(gateapi.NewConfiguration()),
 	}
+	cfg := gateapi.NewConfiguration()
+	cfg.HTTPClient = gc.Limiter.Client()
+	gc.Client = gateapi.NewAPIClient(cfg)
 	key := text.GetString(ctx, exch.Key)
 	sevret := text.GetString(ctx, exch.Secret)
 	if key != "" && sevret != "" {
@@ -44,21 +46,28 @@ func NewGateApiClient(ctx context.Context) *GateApiRequest {
 }
 
 func (gc *GateApiRequest) GetClient() *gateapi.APIClient {
-	gc.Times.Update()
 	return gc.Client
 }
 
+// WithCtx 在调用方 ctx 上带上签名, 超时和取消随调用方
+func (gc *GateApiRequest) WithCtx(ctx context.Context) context.Context {
+	if ctx == nil {
+		return gc.Ctx
+	}
+	if auth, ok := gc.Ctx.Value(gateapi.ContextGateAPIV4).(gateapi.GateAPIV4); ok {
+		return context.WithValue(ctx, gateapi.ContextGateAPIV4, auth)
+	}
+	return ctx
+}
+
 func (gc *GateApiRequest) GetFuturesClient() *gateapi.FuturesApiService {
-	gc.Times.Update()
 	return gc.Client.FuturesApi
 }
 
 func (gc *GateApiRequest) GetSpotClient() *gateapi.SpotApiService {
-	gc.Times.Update()
 	return gc.Client.SpotApi
 }
 
 func (gc *GateApiRequest) GetWalletClient() *gateapi.WalletApiService {
-	gc.Times.Update()
 	return gc.Client.WalletApi
 }
@@ -11,8 +11,6 @@ import (
 
 	"high-freq-quant-go/core/redis"
 
-	"high-freq-quant-go/adapter/mather"
-
 	"high-freq-quant-go/exchange/gate/unify"
 
 	"github.com/antihax/optional"
@@ -37,20 +35,21 @@ func NewGateSpotApi(ctx context.Context) *GateSpotApi {
 	return gf
 }
 
-func (gs *GateSpotApi) CreateOrder(ctx context.Context) (*exch.Order, error) {
-	o := exch.GetOrder(ctx)
+func (gs *GateSpotApi) CreateOrder(ctx context.Context, o *exch.Order) (*exch.Order, error) {
 	if o == nil {
 		log.Errorln(log.Http, gs.Api.ApiSign, "GateSpotApi CreateOrder get order error ")
-		return nil, nil
+		return nil, exch.ErrEmptyOrder
+	}
+	ps := gs.GetPriceScale(o.Symbol)
+	ss := gs.GetSizeScale(o.Symbol)
+	price := o.PriceFixed(ps)
+	size := o.SizeFixed(ss)
+	if size < 0 {
+		size = -size
 	}
-	pn := gs.GetPriceFloat(o.Symbol)
-	sn := gs.GetSizeFloat(o.Symbol)
-	price := mather.FloatRound(o.Price, pn)
-	size := mather.FloatFloor(o.Size, sn)
-	size = math.Abs(size)
 	if size == 0 {
 		log.Errorln(log.Http, gs.Api.ApiSign, o.Symbol, "GateSpotApi CreateOrder size is 0 ", o.Price, o.Size)
-		return nil, nil
+		return nil, exch.ErrZeroSize
 	}
 	side := unify.SideBuy
 	if o.Size < 0 {
@@ -59,24 +58,27 @@ func (gs *GateSpotApi) CreateOrder(ctx context.Context) (*exch.Order, error) {
 	opt := gateapi.Order{
 		CurrencyPair: o.Symbol,
 		Account:      exch.Spot,
-		Amount:       convert.GetString(size),
+		Amount:       ss.String(size),
 		Type:         exch.OrderLimit,
 		Side:         side,
-		Price:        convert.GetString(price),
+		Price:        ps.String(price),
 	}
 	if o.Tif != "" {
 		opt.TimeInForce = o.Tif
 	}
+	if o.PostOnly {
+		opt.TimeInForce = exch.OrderPoc
+	}
 	if o.UUID != "" {
-		opt.Text = OrderPre + o.UUID
+		opt.Text = OrderPre + exch.FitClientId(exch.Gate, o.UUID)
 	}
 	if o.Iceberg != 0 {
 		opt.Iceberg = convert.GetString(o.Iceberg)
 	}
-	res, _, err := gs.Api.GetSpotClient().CreateOrder(gs.Api.Ctx, opt)
+	res, _, err := gs.Api.GetSpotClient().CreateOrder(gs.Api.WithCtx(ctx), opt)
 	if err != nil {
 		log.Errorf(log.Http, "%s %s gate GateSpotApi CreateOrder error %s %+v \r\n", gs.Api.ApiSign, o.Symbol, err, opt)
-		return nil, err
+		return nil, unify.OrderError(err, o.Symbol)
 	}
 	symbol := res.CurrencyPair
 	status := unify.SpotOrderMap[res.Status]
@@ -88,7 +90,7 @@ func (gs *GateSpotApi) CreateOrder(ctx context.Context) (*exch.Order, error) {
 	or := &exch.Order{
 		Id:         res.Id,
 		Symbol:     symbol,
-		UUID:       res.Text,
+		UUID:       unify.ClientId(res.Text),
 		Price:      convert.GetFloat64(res.Price),
 		Status:     status,
 		Size:       amount,
@@ -101,17 +103,17 @@ func (gs *GateSpotApi) CreateOrder(ctx context.Context) (*exch.Order, error) {
 	return or, nil
 }
 
-func (gs *GateSpotApi) CreateBatchOrder(ctx context.Context) ([]*exch.Order, error) {
-	lists := exch.GetOrders(ctx)
-	if lists == nil || len(lists) == 0 {
+func (gs *GateSpotApi) CreateBatchOrder(ctx context.Context, lists []*exch.Order) ([]*exch.Order, error) {
+	if len(lists) == 0 {
 		log.Errorln(log.Http, gs.Api.ApiSign, "GateSpotApi CreateBatchOrder GetOrders error ", lists)
-		return nil, nil
+		return nil, exch.ErrEmptyOrder
 	}
 
 	l := len(lists) - 1
 	orders := []*exch.Order{}
 	tos, os := []*exch.Order{}, []*exch.Order{}
 	var wg sync.WaitGroup
+	var mu sync.Mutex
 	for i, o := range lists {
 		os = append(os, o)
 		if (i+1)%MaxBatchOrderNum == 0 || i == l {
@@ -122,13 +124,14 @@ func (gs *GateSpotApi) CreateBatchOrder(ctx context.Context) ([]*exch.Order, err
 			wg.Add(1)
 			go func(wg *sync.WaitGroup, tos []*exch.Order) {
 				defer wg.Done()
-				octx := context.WithValue(context.Background(), exch.CtxOrders, tos)
-				order, err := gs.CreateLimitBatchOrder(octx)
+				order, err := gs.CreateLimitBatchOrder(ctx, tos)
 				if err != nil {
 					log.Errorf(log.Http, "%s GateSpotApi CreateBatchOrder CreateLimitBatchOrder error %+v %s \r\n", gs.Api.ApiSign, tos, err)
 					return
 				}
+				mu.Lock()
 				orders = append(orders, order...)
+				mu.Unlock()
 			}(&wg, tos)
 			tos = []*exch.Order{}
 		}
@@ -137,19 +140,20 @@ func (gs *GateSpotApi) CreateBatchOrder(ctx context.Context) ([]*exch.Order, err
 	return orders, nil
 }
 
-func (gs *GateSpotApi) CreateLimitBatchOrder(ctx context.Context) ([]*exch.Order, error) {
-	lists := exch.GetOrders(ctx)
-	if lists == nil || len(lists) == 0 {
+func (gs *GateSpotApi) CreateLimitBatchOrder(ctx context.Context, lists []*exch.Order) ([]*exch.Order, error) {
+	if len(lists) == 0 {
 		log.Errorln(log.Http, gs.Api.ApiSign, "GateSpotApi CreateLimitBatchOrder get orders error ", lists)
-		return nil, nil
+		return nil, exch.ErrEmptyOrder
 	}
 	opts := []gateapi.Order{}
 	for _, o := range lists {
-		pn := gs.GetPriceFloat(o.Symbol)
-		sn := gs.GetSizeFloat(o.Symbol)
-		price := mather.FloatRound(o.Price, pn)
-		amount := mather.FloatRound(o.Size, sn)
-		amount = math.Abs(amount)
+		ps := gs.GetPriceScale(o.Symbol)
+		ss := gs.GetSizeScale(o.Symbol)
+		price := o.PriceFixed(ps)
+		amount := o.SizeFixed(ss)
+		if amount < 0 {
+			amount = -amount
+		}
 		if amount == 0 {
 			log.Errorln(log.Http, gs.Api.ApiSign, o.Symbol, "GateSpotApi CreateOrder size is 0 ", o.Price, o.Size)
 			continue
@@ -158,21 +162,24 @@ func (gs *GateSpotApi) CreateLimitBatchOrder(ctx context.Context) ([]*exch.Order
 		if o.Size < 0 {
 			side = unify.SideSell
 		}
-		size := convert.GetString(amount)
+		size := ss.String(amount)
 		opt := gateapi.Order{
 			CurrencyPair: o.Symbol,
 			Account:      exch.Spot,
 			Amount:       size,
 			Type:         exch.OrderLimit,
 			Side:         side,
-			Price:        convert.GetString(price),
+			Price:        ps.String(price),
 		}
 		if o.Tif != "" {
 			opt.TimeInForce = o.Tif
 		}
+		if o.PostOnly {
+			opt.TimeInForce = exch.OrderPoc
+		}
 		opt.Text = OrderPre
 		if o.UUID != "" {
-			opt.Text += o.UUID
+			opt.Text += exch.FitClientId(exch.Gate, o.UUID)
 		} else {
 			opt.Text += exch.GetUUID(o.Symbol)
 		}
@@ -186,7 +193,7 @@ func (gs *GateSpotApi) CreateLimitBatchOrder(ctx context.Context) ([]*exch.Order
 		return nil, nil
 	}
 	client := gs.Api.GetSpotClient()
-	result, _, err := client.CreateBatchOrders(gs.Api.Ctx, opts)
+	result, _, err := client.CreateBatchOrders(gs.Api.WithCtx(ctx), opts)
 	if err != nil {
 		log.Errorln(log.Http, gs.Api.ApiSign, "GateSpotApi CreateLimitBatchOrder error", err)
 		return nil, err
@@ -205,7 +212,7 @@ func (gs *GateSpotApi) CreateLimitBatchOrder(ctx context.Context) ([]*exch.Order
 		or := &exch.Order{
 			Id:         convert.GetString(res.Id),
 			Symbol:     res.CurrencyPair,
-			UUID:       res.Text,
+			UUID:       unify.ClientId(res.Text),
 			Price:      price,
 			Status:     status,
 			Size:       size,
@@ -221,14 +228,13 @@ func (gs *GateSpotApi) CreateLimitBatchOrder(ctx context.Context) ([]*exch.Order
 	return orders, nil
 }
 
-func (gs *GateSpotApi) CannelOrder(ctx context.Context) (*exch.Order, error) {
-	o := exch.GetOrder(ctx)
+func (gs *GateSpotApi) CannelOrder(ctx context.Context, o *exch.Order) (*exch.Order, error) {
 	if o == nil {
 		log.Errorln(log.Http, gs.Api.ApiSign, "GateSpotApi CannelOrder  GetOrder id error")
-		return nil, nil
+		return nil, exch.ErrEmptyOrder
 	}
 	localVarOptionals := &gateapi.CancelOrderOpts{}
-	res, _, err := gs.Api.GetSpotClient().CancelOrder(gs.Api.Ctx, o.Id, o.Symbol, localVarOptionals)
+	res, _, err := gs.Api.GetSpotClient().CancelOrder(gs.Api.WithCtx(ctx), o.Id, o.Symbol, localVarOptionals)
 	if err != nil {
 		log.Errorln(log.Http, gs.Api.ApiSign, "GateSpotApi CannelOrder error ", err)
 		return nil, err
@@ -242,11 +248,14 @@ func (gs *GateSpotApi) CannelOrder(ctx context.Context) (*exch.Order, error) {
 	}
 	or := &exch.Order{
 		Id:         convert.GetString(res.Id),
-		UUID:       res.Text,
+		UUID:       unify.ClientId(res.Text),
+		Symbol:     res.CurrencyPair,
 		Price:      price,
 		Status:     status,
 		Size:       size,
 		Left:       left,
+		Tif:        res.TimeInForce,
+		PostOnly:   res.TimeInForce == exch.OrderPoc,
 		FillPrice:  convert.GetFloat64(res.FillPrice),
 		CreateTime: res.CreateTimeMs,
 		UpdateTime: res.UpdateTimeMs,
@@ -254,10 +263,9 @@ func (gs *GateSpotApi) CannelOrder(ctx context.Context) (*exch.Order, error) {
 	return or, nil
 }
 
-func (gs *GateSpotApi) CannelAllOrder(ctx context.Context) ([]*exch.Order, error) {
-	symbol := text.GetString(ctx, exch.CtxSymbol)
+func (gs *GateSpotApi) CannelAllOrder(ctx context.Context, symbol string) ([]*exch.Order, error) {
 	localVarOptionals := &gateapi.CancelOrdersOpts{}
-	res, _, err := gs.Api.GetSpotClient().CancelOrders(gs.Api.Ctx, symbol, localVarOptionals)
+	res, _, err := gs.Api.GetSpotClient().CancelOrders(gs.Api.WithCtx(ctx), symbol, localVarOptionals)
 	if err != nil {
 		log.Errorln(log.Http, gs.Api.ApiSign, symbol, "GateSpotApi CannelAllOrder error ", err)
 		return nil, err
@@ -273,11 +281,14 @@ func (gs *GateSpotApi) CannelAllOrder(ctx context.Context) ([]*exch.Order, error
 		}
 		ro := &exch.Order{
 			Id:         convert.GetString(s.Id),
-			UUID:       s.Text,
+			UUID:       unify.ClientId(s.Text),
+			Symbol:     s.CurrencyPair,
 			Price:      price,
 			Status:     status,
 			Size:       size,
 			Left:       left,
+			Tif:        s.TimeInForce,
+			PostOnly:   s.TimeInForce == exch.OrderPoc,
 			FillPrice:  convert.GetFloat64(s.FillPrice),
 			CreateTime: s.CreateTimeMs,
 			UpdateTime: s.UpdateTimeMs,
@@ -291,37 +302,69 @@ func (gs *GateSpotApi) CannelAllOrder(ctx context.Context) ([]*exch.Order, error
 func (gs *GateSpotApi) GetOrder(ctx context.Context) (map[string]*exch.Order, error) {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
 	opts := &gateapi.ListOrdersOpts{}
-	res, _, err := gs.Api.GetSpotClient().ListOrders(gs.Api.Ctx, symbol, unify.OrderOpen, opts)
+	res, _, err := gs.Api.GetSpotClient().ListOrders(gs.Api.WithCtx(ctx), symbol, unify.OrderOpen, opts)
 	if err != nil {
 		log.Errorln(log.Http, gs.Api.ApiSign, "GateSpotApi GetOrder error ", err)
 		return nil, err
 	}
 	orders := map[string]*exch.Order{}
 	for _, o := range res {
-		status := unify.SpotOrderMap[o.Status]
-		size := convert.GetFloat64(o.Amount)
-		left := convert.GetFloat64(o.Left)
-		price := convert.GetFloat64(o.Price)
-		if o.Side == unify.SideSell {
-			size = -size
-		}
-		order := &exch.Order{
-			Id:         o.Id,
-			Symbol:     o.CurrencyPair,
-			Size:       size,
-			Price:      price,
-			Status:     status,
-			FillPrice:  convert.GetFloat64(o.FillPrice),
-			Left:       left,
-			Text:       o.Text,
-			CreateTime: o.CreateTimeMs,
-			UpdateTime: o.UpdateTimeMs,
-		}
-		orders[o.Id] = order
+		orders[o.Id] = gs.Order(o)
 	}
 	return orders, nil
 }
 
+// ListOpenOrders 账号所有交易对的挂单, 包含未触发的条件单, 每个交易对最多返回 100 个普通委托
+func (gs *GateSpotApi) ListOpenOrders(ctx context.Context) ([]*exch.Order, error) {
+	opts := &gateapi.ListAllOpenOrdersOpts{Limit: optional.NewInt32(100)}
+	res, _, err := gs.Api.GetSpotClient().ListAllOpenOrders(gs.Api.WithCtx(ctx), opts)
+	if err != nil {
+		log.Errorln(log.Http, gs.Api.ApiSign, "GateSpotApi ListOpenOrders error ", err)
+		return nil, err
+	}
+	triggers, _, err := gs.Api.GetSpotClient().ListSpotPriceTriggeredOrders(gs.Api.WithCtx(ctx), unify.OrderOpen, nil)
+	if err != nil {
+		log.Errorln(log.Http, gs.Api.ApiSign, "GateSpotApi ListOpenOrders trigger error ", err)
+		return nil, err
+	}
+	var lists []*exch.Order
+	for _, r := range triggers {
+		lists = append(lists, TriggerOrder(r))
+	}
+	for _, pair := range res {
+		for _, o := range pair.Orders {
+			if o.CurrencyPair == "" {
+				o.CurrencyPair = pair.CurrencyPair
+			}
+			lists = append(lists, gs.Order(o))
+		}
+	}
+	return lists, nil
+}
+
+// Order 委托单转换为订单, 价格及数量按交易对刻度解析为定点值, 卖单数量为负
+func (gs *GateSpotApi) Order(o gateapi.Order) *exch.Order {
+	order := &exch.Order{
+		Id:         o.Id,
+		Symbol:     o.CurrencyPair,
+		Status:     unify.SpotOrderMap[o.Status],
+		FillPrice:  convert.GetFloat64(o.FillPrice),
+		Left:       convert.GetFloat64(o.Left),
+		Tif:        o.TimeInForce,
+		PostOnly:   o.TimeInForce == exch.OrderPoc,
+		Text:       o.Text,
+		CreateTime: o.CreateTimeMs,
+		UpdateTime: o.UpdateTimeMs,
+	}
+	order.ParsePrice(gs.GetPriceScale(o.CurrencyPair), o.Price)
+	amount := o.Amount
+	if o.Side == unify.SideSell {
+		amount = "-" + amount
+	}
+	order.ParseSize(gs.GetSizeScale(o.CurrencyPair), amount)
+	return order
+}
+
 func (gs *GateSpotApi) GetOrderBook(ctx context.Context) (*gateapi.OrderBook, error) {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
 	limit := text.GetString(ctx, OrderBookLimit)
@@ -330,7 +373,7 @@ func (gs *GateSpotApi) GetOrderBook(ctx context.Context) (*gateapi.OrderBook, er
 		Limit:  optional.NewInt32(limit32),
 		WithId: optional.NewBool(true),
 	}
-	result, _, err := gs.Api.GetSpotClient().ListOrderBook(gs.Api.Ctx, symbol, localVarOptionals)
+	result, _, err := gs.Api.GetSpotClient().ListOrderBook(gs.Api.WithCtx(ctx), symbol, localVarOptionals)
 	if err != nil || result.Asks == nil || len(result.Asks) == 0 {
 		if e, ok := err.(gateapi.GateAPIError); ok {
 			log.Errorln(log.Http, "GateSpotApi GetOrderBook error ", e.Error())
@@ -372,7 +415,7 @@ func (gs *GateSpotApi) GetBalance(ctx context.Context) (map[string]*exch.Balance
 	if isinit && ti-InitBalance.T < 5000 {
 		return InitBalance.D, nil
 	}
-	res, _, err := gs.Api.GetClient().SpotApi.ListSpotAccounts(gs.Api.Ctx, nil)
+	res, _, err := gs.Api.GetClient().SpotApi.ListSpotAccounts(gs.Api.WithCtx(ctx), nil)
 	if err != nil {
 		log.Errorln(log.Http, "GateSpotApi GetBalance error ", err)
 		return nil, err
@@ -454,6 +497,7 @@ func (gs *GateSpotApi) GetBaseInfo(symbol string) (*exch.BaseInfo, error) {
 	}
 	//todo fee rate
 	//log.Warnln(log.Http, "GateSpotApi GetBaseInfo success ", symbol)
+	exch.RegisterInstruments(Instruments(res)...)
 	infos := map[string]interface{}{}
 	ti := timer.MicNow()
 	for _, r := range res {
@@ -463,13 +507,7 @@ func (gs *GateSpotApi) GetBaseInfo(symbol string) (*exch.BaseInfo, error) {
 		}
 		priceft := convert.GetInt(r.Precision)
 		sizeft := convert.GetInt(r.AmountPrecision)
-		sizeStep, priceStep := 1.0, 1.0
-		if sizeft > 0 {
-			sizeStep = float64(1 / sizeft)
-		}
-		if priceft > 0 {
-			priceStep = float64(1 / priceft)
-		}
+		sizeStep, priceStep := math.Pow10(-sizeft), math.Pow10(-priceft)
 		minBase := convert.GetFloat64(r.MinBaseAmount)
 		minQuete := convert.GetFloat64(r.MinQuoteAmount)
 		info := &exch.BaseInfo{
@@ -500,20 +538,20 @@ func (gs *GateSpotApi) GetSignSymbol(symbol string) string {
 	return gs.Api.ApiSign + "-" + symbol
 }
 
-func (gs *GateSpotApi) GetPriceFloat(symbol string) int {
+func (gs *GateSpotApi) GetPriceScale(symbol string) exch.Scale {
 	info, err := gs.GetBaseInfo(symbol)
 	if err != nil || info == nil {
-		log.Errorln(log.Http, "GateSpotApi GetUnit error ")
-		return 0
+		log.Errorln(log.Http, "GateSpotApi GetPriceScale error ")
+		return exch.Scale{}
 	}
-	return info.PriceFloat
+	return info.PriceScale()
 }
 
-func (gs *GateSpotApi) GetSizeFloat(symbol string) int {
+func (gs *GateSpotApi) GetSizeScale(symbol string) exch.Scale {
 	info, err := gs.GetBaseInfo(symbol)
 	if err != nil || info == nil {
-		log.Errorln(log.Http, "GateSpotApi GetUnit error ")
-		return 0
+		log.Errorln(log.Http, "GateSpotApi GetSizeScale error ")
+		return exch.Scale{}
 	}
-	return info.SizeFloat
+	return info.SizeScale()
 }
@@ -0,0 +1,153 @@
+package spot_api
+
+import (
+	"context"
+
+	"github.com/antihax/optional"
+
+	"high-freq-quant-go/adapter/convert"
+	"high-freq-quant-go/core/exch"
+	"high-freq-quant-go/core/log"
+	"high-freq-quant-go/exchange/gate/gateapi"
+	"high-freq-quant-go/exchange/gate/unify"
+)
+
+// CreateTriggerOrder 下价格条件单, 只支持按最新成交价触发后下限价单, 现货没有只减仓
+func (gs *GateSpotApi) CreateTriggerOrder(ctx context.Context, o *exch.Order) (*exch.Order, error) {
+	if o == nil {
+		log.Errorln(log.Http, gs.Api.ApiSign, "GateSpotApi CreateTriggerOrder get order error ")
+		return nil, exch.ErrEmptyOrder
+	}
+	if o.ClosePosition || o.ReduceOnly || (o.TriggerBy != "" && o.TriggerBy != exch.TriggerLast) {
+		return nil, exch.ErrNotSupported
+	}
+	ps := gs.GetPriceScale(o.Symbol)
+	ss := gs.GetSizeScale(o.Symbol)
+	price := o.PriceFixed(ps)
+	trigger := ps.FromFloat(o.TriggerPrice)
+	if price <= 0 || trigger <= 0 {
+		return nil, exch.ErrInvalidPrice
+	}
+	size := o.SizeFixed(ss)
+	if size < 0 {
+		size = -size
+	}
+	if size == 0 {
+		return nil, exch.ErrZeroSize
+	}
+	side := unify.SideBuy
+	if o.Size < 0 {
+		side = unify.SideSell
+	}
+	rule := unify.RuleLte
+	if o.TriggerRising() {
+		rule = unify.RuleGte
+	}
+	tif := exch.OrderGtc
+	if o.Tif == exch.OrderIoc {
+		tif = exch.OrderIoc
+	}
+	req := gateapi.SpotPriceTriggeredOrder{
+		Market: o.Symbol,
+		Trigger: gateapi.SpotPriceTrigger{
+			Price:      ps.String(trigger),
+			Rule:       rule,
+			Expiration: TriggerExpiration,
+		},
+		Put: gateapi.SpotPricePutOrder{
+			Type:        exch.OrderLimit,
+			Side:        side,
+			Price:       ps.String(price),
+			Amount:      ss.String(size),
+			Account:     TriggerAccount,
+			TimeInForce: tif,
+		},
+	}
+	res, _, err := gs.Api.GetSpotClient().CreateSpotPriceTriggeredOrder(gs.Api.WithCtx(ctx), req)
+	if err != nil {
+		log.Errorf(log.Http, "%s %s gate GateSpotApi CreateTriggerOrder error %s %+v \r\n", gs.Api.ApiSign, o.Symbol, err, req)
+		return nil, err
+	}
+	req.Id = res.Id
+	req.Status = unify.OrderOpen
+	ro := TriggerOrder(req)
+	ro.UUID = o.UUID
+	log.Infoln(log.Http, gs.Api.ApiSign, o.Symbol, "GateSpotApi CreateTriggerOrder success:t,p,s", ro.TriggerPrice, ro.Price, ro.Size)
+	return ro, nil
+}
+
+func (gs *GateSpotApi) CancelTriggerOrder(ctx context.Context, o *exch.Order) (*exch.Order, error) {
+	if o == nil {
+		log.Errorln(log.Http, gs.Api.ApiSign, "GateSpotApi CancelTriggerOrder GetOrder id error")
+		return nil, exch.ErrEmptyOrder
+	}
+	res, _, err := gs.Api.GetSpotClient().CancelSpotPriceTriggeredOrder(gs.Api.WithCtx(ctx), o.Id)
+	if err != nil {
+		log.Errorln(log.Http, gs.Api.ApiSign, o.Symbol, "GateSpotApi CancelTriggerOrder error ", err)
+		return nil, err
+	}
+	return TriggerOrder(res), nil
+}
+
+func (gs *GateSpotApi) CancelAllTriggerOrder(ctx context.Context, symbol string) ([]*exch.Order, error) {
+	res, _, err := gs.Api.GetSpotClient().CancelSpotPriceTriggeredOrderList(gs.Api.WithCtx(ctx), &gateapi.CancelSpotPriceTriggeredOrderListOpts{
+		Market:  optional.NewString(symbol),
+		Account: optional.NewString(TriggerAccount),
+	})
+	if err != nil {
+		log.Errorln(log.Http, gs.Api.ApiSign, symbol, "GateSpotApi CancelAllTriggerOrder error ", err)
+		return nil, err
+	}
+	lists := make([]*exch.Order, 0, len(res))
+	for _, r := range res {
+		lists = append(lists, TriggerOrder(r))
+	}
+	return lists, nil
+}
+
+// ListTriggerOrder 交易对未触发的条件单
+func (gs *GateSpotApi) ListTriggerOrder(ctx context.Context, symbol string) (map[string]*exch.Order, error) {
+	res, _, err := gs.Api.GetSpotClient().ListSpotPriceTriggeredOrders(gs.Api.WithCtx(ctx), unify.OrderOpen, &gateapi.ListSpotPriceTriggeredOrdersOpts{
+		Market:  optional.NewString(symbol),
+		Account: optional.NewString(TriggerAccount),
+	})
+	if err != nil {
+		log.Errorln(log.Http, gs.Api.ApiSign, symbol, "GateSpotApi ListTriggerOrder error ", err)
+		return nil, err
+	}
+	orders := map[string]*exch.Order{}
+	for _, r := range res {
+		o := TriggerOrder(r)
+		orders[o.Id] = o
+	}
+	return orders, nil
+}
+
+// TriggerOrder 条件单转换为订单, 类型按触发方向及买卖方向区分止损和止盈
+func TriggerOrder(res gateapi.SpotPriceTriggeredOrder) *exch.Order {
+	size := convert.GetFloat64(res.Put.Amount)
+	if res.Put.Side == unify.SideSell {
+		size = -size
+	}
+	status, state := unify.TriggerState(res.Status, "")
+	otype := exch.OrderStop
+	if (res.Trigger.Rule == unify.RuleGte) != (size > 0) {
+		otype = exch.OrderTakeProfit
+	}
+	return &exch.Order{
+		Id:           convert.GetString(res.Id),
+		TradeId:      convert.GetString(res.FiredOrderId),
+		Symbol:       res.Market,
+		Status:       status,
+		State:        state,
+		Size:         size,
+		Price:        convert.GetFloat64(res.Put.Price),
+		Tif:          res.Put.TimeInForce,
+		Text:         res.Reason,
+		Ordertype:    otype,
+		TriggerPrice: convert.GetFloat64(res.Trigger.Price),
+		TriggerBy:    exch.TriggerLast,
+		CreateTime:   int64(res.Ctime * 1000),
+		UpdateTime:   int64(res.Ftime * 1000),
+	}
+}
@@ -10,4 +10,8 @@ const (
 	OrderBookLimit = "limit"
 
 	MaxBatchOrderNum = 10
+
+	//条件单现货账户及有效期(秒), 到期未触发自动撤销
+	TriggerAccount          = "normal"
+	TriggerExpiration int32 = 86400
 )
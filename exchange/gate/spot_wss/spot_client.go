@@ -3,6 +3,7 @@ package spot_wss
 import (
 	"context"
 	"encoding/json"
+	"sync"
 	"time"
 
 	"high-freq-quant-go/adapter/client"
@@ -32,6 +33,7 @@ type SpotClient struct {
 
 	//reconnect
 	ReConnectMsg []client.SubscribeData
+	rl           sync.Mutex
 }
 
 func NewSpotClient(ctx context.Context) (*SpotClient, error) {
@@ -54,6 +56,7 @@ func (sc *SpotClient) NewClient() error {
 	ctx = context.WithValue(ctx, client.Keepalive, true)
 	ctx = context.WithValue(ctx, client.Timeout, WssTimeout)
 	ctx = context.WithValue(ctx, client.MsgLen, exch.WSChannelLen)
+	ctx = context.WithValue(ctx, client.Overflow, exch.GetOverflow(sc.Ctx, exch.LaneWss))
 	ctx = context.WithValue(ctx, client.Id, sc.Sign)
 	wss, err := client.NewWssSocket(ctx, sc.ReConnect)
 	if err != nil {
@@ -74,7 +77,7 @@ func (sc *SpotClient) Tickers(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	sc.RegisterMsg(sc.Tickers, ctx)
+	sc.RegisterMsg(client.SubscribeKey(ChannelTickers, symbol), sc.Tickers, ctx)
 	ctx = context.WithValue(ctx, client.SendMsg, msg)
 	err = sc.Wss.SendMsg(ctx)
 	return err
@@ -82,14 +85,12 @@ func (sc *SpotClient) Tickers(ctx context.Context) error {
 
 func (sc *SpotClient) OrderBook(ctx context.Context) error {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
-	frequency := "100ms" // 100ms, 1000ms
-	params := []string{symbol, frequency}
-	msg, err := sc.SubscribeChannel(ChannelDepthUpdate, params)
+	msg, err := sc.SubscribeChannel(ChannelDepthUpdate, bookParams(symbol))
 	if err != nil {
 		return err
 	}
 	//todo error no do
-	sc.RegisterMsg(sc.OrderBook, ctx)
+	sc.RegisterMsg(client.SubscribeKey(ChannelDepthUpdate, symbol), sc.OrderBook, ctx)
 	ctx = context.WithValue(ctx, client.SendMsg, msg)
 	err = sc.Wss.SendMsg(ctx)
 	return err
@@ -101,7 +102,7 @@ func (sc *SpotClient) UserTrades(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	sc.RegisterMsg(sc.UserTrades, ctx)
+	sc.RegisterMsg(client.SubscribeKey(ChannelUserTrade, symbol), sc.UserTrades, ctx)
 	ctx = context.WithValue(ctx, client.SendMsg, msg)
 	err = sc.Wss.SendMsg(ctx)
 	return err
@@ -113,18 +114,32 @@ func (sc *SpotClient) Order(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	sc.RegisterMsg(sc.Order, ctx)
+	sc.RegisterMsg(client.SubscribeKey(ChannelOrders, symbol), sc.Order, ctx)
+	ctx = context.WithValue(ctx, client.SendMsg, msg)
+	err = sc.Wss.SendMsg(ctx)
+	return err
+}
+
+func (sc *SpotClient) PriceOrder(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	msg, err := sc.SubscribeChannel(ChannelPriceOrders, []string{symbol})
+	if err != nil {
+		return err
+	}
+	sc.RegisterMsg(client.SubscribeKey(ChannelPriceOrders, symbol), sc.PriceOrder, ctx)
 	ctx = context.WithValue(ctx, client.SendMsg, msg)
 	err = sc.Wss.SendMsg(ctx)
 	return err
 }
 
 func (sc *SpotClient) Position(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
 	msg, err := sc.SubscribeChannel(ChannelBalances, []string{})
 	if err != nil {
 		return err
 	}
-	sc.RegisterMsg(sc.Position, ctx)
+	//现货仓位由余额推送计算, 按交易对区分重连时的初始化
+	sc.RegisterMsg(client.SubscribeKey(ChannelBalances, symbol), sc.Position, ctx)
 	ctx = context.WithValue(ctx, client.SendMsg, msg)
 	err = sc.Wss.SendMsg(ctx)
 	return err
@@ -136,14 +151,59 @@ func (sc *SpotClient) Balance(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	sc.RegisterMsg(sc.Balance, ctx)
+	sc.RegisterMsg(client.SubscribeKey(ChannelBalances, ""), sc.Balance, ctx)
 	ctx = context.WithValue(ctx, client.SendMsg, msg)
 	err = sc.Wss.SendMsg(ctx)
 	return err
 }
 
-func (sc *SpotClient) Trades() error {
-	return nil
+func bookParams(symbol string) []string {
+	frequency := "100ms" // 100ms, 1000ms
+	return []string{symbol, frequency}
+}
+
+func (sc *SpotClient) UnTickers(symbol string) error {
+	return sc.Unsubscribe(ChannelTickers, symbol, []string{symbol})
+}
+
+func (sc *SpotClient) UnOrderBook(symbol string) error {
+	return sc.Unsubscribe(ChannelDepthUpdate, symbol, bookParams(symbol))
+}
+
+func (sc *SpotClient) UnUserTrades(symbol string) error {
+	return sc.Unsubscribe(ChannelUserTrade, symbol, []string{symbol})
+}
+
+func (sc *SpotClient) UnOrder(symbol string) error {
+	return sc.Unsubscribe(ChannelOrders, symbol, []string{symbol})
+}
+
+func (sc *SpotClient) UnPriceOrder(symbol string) error {
+	return sc.Unsubscribe(ChannelPriceOrders, symbol, []string{symbol})
+}
+
+// UnPosition 余额频道与 Balance 共用, 只从重连列表删除
+func (sc *SpotClient) UnPosition(symbol string) {
+	sc.rl.Lock()
+	sc.ReConnectMsg = client.RemoveSubscribe(sc.ReConnectMsg, client.SubscribeKey(ChannelBalances, symbol))
+	sc.rl.Unlock()
+}
+
+// Trades 公开逐笔成交
+func (sc *SpotClient) Trades(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	msg, err := sc.SubscribeChannel(ChannelTrade, []string{symbol})
+	if err != nil {
+		return err
+	}
+	sc.RegisterMsg(client.SubscribeKey(ChannelTrade, symbol), sc.Trades, ctx)
+	ctx = context.WithValue(ctx, client.SendMsg, msg)
+	err = sc.Wss.SendMsg(ctx)
+	return err
+}
+
+func (sc *SpotClient) UnTrades(symbol string) error {
+	return sc.Unsubscribe(ChannelTrade, symbol, []string{symbol})
 }
 
 func (sc *SpotClient) ReceivedMsg() {
@@ -189,21 +249,40 @@ func (sc *SpotClient) SignMsg(subtype, channel string, payload []string) ([]byte
 	return msgByte, nil
 }
 
-func (sc *SpotClient) RegisterMsg(action func(ctx context.Context) error, param context.Context) {
+// RegisterMsg 记录重连后需要重新订阅的频道, 相同 Key 只保留最新一条
+func (sc *SpotClient) RegisterMsg(key string, action func(ctx context.Context) error, param context.Context) {
 	if text.GetBool(param, client.IsReconnect) {
 		ch := exch.GetChan(param)
 		if ch != nil {
-			*ch <- 1
+			select {
+			case *ch <- 1:
+			case <-param.Done():
+			}
 		}
 		return
 	}
 	param = context.WithValue(param, client.IsReconnect, true)
 	method := client.SubscribeData{
+		Key:    key,
 		Action: &action,
 		Param:  param,
 	}
-	sc.ReConnectMsg = append(sc.ReConnectMsg, method)
-	return
+	sc.rl.Lock()
+	sc.ReConnectMsg = append(client.RemoveSubscribe(sc.ReConnectMsg, key), method)
+	sc.rl.Unlock()
+}
+
+// Unsubscribe 发送取消订阅并从重连列表删除
+func (sc *SpotClient) Unsubscribe(channel, symbol string, payload []string) error {
+	sc.rl.Lock()
+	sc.ReConnectMsg = client.RemoveSubscribe(sc.ReConnectMsg, client.SubscribeKey(channel, symbol))
+	sc.rl.Unlock()
+	msg, err := sc.UnSubscribeChannel(channel, payload)
+	if err != nil {
+		return err
+	}
+	ctx := context.WithValue(sc.Ctx, client.SendMsg, msg)
+	return sc.Wss.CancelMsg(ctx)
 }
 
 func (sc *SpotClient) ReConnect(err error) error {
@@ -217,7 +296,10 @@ func (sc *SpotClient) ReConnect(err error) error {
 		return conErr
 	}
 	log.Warnln(log.Wss, sc.Sign, "gate wss SpotClient start reconnect")
-	for _, m := range sc.ReConnectMsg {
+	sc.rl.Lock()
+	msgs := append([]client.SubscribeData{}, sc.ReConnectMsg...)
+	sc.rl.Unlock()
+	for _, m := range msgs {
 		fun := *m.Action
 		ctx := m.Param
 		err := fun(ctx)
@@ -11,10 +11,13 @@ import (
 
 	"high-freq-quant-go/adapter/timer"
 
+	"high-freq-quant-go/adapter/client"
 	"high-freq-quant-go/adapter/convert"
+	"high-freq-quant-go/adapter/queue"
 	"high-freq-quant-go/adapter/text"
 	"high-freq-quant-go/core/exch"
 	"high-freq-quant-go/core/log"
+	"high-freq-quant-go/exchange/gate/gateapi"
 	"high-freq-quant-go/exchange/gate/spot_api"
 )
 
@@ -30,7 +33,9 @@ type SpotWss struct {
 	BaseData     cmap.ConcurrentMap
 	Bookers      cmap.ConcurrentMap
 	TradeData    map[string]*chan *exch.Order
+	PublicTrade  cmap.ConcurrentMap //已订阅公开成交的交易对
 	OrderData    map[string]map[string]*exch.Order
+	TriggerData  *exch.TriggerBook //未触发的价格条件单
 	PositionData cmap.ConcurrentMap
 	BalanceData  cmap.ConcurrentMap
 	//BalanceData  map[string]*exch.Balance
@@ -39,20 +44,31 @@ type SpotWss struct {
 	Cl *SpotClient
 
 	//struct msg chan
-	OrderBookQueue *chan *DepthUpdateAllEvent
-	UserDataQueue  *chan interface{}
+	OrderBookQueue *queue.Queue //订单薄增量 exch.LaneDepth
+	UserDataQueue  *queue.Queue //其他消息 exch.LaneUser
 
 	//struct msg to public
 	BookDataChannel *chan interface{}
 	BookMsgChan     *chan interface{}
+	Feed            *exch.Feed
+
+	bookData, bookMsg *queue.Queue
+
+	//订单薄异常检查及 REST 快照校验, 异常时通过 resync 在 OrderBookEvent 中重新拉取快照
+	Monitor  *exch.BookMonitor
+	Verifier *exch.BookVerifier
+	resync   chan string
+
+	//定时按 REST 校对仓位及资金, 推送丢失时更正
+	Reconciler *exch.Reconciler
+
+	cancels cmap.ConcurrentMap //订阅 Key 对应的重连初始化协程, 取消订阅时结束
 
 	bal, bdl, bl, tdl, odl, pdl sync.RWMutex
 }
 
 func NewGateSpotWss(ctx context.Context) *SpotWss {
 	apiSign := text.GetString(ctx, exch.ApiSign)
-	bq := make(chan *DepthUpdateAllEvent, exch.MsgChannelLen)
-	uq := make(chan interface{}, exch.MsgChannelLen)
 	sg := text.GetString(ctx, exch.ConnSign)
 	ft := &SpotWss{
 		ApiSign: apiSign,
@@ -62,14 +78,24 @@ func NewGateSpotWss(ctx context.Context) *SpotWss {
 		BaseData:     cmap.New(),
 		Bookers:      cmap.New(),
 		TradeData:    map[string]*chan *exch.Order{},
+		PublicTrade:  cmap.New(),
 		OrderData:    map[string]map[string]*exch.Order{},
+		TriggerData:  exch.NewTriggerBook(),
 		PositionData: cmap.New(),
 		BalanceData:  cmap.New(),
 
-		OrderBookQueue: &bq,
-		UserDataQueue:  &uq,
+		OrderBookQueue: exch.NewLane(ctx, exch.LaneDepth, exch.MsgChannelLen),
+		UserDataQueue:  exch.NewLane(ctx, exch.LaneUser, exch.MsgChannelLen),
+		Feed:           exch.GetFeed(ctx),
+		resync:         make(chan string, exch.MsgChannelLen),
+		cancels:        cmap.New(),
 	}
 	ft.SetPubChannel()
+	ft.Monitor = exch.NewBookMonitor(ctx, ft.Sign, ft.Bookers, ft.Resync)
+	ft.Verifier = exch.NewBookVerifier(ctx, ft.Sign, ft.Bookers, ft.Snapshot, ft.Resync)
+	ft.Api = spot_api.NewGateSpotApi(ctx)
+	//现货仓位为本地按成交计算, 只校对资金
+	ft.Reconciler = exch.NewReconciler(ctx, ft.Sign, ft.Api, nil, ft.BalanceData, ft.Feed)
 	cl, err := NewSpotClient(ctx)
 	if err != nil {
 		return nil
@@ -77,6 +103,9 @@ func NewGateSpotWss(ctx context.Context) *SpotWss {
 	ft.Cl = cl
 	go ft.MsgEvent()
 	go ft.OrderBookEvent()
+	go ft.Monitor.Run()
+	go ft.Verifier.Run()
+	go ft.Reconciler.Run()
 	go ft.UserDataMsgEvent()
 	return ft
 }
@@ -96,10 +125,10 @@ func (ws *SpotWss) GetBaseInfo(ctx context.Context) *exch.BaseInfo {
 	return info
 }
 
-func (ws *SpotWss) GetBook(ctx context.Context) *exch.Booker {
+func (ws *SpotWss) GetBook(ctx context.Context) exch.Booker {
 	symbol := text.GetString(ctx, exch.CtxSymbol)
 	if res, ok := ws.Bookers.Get(symbol); ok {
-		return res.(*exch.Booker)
+		return res.(exch.Booker)
 	}
 	return nil
 }
@@ -114,6 +143,10 @@ func (ws *SpotWss) GetOrders(ctx context.Context) map[string]*exch.Order {
 	return map[string]*exch.Order{}
 }
 
+func (ws *SpotWss) GetTriggerOrders(ctx context.Context) map[string]*exch.Order {
+	return ws.TriggerData.Get(text.GetString(ctx, exch.CtxSymbol))
+}
+
 func (ws *SpotWss) GetPosition(ctx context.Context) *exch.Position {
 	if ws.PositionData.Count() == 0 {
 		return nil
@@ -151,9 +184,11 @@ func (ws *SpotWss) GetTradeChan(ctx context.Context) *chan *exch.Order {
 func (ws *SpotWss) SetPubChannel() {
 	if ret, ok := ws.Ctx.Value(exch.BookDataChannel).(*chan interface{}); ok {
 		ws.BookDataChannel = ret
+		ws.bookData = exch.NewOutLane(ws.Ctx, exch.LaneBookData, *ret)
 	}
 	if ret, ok := ws.Ctx.Value(exch.BookMsgChan).(*chan interface{}); ok {
 		ws.BookMsgChan = ret
+		ws.bookMsg = exch.NewOutLane(ws.Ctx, exch.LaneBookMsg, *ret)
 	}
 }
 
@@ -189,7 +224,7 @@ func (ws *SpotWss) SubOrderBook(ctx context.Context) error {
 	if _, ok := ws.Bookers.Get(symbol); ok {
 		return nil
 	}
-	books := exch.NewBooker(ws.Ctx)
+	books := exch.NewBooker(exch.WithSymbol(ws.Ctx, symbol))
 	ws.Bookers.Set(symbol, books)
 	err := ws.Cl.OrderBook(ctx)
 	return err
@@ -210,15 +245,31 @@ func (ws *SpotWss) SubUserTrade(ctx context.Context) error {
 	return err
 }
 
+// SubPublicTrades 公开逐笔成交通过 Feed.OnPublicTrade 推送
+func (ws *SpotWss) SubPublicTrades(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if symbol == "" || ws.PublicTrade.Has(symbol) {
+		return nil
+	}
+	ws.PublicTrade.Set(symbol, true)
+	err := ws.Cl.Trades(ctx)
+	if err != nil {
+		// 订阅失败时清除标记, 之后可以重新订阅
+		ws.PublicTrade.Remove(symbol)
+	}
+	return err
+}
+
 func (ws *SpotWss) SubPosition(ctx context.Context) error {
 	err := ws.InitPosition(ctx)
 	if err != nil {
 		return err
 	}
 	pc := make(exch.PubChan)
+	ctx = ws.watch(client.SubscribeKey(ChannelBalances, text.GetString(ctx, exch.CtxSymbol)), ctx)
 	go ws.ReConnect(ws.InitPosition, ctx, &pc)
 	ctx = context.WithValue(ctx, exch.CtxChan, &pc)
-	err = ws.Cl.UserTrades(ctx)
+	err = ws.Cl.Position(ctx)
 	return err
 }
 
@@ -288,10 +339,38 @@ func (ws *SpotWss) SubOrder(ctx context.Context) error {
 		return err
 	}
 	pc := make(exch.PubChan)
-	go ws.ReConnect(ws.InitOrders, ctx, &pc)
+	octx := ws.watch(client.SubscribeKey(ChannelOrders, text.GetString(ctx, exch.CtxSymbol)), ctx)
+	go ws.ReConnect(ws.InitOrders, octx, &pc)
+	octx = context.WithValue(octx, exch.CtxChan, &pc)
+	err = ws.Cl.Order(octx)
+	if err != nil {
+		return err
+	}
+	return ws.SubPriceOrder(ctx)
+}
+
+// SubPriceOrder 订阅价格条件单, 与委托单分开保存
+func (ws *SpotWss) SubPriceOrder(ctx context.Context) error {
+	err := ws.InitTriggerOrders(ctx)
+	if err != nil {
+		return err
+	}
+	pc := make(exch.PubChan)
+	ctx = ws.watch(client.SubscribeKey(ChannelPriceOrders, text.GetString(ctx, exch.CtxSymbol)), ctx)
+	go ws.ReConnect(ws.InitTriggerOrders, ctx, &pc)
 	ctx = context.WithValue(ctx, exch.CtxChan, &pc)
-	err = ws.Cl.Order(ctx)
-	return err
+	return ws.Cl.PriceOrder(ctx)
+}
+
+func (ws *SpotWss) InitTriggerOrders(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	result, err := ws.Api.ListTriggerOrder(ctx, symbol)
+	if err != nil {
+		return err
+	}
+	log.Infof(log.Wss, " %s gate wss InitTriggerOrders %+v \r\n", ws.Sign, result)
+	ws.TriggerData.Reset(symbol, result)
+	return nil
 }
 
 func (ws *SpotWss) InitOrders(ctx context.Context) error {
@@ -321,9 +400,9 @@ func (ws *SpotWss) MsgEvent() {
 			switch msg.(type) {
 			case *DepthUpdateAllEvent: // handle order book update
 				//m.DepthUpdateEventHandler(rtype)
-				*ws.OrderBookQueue <- msg.(*DepthUpdateAllEvent)
+				ws.OrderBookQueue.Push(msg.(*DepthUpdateAllEvent).Result.Symbol, msg)
 			default:
-				*ws.UserDataQueue <- msg
+				ws.UserDataQueue.Push("", msg)
 			}
 		}
 	}
@@ -335,51 +414,59 @@ func (ws *SpotWss) OrderBookEvent() {
 		case <-ws.Ctx.Done():
 			log.Warnln(log.Wss, ws.Sign, " GateFutures OrderBookEvent return by done")
 			return
-		case msg := <-*ws.OrderBookQueue:
+		case symbol := <-ws.resync:
+			ws.InitOrderbook(symbol)
+			if booki, ok := ws.Bookers.Get(symbol); ok && ws.BookMsgChan != nil {
+				ws.bookMsg.Push(symbol, booki)
+			}
+		case m := <-ws.OrderBookQueue.C:
+			msg := m.(*DepthUpdateAllEvent)
 			//st := time.Now().UnixNano() / 1000000
 			symbol := strings.ToUpper(msg.Result.Symbol)
-			var book *exch.Booker
+			var book exch.Booker
 			if booki, ok := ws.Bookers.Get(symbol); !ok {
 				log.Warnln(log.Wss, ws.Sign, symbol, "no symbolbook")
 				continue
 			} else {
-				book = booki.(*exch.Booker)
+				book = booki.(exch.Booker)
 			}
-			if book.UpdateID == 0 || msg.Result.FirstId > book.UpdateID+1 {
-				log.Debugln(log.Wss, ws.Sign, symbol, "gate init Orderbook start FirstId LastId LastUpdateID ", msg.Result.FirstId, msg.Result.LastId, book.UpdateID)
+			bm := book.Meta()
+			if bm.UpdateID == 0 || msg.Result.FirstId > bm.UpdateID+1 {
+				log.Debugln(log.Wss, ws.Sign, symbol, "gate init Orderbook start FirstId LastId LastUpdateID ", msg.Result.FirstId, msg.Result.LastId, bm.UpdateID)
 				ws.InitOrderbook(symbol)
 				if booki, ok := ws.Bookers.Get(symbol); !ok {
 					continue
 				} else {
-					book = booki.(*exch.Booker)
+					book = booki.(exch.Booker)
 				}
+				bm = book.Meta()
 				if ws.BookMsgChan != nil {
-					*ws.BookMsgChan <- book
+					ws.bookMsg.Push(symbol, book)
 				}
 			}
 			if book.GetAskLen() == 0 || book.GetBidLen() == 0 {
 				log.Warnln(log.Wss, ws.Sign, symbol, "gate init Orderbook error asks bids:", book.GetAskLen(), book.GetBidLen())
 				continue
 			}
-			if msg.Result.LastId < book.UpdateID {
+			if msg.Result.LastId < bm.UpdateID {
 				continue
 			}
-			book.Rw.Lock()
-			if !book.IsReady {
-				log.Infoln(log.Wss, ws.Sign, symbol, "gate orderBook msg IsReading  FirstId LastId LastUpdateID ", msg.Result.FirstId, msg.Result.LastId, book.UpdateID)
-				book.IsReady = true
+			bm.Rw.Lock()
+			if !bm.IsReady {
+				log.Infoln(log.Wss, ws.Sign, symbol, "gate orderBook msg IsReading  FirstId LastId LastUpdateID ", msg.Result.FirstId, msg.Result.LastId, bm.UpdateID)
+				bm.IsReady = true
 			}
 			var wg sync.WaitGroup
 			wg.Add(2)
 			//todo Quantity
-			go func(result DepthUpdateResult, book *exch.Booker, wg *sync.WaitGroup) {
+			go func(result DepthUpdateResult, book exch.Booker, wg *sync.WaitGroup) {
 				defer wg.Done()
 				for _, bid := range result.Bids {
 					size := convert.GetFloat64(bid[1])
 					book.UpdateBid(bid[0], size)
 				}
 			}(msg.Result, book, &wg)
-			go func(result DepthUpdateResult, book *exch.Booker, wg *sync.WaitGroup) {
+			go func(result DepthUpdateResult, book exch.Booker, wg *sync.WaitGroup) {
 				defer wg.Done()
 				for _, ask := range result.Asks {
 					size := convert.GetFloat64(ask[1])
@@ -387,33 +474,71 @@ func (ws *SpotWss) OrderBookEvent() {
 				}
 			}(msg.Result, book, &wg)
 			wg.Wait()
-			book.UpdateID = msg.Result.LastId
-			book.ResponTime = msg.Result.UpdateTimeMs
-			book.UpdateTime = timer.MicNow()
-			book.Rw.Unlock()
+			bm.UpdateID = msg.Result.LastId
+			bm.ResponTime = msg.Result.UpdateTimeMs
+			bm.UpdateTime = timer.MicNow()
+			bm.Rw.Unlock()
+			if bm.Hooked() {
+				u := &exch.BookUpdate{FirstID: msg.Result.FirstId, LastID: msg.Result.LastId,
+					Asks: make(map[string]float64, len(msg.Result.Asks)), Bids: make(map[string]float64, len(msg.Result.Bids))}
+				for _, ask := range msg.Result.Asks {
+					u.Asks[ask[0]] = convert.GetFloat64(ask[1])
+				}
+				for _, bid := range msg.Result.Bids {
+					u.Bids[bid[0]] = convert.GetFloat64(bid[1])
+				}
+				bm.RunHooks(book, u)
+			}
 			if ws.BookMsgChan != nil {
-				*ws.BookMsgChan <- msg
+				ws.bookMsg.Push(symbol, msg)
 			}
 			if ws.BookDataChannel != nil {
-				*ws.BookDataChannel <- book
+				ws.bookData.Push(symbol, book)
 			}
+			ws.Feed.PubBook(symbol, book)
 		}
 	}
 }
 
+// Resync 请求 OrderBookEvent 重新拉取交易对快照
+func (ws *SpotWss) Resync(symbol string) {
+	select {
+	case ws.resync <- symbol:
+	default:
+	}
+}
+
 func (ws *SpotWss) InitOrderbook(symbol string) {
+	snap, err := ws.Snapshot(symbol)
+	if err != nil {
+		return
+	}
+	book := exch.NewBooker(exch.WithSymbol(ws.Ctx, symbol))
+	bm := book.Meta()
+	bm.Symbol = symbol
+	bm.IsReady = false
+	if info, err := ws.Api.GetBaseInfo(symbol); err == nil && info != nil {
+		book.SetScale(info.PriceScale())
+	}
+	book.SetBook(snap.Asks, snap.Bids)
+	bm.UpdateID = snap.UpdateID
+	bm.ResponTime = snap.Time
+	bm.UpdateTime = timer.MicNow()
+	ws.Bookers.Set(symbol, book)
+	log.Debugf(log.Wss, "%s %s gate init Orderbook success [ResponTime=%d] [UpdateTime=%d][bookName=%s][lastid=%d] \r\n", ws.Sign, symbol, bm.ResponTime, bm.UpdateTime, bm.Name, bm.UpdateID)
+}
+
+// Snapshot REST 深度快照, 供 BookVerifier 校验
+func (ws *SpotWss) Snapshot(symbol string) (*exch.BookSnapshot, error) {
 	if ws.Api == nil {
 		ws.Api = spot_api.NewGateSpotApi(ws.Ctx)
 	}
 	ctx := context.WithValue(context.Background(), exch.CtxSymbol, symbol)
 	ctx = context.WithValue(ctx, spot_api.OrderBookLimit, OrderBookNum)
-	result, _ := ws.Api.GetOrderBook(ctx)
+	result, err := ws.Api.GetOrderBook(ctx)
 	if result == nil {
-		return
+		return nil, err
 	}
-	book := exch.NewBooker(ws.Ctx)
-	book.Symbol = symbol
-	book.IsReady = false
 	askMap, bidMap := map[string]float64{}, map[string]float64{}
 	var wg sync.WaitGroup
 	wg.Add(2)
@@ -432,12 +557,13 @@ func (ws *SpotWss) InitOrderbook(symbol string) {
 		}
 	}(askMap, &wg)
 	wg.Wait()
-	book.SetBook(askMap, bidMap)
-	book.UpdateID = result.Id
-	book.ResponTime = int64(result.Current * 1000)
-	book.UpdateTime = timer.MicNow()
-	ws.Bookers.Set(symbol, book)
-	log.Debugf(log.Wss, "%s %s gate init Orderbook success [ResponTime=%d] [UpdateTime=%d][bookName=%s][lastid=%d] \r\n", ws.Sign, symbol, book.ResponTime, book.UpdateTime, book.Name, book.UpdateID)
+	return &exch.BookSnapshot{
+		Symbol:   symbol,
+		UpdateID: result.Id,
+		Time:     int64(result.Current * 1000),
+		Asks:     askMap,
+		Bids:     bidMap,
+	}, nil
 }
 
 func (ws *SpotWss) UserDataMsgEvent() {
@@ -446,16 +572,20 @@ func (ws *SpotWss) UserDataMsgEvent() {
 		case <-ws.Ctx.Done():
 			log.Warnln(log.Wss, ws.Sign, " GateFutures UserTradeMsgEvent return by done")
 			return
-		case msg := <-*ws.UserDataQueue:
+		case msg := <-ws.UserDataQueue.C:
 			switch msg.(type) {
 			case *UserTradeEvent:
 				ws.UpdateUserTrade(msg.(*UserTradeEvent))
+			case *TradeEvent:
+				ws.UpdateTrades(msg.(*TradeEvent))
 			case *TickersEvent:
 				ws.UpdateTickers(msg.(*TickersEvent))
 			case *BalancesEvent:
 				ws.UpdateBalances(msg.(*BalancesEvent))
 			case *OrdersEvent:
 				ws.UpdateOrders(msg.(*OrdersEvent))
+			case *PriceOrdersEvent:
+				ws.UpdatePriceOrders(msg.(*PriceOrdersEvent))
 			default:
 				log.Errorln(log.Wss, ws.Sign, "gate unknown msg type", msg)
 			}
@@ -486,6 +616,7 @@ func (ws *SpotWss) UpdateUserTrade(data *UserTradeEvent) {
 			ApiSign:    ws.ApiSign,
 			Id:         v.OrderId,
 			TradeId:    convert.GetString(v.Id),
+			UUID:       unify.ClientId(v.Text),
 			Symbol:     v.Symbol,
 			Price:      convert.GetFloat64(v.Price),
 			Size:       size,
@@ -500,6 +631,7 @@ func (ws *SpotWss) UpdateUserTrade(data *UserTradeEvent) {
 		}
 		*ws.TradeData[v.Symbol] <- &or
 		ws.tdl.Unlock()
+		ws.Feed.PubTrade(&or)
 
 		log.Infof(log.Wss, " %s gate user wss  UpdateUserTrade ClientId %d result %+v \r\n", ws.Sign, ws.Cl.ClientId, or)
 		pos := &exch.Position{}
@@ -512,9 +644,26 @@ func (ws *SpotWss) UpdateUserTrade(data *UserTradeEvent) {
 		pos.Pnl = pos.Pnl + pnl
 		pos.UnPnl = (or.Price - pos.Price) * pos.Size
 		ws.PositionData.Set(v.Symbol, pos)
+		ws.Feed.PubPosition(pos)
 	}
 }
 
+func (ws *SpotWss) UpdateTrades(data *TradeEvent) {
+	v := data.Result
+	size := v.Amount
+	if v.Side == unify.SideSell {
+		size = -size
+	}
+	ws.Feed.PubPublicTrade(&exch.Trade{
+		Symbol: v.Symbol,
+		Id:     convert.GetString(v.Id),
+		Price:  v.Price,
+		Size:   size,
+		Side:   exch.TakerSide(size),
+		Time:   int64(v.CreateTimeMs),
+	})
+}
+
 func (ws *SpotWss) UpdateBalances(data *BalancesEvent) {
 	//todo lock change no push
 	ds := map[string]interface{}{}
@@ -526,6 +675,7 @@ func (ws *SpotWss) UpdateBalances(data *BalancesEvent) {
 			Avative: res.Available,
 		}
 		ds[res.Currency] = d
+		ws.Feed.PubBalance(d)
 	}
 	ws.BalanceData.MSet(ds)
 }
@@ -541,7 +691,7 @@ func (ws *SpotWss) UpdateOrders(data *OrdersEvent) {
 		o := exch.Order{
 			ApiSign:    ws.ApiSign,
 			Id:         convert.GetString(res.Id),
-			UUID:       res.Text,
+			UUID:       unify.ClientId(res.Text),
 			Symbol:     res.Symbol,
 			Status:     status,
 			Size:       size,
@@ -559,17 +709,153 @@ func (ws *SpotWss) UpdateOrders(data *OrdersEvent) {
 			delete(ws.OrderData[res.Symbol], o.Id)
 		}
 		ws.odl.Unlock()
+		ws.Feed.PubOrder(&o)
 		log.Debugf(log.Wss, " %s gate user wss  UpdateOrders result %+v \r\n", ws.Sign, ws.OrderData)
 	}
 }
 
+// UpdatePriceOrders 条件单结束时有触发委托单 Id 为触发成功, 否则为撤销,
+// 触发后生成的委托单通过 spot.orders 推送
+func (ws *SpotWss) UpdatePriceOrders(data *PriceOrdersEvent) {
+	res := data.Result
+	status := unify.OrderOpen
+	fired := convert.GetInt64(res.FiredOrderId)
+	if data.Event == unify.EventFinish {
+		status = unify.FinishCancelled
+		if fired != 0 {
+			status = unify.EventFinish
+		}
+	}
+	o := spot_api.TriggerOrder(gateapi.SpotPriceTriggeredOrder{
+		Trigger: gateapi.SpotPriceTrigger{
+			Price: res.TriggerPrice,
+			Rule:  res.TriggerRule,
+		},
+		Put: gateapi.SpotPricePutOrder{
+			Type:        res.OrderType,
+			Side:        res.Side,
+			Price:       res.Price,
+			Amount:      res.Amount,
+			TimeInForce: res.TimeInForce,
+		},
+		Id:           convert.GetInt64(res.Id),
+		Market:       res.Market,
+		Ctime:        convert.GetFloat64(res.Ctime),
+		Ftime:        convert.GetFloat64(res.Ftime),
+		FiredOrderId: fired,
+		Status:       status,
+		Reason:       res.Reason,
+	})
+	o.ApiSign = ws.ApiSign
+	ws.TriggerData.Update(o)
+	log.Infof(log.Wss, " %s gate user wss UpdatePriceOrders %+v \r\n", ws.Sign, o)
+}
+
+// watch 订阅的重连初始化协程在取消订阅时结束
+func (ws *SpotWss) watch(key string, ctx context.Context) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+	if old, ok := ws.cancels.Get(key); ok {
+		old.(context.CancelFunc)()
+	}
+	ws.cancels.Set(key, cancel)
+	return ctx
+}
+
+func (ws *SpotWss) unwatch(key string) {
+	if cancel, ok := ws.cancels.Pop(key); ok {
+		cancel.(context.CancelFunc)()
+	}
+}
+
+func (ws *SpotWss) UnsubTicker(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if !ws.BaseData.Has(symbol) {
+		return nil
+	}
+	ws.BaseData.Remove(symbol)
+	return ws.Cl.UnTickers(symbol)
+}
+
+func (ws *SpotWss) UnsubOrderBook(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if !ws.Bookers.Has(symbol) {
+		return nil
+	}
+	ws.Bookers.Remove(symbol)
+	return ws.Cl.UnOrderBook(symbol)
+}
+
+func (ws *SpotWss) UnsubUserTrade(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	ws.tdl.Lock()
+	_, ok := ws.TradeData[symbol]
+	delete(ws.TradeData, symbol)
+	ws.tdl.Unlock()
+	if !ok {
+		return nil
+	}
+	return ws.Cl.UnUserTrades(symbol)
+}
+
+func (ws *SpotWss) UnsubPublicTrades(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	if !ws.PublicTrade.Has(symbol) {
+		return nil
+	}
+	ws.PublicTrade.Remove(symbol)
+	return ws.Cl.UnTrades(symbol)
+}
+
+func (ws *SpotWss) UnsubOrder(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	ws.unwatch(client.SubscribeKey(ChannelOrders, symbol))
+	ws.unwatch(client.SubscribeKey(ChannelPriceOrders, symbol))
+	ws.odl.Lock()
+	_, ok := ws.OrderData[symbol]
+	delete(ws.OrderData, symbol)
+	ws.odl.Unlock()
+	ws.TriggerData.Delete(symbol)
+	if !ok {
+		return nil
+	}
+	if err := ws.Cl.UnPriceOrder(symbol); err != nil {
+		return err
+	}
+	return ws.Cl.UnOrder(symbol)
+}
+
+// UnsubPosition 余额频道仍由 SubBalance 使用, 不发送取消订阅
+func (ws *SpotWss) UnsubPosition(ctx context.Context) error {
+	symbol := text.GetString(ctx, exch.CtxSymbol)
+	ws.unwatch(client.SubscribeKey(ChannelBalances, symbol))
+	ws.PositionData.Remove(symbol)
+	ws.Cl.UnPosition(symbol)
+	return nil
+}
+
 func (ws *SpotWss) ReConnect(call func(ctx context.Context) error, ctx context.Context, pc *exch.PubChan) {
 	for {
 		select {
 		case <-ws.Ctx.Done():
 			return
+		case <-ctx.Done():
+			return
 		case <-*pc:
 			call(ctx)
 		}
 	}
 }
+
+// QueueStats 原始消息, 订单薄, 其他消息及推送通道的积压及丢弃统计
+func (ws *SpotWss) QueueStats() []queue.Stat {
+	var stats []queue.Stat
+	if ws.Cl.Wss != nil {
+		stats = append(stats, ws.Cl.Wss.Stat())
+	}
+	for _, q := range []*queue.Queue{ws.OrderBookQueue, ws.UserDataQueue, ws.bookData, ws.bookMsg} {
+		if q != nil {
+			stats = append(stats, q.Stat())
+		}
+	}
+	return stats
+}
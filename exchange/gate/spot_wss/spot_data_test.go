@@ -0,0 +1,33 @@
+package spot_wss
+
+import (
+	"context"
+	"testing"
+
+	"high-freq-quant-go/core/exch"
+
+	cmap "github.com/orcaman/concurrent-map"
+)
+
+func TestUpdateUserTradeClientId(t *testing.T) {
+	uuid := exch.NewClientIdGen("mm1").Next()
+	ws := &SpotWss{
+		ApiSign:      "test",
+		Sign:         "test",
+		Cl:           &SpotClient{},
+		TradeData:    map[string]*chan *exch.Order{},
+		PositionData: cmap.New(),
+		Feed:         exch.NewFeed(context.Background()),
+	}
+	q := make(exch.MsgQueue, 1)
+	msg := []byte(`{"time":1605176741,"channel":"spot.usertrades","event":"update","result":[` +
+		`{"id":5736713,"user_id":1000001,"order_id":"30784428","currency_pair":"BTC_USDT","create_time":1605176741,` +
+		`"create_time_ms":"1605176741123.456","side":"sell","amount":"1.00000000","role":"taker","price":"10000.00000000",` +
+		`"fee":"0.00200000000000","fee_currency":"USDT","point_fee":"0","gt_fee":"0","text":"t-` + uuid + `"}]}`)
+	NewMsgHandler().ReadMessage(&msg, &q)
+	ws.UpdateUserTrade((<-q).(*UserTradeEvent))
+	or := <-*ws.TradeData["BTC_USDT"]
+	if or.UUID != uuid || exch.OrderTag(or) != "mm1" || or.Size != -1 {
+		t.Fatalf("fill %+v tag %q", or, exch.OrderTag(or))
+	}
+}
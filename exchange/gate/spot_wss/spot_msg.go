@@ -50,6 +50,15 @@ func (mh *MsgHandler) ReadMessage(message *[]byte, WsQueue *exch.MsgQueue) {
 			return
 		}
 		*WsQueue <- &event
+	case ChannelPriceOrders:
+		log.Debugln(log.Wss, "gate ChannelPriceOrders msg", string(*message))
+		var event PriceOrdersEvent
+		err := json.Unmarshal(*message, &event)
+		if err != nil {
+			log.Warnln(log.Wss, " json error", string(*message), err)
+			return
+		}
+		*WsQueue <- &event
 	case ChannelBalances:
 		log.Debugln(log.Wss, "gate ChannelBalances msg", string(*message))
 		var event BalancesEvent
@@ -23,6 +23,7 @@ const (
 	ChannelTrade       = "spot.trades"
 	ChannelUserTrade   = "spot.usertrades"
 	ChannelOrders      = "spot.orders"
+	ChannelPriceOrders = "spot.priceorders"
 	ChannelBalances    = "spot.balances"
 	ChannelPositions   = "spot.balances"
 
@@ -222,26 +223,44 @@ type Balances struct {
 	Available   float64 `json:"available,omitempty,string"`
 }
 
+// TradeEvent 公开逐笔成交, 每条推送一笔成交
 type TradeEvent struct {
-	Time    int     `json:"time"`
-	Channel string  `json:"channel"`
-	Event   string  `json:"event"`
-	Result  []Trade `json:"result"`
+	Time    int    `json:"time"`
+	Channel string `json:"channel"`
+	Event   string `json:"event"`
+	Result  Trade  `json:"result"`
 }
 
 type Trade struct {
 	Id           int64   `json:"id"`
-	UserId       int64   `json:"user_id"`
-	OrderId      int64   `json:"order_id,omitempty,string"`
 	Symbol       string  `json:"currency_pair"`
-	CreateTime   int64   `json:"create_time,omitempty,string"`
-	CreateTimeMs int64   `json:"create_time_ms,omitempty,string"`
-	Side         string  `json:"side"`
+	CreateTime   int64   `json:"create_time"`
+	CreateTimeMs float64 `json:"create_time_ms,omitempty,string"` //毫秒, 带小数
+	Side         string  `json:"side"`                            //主动成交方向
 	Amount       float64 `json:"amount,omitempty,string"`
-	Role         string  `json:"role"`
 	Price        float64 `json:"price,omitempty,string"`
-	Fee          float64 `json:"fee,omitempty,string"`
-	PointFee     float64 `json:"point_fee,omitempty,string"`
-	GtFee        float64 `json:"gt_fee,omitempty,string"`
-	Text         string  `json:"text"`
+}
+
+type PriceOrdersEvent struct {
+	Time    int         `json:"time"`
+	Channel string      `json:"channel"`
+	Event   string      `json:"event"`
+	Result  PriceOrders `json:"result"`
+}
+
+// PriceOrders 现货价格条件单推送, event 为 put 时生效, finish 时结束
+type PriceOrders struct {
+	Market       string      `json:"market"`
+	Id           interface{} `json:"id"`
+	FiredOrderId interface{} `json:"fired_order_id"`
+	TriggerPrice string      `json:"trigger_price"`
+	TriggerRule  string      `json:"trigger_rule"`
+	Price        string      `json:"price"`
+	Amount       string      `json:"amount"`
+	Side         string      `json:"side"`
+	OrderType    string      `json:"order_type"`
+	TimeInForce  string      `json:"time_in_force"`
+	Ctime        interface{} `json:"ctime"`
+	Ftime        interface{} `json:"ftime"`
+	Reason       string      `json:"reason"`
 }
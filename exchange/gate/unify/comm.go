@@ -1,12 +1,21 @@
 package unify
 
 import (
+	"math"
+	"net/http"
 	"strings"
 
+	"high-freq-quant-go/core/exch"
 	"high-freq-quant-go/core/log"
+	"high-freq-quant-go/core/request"
+	"high-freq-quant-go/exchange/gate/gateapi"
 )
 
+// Settle 结算货币, 优先取合约信息, 未加载时按计价货币
 func Settle(symbol string) string {
+	if in := exch.GetInstrument(exch.Gate, exch.Futures, symbol); in != nil && in.Settle != "" {
+		return strings.ToLower(in.Settle)
+	}
 	pq := strings.Split(symbol, "_")
 	if len(pq) != 2 {
 		log.Errorln(log.Conn, "get symbol settle error", symbol)
@@ -15,11 +24,69 @@ func Settle(symbol string) string {
 	return strings.ToLower(pq[1])
 }
 
-func UnitSize(size, unit float64) float64 {
-	if unit < 1 {
-		size = size / (1 / unit)
-	} else {
-		size = size * unit
+// OrderState 根据订单状态、结束方式及剩余数量转换为生命周期状态
+func OrderState(status, finishAs string, size, left float64) string {
+	if status == OrderFinished {
+		if state, ok := FinishStateMap[finishAs]; ok {
+			return state
+		}
+		if left == 0 {
+			return exch.StateFilled
+		}
+		return exch.StateCancelled
+	}
+	if left != 0 && math.Abs(left) < math.Abs(size) {
+		return exch.StatePartiallyFilled
+	}
+	return exch.StateNew
+}
+
+// TriggerState 条件单状态, 返回 open/finished 及生命周期状态, 合约已结束时按 finishAs
+func TriggerState(status, finishAs string) (string, string) {
+	switch status {
+	case OrderOpen, TriggerInactive:
+		return exch.OrderOpen, exch.StateNew
+	case OrderFinished:
+		status = finishAs
+	}
+	if state, ok := TriggerStateMap[status]; ok {
+		return exch.OrderFinished, state
+	}
+	return exch.OrderFinished, exch.StateCancelled
+}
+
+// TriggerBy 合约条件单触发价格类型
+func TriggerBy(priceType int32) string {
+	for by, t := range TriggerPriceType {
+		if t == priceType {
+			return by
+		}
+	}
+	return exch.TriggerLast
+}
+
+// ClientId 去掉 gate 自定义订单 Id 的 t- 前缀, 与下单时的 UUID 一致
+func ClientId(text string) string {
+	return strings.TrimPrefix(text, "t-")
+}
+
+// LimitClass 请求限频类型, 批量下单按 1 次计
+func LimitClass(req *http.Request) (string, float64) {
+	path := req.URL.Path
+	switch {
+	case req.Method == http.MethodDelete || strings.HasSuffix(path, "/cancel_batch_orders"):
+		return request.ClassCancel, 1
+	case req.Method != http.MethodGet && (strings.HasSuffix(path, "/orders") ||
+		strings.HasSuffix(path, "/batch_orders") || strings.Contains(path, "/orders/")):
+		return request.ClassOrder, 1
+	}
+	return request.ClassQuery, 1
+}
+
+// OrderError 只挂单的订单会立即成交时转换为 exch.PostOnlyError, 其他错误原样返回
+func OrderError(err error, symbol string) error {
+	if e, ok := err.(gateapi.GateAPIError); ok && (e.Label == LabelPocImmediate || e.Label == LabelPocFill) {
+		return &exch.PostOnlyError{Exname: exch.Gate, Symbol: symbol, Detail: e.GetMessage()}
 	}
-	return size
+	return err
 }
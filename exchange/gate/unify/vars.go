@@ -14,8 +14,36 @@ const (
 	EventUpdate    = "update"
 	EventFinish    = "finish"
 
+	OrderFinished    = "finished"
+	FinishFilled     = "filled"
+	FinishCancelled  = "cancelled"
+	FinishLiquidated = "liquidated"
+	FinishIoc        = "ioc"
+	FinishAdl        = "auto_deleveraged"
+	FinishReduceOnly = "reduce_only"
+	FinishPosClosed  = "position_closed"
+	FinishStp        = "stp"
+
 	SideSell = "sell"
 	SideBuy  = "buy"
+
+	//条件单状态及结束方式, 合约 open,finished,inactive,invalid 现货 open,cancelled,finish,failed,expired
+	TriggerInactive  = "inactive"
+	TriggerInvalid   = "invalid"
+	TriggerSucceeded = "succeeded"
+	TriggerFailed    = "failed"
+	TriggerExpired   = "expired"
+
+	//条件单触发规则
+	RuleGte = ">="
+	RuleLte = "<="
+
+	//只挂单的订单会立即成交时的错误标签, 合约及现货
+	LabelPocImmediate = "ORDER_POC_IMMEDIATE"
+	LabelPocFill      = "POC_FILL_IMMEDIATELY"
+
+	//限频返回头, 当前接口剩余次数
+	HeaderRemain = "X-Gate-RateLimit-Requests-Remain"
 )
 
 var (
@@ -28,9 +56,49 @@ var (
 		OrderCancelled: exch.OrderFinished,
 	}
 
+	FinishStateMap = map[string]string{
+		FinishFilled:     exch.StateFilled,
+		FinishLiquidated: exch.StateFilled,
+		FinishAdl:        exch.StateFilled,
+		FinishCancelled:  exch.StateCancelled,
+		FinishReduceOnly: exch.StateCancelled,
+		FinishPosClosed:  exch.StateCancelled,
+		FinishStp:        exch.StateCancelled,
+		FinishIoc:        exch.StateExpired,
+	}
+
+	//条件单结束方式对应的生命周期状态, 触发成功为 filled
+	TriggerStateMap = map[string]string{
+		TriggerSucceeded: exch.StateFilled,
+		EventFinish:      exch.StateFilled,
+		FinishCancelled:  exch.StateCancelled,
+		TriggerFailed:    exch.StateRejected,
+		TriggerInvalid:   exch.StateRejected,
+		TriggerExpired:   exch.StateExpired,
+	}
+
+	//合约条件单触发价格类型
+	TriggerPriceType = map[string]int32{
+		exch.TriggerLast:  0,
+		exch.TriggerMark:  1,
+		exch.TriggerIndex: 2,
+	}
+
 	PositionMap = map[string]string{
 		PosSingle: exch.PositionBoth,
 		PosLong:   exch.PositionLong,
 		PosShort:  exch.PositionShort,
 	}
+
+	//双向持仓调整保证金的方向参数
+	DualSide = map[string]string{
+		exch.PositionLong:  PosLong,
+		exch.PositionShort: PosShort,
+	}
+
+	//双向持仓全部平仓的 auto_size
+	AutoSize = map[string]string{
+		exch.PositionLong:  "close_long",
+		exch.PositionShort: "close_short",
+	}
 )
@@ -2,6 +2,8 @@ package exchange
 
 import (
 	"high-freq-quant-go/core/exch"
+	_ "high-freq-quant-go/core/exch/cbooker"
+	_ "high-freq-quant-go/core/exch/mbooker"
 	_ "high-freq-quant-go/exchange/binance"
 	_ "high-freq-quant-go/exchange/gate"
 )
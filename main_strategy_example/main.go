@@ -4,13 +4,11 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"high-freq-quant-go/adapter/convert"
 	"high-freq-quant-go/adapter/timer"
 	"high-freq-quant-go/core/config"
 	"high-freq-quant-go/core/exch"
 	"high-freq-quant-go/core/log"
 	_ "high-freq-quant-go/exchange"
-	"high-freq-quant-go/strategy/confer"
 	"math"
 	"os"
 	"os/signal"
@@ -168,14 +166,10 @@ func main() {
 	log.Errorln(log.Stt, fmt.Sprintf("======kill by [%v]======", sgName))
 	exs.cancel()
 
-	for {
-		time.Sleep(orderSleepTime * time.Millisecond)
-		if exs.cancelTimes < 6 {
-			continue
-		}
-		cannelOrder(exs)
-		break
-	}
+	// 拒绝新订单, 撤销所有账号的挂单直到推送确认
+	exch.Kill.Configure(exch.KillConfig{Retries: 10, Interval: 3 * time.Second, Symbols: []string{symbol}})
+	report := exch.Kill.Trigger(fmt.Sprintf("signal %v", sgName))
+	log.Errorln(log.Stt, "======kill done======", report.Done())
 }
 
 func setDiff(exs *Exs) {
@@ -401,7 +395,8 @@ func setGtBook(exs *Exs) {
 		exs.gstatus = 1
 		gctx := context.WithValue(context.Background(), exch.CtxSymbol, symbol)
 		gbook := gtex.Ex.GetOrderBook(gctx)
-		if gbook == nil {
+		// BookMonitor 发现订单薄停止更新或异常时会标记未就绪
+		if gbook == nil || !gbook.Meta().IsReady {
 			continue
 		}
 		gasks, gbids, _, _ := gbook.GetBook()
@@ -414,7 +409,7 @@ func setGtBook(exs *Exs) {
 		gbt := &Bt{
 			ask: exs.gask,
 			bid: exs.gbid,
-			t:   gbook.UpdateTime,
+			t:   gbook.Meta().UpdateTime,
 		}
 		gl := len(exs.gbooks)
 		if gl == 0 || gbt.t-exs.gbooks[gl-1].t > bookTickerTime {
@@ -423,7 +418,6 @@ func setGtBook(exs *Exs) {
 			}
 			exs.gbooks = append(exs.gbooks, gbt)
 		}
-		//todo 推送时间间隔判断wss异常
 	}
 }
 
@@ -453,7 +447,7 @@ func setBnBook(exs *Exs) {
 
 		bctx := context.WithValue(context.Background(), exch.CtxSymbol, symbol)
 		bbook := bnex.Ex.GetOrderBook(bctx)
-		if bbook == nil {
+		if bbook == nil || !bbook.Meta().IsReady {
 			continue
 		}
 		basks, bbids, _, _ := bbook.GetBook()
@@ -466,7 +460,7 @@ func setBnBook(exs *Exs) {
 		bbt := &Bt{
 			ask: exs.gask,
 			bid: exs.gbid,
-			t:   bbook.UpdateTime,
+			t:   bbook.Meta().UpdateTime,
 		}
 		bl := len(exs.bbooks)
 		if bl == 0 || bbt.t-exs.bbooks[bl-1].t > bookTickerTime {
@@ -478,37 +472,40 @@ func setBnBook(exs *Exs) {
 	}
 }
 
+// subShared 通过共享订阅获取账号连接, 多个交易对的策略共用同一账号的连接
+func subShared(ctx context.Context, symbol string) *exch.Exchanger {
+	var ex *exch.Exchanger
+	for _, kind := range []string{exch.StreamUserTrade, exch.StreamOrder, exch.StreamPosition, exch.StreamBalance, exch.StreamBook} {
+		res, err := exch.Shared.Subscribe(ctx, symbol, kind, symbol)
+		if err != nil {
+			log.Errorln(log.Stt, symbol, "subShared Subscribe", kind, "error", err)
+			return nil
+		}
+		// 行情可能由其他账号的连接提供, 返回账号自己的连接
+		if !exch.IsPublic(kind) {
+			ex = res
+		}
+	}
+	return ex
+}
+
 func setGtex(exs *Exs, keys map[string]config.ApiUser) {
 	symbol := exs.symbol
 	gtapi := "gate-cd"
 	gtkey := keys[gtapi]
 	gtkey.ApiSign = gtapi
-	_, gtid := confer.GetPubCid(gtkey.ApiSign, symbol)
 	gtctx := exch.ApiCtx(&gtkey)
-	ex := exch.NewExchanger(gtctx, gtid)
-	gtctx = context.WithValue(context.Background(), exch.CtxSymbol, symbol)
-	gtctx = context.WithValue(gtctx, exch.CtxLv, convert.GetString(initLv))
-	_ = ex.Ex.SubUserTrade(gtctx)
-	_ = ex.Ex.SubOrder(gtctx)
-	_ = ex.Ex.SubPosition(gtctx)
-	_ = ex.Ex.SubBalance(gtctx)
-	_ = ex.Ex.SubOrderBook(gtctx)
-
-	for {
-		time.Sleep(1 * time.Second)
-
-		gtpos := ex.Ex.GetPosition(gtctx)
-		if gtpos != nil && gtpos.Lv == float64(initLv) {
-			log.Errorln(log.Stt, gtapi, symbol, "setGtex GetPosition success")
-			exs.gtex = ex
-			break
-		}
-
-		_, err := ex.Ex.UpdateLeverage(gtctx)
-		if err != nil {
-			log.Errorln(log.Stt, gtapi, symbol, "setGtex UpdateLeverage set error", initLv)
-		}
+	ex := subShared(gtctx, symbol)
+	if ex == nil {
+		return
+	}
+	pos, err := ex.Setup(context.Background(), accountSetup(symbol))
+	if err != nil {
+		log.Errorln(log.Stt, gtapi, symbol, "setGtex Setup error", err)
+		return
 	}
+	log.Errorln(log.Stt, gtapi, symbol, "setGtex Setup success", pos)
+	exs.gtex = ex
 }
 
 func setBnex(exs *Exs, keys map[string]config.ApiUser) {
@@ -516,84 +513,29 @@ func setBnex(exs *Exs, keys map[string]config.ApiUser) {
 	bnapi := "binance-cd"
 	bnkey := keys[bnapi]
 	bnkey.ApiSign = bnapi
-	_, bnid := confer.GetPubCid(bnkey.ApiSign, symbol)
 	bnctx := exch.ApiCtx(&bnkey)
-	ex := exch.NewExchanger(bnctx, bnid)
-	bnctx = context.WithValue(context.Background(), exch.CtxSymbol, symbol)
-	bnctx = context.WithValue(bnctx, exch.CtxLv, convert.GetString(initLv))
-	_ = ex.Ex.SubUserTrade(bnctx)
-	_ = ex.Ex.SubOrder(bnctx)
-	_ = ex.Ex.SubPosition(bnctx)
-	_ = ex.Ex.SubBalance(bnctx)
-	_ = ex.Ex.SubOrderBook(bnctx)
-	for {
-		time.Sleep(1 * time.Second)
-		bnpos := ex.Ex.GetPosition(bnctx)
-		if bnpos != nil && bnpos.Lv == float64(initLv) {
-			log.Errorln(log.Stt, bnapi, symbol, "setBnex GetPosition success", bnpos)
-			exs.bnex = ex
-			break
-		}
-		_, err := ex.Ex.UpdateLeverage(bnctx)
-		if err == nil {
-			log.Errorln(log.Stt, bnapi, symbol, "setBnex UpdateLeverage success", bnpos)
-			exs.bnex = ex
-			break
-		} else {
-			log.Errorln(log.Stt, bnapi, symbol, "UpdateLeverage set error", initLv)
-		}
+	ex := subShared(bnctx, symbol)
+	if ex == nil {
+		return
+	}
+	pos, err := ex.Setup(context.Background(), accountSetup(symbol))
+	if err != nil {
+		log.Errorln(log.Stt, bnapi, symbol, "setBnex Setup error", err)
+		return
 	}
+	log.Errorln(log.Stt, bnapi, symbol, "setBnex Setup success", pos)
+	exs.bnex = ex
 }
 
-func cannelOrder(exs *Exs) {
-	symbol := exs.symbol
-	var gdone, bdone, gdoneErr, bdoneErr int
-	for {
-		gtex := exs.gtex
-		bnex := exs.bnex
-		if gtex == nil || bnex == nil {
-			break
-		}
-		if gdone == 1 || bdone == 1 {
-			break
-		}
-		if gdone == 1 && bdoneErr > 10 {
-			break
-		}
-		if bdone == 1 && gdoneErr > 10 {
-			break
-		}
-		if bdoneErr > 10 && gdoneErr > 10 {
-			break
-		}
-		ctx := context.WithValue(context.Background(), exch.CtxSymbol, symbol)
-		if gdone == 0 {
-			log.Infoln(log.Stt, "gtex CannelAllOrder start")
-			go func() {
-				_, gerr := gtex.Ex.CannelAllOrder(ctx)
-				if gerr == nil {
-					log.Infoln(log.Stt, "======gtex CannelAllOrder success======")
-					gdone = 1
-				} else {
-					gdoneErr++
-					log.Errorln(log.Stt, "gtex CannelAllOrder error", gerr)
-				}
-			}()
-		}
-		if bdone == 0 {
-			log.Infoln(log.Stt, "bnex CannelAllOrder start")
-			go func() {
-				_, berr := bnex.Ex.CannelAllOrder(ctx)
-				if berr == nil {
-					log.Infoln(log.Stt, "======bnex CannelAllOrder success======")
-					bdone = 1
-				} else {
-					bdoneErr++
-					log.Errorln(log.Stt, "bnex CannelAllOrder error", berr)
-				}
-			}()
-		}
-		time.Sleep(3 * time.Second)
+// accountSetup 单向持仓, 逐仓 initLv 倍杠杆
+func accountSetup(symbol string) exch.AccountSetup {
+	return exch.AccountSetup{
+		Symbol:       symbol,
+		PositionMode: true,
+		MarginMode:   exch.MarginIsolated,
+		Leverage:     initLv,
+		Retries:      30,
+		Interval:     time.Second,
 	}
 }
 
@@ -1070,7 +1012,7 @@ func HedgeClose(exs *Exs) {
 			wg.Add(1)
 			go func(wg *sync.WaitGroup) {
 				log.Infoln(log.Stt, "HedgeClose", symbol, "11 bnex create order  bprice, bsize", bprice, bsize)
-				ctx = context.WithValue(context.Background(), exch.CtxOrder, &exch.Order{Symbol: symbol, Price: bprice, Size: bsize})
+				ctx = context.WithValue(context.Background(), exch.CtxOrder, &exch.Order{Symbol: symbol, Price: bprice, Size: bsize, ReduceOnly: true})
 				_, _ = bnex.Ex.CreateOrder(ctx)
 				wg.Done()
 			}(&wg)
@@ -1098,7 +1040,7 @@ func HedgeClose(exs *Exs) {
 			wg.Add(1)
 			go func(wg *sync.WaitGroup) {
 				log.Infoln(log.Stt, "HedgeClose", symbol, "13 gtex create order  gprice, gsize", gprice, gsize)
-				ctx = context.WithValue(context.Background(), exch.CtxOrder, &exch.Order{Symbol: symbol, Price: gprice, Size: gsize})
+				ctx = context.WithValue(context.Background(), exch.CtxOrder, &exch.Order{Symbol: symbol, Price: gprice, Size: gsize, ReduceOnly: true})
 				_, _ = gtex.Ex.CreateOrder(ctx)
 				wg.Done()
 			}(&wg)